@@ -2364,6 +2364,8 @@ func (m *ListUserExampleReply) validate(all bool) error {
 
 	}
 
+	// no validation rules for NextCursor
+
 	if len(errors) > 0 {
 		return ListUserExampleReplyMultiError(errors)
 	}
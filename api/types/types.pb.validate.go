@@ -96,6 +96,8 @@ func (m *Params) validate(all bool) error {
 
 	}
 
+	// no validation rules for Cursor
+
 	if len(errors) > 0 {
 		return ParamsMultiError(errors)
 	}
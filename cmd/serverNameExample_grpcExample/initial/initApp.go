@@ -15,6 +15,7 @@ import (
 	"github.com/zhufuyi/sponge/pkg/conf"
 	"github.com/zhufuyi/sponge/pkg/logger"
 	"github.com/zhufuyi/sponge/pkg/nacoscli"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
 	"github.com/zhufuyi/sponge/pkg/stat"
 	"github.com/zhufuyi/sponge/pkg/tracer"
 
@@ -49,6 +50,14 @@ func InitApp() {
 	logger.Infof("init %s succeeded", cfg.Database.Driver)
 	model.InitCache(cfg.App.CacheType)
 
+	// initializing the rate limit and circuit breaker policies of routes
+	if cfg.App.EnableLimit || cfg.App.EnableCircuitBreaker {
+		if err = initPolicy(); err != nil {
+			panic("init policy error: " + err.Error())
+		}
+		logger.Info("init policy succeeded")
+	}
+
 	// initializing tracing
 	if cfg.App.EnableTrace {
 		tracer.InitWithConfig(
@@ -109,7 +118,7 @@ func initConfig() {
 		if configFile == "" {
 			configFile = configs.Path("serverNameExample.yml")
 		}
-		err := config.Init(configFile)
+		err := config.Init(configFile, reloadPolicy) // reload the policies when the configuration file changes
 		if err != nil {
 			panic("init config error: " + err.Error())
 		}
@@ -119,3 +128,32 @@ func initConfig() {
 		config.Get().App.Version = version
 	}
 }
+
+// the rate limit policies of tokenBucket and slidingWindow require redis
+func initPolicy() error {
+	policyCfg := &policy.Config{}
+	_ = copier.Copy(policyCfg, &config.Get().Policy)
+
+	var opts []policy.Option
+	if policyCfg.UseRedis() {
+		redisCli, err := model.GetRedisCliE() // not panic when reloading the configuration
+		if err != nil {
+			return err
+		}
+		opts = append(opts, policy.WithRedisClient(redisCli))
+	}
+	return policy.Init(policyCfg, opts...)
+}
+
+func reloadPolicy() {
+	cfg := config.Get()
+	if !cfg.App.EnableLimit && !cfg.App.EnableCircuitBreaker {
+		return
+	}
+
+	if err := initPolicy(); err != nil {
+		logger.Warn("reload policy error", logger.Err(err))
+		return
+	}
+	logger.Info("reload policy succeeded")
+}
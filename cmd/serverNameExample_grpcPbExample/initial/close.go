@@ -26,7 +26,7 @@ func Close(servers []app.IServer) []app.Close {
 	//})
 
 	// close redis
-	//if config.Get().App.CacheType == "redis" {
+	//if config.Get().App.CacheType == "redis" || config.Get().App.CacheType == "multi" {
 	//	closes = append(closes, func() error {
 	//		return model.CloseRedis()
 	//	})
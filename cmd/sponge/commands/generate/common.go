@@ -52,6 +52,7 @@ var (
 	handlerFileMark = "// todo generate the request and response struct to here"
 	handlerTestFile = "handler/userExample_test.go"
 
+	handlerGinFile   = "handler/userExample.go"
 	handlerLogicFile = "handler/userExample_logic.go"
 	serviceLogicFile = "service/userExample.go"
 	embedTimeMark    = "// todo generate the conversion createdAt and updatedAt code here"
@@ -113,6 +114,14 @@ var (
 	wellStartMark = symbolConvert(startMarkStr)
 	wellEndMark   = symbolConvert(endMarkStr)
 
+	// the marked code is replaced according to the primary key of table, the marks are removed
+	lastIDQueryStartMark = "// query by last id code start"
+	lastIDQueryEndMark   = "// query by last id code end"
+	lastIDStartMark      = "// get last id code start"
+	lastIDEndMark        = "// get last id code end"
+	idFromPathStartMark  = "// get id from path code start"
+	idFromPathEndMark    = "// get id from path code end"
+
 	// embed FS template file when using
 	selfPackageName = "github.com/zhufuyi/sponge"
 )
@@ -143,7 +152,10 @@ func convertProjectAndServerName(projectName, serverName string) (pn string, sn
 	return pn, sn
 }
 
-func adjustmentOfIDType(handlerCodes string, dbDriver string) string {
+func adjustmentOfIDType(handlerCodes string, dbDriver string, isDefaultPrimaryKey bool) string {
+	if !isDefaultPrimaryKey { // the type of primary key is kept
+		return handlerCodes
+	}
 	if dbDriver == DBDriverMongodb {
 		return idTypeToStr(handlerCodes)
 	}
@@ -194,6 +206,31 @@ func deleteFieldsMark(r replacer.Replacer, filename string, startMark []byte, en
 	return fields
 }
 
+// replaceMarkedCode replace the code between the start and end mark lines of file with the code returned by fn,
+// the mark lines are removed, no field is returned if the file is not processed.
+func replaceMarkedCode(r replacer.Replacer, filename string, startMark string, endMark string, fn func(code string) string) []replacer.Field {
+	var fields []replacer.Field
+
+	data, err := r.ReadFile(filename)
+	if err != nil {
+		return fields
+	}
+	re := regexp.MustCompile(`(?ms)^[ \t]*` + regexp.QuoteMeta(startMark) + `\r?\n(?:\r?\n)?(.*?)^[ \t]*` +
+		regexp.QuoteMeta(endMark) + `\r?\n((?:\r?\n)?)`)
+	for _, match := range re.FindAllStringSubmatch(string(data), -1) {
+		newCode := fn(strings.TrimRight(match[1], "\r\n"))
+		if newCode != "" {
+			newCode += "\n"
+		}
+		fields = append(fields, replacer.Field{
+			Old: match[0],
+			New: newCode + match[2], // keep the blank line after the end mark
+		})
+	}
+
+	return fields
+}
+
 // DeleteCodeMark delete code mark fragment
 func DeleteCodeMark(r replacer.Replacer, filename string, startMark []byte, endMark []byte) []replacer.Field {
 	return deleteFieldsMark(r, filename, startMark, endMark)
@@ -19,6 +19,7 @@ func DaoCommand(parentName string) *cobra.Command {
 		outPath         string // output directory
 		dbTables        string // table names
 		isIncludeInitDB bool
+		isStats         bool // generate the aggregate statistics method
 
 		sqlArgs = sql2code.Args{
 			Package:  "model",
@@ -56,6 +57,7 @@ Examples:
 			}
 
 			tableNames := strings.Split(dbTables, ",")
+			sqlArgs.RelatedTables = tableNames // parse the relations between tables in the same batch
 			for count, tableName := range tableNames {
 				if tableName == "" {
 					continue
@@ -81,6 +83,7 @@ Examples:
 					moduleName:      moduleName,
 					dbDriver:        sqlArgs.DBDriver,
 					isIncludeInitDB: isIncludeInitDB,
+					isStats:         isStats,
 					codes:           codes,
 					outPath:         outPath,
 				}
@@ -112,6 +115,7 @@ using help:
 	cmd.Flags().StringVarP(&outPath, "out", "o", "", "output directory, default is ./dao_<time>, "+
 		"if you specify the directory where the web or microservice generated by sponge, the module-name flag can be ignored")
 	cmd.Flags().BoolVarP(&isIncludeInitDB, "include-init-db", "i", false, "if true, includes mysql and redis initialization code")
+	cmd.Flags().BoolVarP(&isStats, "stats", "", false, "whether to generate the api of aggregate statistics, e.g. count the records of each group")
 
 	return cmd
 }
@@ -120,6 +124,7 @@ type daoGenerator struct {
 	moduleName      string
 	dbDriver        string
 	isIncludeInitDB bool
+	isStats         bool
 	codes           map[string]string
 	outPath         string
 }
@@ -160,6 +165,13 @@ func (g *daoGenerator) generateCode() (string, error) {
 		return "", errors.New("unsupported db driver: " + g.dbDriver)
 	}
 
+	pk := newPrimaryKey(g.codes)
+	if err := pk.check(g.codes[parser.TableName], false); err != nil {
+		return "", err
+	}
+	ignoreFiles = append(ignoreFiles, pk.ignoreFiles()...)
+	ignoreFiles = append(ignoreFiles, newRelation(g.codes).ignoreFiles()...)
+
 	r.SetSubDirsAndFiles(subDirs)
 	r.SetIgnoreSubDirs(ignoreDirs...)
 	r.SetIgnoreSubFiles(ignoreFiles...)
@@ -181,6 +193,10 @@ func (g *daoGenerator) addFields(r replacer.Replacer) []replacer.Field {
 	fields = append(fields, deleteFieldsMark(r, daoFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, daoMgoFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, daoTestFile, startMark, endMark)...)
+	fields = append(fields, newPrimaryKey(g.codes).replacementFields(r)...)
+	fields = append(fields, newRelation(g.codes).replacementFields()...)
+	fields = append(fields, newQueryColumn(g.codes).replacementFields()...)
+	fields = append(fields, newStats(g.isStats).replacementFields()...)
 	fields = append(fields, []replacer.Field{
 		{ // replace the contents of the model/userExample.go file
 			Old: modelFileMark,
@@ -0,0 +1,163 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/gofile"
+	"github.com/zhufuyi/sponge/pkg/openapi2code"
+
+	"github.com/spf13/cobra"
+)
+
+// HandlerOpenAPICommand generate handler code based on openapi 3 document
+func HandlerOpenAPICommand() *cobra.Command {
+	var (
+		moduleName  string // module name for go.mod
+		serverName  string // server name
+		serviceName string // service name of operations without tags
+		file        string // openapi document file
+		outPath     string // output directory
+	)
+
+	cmd := &cobra.Command{
+		Use:   "openapi",
+		Short: "Generate gin router, handler, types and error code based on openapi 3 document",
+		Long: `generate gin router, handler, types and error code based on openapi 3 document.
+
+Examples:
+  # generate handler code.
+  sponge web openapi --module-name=yourModuleName --server-name=yourServerName --file=./api/petstore.yaml
+
+  # generate handler code and specify the server directory, Note: the files in api and docs are overwritten,
+  # if the other generated file already exists, it is saved as a new file with suffix .gen<time>,
+  # use the command "sponge merge http-pb" to merge code.
+  sponge web openapi --file=./api/petstore.yaml --out=./yourServerDir
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mdName, srvName := getNamesFromOutDir(outPath)
+			if mdName != "" {
+				moduleName = mdName
+			} else if moduleName == "" {
+				return errors.New(`required flag(s) "module-name" not set, use "sponge web openapi -h" for help`)
+			}
+			if srvName != "" {
+				serverName = srvName
+			} else if serverName == "" {
+				return errors.New(`required flag(s) "server-name" not set, use "sponge web openapi -h" for help`)
+			}
+			serverName = convertServerName(serverName)
+
+			oaArgs := &openapi2code.Args{
+				File:        file,
+				ModuleName:  moduleName,
+				ServerName:  serverName,
+				ServiceName: serviceName,
+			}
+			codes, err := openapi2code.Generate(oaArgs)
+			if err != nil {
+				return err
+			}
+
+			g := &handlerOpenAPIGenerator{
+				serverName: serverName,
+				prefix:     oaArgs.GetFilenamePrefix(),
+				codes:      codes,
+				outPath:    outPath,
+			}
+			outPath, err = g.generateCode()
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf(`
+using help:
+  1. move the folders "api", "internal" and "docs" to your project code folder, if there are files with the suffix .gen<time>,
+     merge them with the command: sponge merge http-pb
+  2. fill in the business logic code in internal/handler.
+  3. compile and run service: make run
+  4. visit http://localhost:8080/apis/swagger/index.html in your browser, and test the api interface.
+
+`)
+			fmt.Printf("generate \"openapi\" code successfully, out = %s\n", outPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "openapi 3 document file, support yaml and json")
+	_ = cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVarP(&moduleName, "module-name", "m", "", "module-name is the name of the module in the go.mod file")
+	cmd.Flags().StringVarP(&serverName, "server-name", "s", "", "server name")
+	cmd.Flags().StringVarP(&serviceName, "service-name", "n", "", "service name of the operations without tags, default is the file name")
+	cmd.Flags().StringVarP(&outPath, "out", "o", "", "output directory, default is ./openapi_<time>,"+
+		" if you specify the directory where the web service generated by sponge, the module-name and server-name flag can be ignored")
+
+	return cmd
+}
+
+type handlerOpenAPIGenerator struct {
+	serverName string
+	prefix     string
+	codes      map[string]string
+	outPath    string
+}
+
+func (g *handlerOpenAPIGenerator) generateCode() (string, error) {
+	if g.outPath == "" {
+		pwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		g.outPath = pwd + gofile.GetPathDelimiter() + "openapi_" + time.Now().Format("150405")
+	} else {
+		abs, err := filepath.Abs(g.outPath)
+		if err != nil {
+			return "", err
+		}
+		g.outPath = abs
+	}
+
+	files := []struct {
+		codeType  string
+		file      string
+		overwrite bool // the file is generated only and must not be edited, e.g. DO NOT EDIT
+	}{
+		{openapi2code.CodeTypeTypes, "api/" + g.serverName + "/v1/" + g.prefix + "_types.go", true},
+		{openapi2code.CodeTypeRouter, "api/" + g.serverName + "/v1/" + g.prefix + "_router.go", true},
+		{openapi2code.CodeTypeHandler, "internal/handler/" + g.prefix + ".go", false},
+		{openapi2code.CodeTypeHandlerRouter, "internal/routers/" + g.prefix + "_router.go", false},
+		{openapi2code.CodeTypeErrCode, "internal/ecode/" + g.prefix + "_http.go", false},
+		{openapi2code.CodeTypeSwagger, "docs/apis.swagger.json", true},
+	}
+	for _, f := range files {
+		if err := saveOpenAPIFile(g.outPath, f.file, g.codes[f.codeType], f.overwrite); err != nil {
+			return "", err
+		}
+	}
+
+	return g.outPath, nil
+}
+
+// the generated only file is overwritten, the other files may be modified by the user,
+// if they already exist, save them as new files with suffix .gen<time>
+func saveOpenAPIFile(outPath string, file string, content string, overwrite bool) error {
+	if content == "" {
+		return nil
+	}
+
+	file = filepath.Join(outPath, file)
+	_ = os.MkdirAll(filepath.Dir(file), 0766)
+	if !overwrite && gofile.IsExists(file) {
+		file += ".gen" + time.Now().Format("20060102T150405")
+	}
+	err := os.WriteFile(file, []byte(content), 0666)
+	if err != nil {
+		return fmt.Errorf("save file %s error, %v", file, err)
+	}
+	return nil
+}
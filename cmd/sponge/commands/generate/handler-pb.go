@@ -69,6 +69,7 @@ Examples:
 			}
 
 			tableNames := strings.Split(dbTables, ",")
+			sqlArgs.RelatedTables = tableNames // parse the relations between tables in the same batch
 			for _, tableName := range tableNames {
 				if tableName == "" {
 					continue
@@ -172,6 +173,13 @@ func (g *handlerPbGenerator) generateCode() (string, error) {
 		return "", errors.New("unsupported db driver: " + g.dbDriver)
 	}
 
+	pk := newPrimaryKey(g.codes)
+	if err := pk.check(g.codes[parser.TableName], true); err != nil {
+		return "", err
+	}
+	ignoreFiles = append(ignoreFiles, pk.ignoreFiles()...)
+	ignoreFiles = append(ignoreFiles, newRelation(g.codes).ignoreFiles()...)
+
 	r.SetSubDirsAndFiles(subDirs)
 	r.SetIgnoreSubDirs(ignoreDirs...)
 	r.SetIgnoreSubFiles(ignoreFiles...)
@@ -194,6 +202,9 @@ func (g *handlerPbGenerator) addFields(r replacer.Replacer) []replacer.Field {
 	fields = append(fields, deleteFieldsMark(r, daoTestFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, handlerLogicFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, protoFile, startMark, endMark)...)
+	fields = append(fields, newPrimaryKey(g.codes).replacementFields(r)...)
+	fields = append(fields, newRelation(g.codes).replacementFields()...)
+	fields = append(fields, newQueryColumn(g.codes).replacementFields()...)
 	fields = append(fields, []replacer.Field{
 		{ // replace the contents of the model/userExample.go file
 			Old: modelFileMark,
@@ -205,7 +216,7 @@ func (g *handlerPbGenerator) addFields(r replacer.Replacer) []replacer.Field {
 		},
 		{ // replace the contents of the handler/userExample_logic.go file
 			Old: embedTimeMark,
-			New: getEmbedTimeCode(g.isEmbed),
+			New: getEmbedTimeCode(g.isEmbed && newPrimaryKey(g.codes).isDefault()),
 		},
 		{ // replace the contents of the v1/userExample.proto file
 			Old: protoFileMark,
@@ -19,6 +19,7 @@ func HandlerCommand() *cobra.Command {
 		moduleName string // module name for go.mod
 		outPath    string // output directory
 		dbTables   string // table names
+		isStats    bool   // generate the api of aggregate statistics
 
 		sqlArgs = sql2code.Args{
 			Package:  "model",
@@ -42,6 +43,9 @@ Examples:
   # generate handler code, structure fields correspond to the column names of the table.
   sponge web handler --module-name=yourModuleName --db-driver=mysql --db-dsn=root:123456@(192.168.3.37:3306)/test --db-table=user --embed=false
 
+  # generate handler code with the api of aggregate statistics, e.g. count the records of each group.
+  sponge web handler --module-name=yourModuleName --db-driver=mysql --db-dsn=root:123456@(192.168.3.37:3306)/test --db-table=user --stats=true
+
   # generate handler code and specify the server directory, Note: code generation will be canceled when the latest generated file already exists.
   sponge web handler --db-driver=mysql --db-dsn=root:123456@(192.168.3.37:3306)/test --db-table=user --out=./yourServerDir
 `,
@@ -59,6 +63,7 @@ Examples:
 			}
 
 			tableNames := strings.Split(dbTables, ",")
+			sqlArgs.RelatedTables = tableNames // parse the relations between tables in the same batch
 			for _, tableName := range tableNames {
 				if tableName == "" {
 					continue
@@ -73,6 +78,7 @@ Examples:
 				g := &handlerGenerator{
 					moduleName: moduleName,
 					dbDriver:   sqlArgs.DBDriver,
+					isStats:    isStats,
 					codes:      codes,
 					outPath:    outPath,
 				}
@@ -104,6 +110,7 @@ using help:
 	_ = cmd.MarkFlagRequired("db-table")
 	cmd.Flags().BoolVarP(&sqlArgs.IsEmbed, "embed", "e", true, "whether to embed gorm.model struct")
 	cmd.Flags().IntVarP(&sqlArgs.JSONNamedType, "json-name-type", "j", 1, "json tags name type, 0:snake case, 1:camel case")
+	cmd.Flags().BoolVarP(&isStats, "stats", "", false, "whether to generate the api of aggregate statistics, e.g. count the records of each group")
 	cmd.Flags().StringVarP(&outPath, "out", "o", "", "output directory, default is ./handler_<time>, "+
 		"if you specify the directory where the web or microservice generated by sponge, the module-name flag can be ignored")
 
@@ -113,6 +120,7 @@ using help:
 type handlerGenerator struct {
 	moduleName string
 	dbDriver   string
+	isStats    bool
 	codes      map[string]string
 	outPath    string
 }
@@ -154,6 +162,13 @@ func (g *handlerGenerator) generateCode() (string, error) {
 		return "", errors.New("unsupported db driver: " + g.dbDriver)
 	}
 
+	pk := newPrimaryKey(g.codes)
+	if err := pk.check(g.codes[parser.TableName], false); err != nil {
+		return "", err
+	}
+	ignoreFiles = append(ignoreFiles, pk.ignoreFiles()...)
+	ignoreFiles = append(ignoreFiles, newRelation(g.codes).ignoreFiles()...)
+
 	r.SetSubDirsAndFiles(subDirs)
 	r.SetIgnoreSubDirs(ignoreDirs...)
 	r.SetIgnoreSubFiles(ignoreFiles...)
@@ -177,6 +192,10 @@ func (g *handlerGenerator) addFields(r replacer.Replacer) []replacer.Field {
 	fields = append(fields, deleteFieldsMark(r, handlerFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, handlerMgoFile, startMark, endMark)...)
 	fields = append(fields, deleteFieldsMark(r, handlerTestFile, startMark, endMark)...)
+	fields = append(fields, newPrimaryKey(g.codes).replacementFields(r)...)
+	fields = append(fields, newRelation(g.codes).replacementFields()...)
+	fields = append(fields, newQueryColumn(g.codes).replacementFields()...)
+	fields = append(fields, newStats(g.isStats).replacementFields()...)
 	fields = append(fields, []replacer.Field{
 		{ // replace the contents of the model/userExample.go file
 			Old: modelFileMark,
@@ -188,7 +207,7 @@ func (g *handlerGenerator) addFields(r replacer.Replacer) []replacer.Field {
 		},
 		{ // replace the contents of the handler/userExample.go file
 			Old: handlerFileMark,
-			New: adjustmentOfIDType(g.codes[parser.CodeTypeHandler], g.dbDriver),
+			New: adjustmentOfIDType(g.codes[parser.CodeTypeHandler], g.dbDriver, newPrimaryKey(g.codes).isDefault()),
 		},
 		{
 			Old: selfPackageName + "/" + r.GetSourcePath(),
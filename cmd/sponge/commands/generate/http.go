@@ -23,6 +23,7 @@ func HTTPCommand() *cobra.Command {
 		repoAddr    string // image repo address
 		outPath     string // output directory
 		dbTables    string // table names
+		isStats     bool   // generate the api of aggregate statistics
 		sqlArgs     = sql2code.Args{
 			Package:  "model",
 			JSONTag:  true,
@@ -58,6 +59,7 @@ Examples:
 			var firstTable string
 			var handlerTableNames []string
 			tableNames := strings.Split(dbTables, ",")
+			sqlArgs.RelatedTables = tableNames // parse the relations between tables in the same batch
 			if len(tableNames) == 1 {
 				firstTable = tableNames[0]
 			} else if len(tableNames) > 1 {
@@ -82,6 +84,7 @@ Examples:
 				repoAddr:    repoAddr,
 				dbDSN:       sqlArgs.DBDsn,
 				dbDriver:    sqlArgs.DBDriver,
+				isStats:     isStats,
 				codes:       codes,
 				outPath:     outPath,
 			}
@@ -104,6 +107,7 @@ Examples:
 				hg := &handlerGenerator{
 					moduleName: moduleName,
 					dbDriver:   sqlArgs.DBDriver,
+					isStats:    isStats,
 					codes:      codes,
 					outPath:    outPath,
 				}
@@ -139,6 +143,7 @@ using help:
 	cmd.Flags().StringVarP(&dbTables, "db-table", "t", "", "table name, multiple names separated by commas")
 	_ = cmd.MarkFlagRequired("db-table")
 	cmd.Flags().BoolVarP(&sqlArgs.IsEmbed, "embed", "e", true, "whether to embed gorm.model struct")
+	cmd.Flags().BoolVarP(&isStats, "stats", "", false, "whether to generate the api of aggregate statistics, e.g. count the records of each group")
 	cmd.Flags().IntVarP(&sqlArgs.JSONNamedType, "json-name-type", "j", 1, "json tags name type, 0:snake case, 1:camel case")
 	cmd.Flags().StringVarP(&repoAddr, "repo-addr", "r", "", "docker image repository address, excluding http and repository names")
 	cmd.Flags().StringVarP(&outPath, "out", "o", "", "output directory, default is ./serverName_http_<time>")
@@ -153,6 +158,7 @@ type httpGenerator struct {
 	repoAddr    string
 	dbDSN       string
 	dbDriver    string
+	isStats     bool
 	codes       map[string]string
 	outPath     string
 }
@@ -208,6 +214,13 @@ func (g *httpGenerator) generateCode() (string, error) {
 		return "", errors.New("unsupported db driver: " + g.dbDriver)
 	}
 
+	pk := newPrimaryKey(g.codes)
+	if err := pk.check(g.codes[parser.TableName], false); err != nil {
+		return "", err
+	}
+	ignoreFiles = append(ignoreFiles, pk.ignoreFiles()...)
+	ignoreFiles = append(ignoreFiles, newRelation(g.codes).ignoreFiles()...)
+
 	r.SetSubDirsAndFiles(subDirs, subFiles...)
 	r.SetIgnoreSubDirs(ignoreDirs...)
 	r.SetIgnoreSubFiles(ignoreFiles...)
@@ -249,6 +262,10 @@ func (g *httpGenerator) addFields(r replacer.Replacer) []replacer.Field {
 	fields = append(fields, deleteAllFieldsMark(r, appConfigFile, wellStartMark, wellEndMark)...)
 	//fields = append(fields, deleteFieldsMark(r, deploymentConfigFile, wellStartMark, wellEndMark)...)
 	fields = append(fields, replaceFileContentMark(r, readmeFile, "## "+g.serverName)...)
+	fields = append(fields, newPrimaryKey(g.codes).replacementFields(r)...)
+	fields = append(fields, newRelation(g.codes).replacementFields()...)
+	fields = append(fields, newQueryColumn(g.codes).replacementFields()...)
+	fields = append(fields, newStats(g.isStats).replacementFields()...)
 	fields = append(fields, []replacer.Field{
 		{ // replace the configuration of the *.yml file
 			Old: appConfigFileMark,
@@ -272,7 +289,7 @@ func (g *httpGenerator) addFields(r replacer.Replacer) []replacer.Field {
 		},
 		{ // replace the contents of the handler/userExample.go file
 			Old: handlerFileMark,
-			New: adjustmentOfIDType(g.codes[parser.CodeTypeHandler], g.dbDriver),
+			New: adjustmentOfIDType(g.codes[parser.CodeTypeHandler], g.dbDriver, newPrimaryKey(g.codes).isDefault()),
 		},
 		{ // replace the contents of the Dockerfile file
 			Old: dockerFileMark,
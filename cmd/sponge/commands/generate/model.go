@@ -47,6 +47,7 @@ Examples:
 		SilenceUsage:  true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			tableNames := strings.Split(dbTables, ",")
+			sqlArgs.RelatedTables = tableNames // parse the relations between tables in the same batch
 			for _, tableName := range tableNames {
 				if tableName == "" {
 					continue
@@ -0,0 +1,393 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zhufuyi/sponge/pkg/replacer"
+	"github.com/zhufuyi/sponge/pkg/sql2code/parser"
+)
+
+// the template code uses a single primary key named id and the type is uint64,
+// primaryKey generates the replacement fields of the template code according to the real primary key of the table.
+type primaryKey struct {
+	fields []parser.PrimaryKeyField
+}
+
+func newPrimaryKey(codes map[string]string) *primaryKey {
+	return &primaryKey{fields: parser.GetPrimaryKeys(codes)}
+}
+
+func (p *primaryKey) isDefault() bool {
+	return parser.IsDefaultPrimaryKey(p.fields)
+}
+
+func (p *primaryKey) isComposite() bool {
+	return parser.IsCompositePrimaryKey(p.fields)
+}
+
+// check whether the primary key is supported, isProtobuf indicates that the code is generated based on protobuf
+func (p *primaryKey) check(tableName string, isProtobuf bool) error {
+	if p.isDefault() {
+		return nil
+	}
+	if p.isComposite() {
+		if isProtobuf {
+			return fmt.Errorf("the composite primary key of table %s is not supported when generating code based on protobuf", tableName)
+		}
+		for _, field := range p.fields {
+			if field.GoType != "string" && !isIntegerGoType(field.GoType) {
+				return fmt.Errorf("unsupported type %s of primary key %s in table %s", field.GoType, field.ColName, tableName)
+			}
+		}
+		return nil
+	}
+
+	if goType := p.fields[0].GoType; goType != "uint64" && goType != "string" {
+		return fmt.Errorf("unsupported type %s of primary key %s in table %s", goType, p.fields[0].ColName, tableName)
+	}
+	return nil
+}
+
+// ignore the template test files, they use the default primary key
+func (p *primaryKey) ignoreFiles() []string {
+	if p.isDefault() {
+		return nil
+	}
+	return []string{
+		"dao/userExample_test.go", "cache/userExample_test.go",
+		"handler/userExample_test.go", "handler/userExample_logic_test.go",
+		"service/userExample_test.go", "service/userExample_client_test.go",
+	}
+}
+
+// type of id in the template code
+func (p *primaryKey) goType() string {
+	if p.isComposite() {
+		return "model.UserExampleKey"
+	}
+	return p.fields[0].GoType
+}
+
+// expression to get the primary key from record in the template code
+func (p *primaryKey) getter() string {
+	if p.isComposite() {
+		return ".Key()"
+	}
+	return "." + p.fields[0].Name
+}
+
+// replacementFields the replacement fields must be set before the field that replaces UserExample
+func (p *primaryKey) replacementFields(r replacer.Replacer) []replacer.Field {
+	fields := p.markedCodeFields(r)
+	if p.isDefault() {
+		return fields
+	}
+
+	first := p.fields[0]
+	goType := p.goType()
+	fields = append(fields, []replacer.Field{
+		// types
+		{Old: "id uint64", New: "id " + goType},
+		{Old: "ids []uint64", New: "ids []" + goType},
+		{Old: "ids ...uint64", New: "ids ..." + goType},
+		{Old: "IDs []uint64", New: "IDs []" + goType},
+		{Old: "map[uint64]*model.UserExample", New: "map[" + goType + "]*model.UserExample"},
+		{Old: "[uint64, *model.UserExample]", New: "[" + goType + ", *model.UserExample]"},
+		{Old: "var missedIDs []uint64", New: "var missedIDs []" + goType},
+		{Old: "var realMissedIDs []uint64", New: "var realMissedIDs []" + goType},
+		{Old: "*model.UserExample) (uint64, error)", New: "*model.UserExample) (" + goType + ", error)"},
+		{Old: "lastID uint64", New: "lastID " + goType},
+
+		// dao query
+		{Old: `Where("id = ?", id)`, New: p.whereByID()},
+		{Old: `Where("id IN (?)", ids)`, New: p.whereByIDs("ids")},
+		{Old: `Where("id IN (?)", realMissedIDs)`, New: p.whereByIDs("realMissedIDs")},
+		{Old: `Select([]string{"id"})`, New: fmt.Sprintf(`Select([]string{"%s"})`, first.ColName)},
+		{Old: "\torder, limit, offset := params.ConvertToPage()\n",
+			New: fmt.Sprintf("\tif params.Sort == \"\" {\n\t\tparams.Sort = \"%s\"\n\t}\n\torder, limit, offset := params.ConvertToPage()\n", p.defaultSort())},
+		{Old: "if table.ID < 1 {\n\t\treturn errors.New(\"id cannot be 0\")", New: p.checkRecordID()},
+		{Old: "if data == nil || id == 0 {", New: fmt.Sprintf("if data == nil || %s {", p.isZero("id"))},
+		{Old: "utils.Uint64ToStr(id)", New: "utils.AnyToStr(id)"},
+		{Old: "id=%d", New: "id=%v"},
+
+		// handler
+		{Old: "\tdata.ID = idStr\n", New: ""},
+		{Old: "idStr, id, isAbort := getUserExampleIDFromPath(c)", New: "_, id, isAbort := getUserExampleIDFromPath(c)"},
+		{Old: "\tdata.ID = utils.Uint64ToStr(userExample.ID)\n", New: ""},
+		{Old: `gin.H{"id": userExample.ID}`, New: p.createdID()},
+		{Old: "\tform.ID = id\n", New: p.setFormID()},
+	}...)
+
+	// last id, the code of getting last id is replaced by markedCodeFields
+	switch {
+	case p.isComposite():
+		var docParams []string
+		for _, field := range p.fields {
+			docParams = append(docParams, fmt.Sprintf(`// @Param %s query string false "%s of the last record"`, field.JSONName, field.ColName))
+		}
+		fields = append(fields, []replacer.Field{
+			{Old: `// @Param lastID query int true "last id, default is MaxInt32"`, New: strings.Join(docParams, "\n")},
+			{Old: `logger.Uint64("latsID", lastID)`, New: `logger.Any("lastID", lastID)`},
+			{Old: "\"errors\"\n\t\"math\"\n", New: "\"errors\"\n"},
+		}...)
+	case first.GoType == "string":
+		fields = append(fields, []replacer.Field{
+			{Old: `// @Param lastID query int true "last id, default is MaxInt32"`, New: `// @Param lastID query string false "last id"`},
+			{Old: `logger.Uint64("latsID", lastID)`, New: `logger.String("lastID", lastID)`},
+			{Old: "\"errors\"\n\t\"math\"\n", New: "\"errors\"\n"},
+		}...)
+	}
+
+	// record id, must be after the handler fields
+	getter := p.getter()
+	for _, name := range []string{"table", "record", "data", "v", "userExample"} {
+		fields = append(fields, replacer.Field{Old: name + ".ID", New: name + getter})
+	}
+
+	// route path
+	if p.isComposite() || first.JSONName != "id" {
+		var ginPath, docPath, docParams []string
+		for _, field := range p.fields {
+			ginPath = append(ginPath, ":"+field.JSONName)
+			docPath = append(docPath, "{"+field.JSONName+"}")
+			docParams = append(docParams, fmt.Sprintf(`// @Param %s path string true "%s"`, field.JSONName, field.ColName))
+		}
+		fields = append(fields, []replacer.Field{
+			{Old: `/userExample/:id"`, New: `/userExample/` + strings.Join(ginPath, "/") + `"`},
+			{Old: "/userExample/{id} [", New: "/userExample/" + strings.Join(docPath, "/") + " ["},
+			{Old: `// @Param id path string true "id"`, New: strings.Join(docParams, "\n")},
+		}...)
+	}
+
+	// the name of id field in protobuf message
+	if !p.isComposite() && first.JSONName != "id" {
+		name := protoGoName(first.JSONName)
+		fields = append(fields, []replacer.Field{
+			{Old: "req.Id)", New: "req." + name + ")"},
+			{Old: "req.Id,", New: "req." + name + ","},
+			{Old: "req.Id\n", New: "req." + name + "\n"},
+			{Old: "{Id: ", New: "{" + name + ": "},
+			{Old: "value.Id = ", New: "value." + name + " = "},
+		}...)
+	}
+
+	// import model package in types
+	if p.isComposite() {
+		fields = append(fields, replacer.Field{
+			Old: "\t\"github.com/zhufuyi/sponge/pkg/ggorm/query\"\n)\n\nvar _ time.Time",
+			New: "\t\"github.com/zhufuyi/sponge/internal/model\"\n\t\"github.com/zhufuyi/sponge/pkg/ggorm/query\"\n)\n\nvar _ time.Time",
+		})
+	}
+
+	return fields
+}
+
+func (p *primaryKey) defaultSort() string {
+	var ss []string
+	for _, field := range p.fields {
+		ss = append(ss, "-"+field.ColName)
+	}
+	return strings.Join(ss, ",")
+}
+
+func (p *primaryKey) whereByID() string {
+	if p.isComposite() {
+		return "Where(id.Conditions())"
+	}
+	return fmt.Sprintf(`Where("%s = ?", id)`, p.fields[0].ColName)
+}
+
+func (p *primaryKey) whereByIDs(name string) string {
+	if p.isComposite() {
+		return fmt.Sprintf(`Where(model.UserExampleKeyColumns+" IN ?", model.UserExampleKeyValues(%s))`, name)
+	}
+	return fmt.Sprintf(`Where("%s IN (?)", %s)`, p.fields[0].ColName, name)
+}
+
+// markedCodeFields replace the marked code of template according to the primary key, the marks are removed
+func (p *primaryKey) markedCodeFields(r replacer.Replacer) []replacer.Field {
+	keep := func(code string) string { return code }
+	queryByLastID, getLastID, getPbLastID, getIDFromPath := keep, keep, keep, keep
+	if !p.isDefault() {
+		queryByLastID, getLastID, getPbLastID, getIDFromPath = p.queryByLastIDCode, p.getLastIDCode, p.getPbLastIDCode, p.getIDFromPathCode
+	}
+
+	var fields []replacer.Field
+	fields = append(fields, replaceMarkedCode(r, daoFile, lastIDQueryStartMark, lastIDQueryEndMark, queryByLastID)...)
+	fields = append(fields, replaceMarkedCode(r, handlerGinFile, lastIDStartMark, lastIDEndMark, getLastID)...)
+	fields = append(fields, replaceMarkedCode(r, handlerLogicFile, lastIDStartMark, lastIDEndMark, getPbLastID)...)
+	fields = append(fields, replaceMarkedCode(r, serviceLogicFile, lastIDStartMark, lastIDEndMark, getPbLastID)...)
+	fields = append(fields, replaceMarkedCode(r, handlerGinFile, idFromPathStartMark, idFromPathEndMark, getIDFromPath)...)
+	return fields
+}
+
+// paging by the last record, the composite primary key uses the keyset condition of all key columns
+func (p *primaryKey) queryByLastIDCode(string) string {
+	first := p.fields[0]
+	if p.isComposite() {
+		return fmt.Sprintf(`	// the records must be sorted by all primary key columns, otherwise the records are skipped or repeated
+	page := query.NewPage(0, limit, "%s")
+
+	records := []*model.UserExample{}
+	db := d.db.WithContext(ctx).Order(page.Sort()).Limit(page.Size())
+	if !lastID.IsZero() {
+		db = db.Where(model.UserExampleKeyColumns+" < ?", lastID.Values())
+	}
+	err := db.Find(&records).Error`, p.defaultSort())
+	}
+
+	code := fmt.Sprintf(`	if sort == "" {
+		sort = "%s"
+	}
+	page := query.NewPage(0, limit, sort)
+
+	records := []*model.UserExample{}
+`, p.defaultSort())
+	if first.GoType == "string" {
+		return code + fmt.Sprintf(`	db := d.db.WithContext(ctx).Order(page.Sort()).Limit(page.Size())
+	if lastID != "" {
+		db = db.Where("%s < ?", lastID)
+	}
+	err := db.Find(&records).Error`, first.ColName)
+	}
+	return code + fmt.Sprintf(`	err := d.db.WithContext(ctx).Order(page.Sort()).Limit(page.Size()).Where("%s < ?", lastID).Find(&records).Error`, first.ColName)
+}
+
+// get the last id from the query parameters, the composite primary key gets each column
+func (p *primaryKey) getLastIDCode(code string) string {
+	if p.isComposite() {
+		code = "\tlastID := model.UserExampleKey{}"
+		for _, field := range p.fields {
+			switch field.GoType {
+			case "string":
+				code += fmt.Sprintf("\n\tlastID.%s = c.Query(\"%s\")", field.Name, field.JSONName)
+			case "uint64":
+				code += fmt.Sprintf("\n\tlastID.%s = utils.StrToUint64(c.Query(\"%s\"))", field.Name, field.JSONName)
+			default:
+				code += fmt.Sprintf("\n\tlastID.%s = %s(utils.StrToUint64(c.Query(\"%s\")))", field.Name, field.GoType, field.JSONName)
+			}
+		}
+		return code
+	}
+	if p.fields[0].GoType == "string" {
+		return "\tlastID := c.Query(\"lastID\")"
+	}
+	return code
+}
+
+// the default last id of protobuf request, the composite primary key is not supported by protobuf
+func (p *primaryKey) getPbLastIDCode(code string) string {
+	if p.fields[0].GoType == "string" {
+		return ""
+	}
+	return code
+}
+
+func (p *primaryKey) isZero(name string) string {
+	if p.isComposite() {
+		return name + ".IsZero()"
+	}
+	if p.fields[0].GoType == "string" {
+		return name + ` == ""`
+	}
+	return name + " == 0"
+}
+
+func (p *primaryKey) checkRecordID() string {
+	if p.isComposite() {
+		return "if table.Key().IsZero() {\n\t\treturn errors.New(\"primary key cannot be empty\")"
+	}
+	field := p.fields[0]
+	if field.GoType == "string" {
+		return fmt.Sprintf("if table.%s == \"\" {\n\t\treturn errors.New(\"%s cannot be empty\")", field.Name, field.ColName)
+	}
+	return fmt.Sprintf("if table.%s < 1 {\n\t\treturn errors.New(\"%s cannot be 0\")", field.Name, field.ColName)
+}
+
+func (p *primaryKey) createdID() string {
+	var ss []string
+	for _, field := range p.fields {
+		ss = append(ss, fmt.Sprintf(`"%s": userExample.%s`, field.JSONName, field.Name))
+	}
+	return "gin.H{" + strings.Join(ss, ", ") + "}"
+}
+
+func (p *primaryKey) setFormID() string {
+	if !p.isComposite() {
+		return fmt.Sprintf("\tform.%s = id\n", p.fields[0].Name)
+	}
+	code := ""
+	for _, field := range p.fields {
+		code += fmt.Sprintf("\tform.%s = id.%s\n", field.Name, field.Name)
+	}
+	return code
+}
+
+func (p *primaryKey) getIDFromPathCode(code string) string {
+	if !p.isComposite() {
+		field := p.fields[0]
+		if field.GoType == "string" {
+			return fmt.Sprintf(`func getUserExampleIDFromPath(c *gin.Context) (string, string, bool) {
+	id := c.Param("%s")
+	if id == "" {
+		logger.Warn("%s is empty", middleware.GCtxRequestIDField(c))
+		return "", "", true
+	}
+
+	return id, id, false
+}`, field.JSONName, field.JSONName)
+		}
+		return strings.Replace(code, `c.Param("id")`, fmt.Sprintf(`c.Param("%s")`, field.JSONName), 1)
+	}
+
+	code = "func getUserExampleIDFromPath(c *gin.Context) (string, model.UserExampleKey, bool) {\n\tid := model.UserExampleKey{}\n"
+	for _, field := range p.fields {
+		if field.GoType == "string" {
+			code += fmt.Sprintf(`	id.%s = c.Param("%s")
+	if id.%s == "" {
+		logger.Warn("%s is empty", middleware.GCtxRequestIDField(c))
+		return "", id, true
+	}
+`, field.Name, field.JSONName, field.Name, field.JSONName)
+			continue
+		}
+		varName := strings.ToLower(field.Name[:1]) + field.Name[1:]
+		code += fmt.Sprintf(`	%s, err := utils.StrToUint64E(c.Param("%s"))
+	if err != nil || %s == 0 {
+		logger.Warn("StrToUint64E error: ", logger.String("%s", c.Param("%s")), middleware.GCtxRequestIDField(c))
+		return "", id, true
+	}
+	id.%s = %s(%s)
+`, varName, field.JSONName, varName, field.JSONName, field.JSONName, field.Name, field.GoType, varName)
+	}
+	code += "\n\treturn id.String(), id, false\n}"
+	return code
+}
+
+// field name of the generated go code of protobuf, e.g. user_id --> UserId, userID --> UserID
+func protoGoName(name string) string {
+	b := []byte{}
+	upperNext := true
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == '_' {
+			upperNext = true
+			continue
+		}
+		if upperNext && c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upperNext = c >= '0' && c <= '9'
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+func isIntegerGoType(goType string) bool {
+	switch goType {
+	case "int8", "int16", "int32", "int64", "int", "uint8", "uint16", "uint32", "uint64", "uint":
+		return true
+	}
+	return false
+}
@@ -65,6 +65,7 @@ Examples:
 			}
 
 			tableNames := strings.Split(dbTables, ",")
+			sqlArgs.RelatedTables = tableNames // parse the relations between tables in the same batch
 			for _, tableName := range tableNames {
 				if tableName == "" {
 					continue
@@ -0,0 +1,92 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zhufuyi/sponge/pkg/replacer"
+	"github.com/zhufuyi/sponge/pkg/sql2code/parser"
+)
+
+const (
+	daoQueryValidatorCode = `var userExampleQueryValidator = query.NewValidator(map[string]string{
+	"id":         query.TypeUint,
+	"created_at": query.TypeTime,
+	"updated_at": query.TypeTime,
+	"name":       query.TypeString,
+	"email":      query.TypeString,
+	"phone":      query.TypeString,
+	"avatar":     query.TypeString,
+	"age":        query.TypeInt,
+	"gender":     query.TypeInt,
+	"status":     query.TypeInt,
+	"login_at":   query.TypeInt,
+})`
+
+	daoMgoQueryValidatorCode = `var userExampleQueryValidator = query.NewValidator(map[string]string{
+	"id":         query.TypeString,
+	"created_at": query.TypeTime,
+	"updated_at": query.TypeTime,
+	"name":       query.TypeString,
+	"email":      query.TypeString,
+	"phone":      query.TypeString,
+	"avatar":     query.TypeString,
+	"age":        query.TypeInt,
+	"gender":     query.TypeInt,
+	"status":     query.TypeInt,
+	"login_at":   query.TypeInt,
+})`
+)
+
+// queryColumn generates the columns of the query validator in the dao template code according to the columns of the table.
+type queryColumn struct {
+	fields []parser.QueryColumnField
+}
+
+func newQueryColumn(codes map[string]string) *queryColumn {
+	return &queryColumn{fields: parser.GetQueryColumns(codes)}
+}
+
+// replacementFields the replacement fields must be set before the field that replaces UserExample
+func (q *queryColumn) replacementFields() []replacer.Field {
+	if len(q.fields) == 0 {
+		return nil
+	}
+
+	code := q.validatorCode()
+	return []replacer.Field{
+		{Old: daoQueryValidatorCode, New: code},
+		{Old: daoMgoQueryValidatorCode, New: code},
+	}
+}
+
+func (q *queryColumn) validatorCode() string {
+	names := make([]string, 0, len(q.fields))
+	maxLen := 0
+	for _, field := range q.fields {
+		name := field.ColName
+		if name == "_id" { // the id of mongodb
+			name = "id"
+		}
+		names = append(names, fmt.Sprintf("%q:", name))
+		if len(names[len(names)-1]) > maxLen {
+			maxLen = len(names[len(names)-1])
+		}
+	}
+
+	lines := make([]string, 0, len(q.fields))
+	for i, field := range q.fields {
+		lines = append(lines, fmt.Sprintf("\t%-*s query.%s,", maxLen, names[i], toQueryTypeName(field.Type)))
+	}
+
+	return "var userExampleQueryValidator = query.NewValidator(map[string]string{\n" + strings.Join(lines, "\n") + "\n})"
+}
+
+// e.g. string --> TypeString
+func toQueryTypeName(typ string) string {
+	switch typ {
+	case "string", "int", "uint", "float", "bool", "time":
+		return "Type" + strings.ToUpper(typ[:1]) + typ[1:]
+	}
+	return "TypeAny"
+}
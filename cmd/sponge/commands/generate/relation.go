@@ -0,0 +1,194 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/huandu/xstrings"
+	"github.com/jinzhu/inflection"
+
+	"github.com/zhufuyi/sponge/pkg/replacer"
+	"github.com/zhufuyi/sponge/pkg/sql2code/parser"
+)
+
+// relation generates the replacement fields of the template code according to the relations between tables,
+// e.g. dao methods to query records by foreign key, load associations in the api of getting detail.
+type relation struct {
+	tableName string
+	fields    []parser.RelationField
+}
+
+func newRelation(codes map[string]string) *relation {
+	return &relation{
+		tableName: codes[parser.TableName],
+		fields:    parser.GetRelations(codes),
+	}
+}
+
+// ignore the template test files, the api of getting detail loads the associations
+func (r *relation) ignoreFiles() []string {
+	if len(r.fields) == 0 {
+		return nil
+	}
+	return []string{
+		"handler/userExample_test.go", "handler/userExample_logic_test.go", "service/userExample_test.go",
+	}
+}
+
+// association field names, the proto message only contains belongsTo associations
+func (r *relation) preloads(isProtobuf bool) []string {
+	var names []string
+	for _, field := range r.fields {
+		if isProtobuf && field.Type != parser.RelationBelongsTo {
+			continue
+		}
+		names = append(names, fmt.Sprintf("%q", field.Name))
+	}
+	return names
+}
+
+// replacementFields the replacement fields must be set before the field that replaces UserExample
+func (r *relation) replacementFields() []replacer.Field {
+	if len(r.fields) == 0 {
+		return nil
+	}
+
+	fields := []replacer.Field{
+		// dao
+		{
+			Old: "\tUpdateByTx(ctx context.Context, tx *gorm.DB, table *model.UserExample) error\n}",
+			New: "\tUpdateByTx(ctx context.Context, tx *gorm.DB, table *model.UserExample) error\n\n" + r.daoInterfaceCode() + "}",
+		},
+		{
+			Old: daoUpdateByTxCode,
+			New: daoUpdateByTxCode + r.daoCode(),
+		},
+
+		// gin handler
+		{
+			Old: "h.iDao.GetByID(ctx, id)",
+			New: "h.iDao.GetDetailByID(ctx, id, " + strings.Join(r.preloads(false), ", ") + ")",
+		},
+		{
+			Old: "\tdata.ID = idStr\n",
+			New: "\tdata.ID = idStr\n" + r.handlerIDCode(),
+		},
+		{
+			Old: "\tdata.ID = utils.Uint64ToStr(userExample.ID)\n",
+			New: "\tdata.ID = utils.Uint64ToStr(userExample.ID)\n" + r.handlerIDCode(),
+		},
+	}
+
+	// grpc service and handler based on protobuf
+	if preloads := r.preloads(true); len(preloads) > 0 {
+		fields = append(fields, []replacer.Field{
+			{
+				Old: ".GetByID(ctx, req.Id)",
+				New: ".GetDetailByID(ctx, req.Id, " + strings.Join(preloads, ", ") + ")",
+			},
+			{
+				Old: "\tvalue.Id = record.ID\n",
+				New: "\tvalue.Id = record.ID\n" + r.protoIDCode(),
+			},
+		}...)
+	}
+
+	return fields
+}
+
+const daoUpdateByTxCode = `// UpdateByTx update a record by id in the database using the provided transaction,
+// the cache is deleted after the transaction is committed if tx is started by ggorm.Transaction, otherwise it is deleted immediately.
+func (d *userExampleDao) UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.UserExample) error {
+	err := d.updateDataByID(ctx, tx, table)
+
+	// delete cache after commit
+	ggorm.AfterCommit(tx, func() { _ = d.deleteCache(ctx, table.ID) })
+
+	return err
+}
+`
+
+// e.g. ListOrdersByUserID
+func (r *relation) listMethodName(field parser.RelationField) string {
+	return "List" + inflection.Plural(r.tableName) + "By" + field.ForeignKey
+}
+
+func (r *relation) daoInterfaceCode() string {
+	code := "\tGetDetailByID(ctx context.Context, id uint64, preloads ...string) (*model.UserExample, error)\n"
+	for _, field := range r.fields {
+		if field.Type != parser.RelationBelongsTo {
+			continue
+		}
+		code += fmt.Sprintf("\t%s(ctx context.Context, %s %s, preloads ...string) ([]*model.UserExample, error)\n",
+			r.listMethodName(field), xstrings.FirstRuneToLower(field.ForeignKey), field.ForeignKeyType)
+	}
+	return code
+}
+
+func (r *relation) daoCode() string {
+	code := fmt.Sprintf(`
+// GetDetailByID get a record by id and load the associations, preloads are the association field names, e.g. %s
+func (d *userExampleDao) GetDetailByID(ctx context.Context, id uint64, preloads ...string) (*model.UserExample, error) {
+	db := d.db.WithContext(ctx)
+	for _, name := range preloads {
+		db = db.Preload(name)
+	}
+
+	record := &model.UserExample{}
+	err := db.Where("id = ?", id).First(record).Error
+	return record, err
+}
+`, strings.Join(r.preloads(false), ", "))
+
+	for _, field := range r.fields {
+		if field.Type != parser.RelationBelongsTo {
+			continue
+		}
+		argName := xstrings.FirstRuneToLower(field.ForeignKey)
+		code += fmt.Sprintf(`
+// %s get records by %s, preloads are the association field names, e.g. "%s"
+func (d *userExampleDao) %s(ctx context.Context, %s %s, preloads ...string) ([]*model.UserExample, error) {
+	db := d.db.WithContext(ctx)
+	for _, name := range preloads {
+		db = db.Preload(name)
+	}
+
+	var records []*model.UserExample
+	err := db.Where("%s = ?", %s).Order("id DESC").Find(&records).Error
+	return records, err
+}
+`, r.listMethodName(field), field.ForeignKeyCol, field.Name,
+			r.listMethodName(field), argName, field.ForeignKeyType,
+			field.ForeignKeyCol, argName)
+	}
+
+	return code
+}
+
+// the id of the detail struct is string, copier can't convert the id of the associations
+func (r *relation) handlerIDCode() string {
+	code := ""
+	for _, field := range r.fields {
+		if field.Type == parser.RelationBelongsTo {
+			code += fmt.Sprintf("\tif userExample.%s != nil && data.%s != nil {\n\t\tdata.%s.ID = utils.Uint64ToStr(userExample.%s.ID)\n\t}\n",
+				field.Name, field.Name, field.Name, field.Name)
+			continue
+		}
+		code += fmt.Sprintf("\tfor i, v := range userExample.%s {\n\t\tif i < len(data.%s) {\n\t\t\tdata.%s[i].ID = utils.Uint64ToStr(v.ID)\n\t\t}\n\t}\n",
+			field.Name, field.Name, field.Name)
+	}
+	return code
+}
+
+// the name of id field in the go code of protobuf is Id, copier can't copy the id of the associations
+func (r *relation) protoIDCode() string {
+	code := ""
+	for _, field := range r.fields {
+		if field.Type != parser.RelationBelongsTo {
+			continue
+		}
+		code += fmt.Sprintf("\tif record.%s != nil && value.%s != nil {\n\t\tvalue.%s.Id = record.%s.ID\n\t}\n",
+			field.Name, field.Name, field.Name, field.Name)
+	}
+	return code
+}
@@ -59,6 +59,7 @@ Examples:
 			var firstTable string
 			var servicesTableNames []string
 			tableNames := strings.Split(dbTables, ",")
+			sqlArgs.RelatedTables = tableNames // parse the relations between tables in the same batch
 			if len(tableNames) == 1 {
 				firstTable = tableNames[0]
 			} else if len(tableNames) > 1 {
@@ -211,6 +212,13 @@ func (g *rpcGenerator) generateCode() (string, error) {
 		return "", errors.New("unsupported db driver: " + g.dbDriver)
 	}
 
+	pk := newPrimaryKey(g.codes)
+	if err := pk.check(g.codes[parser.TableName], true); err != nil {
+		return "", err
+	}
+	ignoreFiles = append(ignoreFiles, pk.ignoreFiles()...)
+	ignoreFiles = append(ignoreFiles, newRelation(g.codes).ignoreFiles()...)
+
 	r.SetSubDirsAndFiles(subDirs, subFiles...)
 	r.SetIgnoreSubDirs(ignoreDirs...)
 	r.SetIgnoreSubFiles(ignoreFiles...)
@@ -253,6 +261,9 @@ func (g *rpcGenerator) addFields(r replacer.Replacer) []replacer.Field {
 	fields = append(fields, deleteAllFieldsMark(r, appConfigFile, wellStartMark, wellEndMark)...)
 	//fields = append(fields, deleteFieldsMark(r, deploymentConfigFile, wellStartMark, wellEndMark)...)
 	fields = append(fields, replaceFileContentMark(r, readmeFile, "## "+g.serverName)...)
+	fields = append(fields, newPrimaryKey(g.codes).replacementFields(r)...)
+	fields = append(fields, newRelation(g.codes).replacementFields()...)
+	fields = append(fields, newQueryColumn(g.codes).replacementFields()...)
 	fields = append(fields, []replacer.Field{
 		{ // replace the configuration of the *.yml file
 			Old: appConfigFileMark,
@@ -276,7 +287,7 @@ func (g *rpcGenerator) addFields(r replacer.Replacer) []replacer.Field {
 		},
 		{ // replace the contents of the handler/userExample_logic.go file
 			Old: embedTimeMark,
-			New: getEmbedTimeCode(g.isEmbed),
+			New: getEmbedTimeCode(g.isEmbed && newPrimaryKey(g.codes).isDefault()),
 		},
 		{ // replace the contents of the v1/userExample.proto file
 			Old: protoFileMark,
@@ -292,7 +303,7 @@ func (g *rpcGenerator) addFields(r replacer.Replacer) []replacer.Field {
 		},
 		{ // replace the contents of the service/userExample_client_test.go file
 			Old: serviceFileMark,
-			New: adjustmentOfIDType(g.codes[parser.CodeTypeService], g.dbDriver),
+			New: adjustmentOfIDType(g.codes[parser.CodeTypeService], g.dbDriver, newPrimaryKey(g.codes).isDefault()),
 		},
 		{ // replace the contents of the Dockerfile file
 			Old: dockerFileMark,
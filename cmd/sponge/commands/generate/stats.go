@@ -0,0 +1,207 @@
+package generate
+
+import (
+	"github.com/zhufuyi/sponge/pkg/replacer"
+)
+
+// stats generates the api of aggregate statistics based on query.Aggregation, e.g. count the records of each status,
+// the template code does not contain the api, it is optional.
+type stats struct {
+	enable bool
+}
+
+func newStats(enable bool) *stats {
+	return &stats{enable: enable}
+}
+
+// replacementFields the replacement fields must be set before the field that replaces UserExample
+func (s *stats) replacementFields() []replacer.Field {
+	if !s.enable {
+		return nil
+	}
+
+	return []replacer.Field{
+		// dao
+		{
+			Old: daoGetByColumnsInterfaceCode,
+			New: daoGetByColumnsInterfaceCode + "\tStats(ctx context.Context, params *query.Aggregation) ([]map[string]interface{}, error)\n",
+		},
+		{
+			Old: daoGetByColumnsEndCode,
+			New: daoGetByColumnsEndCode + daoStatsCode,
+		},
+		{
+			Old: daoMgoGetByColumnsEndCode,
+			New: daoMgoGetByColumnsEndCode + daoMgoStatsCode,
+		},
+
+		// gin handler
+		{
+			Old: "\tList(c *gin.Context)\n}",
+			New: "\tList(c *gin.Context)\n\tStats(c *gin.Context)\n}",
+		},
+		{
+			Old: handlerListEndCode,
+			New: handlerListEndCode + handlerStatsCode,
+		},
+		{
+			Old: "\tgroup.POST(\"/userExample/list\", h.List)\n",
+			New: "\tgroup.POST(\"/userExample/list\", h.List)\n\tgroup.POST(\"/userExample/stats\", h.Stats)\n",
+		},
+		{
+			Old: "func (u mock) List(c *gin.Context)           { return }\n",
+			New: "func (u mock) List(c *gin.Context)           { return }\nfunc (u mock) Stats(c *gin.Context)          { return }\n",
+		},
+		{
+			Old: "// ListUserExamplesRespond only for api docs",
+			New: typesStatsCode + "// ListUserExamplesRespond only for api docs",
+		},
+	}
+}
+
+const (
+	daoGetByColumnsInterfaceCode = "\tGetByColumns(ctx context.Context, params *query.Params) ([]*model.UserExample, int64, error)\n"
+
+	daoGetByColumnsEndCode = `	err = db.Find(&records).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return records, total, err
+}
+`
+
+	daoMgoGetByColumnsEndCode = `	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return records, total, err
+}
+`
+
+	daoStatsCode = `
+// Stats aggregate statistics of records by group by columns, aggregate functions and having conditions,
+// each result contains the group by columns and the aggregate values, e.g. {"gender": 1, "count": 10, "avg_age": 20.5}
+func (d *userExampleDao) Stats(ctx context.Context, params *query.Aggregation) ([]map[string]interface{}, error) {
+	err := userExampleQueryValidator.ValidateAggregation(params)
+	if err != nil {
+		return nil, err
+	}
+	agg, err := params.ConvertToGorm(query.WithDBDriver(d.db.Dialector.Name()))
+	if err != nil {
+		return nil, &query.FieldError{Field: "aggregation", Msg: err.Error()}
+	}
+
+	db := d.db.WithContext(ctx).Model(&model.UserExample{}).Select(agg.Select).Where(agg.Where, agg.WhereArgs...)
+	if agg.Group != "" {
+		db = db.Group(agg.Group)
+	}
+	if agg.Having != "" {
+		db = db.Having(agg.Having, agg.HavingArgs...)
+	}
+	if agg.Order != "" {
+		db = db.Order(agg.Order)
+	}
+	if agg.Limit > 0 {
+		db = db.Limit(agg.Limit)
+	}
+
+	records := []map[string]interface{}{}
+	err = db.Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+`
+
+	daoMgoStatsCode = `
+// Stats aggregate statistics of documents by group by columns, aggregate functions and having conditions,
+// each result contains the group by columns and the aggregate values, e.g. {"gender": 1, "count": 10, "avg_age": 20.5}
+func (d *userExampleDao) Stats(ctx context.Context, params *query.Aggregation) ([]map[string]interface{}, error) {
+	err := userExampleQueryValidator.ValidateAggregation(params)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := params.ConvertToMongoPipeline()
+	if err != nil {
+		return nil, &query.FieldError{Field: "aggregation", Msg: err.Error()}
+	}
+	pipeline = append([]bson.M{{"$match": mgo.ExcludeDeleted(bson.M{})}}, pipeline...)
+
+	cursor, err := d.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	records := []map[string]interface{}{}
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+`
+
+	handlerListEndCode = `		"nextCursor":   nextCursor,
+	})
+}
+`
+
+	handlerStatsCode = `
+// Stats aggregate statistics of records
+// @Summary aggregate statistics of userExamples
+// @Description count, sum, avg, min, max of userExamples by group by columns and conditions
+// @Tags userExample
+// @accept json
+// @Produce json
+// @Param data body types.Aggregation true "aggregation parameters"
+// @Success 200 {object} types.StatsUserExamplesRespond{}
+// @Router /api/v1/userExample/stats [post]
+func (h *userExampleHandler) Stats(c *gin.Context) {
+	form := &types.StatsUserExamplesRequest{}
+	err := c.ShouldBindJSON(form)
+	if err != nil {
+		logger.Warn("ShouldBindJSON error: ", logger.Err(err), middleware.GCtxRequestIDField(c))
+		response.Error(c, ecode.InvalidParams)
+		return
+	}
+
+	ctx := middleware.WrapCtx(c)
+	records, err := h.iDao.Stats(ctx, &form.Aggregation)
+	if err != nil {
+		var fieldErr *query.FieldError
+		if errors.As(err, &fieldErr) {
+			logger.Warn("Stats error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+			response.Out(c, ecode.InvalidParams.WithDetails(err.Error()))
+			return
+		}
+		logger.Error("Stats error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+		response.Output(c, ecode.InternalServerError.ToHTTPCode())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"stats": records,
+	})
+}
+`
+
+	typesStatsCode = `// StatsUserExamplesRequest request params
+type StatsUserExamplesRequest struct {
+	query.Aggregation
+}
+
+// StatsUserExamplesRespond only for api docs
+type StatsUserExamplesRespond struct {
+	Code int    ` + "`json:\"code\"`" + ` // return code
+	Msg  string ` + "`json:\"msg\"`" + `  // return information description
+	Data struct {
+		Stats []map[string]interface{} ` + "`json:\"stats\"`" + ` // group by columns and aggregate values
+	} ` + "`json:\"data\"`" + ` // return data
+}
+
+`
+)
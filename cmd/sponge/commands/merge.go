@@ -13,7 +13,8 @@ func MergeCommand() *cobra.Command {
 		Short: "Merge the generated code into the template file",
 		Long: `merge the generated code into the template file, you don't worry about it affecting
 the logic code you have already written, in case of accidents, you can find the
-pre-merge code in the directory /tmp/sponge_merge_backup_code`,
+pre-merge code in the directory /tmp/sponge_merge_backup_code. go code is merged by three-way merge
+based on AST, the code that cannot be merged automatically is kept and reported in .sponge/merge_conflicts_<time>.txt`,
 		SilenceErrors: true,
 		SilenceUsage:  true,
 	}
@@ -22,6 +23,7 @@ pre-merge code in the directory /tmp/sponge_merge_backup_code`,
 		merge.GinHandlerCode(),
 		merge.GinServiceCode(),
 		merge.GRPCServiceCode(),
+		merge.GoCode(),
 	)
 
 	return cmd
@@ -0,0 +1,457 @@
+package merge
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// conflict the code that cannot be merged automatically, the current code is kept
+type conflict struct {
+	file   string
+	name   string
+	reason string
+}
+
+func (c *conflict) String() string {
+	return fmt.Sprintf("%s: %s, %s", c.file, c.name, c.reason)
+}
+
+// goCodeResult the result of three-way merge go code
+type goCodeResult struct {
+	data      []byte
+	conflicts []*conflict
+}
+
+// unit top level declaration in go file, func, method, type, var, const
+type unit struct {
+	key    string
+	name   string
+	start  int
+	end    int
+	text   string
+	norm   string  // text ignoring whitespace differences, e.g. alignment of struct fields
+	fields []*unit // fields of struct type
+	// position of the closing brace of struct type, -1 means not a struct type
+	closing int
+}
+
+type goFile struct {
+	src     []byte
+	units   []*unit
+	unitMap map[string]*unit
+	imports map[string]string // path:import spec text
+	// position to insert new imports, -1 means no import declaration
+	importEnd int
+	// position of the right parenthesis of import declaration, -1 means no parenthesis
+	importRparen int
+	// start position and spec of the last import declaration without parenthesis, e.g. import "fmt"
+	importStart int
+	importSpec  string
+	// end position of package clause
+	packageEnd int
+}
+
+type edit struct {
+	start int
+	end   int
+	text  string
+	seq   int
+}
+
+// mergeGoCode three-way merge go code, base is the code generated last time (can be empty),
+// current is the code in use (may be modified by the user), generated is the code generated this time.
+// user modifications are kept, the new declarations and struct fields in generated are added,
+// the declarations changed or removed in generated are applied only if the user has not modified them,
+// otherwise the current code is kept and a conflict is reported.
+func mergeGoCode(base []byte, current []byte, generated []byte) (*goCodeResult, error) {
+	curFile, err := parseGoFile(current)
+	if err != nil {
+		return nil, fmt.Errorf("parse current code error: %v", err)
+	}
+	genFile, err := parseGoFile(generated)
+	if err != nil {
+		return nil, fmt.Errorf("parse generated code error: %v", err)
+	}
+	baseFile := &goFile{unitMap: map[string]*unit{}, imports: map[string]string{}}
+	if len(base) > 0 {
+		if baseFile, err = parseGoFile(base); err != nil {
+			return nil, fmt.Errorf("parse base code error: %v", err)
+		}
+	}
+
+	m := &goCodeMerger{base: baseFile, cur: curFile, gen: genFile}
+	m.mergeImports()
+	m.mergeUnits()
+
+	data := applyEdits(curFile.src, m.edits)
+	if !bytes.Equal(data, curFile.src) {
+		if data, err = format.Source(data); err != nil {
+			return nil, fmt.Errorf("format merged code error: %v", err)
+		}
+	}
+
+	return &goCodeResult{data: data, conflicts: m.conflicts}, nil
+}
+
+type goCodeMerger struct {
+	base      *goFile
+	cur       *goFile
+	gen       *goFile
+	edits     []*edit
+	conflicts []*conflict
+}
+
+func (m *goCodeMerger) addEdit(start int, end int, text string) {
+	m.edits = append(m.edits, &edit{start: start, end: end, text: text, seq: len(m.edits)})
+}
+
+func (m *goCodeMerger) addConflict(name string, reason string) {
+	m.conflicts = append(m.conflicts, &conflict{name: name, reason: reason})
+}
+
+func (m *goCodeMerger) mergeImports() {
+	var paths []string
+	for path := range m.gen.imports {
+		if _, ok := m.cur.imports[path]; ok {
+			continue
+		}
+		if _, ok := m.base.imports[path]; ok {
+			continue // removed by the user
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return
+	}
+	sort.Strings(paths)
+
+	var texts []string
+	for _, path := range paths {
+		texts = append(texts, m.gen.imports[path])
+	}
+	if m.cur.importRparen >= 0 {
+		m.addEdit(m.cur.importRparen, m.cur.importRparen, "\t"+strings.Join(texts, "\n\t")+"\n")
+	} else if m.cur.importEnd >= 0 {
+		// convert to import declaration with parenthesis
+		texts = append([]string{m.cur.importSpec}, texts...)
+		m.addEdit(m.cur.importStart, m.cur.importEnd, "import (\n\t"+strings.Join(texts, "\n\t")+"\n)")
+	} else {
+		m.addEdit(m.cur.packageEnd, m.cur.packageEnd, "\n\nimport (\n\t"+strings.Join(texts, "\n\t")+"\n)")
+	}
+}
+
+func (m *goCodeMerger) mergeUnits() {
+	insertPos := -1 // the position of the previous generated unit in the current code
+	for _, genUnit := range m.gen.units {
+		curUnit, inCur := m.cur.unitMap[genUnit.key]
+		baseUnit, inBase := m.base.unitMap[genUnit.key]
+
+		switch {
+		case inCur:
+			m.mergeUnit(baseUnit, curUnit, genUnit)
+			insertPos = curUnit.end
+
+		case inBase: // removed by the user
+			if baseUnit.norm != genUnit.norm {
+				m.addConflict(genUnit.name, "removed in current code but changed in generated code")
+			}
+
+		default: // new unit
+			pos := insertPos
+			if pos < 0 {
+				pos = len(m.cur.src)
+			}
+			m.addEdit(pos, pos, "\n\n"+genUnit.text)
+		}
+	}
+
+	// units removed in generated code
+	for _, baseUnit := range m.base.units {
+		if _, ok := m.gen.unitMap[baseUnit.key]; ok {
+			continue
+		}
+		curUnit, ok := m.cur.unitMap[baseUnit.key]
+		if !ok {
+			continue
+		}
+		if curUnit.norm == baseUnit.norm {
+			m.addEdit(curUnit.start, lineEnd(m.cur.src, curUnit.end), "")
+		} else {
+			m.addConflict(curUnit.name, "removed in generated code but modified in current code")
+		}
+	}
+}
+
+func (m *goCodeMerger) mergeUnit(baseUnit *unit, curUnit *unit, genUnit *unit) {
+	if curUnit.norm == genUnit.norm {
+		return
+	}
+	if curUnit.closing >= 0 && genUnit.closing >= 0 {
+		m.mergeFields(baseUnit, curUnit, genUnit)
+		return
+	}
+	if baseUnit == nil || baseUnit.norm == genUnit.norm {
+		return // keep the user's code
+	}
+	if curUnit.norm == baseUnit.norm {
+		m.addEdit(curUnit.start, curUnit.end, genUnit.text)
+		return
+	}
+	m.addConflict(curUnit.name, "modified in both current code and generated code")
+}
+
+func (m *goCodeMerger) mergeFields(baseUnit *unit, curUnit *unit, genUnit *unit) {
+	baseFields := map[string]*unit{}
+	if baseUnit != nil {
+		for _, field := range baseUnit.fields {
+			baseFields[field.key] = field
+		}
+	}
+	curFields, genFields := map[string]*unit{}, map[string]*unit{}
+	for _, field := range curUnit.fields {
+		curFields[field.key] = field
+	}
+	for _, field := range genUnit.fields {
+		genFields[field.key] = field
+	}
+
+	for _, genField := range genUnit.fields {
+		curField, inCur := curFields[genField.key]
+		baseField, inBase := baseFields[genField.key]
+		switch {
+		case inCur:
+			if curField.norm == genField.norm || !inBase || baseField.norm == genField.norm {
+				continue
+			}
+			if curField.norm == baseField.norm {
+				m.addEdit(curField.start, curField.end, genField.text)
+			} else {
+				m.addConflict(curField.name, "field modified in both current code and generated code")
+			}
+
+		case inBase: // removed by the user
+			if baseField.norm != genField.norm {
+				m.addConflict(genField.name, "field removed in current code but changed in generated code")
+			}
+
+		default: // new field
+			text := genField.text + "\n"
+			if curUnit.closing > 0 && m.cur.src[curUnit.closing-1] != '\n' {
+				text = "\n" + text
+			}
+			m.addEdit(curUnit.closing, curUnit.closing, text)
+		}
+	}
+
+	if baseUnit == nil {
+		return
+	}
+	for _, baseField := range baseUnit.fields {
+		if _, ok := genFields[baseField.key]; ok {
+			continue
+		}
+		curField, ok := curFields[baseField.key]
+		if !ok {
+			continue
+		}
+		if curField.norm == baseField.norm {
+			m.addEdit(lineStart(m.cur.src, curField.start), lineEnd(m.cur.src, curField.end), "")
+		} else {
+			m.addConflict(curField.name, "field removed in generated code but modified in current code")
+		}
+	}
+}
+
+func parseGoFile(src []byte) (*goFile, error) {
+	// format the code first, ignoring differences in code style
+	src, err := format.Source(src)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	gf := &goFile{
+		src:          src,
+		unitMap:      map[string]*unit{},
+		imports:      map[string]string{},
+		importEnd:    -1,
+		importRparen: -1,
+		packageEnd:   fset.Position(f.Name.End()).Offset,
+	}
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+	newUnit := func(key string, name string, doc *ast.CommentGroup, node ast.Node, comment *ast.CommentGroup) *unit {
+		u := &unit{key: key, name: name, start: offset(node.Pos()), end: offset(node.End()), closing: -1}
+		if doc != nil {
+			u.start = offset(doc.Pos())
+		}
+		if comment != nil && offset(comment.End()) > u.end {
+			u.end = offset(comment.End())
+		}
+		u.text = string(src[u.start:u.end])
+		u.norm = strings.Join(strings.Fields(u.text), " ")
+		return u
+	}
+	addUnit := func(u *unit) {
+		if _, ok := gf.unitMap[u.key]; ok {
+			return
+		}
+		gf.units = append(gf.units, u)
+		gf.unitMap[u.key] = u
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name := d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name = "(" + recvTypeName(d.Recv.List[0].Type) + ") " + name
+			}
+			addUnit(newUnit("func:"+name, "func "+name, d.Doc, d, nil))
+
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				for _, spec := range d.Specs {
+					is := spec.(*ast.ImportSpec)
+					path, _ := strconv.Unquote(is.Path.Value)
+					gf.imports[path] = string(src[offset(is.Pos()):offset(is.End())])
+				}
+				gf.importEnd = offset(d.End())
+				if d.Rparen.IsValid() {
+					gf.importRparen = offset(d.Rparen)
+				} else if len(d.Specs) > 0 {
+					gf.importStart = offset(d.Pos())
+					gf.importSpec = string(src[offset(d.Specs[0].Pos()):offset(d.Specs[0].End())])
+				}
+				continue
+			}
+
+			isGroup := d.Lparen.IsValid()
+			for _, spec := range d.Specs {
+				var u *unit
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if isGroup {
+						u = newUnit("type:"+s.Name.Name, "type "+s.Name.Name, s.Doc, s, s.Comment)
+					} else {
+						u = newUnit("type:"+s.Name.Name, "type "+s.Name.Name, d.Doc, d, nil)
+					}
+					if st, ok := s.Type.(*ast.StructType); ok && !isGroup {
+						u.closing = offset(st.Fields.Closing)
+						u.fields = parseFields(s.Name.Name, st, newUnit)
+					}
+				case *ast.ValueSpec:
+					var names []string
+					for _, n := range s.Names {
+						names = append(names, n.Name)
+					}
+					name := d.Tok.String() + " " + strings.Join(names, ", ")
+					if isGroup {
+						u = newUnit(d.Tok.String()+":"+strings.Join(names, ","), name, s.Doc, s, s.Comment)
+					} else {
+						u = newUnit(d.Tok.String()+":"+strings.Join(names, ","), name, d.Doc, d, nil)
+					}
+				}
+				if u != nil {
+					addUnit(u)
+				}
+			}
+		}
+	}
+
+	return gf, nil
+}
+
+func parseFields(typeName string, st *ast.StructType, newUnit func(string, string, *ast.CommentGroup, ast.Node, *ast.CommentGroup) *unit) []*unit {
+	var fields []*unit
+	for _, field := range st.Fields.List {
+		var name string
+		if len(field.Names) == 0 {
+			name = exprString(field.Type) // embedded field
+		} else {
+			var names []string
+			for _, n := range field.Names {
+				names = append(names, n.Name)
+			}
+			name = strings.Join(names, ", ")
+		}
+		fields = append(fields, newUnit("field:"+name, "type "+typeName+" field "+name, field.Doc, field, field.Comment))
+	}
+	return fields
+}
+
+func recvTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + recvTypeName(t.X)
+	case *ast.IndexExpr:
+		return recvTypeName(t.X)
+	case *ast.IndexListExpr:
+		return recvTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return exprString(expr)
+}
+
+func exprString(expr ast.Expr) string {
+	buf := &bytes.Buffer{}
+	_ = format.Node(buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+// applyEdits apply the edits from back to front, edits at the same position are applied in the order of addition
+func applyEdits(src []byte, edits []*edit) []byte {
+	if len(edits) == 0 {
+		return src
+	}
+
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start > edits[j].start
+		}
+		return edits[i].seq > edits[j].seq
+	})
+
+	data := make([]byte, len(src))
+	copy(data, src)
+	for _, e := range edits {
+		var buf bytes.Buffer
+		buf.Write(data[:e.start])
+		buf.WriteString(e.text)
+		buf.Write(data[e.end:])
+		data = buf.Bytes()
+	}
+
+	return data
+}
+
+func lineStart(src []byte, pos int) int {
+	for pos > 0 && src[pos-1] != '\n' {
+		pos--
+	}
+	return pos
+}
+
+func lineEnd(src []byte, pos int) int {
+	for pos < len(src) && src[pos] != '\n' {
+		pos++
+	}
+	if pos < len(src) {
+		pos++
+	}
+	return pos
+}
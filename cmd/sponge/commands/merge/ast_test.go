@@ -0,0 +1,171 @@
+package merge
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mergeGoCode(t *testing.T) {
+	tests := []struct {
+		name         string
+		base         string
+		current      string
+		generated    string
+		contains     []string
+		notContains  []string
+		conflicts    int
+		importBlocks int // number of import declarations, 0 means not checked
+	}{
+		{
+			name:      "add new func",
+			base:      "package a\n\nfunc A() {}\n",
+			current:   "package a\n\nfunc A() {}\n",
+			generated: "package a\n\nfunc A() {}\n\nfunc B() {}\n",
+			contains:  []string{"func A() {}", "func B() {}"},
+		},
+		{
+			name:        "generated changed and current not modified",
+			base:        "package a\n\nfunc A() int { return 1 }\n",
+			current:     "package a\n\nfunc A() int { return 1 }\n",
+			generated:   "package a\n\nfunc A() int { return 2 }\n",
+			contains:    []string{"return 2"},
+			notContains: []string{"return 1"},
+		},
+		{
+			name:        "generated not changed and current modified",
+			base:        "package a\n\nfunc A() int { return 1 }\n",
+			current:     "package a\n\nfunc A() int { return 3 }\n",
+			generated:   "package a\n\nfunc A() int { return 1 }\n",
+			contains:    []string{"return 3"},
+			notContains: []string{"return 1"},
+		},
+		{
+			name:        "conflict: modified in both",
+			base:        "package a\n\nfunc A() int { return 1 }\n",
+			current:     "package a\n\nfunc A() int { return 3 }\n",
+			generated:   "package a\n\nfunc A() int { return 2 }\n",
+			contains:    []string{"return 3"},
+			notContains: []string{"return 2"},
+			conflicts:   1,
+		},
+		{
+			name:      "no base, the current code is kept",
+			current:   "package a\n\nfunc A() int { return 3 }\n",
+			generated: "package a\n\nfunc A() int { return 2 }\n",
+			contains:  []string{"return 3"},
+		},
+		{
+			name:        "deleted in current and not changed in generated",
+			base:        "package a\n\nfunc A() {}\n\nfunc B() {}\n",
+			current:     "package a\n\nfunc A() {}\n",
+			generated:   "package a\n\nfunc A() {}\n\nfunc B() {}\n",
+			notContains: []string{"func B()"},
+		},
+		{
+			name:        "conflict: deleted in current and changed in generated",
+			base:        "package a\n\nfunc A() {}\n\nfunc B() int { return 1 }\n",
+			current:     "package a\n\nfunc A() {}\n",
+			generated:   "package a\n\nfunc A() {}\n\nfunc B() int { return 2 }\n",
+			notContains: []string{"func B()"},
+			conflicts:   1,
+		},
+		{
+			name:        "deleted in generated and current not modified",
+			base:        "package a\n\nfunc A() {}\n\n// B comment\nfunc B() {}\n",
+			current:     "package a\n\nfunc A() {}\n\n// B comment\nfunc B() {}\n",
+			generated:   "package a\n\nfunc A() {}\n",
+			notContains: []string{"func B()", "B comment"},
+		},
+		{
+			name:      "conflict: deleted in generated and current modified",
+			base:      "package a\n\nfunc A() {}\n\nfunc B() int { return 1 }\n",
+			current:   "package a\n\nfunc A() {}\n\nfunc B() int { return 3 }\n",
+			generated: "package a\n\nfunc A() {}\n",
+			contains:  []string{"return 3"},
+			conflicts: 1,
+		},
+		{
+			name:        "struct fields added and deleted",
+			base:        "package a\n\ntype T struct {\n\tA int\n\tB int\n}\n",
+			current:     "package a\n\ntype T struct {\n\tA int\n\tB int\n\tMine int\n}\n",
+			generated:   "package a\n\ntype T struct {\n\tA int\n\tC string\n}\n",
+			contains:    []string{"Mine int", "C    string"},
+			notContains: []string{"B int"},
+		},
+		{
+			name:      "conflict: struct field modified in both",
+			base:      "package a\n\ntype T struct {\n\tA int\n}\n",
+			current:   "package a\n\ntype T struct {\n\tA int64\n}\n",
+			generated: "package a\n\ntype T struct {\n\tA string\n}\n",
+			contains:  []string{"A int64"},
+			conflicts: 1,
+		},
+		{
+			name:         "add import into parenthesized import",
+			base:         "package a\n\nimport (\n\t\"fmt\"\n)\n\nfunc A() { fmt.Println() }\n",
+			current:      "package a\n\nimport (\n\t\"fmt\"\n)\n\nfunc A() { fmt.Println() }\n",
+			generated:    "package a\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n\nfunc A() { fmt.Println(strings.ToLower(\"\")) }\n",
+			contains:     []string{`"strings"`, `"fmt"`},
+			importBlocks: 1,
+		},
+		{
+			name:         "add import into import without parenthesis",
+			base:         "package a\n\nimport \"fmt\"\n\nfunc A() { fmt.Println() }\n",
+			current:      "package a\n\nimport \"fmt\"\n\nfunc A() { fmt.Println() }\n",
+			generated:    "package a\n\nimport (\n\t\"fmt\"\n\tstr \"strings\"\n)\n\nfunc A() { fmt.Println(str.ToLower(\"\")) }\n",
+			contains:     []string{"import (", `str "strings"`, `"fmt"`},
+			importBlocks: 1,
+		},
+		{
+			name:         "add import into code without import",
+			current:      "package a\n\nfunc A() {}\n",
+			generated:    "package a\n\nimport \"fmt\"\n\nfunc A() {}\n\nfunc B() { fmt.Println() }\n",
+			contains:     []string{`"fmt"`, "func B()"},
+			importBlocks: 1,
+		},
+		{
+			name:         "import removed in current is not added again",
+			base:         "package a\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n",
+			current:      "package a\n\nimport (\n\t\"fmt\"\n)\n",
+			generated:    "package a\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n",
+			notContains:  []string{`"strings"`},
+			importBlocks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := mergeGoCode([]byte(tt.base), []byte(tt.current), []byte(tt.generated))
+			assert.NoError(t, err)
+			code := string(result.data)
+			for _, s := range tt.contains {
+				assert.Contains(t, code, s)
+			}
+			for _, s := range tt.notContains {
+				assert.NotContains(t, code, s)
+			}
+			assert.Len(t, result.conflicts, tt.conflicts)
+
+			f, err := parser.ParseFile(token.NewFileSet(), "", result.data, parser.ImportsOnly)
+			assert.NoError(t, err, code)
+			if tt.importBlocks > 0 {
+				assert.Len(t, f.Decls, tt.importBlocks, code)
+			}
+		})
+	}
+}
+
+func Test_mergeGoCodeError(t *testing.T) {
+	valid := []byte("package a\n")
+	invalid := []byte("package a\nfunc {")
+
+	_, err := mergeGoCode(nil, invalid, valid)
+	assert.Error(t, err)
+	_, err = mergeGoCode(nil, valid, invalid)
+	assert.Error(t, err)
+	_, err = mergeGoCode(invalid, valid, valid)
+	assert.Error(t, err)
+}
@@ -1,6 +1,8 @@
 // Package merge is merge the generated code into the template file, you don't worry about it affecting
 // the logic code you have already written, in case of accidents, you can find the
-// pre-merge code in the directory /tmp/sponge_merge_backup_code
+// pre-merge code in the directory /tmp/sponge_merge_backup_code.
+// go code is merged by three-way merge based on AST, the code that cannot be merged automatically
+// is kept and reported in the file .sponge/merge_conflicts_<time>.txt
 package merge
 
 import (
@@ -21,6 +23,9 @@ import (
 var (
 	defaultFuzzyFilename = "*.go.gen*"
 	defaultSplitLineMark = []byte(`// ---------- Do not delete or move this split line, this is the merge code marker ----------`)
+
+	// the code generated last time is saved in this directory, used as the base of three-way merge
+	defaultBaseDir = ".sponge" + gofile.GetPathDelimiter() + "merge_base"
 )
 
 type code struct {
@@ -37,6 +42,8 @@ type mergeParam struct {
 	parseCode     func(data []byte, markStr string) []code // parsing code method
 	dt            string                                   // character form of date and time
 	backupDir     string                                   // backup Code Catalog
+	isGoCode      bool                                     // true: three-way merge go code based on AST
+	conflicts     []*conflict                              // the code that cannot be merged automatically
 }
 
 func newMergeParam(dir string, mark string, isLineCode bool, parseCode func(data []byte, markStr string) []code) *mergeParam {
@@ -52,6 +59,12 @@ func newMergeParam(dir string, mark string, isLineCode bool, parseCode func(data
 	}
 }
 
+func newGoCodeMergeParam(dir string) *mergeParam {
+	m := newMergeParam(dir, "", false, nil)
+	m.isGoCode = true
+	return m
+}
+
 // SetFuzzyFileName setting fuzzy matching file names, use * for fuzzy matching
 func (m *mergeParam) SetFuzzyFileName(fuzzyFilename string) {
 	m.fuzzyFilename = fuzzyFilename
@@ -85,6 +98,10 @@ func (m *mergeParam) runMerge() {
 			fmt.Printf("merge code to \"%s\" successfully.\n", successFile)
 		}
 	}
+
+	if err := saveConflictReport(".", m.conflicts, m.dt); err != nil {
+		fmt.Println(err)
+	}
 }
 
 func (m *mergeParam) runMergeCode(file string) (string, error) {
@@ -93,6 +110,9 @@ func (m *mergeParam) runMergeCode(file string) (string, error) {
 	}
 
 	oldFile := getOldFile(file)
+	if m.isGoCode {
+		return m.runMergeGoCode(oldFile, file)
+	}
 
 	data1, err := os.ReadFile(oldFile)
 	if err != nil {
@@ -164,6 +184,77 @@ func (m *mergeParam) saveFile(file string, data []byte) error {
 	return os.WriteFile(file, data, 0766)
 }
 
+func (m *mergeParam) runMergeGoCode(currentFile string, generatedFile string) (string, error) {
+	current, err := os.ReadFile(currentFile)
+	if err != nil {
+		return "", err
+	}
+	generated, err := os.ReadFile(generatedFile)
+	if err != nil {
+		return "", err
+	}
+	baseFile := getBaseFile(".", currentFile)
+	base, _ := os.ReadFile(baseFile) // no base file, only the new code is added
+
+	result, err := mergeGoCode(base, current, generated)
+	if err != nil {
+		return "", fmt.Errorf("%v, please merge codes manually, file = %s", err, generatedFile)
+	}
+	for _, c := range result.conflicts {
+		c.file = currentFile
+	}
+	m.conflicts = append(m.conflicts, result.conflicts...)
+
+	if err = saveBaseFile(baseFile, generated); err != nil {
+		return "", err
+	}
+
+	if bytes.Equal(result.data, current) {
+		return "", os.Remove(generatedFile)
+	}
+	if err = os.WriteFile(currentFile, result.data, 0766); err != nil {
+		return "", err
+	}
+
+	return currentFile, os.Remove(generatedFile)
+}
+
+func getBaseFile(rootDir string, file string) string {
+	return filepath.Join(rootDir, defaultBaseDir, file)
+}
+
+func saveBaseFile(baseFile string, data []byte) error {
+	_ = os.MkdirAll(filepath.Dir(baseFile), 0766)
+	return os.WriteFile(baseFile, data, 0666)
+}
+
+func saveConflictReport(rootDir string, conflicts []*conflict, dt string) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	var lines []string
+	for _, c := range conflicts {
+		lines = append(lines, c.String())
+	}
+	content := strings.Join(lines, "\n") + "\n"
+
+	file := filepath.Join(rootDir, ".sponge", "merge_conflicts_"+dt+".txt")
+	_ = os.MkdirAll(filepath.Dir(file), 0766)
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint
+	if _, err = f.WriteString(content); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nthe following codes cannot be merged automatically, the current code is kept, please merge them manually.\n"+
+		"report = %s\n    %s\n\n", file, strings.Join(lines, "\n    "))
+	return nil
+}
+
 func getOldFile(file string) string {
 	dir, name := filepath.Split(file)
 	return dir + strings.TrimSuffix(name, path.Ext(name))
@@ -312,23 +403,6 @@ func getRoutersMarkName(str string, markStr string) string {
 
 // ------------- parsing the core of data in the internal/handler or internal/handler directory -------------
 
-func parseFromTmplCode(data []byte, markStr string) []code {
-	var codes []code
-	str := string(data)
-	reg1 := regexp.MustCompile(markStr)
-	matches := reg1.FindAllStringSubmatch(str, -1)
-	for _, match := range matches {
-		for _, v := range match {
-			key, _ := getTmplKey(v)
-			codes = append(codes, code{
-				key:   key,
-				value: v,
-			})
-		}
-	}
-	return codes
-}
-
 func getTmplKey(str string) (key string, methodName string) {
 	regStr2 := `func \((.*?)\) (.*?)\(`
 	reg2 := regexp.MustCompile(regStr2)
@@ -387,31 +461,11 @@ func mergeGinRouters() {
 }
 
 func mergeHTTPHandlerTmpl() {
-	m := newMergeParam(
-		"internal/handler",
-		`func \(h[\w\W]*?\n}`,
-		false,
-		parseFromTmplCode,
-	)
-	m.runMerge()
-}
-
-func mergeGRPCServiceClientTmpl() {
-	m := newMergeParam(
-		"internal/service",
-		`func \(c[\w\W]*?\n}`,
-		false,
-		parseFromTmplCode,
-	)
+	m := newGoCodeMergeParam("internal/handler")
 	m.runMerge()
 }
 
 func mergeGRPCServiceTmpl() {
-	m := newMergeParam(
-		"internal/service",
-		`func \(s[\w\W]*?\n}`,
-		false,
-		parseFromTmplCode,
-	)
+	m := newGoCodeMergeParam("internal/service")
 	m.runMerge()
 }
@@ -0,0 +1,115 @@
+package merge
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/gofile"
+
+	"github.com/spf13/cobra"
+)
+
+// GoCode three-way merge the regenerated go code into the server code
+func GoCode() *cobra.Command {
+	var (
+		fromDir string // directory of the regenerated code
+		toDir   string // server directory
+	)
+
+	cmd := &cobra.Command{
+		Use:   "go-code",
+		Short: "Three-way merge the regenerated go code into the server code",
+		Long: `three-way merge the regenerated go code into the server code, the merge is based on AST,
+the code generated last time is used as the base, user modifications are kept, new declarations and
+struct fields are added, the code that cannot be merged automatically is kept and reported.
+
+Examples:
+  # regenerate handler code after adding a column to the table, then merge it into the server code.
+  sponge web handler --module-name=yourModuleName --db-driver=mysql --db-dsn=root:123456@(192.168.3.37:3306)/test --db-table=user --out=./handler_code
+  sponge merge go-code --from=./handler_code --to=./yourServerDir
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dt := time.Now().Format("20060102T150405")
+			var conflicts []*conflict
+
+			absFromDir, err := filepath.Abs(fromDir)
+			if err != nil {
+				return err
+			}
+			files, err := gofile.ListFiles(absFromDir, gofile.WithSuffix(".go"))
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				relFile, err := filepath.Rel(absFromDir, file)
+				if err != nil {
+					return err
+				}
+				cs, err := mergeGoCodeFile(file, toDir, relFile)
+				if err != nil {
+					fmt.Println(err)
+					continue
+				}
+				conflicts = append(conflicts, cs...)
+			}
+
+			return saveConflictReport(toDir, conflicts, dt)
+		},
+	}
+
+	cmd.Flags().StringVarP(&fromDir, "from", "f", "", "directory of the regenerated code")
+	_ = cmd.MarkFlagRequired("from")
+	cmd.Flags().StringVarP(&toDir, "to", "t", ".", "server directory")
+
+	return cmd
+}
+
+func mergeGoCodeFile(generatedFile string, toDir string, relFile string) ([]*conflict, error) {
+	generated, err := os.ReadFile(generatedFile)
+	if err != nil {
+		return nil, err
+	}
+
+	currentFile := filepath.Join(toDir, relFile)
+	baseFile := getBaseFile(toDir, relFile)
+	if !gofile.IsExists(currentFile) {
+		_ = os.MkdirAll(filepath.Dir(currentFile), 0766)
+		if err = os.WriteFile(currentFile, generated, 0666); err != nil {
+			return nil, err
+		}
+		fmt.Printf("add code \"%s\" successfully.\n", relFile)
+		return nil, saveBaseFile(baseFile, generated)
+	}
+
+	current, err := os.ReadFile(currentFile)
+	if err != nil {
+		return nil, err
+	}
+	base, _ := os.ReadFile(baseFile) // no base file, only the new code is added
+
+	result, err := mergeGoCode(base, current, generated)
+	if err != nil {
+		return nil, fmt.Errorf("%v, please merge codes manually, file = %s", err, relFile)
+	}
+	for _, c := range result.conflicts {
+		c.file = strings.ReplaceAll(relFile, "\\", "/")
+	}
+
+	if err = saveBaseFile(baseFile, generated); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(result.data, current) {
+		if err = os.WriteFile(currentFile, result.data, 0766); err != nil {
+			return nil, err
+		}
+		fmt.Printf("merge code to \"%s\" successfully.\n", relFile)
+	}
+
+	return result.conflicts, nil
+}
@@ -19,7 +19,7 @@ Examples:
 		RunE: func(cmd *cobra.Command, args []string) error {
 			mergeGRPCECode()
 			mergeGinRouters()
-			mergeGRPCServiceClientTmpl()
+			mergeGRPCServiceTmpl()
 			return nil
 		},
 	}
@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"github.com/zhufuyi/sponge/cmd/sponge/commands/migrate"
+
+	"github.com/spf13/cobra"
+)
+
+// MigrateCommand database schema migration
+func MigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "migrate",
+		Short:         "Command set for database schema migration",
+		Long:          `command set for database schema migration, generate versioned up/down migration files by comparing DDL, and apply or roll back them.`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+
+	cmd.AddCommand(
+		migrate.GenerateCommand(),
+		migrate.UpCommand(),
+		migrate.DownCommand(),
+		migrate.StatusCommand(),
+	)
+
+	return cmd
+}
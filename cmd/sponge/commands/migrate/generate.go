@@ -0,0 +1,103 @@
+// Package migrate is used to generate and execute database schema migration files.
+package migrate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zhufuyi/sponge/pkg/ggorm/migrate"
+	"github.com/zhufuyi/sponge/pkg/sql2code"
+
+	"github.com/spf13/cobra"
+)
+
+// GenerateCommand generate up/down migration files by comparing DDL
+func GenerateCommand() *cobra.Command {
+	var (
+		dbDriver string // database driver
+		oldFile  string // old ddl file
+		oldDsn   string // old database dsn
+		newFile  string // new ddl file
+		newDsn   string // new database dsn
+		dbTables string // table names
+		name     string // migration name
+		outPath  string // output directory
+	)
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate up/down migration files by comparing DDL",
+		Long: `generate up/down migration files by comparing DDL, the DDL source can be a sql file (mysql syntax) or a database.
+
+Examples:
+  # generate migration files by comparing the new sql file with the tables in the database.
+  sponge migrate generate --db-driver=mysql --old-db-dsn=root:123456@(192.168.3.37:3306)/test --new-file=./test.sql --db-table=user --name=add_user_phone
+
+  # generate migration files by comparing two databases.
+  sponge migrate generate --db-driver=postgresql --old-db-dsn=root:123456@192.168.3.37:5432/test --new-db-dsn=root:123456@192.168.3.37:5432/test2 --db-table=t1,t2 --name=sync_t1_t2
+
+  # generate migration files by comparing two sql files, and specify the output directory.
+  sponge migrate generate --old-file=./v1.sql --new-file=./v2.sql --name=v2 --out=./migrations
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if oldFile == "" && oldDsn == "" {
+				return fmt.Errorf(`one of the flag(s) "old-file" or "old-db-dsn" must be set, use "sponge migrate generate -h" for help`)
+			}
+			if newFile == "" && newDsn == "" {
+				return fmt.Errorf(`one of the flag(s) "new-file" or "new-db-dsn" must be set, use "sponge migrate generate -h" for help`)
+			}
+
+			tableNames := []string{""}
+			if dbTables != "" {
+				tableNames = strings.Split(dbTables, ",")
+			} else if oldDsn != "" || newDsn != "" {
+				return fmt.Errorf(`required flag(s) "db-table" not set when comparing database, use "sponge migrate generate -h" for help`)
+			}
+
+			var ups, downs []string
+			for _, tableName := range tableNames {
+				tableName = strings.TrimSpace(tableName)
+				if tableName == "" && dbTables != "" {
+					continue
+				}
+				oldArgs := &sql2code.Args{DBDriver: dbDriver, DDLFile: oldFile, DBDsn: oldDsn, DBTable: tableName}
+				newArgs := &sql2code.Args{DBDriver: dbDriver, DDLFile: newFile, DBDsn: newDsn, DBTable: tableName}
+				codes, err := sql2code.GenerateMigration(oldArgs, newArgs)
+				if err != nil {
+					return err
+				}
+				if codes.IsEmpty() {
+					continue
+				}
+				ups = append(ups, codes.Up)
+				downs = append([]string{codes.Down}, downs...) // roll back in reverse order
+			}
+			if len(ups) == 0 {
+				fmt.Println("no schema changes found, no need to generate migration files.")
+				return nil
+			}
+
+			upFile, downFile, err := migrate.WriteFiles(outPath, "", name, strings.Join(ups, "\n"), strings.Join(downs, "\n"))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("generate migration files successfully, out = %s, %s\n", upFile, downFile)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&dbDriver, "db-driver", "k", "mysql", "database driver, the dialect of the generated sql, support mysql, postgresql, tidb, sqlite")
+	cmd.Flags().StringVarP(&oldFile, "old-file", "", "", "old DDL sql file, mysql syntax")
+	cmd.Flags().StringVarP(&oldDsn, "old-db-dsn", "", "", "old database content address, e.g. user:password@(host:port)/database. Note: if db-driver=sqlite, db-dsn must be a local sqlite db file")
+	cmd.Flags().StringVarP(&newFile, "new-file", "", "", "new DDL sql file, mysql syntax")
+	cmd.Flags().StringVarP(&newDsn, "new-db-dsn", "", "", "new database content address, e.g. user:password@(host:port)/database")
+	cmd.Flags().StringVarP(&dbTables, "db-table", "t", "", "table name, multiple names separated by commas, if empty, all tables in the sql file are compared")
+	cmd.Flags().StringVarP(&name, "name", "n", "", "migration name, e.g. add_user_phone")
+	_ = cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVarP(&outPath, "out", "o", "./migrations", "output directory of migration files")
+
+	return cmd
+}
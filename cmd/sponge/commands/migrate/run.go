@@ -0,0 +1,177 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zhufuyi/sponge/pkg/ggorm"
+	"github.com/zhufuyi/sponge/pkg/ggorm/migrate"
+	"github.com/zhufuyi/sponge/pkg/utils"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+type dbFlags struct {
+	dbDriver  string
+	dbDsn     string
+	dir       string
+	tableName string
+}
+
+func (f *dbFlags) setFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.dbDriver, "db-driver", "k", "mysql", "database driver, support mysql, postgresql, tidb, sqlite")
+	cmd.Flags().StringVarP(&f.dbDsn, "db-dsn", "d", "", "database content address, e.g. user:password@(host:port)/database. Note: if db-driver=sqlite, db-dsn must be a local sqlite db file")
+	_ = cmd.MarkFlagRequired("db-dsn")
+	cmd.Flags().StringVarP(&f.dir, "dir", "", "./migrations", "directory of migration files")
+	cmd.Flags().StringVarP(&f.tableName, "table", "", migrate.DefaultTableName, "table name for recording applied versions")
+}
+
+func (f *dbFlags) newMigrator() (*migrate.Migrator, func(), error) {
+	var (
+		db  *gorm.DB
+		err error
+	)
+	switch strings.ToLower(f.dbDriver) {
+	case "mysql", "tidb":
+		db, err = ggorm.InitMysql(utils.AdaptiveMysqlDsn(f.dbDsn))
+	case "postgresql":
+		db, err = ggorm.InitPostgresql(utils.AdaptivePostgresqlDsn(f.dbDsn))
+	case "sqlite":
+		db, err = ggorm.InitSqlite(utils.AdaptiveSqlite(f.dbDsn))
+	default:
+		return nil, nil, fmt.Errorf("unsupported database driver: %s", f.dbDriver)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	closeFn := func() { _ = ggorm.CloseDB(db) }
+	return migrate.New(db, f.dir, migrate.WithTableName(f.tableName)), closeFn, nil
+}
+
+// UpCommand apply all pending migrations
+func UpCommand() *cobra.Command {
+	f := &dbFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Long: `apply all pending migrations.
+
+Examples:
+  # apply all pending migrations in directory ./migrations.
+  sponge migrate up --db-driver=mysql --db-dsn=root:123456@(192.168.3.37:3306)/test
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, closeFn, err := f.newMigrator()
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			versions, err := m.Up(context.Background())
+			for _, version := range versions {
+				fmt.Printf("applied migration %s\n", version)
+			}
+			if err != nil {
+				return err
+			}
+			if len(versions) == 0 {
+				fmt.Println("no pending migrations.")
+			}
+			return nil
+		},
+	}
+	f.setFlags(cmd)
+
+	return cmd
+}
+
+// DownCommand roll back applied migrations
+func DownCommand() *cobra.Command {
+	var steps int
+	f := &dbFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "Roll back applied migrations",
+		Long: `roll back applied migrations.
+
+Examples:
+  # roll back the latest applied migration.
+  sponge migrate down --db-driver=mysql --db-dsn=root:123456@(192.168.3.37:3306)/test
+
+  # roll back all applied migrations.
+  sponge migrate down --db-driver=mysql --db-dsn=root:123456@(192.168.3.37:3306)/test --steps=0
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, closeFn, err := f.newMigrator()
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			versions, err := m.Down(context.Background(), steps)
+			for _, version := range versions {
+				fmt.Printf("rolled back migration %s\n", version)
+			}
+			if err != nil {
+				return err
+			}
+			if len(versions) == 0 {
+				fmt.Println("no applied migrations.")
+			}
+			return nil
+		},
+	}
+	f.setFlags(cmd)
+	cmd.Flags().IntVarP(&steps, "steps", "s", 1, "number of migrations to roll back, 0 means all")
+
+	return cmd
+}
+
+// StatusCommand show the status of migrations
+func StatusCommand() *cobra.Command {
+	f := &dbFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of migrations",
+		Long: `show the status of migrations.
+
+Examples:
+  sponge migrate status --db-driver=mysql --db-dsn=root:123456@(192.168.3.37:3306)/test
+`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, closeFn, err := f.newMigrator()
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			statuses, err := m.Status(context.Background())
+			if err != nil {
+				return err
+			}
+			for _, s := range statuses {
+				appliedAt := "pending"
+				if s.IsApplied {
+					appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Printf("%s  %-40s  %s\n", s.Version, s.Name, appliedAt)
+			}
+			return nil
+		},
+	}
+	f.setFlags(cmd)
+
+	return cmd
+}
@@ -38,6 +38,7 @@ docs: https://go-sponge.com`,
 		OpenUICommand(),
 		MergeCommand(),
 		PatchCommand(),
+		MigrateCommand(),
 	)
 
 	return cmd
@@ -25,6 +25,7 @@ func GenWebCommand() *cobra.Command {
 		generate.HTTPPbCommand(),
 		generate.ConvertSwagJSONCommand("web"),
 		generate.HandlerPbCommand(),
+		generate.HandlerOpenAPICommand(),
 	)
 
 	return cmd
@@ -407,8 +407,15 @@ const docTemplate = `{
         "github_com_zhufuyi_sponge_internal_types.Column": {
             "type": "object",
             "properties": {
+                "columns": {
+                    "description": "sub conditions in parentheses, if not empty, the name, exp and value are ignored",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_zhufuyi_sponge_internal_types.Column"
+                    }
+                },
                 "exp": {
-                    "description": "expressions, which default to = when the value is null, have =, !=, \u003e, \u003e=, \u003c, \u003c=, like",
+                    "description": "expressions, which default to = when the value is null, have =, !=, \u003e, \u003e=, \u003c, \u003c=, like, in, notin, between, isnull, notnull, prefix, suffix",
                     "type": "string"
                 },
                 "logic": {
@@ -446,6 +453,10 @@ const docTemplate = `{
                         "$ref": "#/definitions/github_com_zhufuyi_sponge_internal_types.Column"
                     }
                 },
+                "cursor": {
+                    "description": "cursor of keyset pagination returned by the previous page, if not empty, the page is ignored",
+                    "type": "string"
+                },
                 "page": {
                     "description": "page number, starting from page 0",
                     "type": "integer"
@@ -669,6 +680,14 @@ const docTemplate = `{
                     "description": "return data",
                     "type": "object",
                     "properties": {
+                        "nextCursor": {
+                            "description": "cursor of next page, empty if there is no more data",
+                            "type": "string"
+                        },
+                        "total": {
+                            "description": "total number of records",
+                            "type": "integer"
+                        },
                         "userExamples": {
                             "type": "array",
                             "items": {
@@ -35,54 +35,43 @@ type CacheNameExampleCache interface {
 }
 
 type cacheNameExampleCache struct {
-	cache cache.Cache
+	cache *cache.Typed[keyTypeExample, valueTypeExample]
 }
 
 // NewCacheNameExampleCache create a new cache
 func NewCacheNameExampleCache(cacheType *model.CacheType) CacheNameExampleCache {
-	newObject := func() interface{} {
-		return ""
-	}
 	cachePrefix := ""
 	jsonEncoding := encoding.JSONEncoding{}
 
+	var c cache.Cache
 	cType := strings.ToLower(cacheType.CType)
 	switch cType {
 	case "redis":
-		c := cache.NewRedisCache(cacheType.Rdb, cachePrefix, jsonEncoding, newObject)
-		return &cacheNameExampleCache{cache: c}
+		c = cache.NewRedisCache(cacheType.Rdb, cachePrefix, jsonEncoding, nil) // the values are decoded by Typed
+	case "multi":
+		c = cache.NewMultiCache(cacheType.Rdb, cachePrefix, jsonEncoding, nil)
 	case "memory":
-		c := cache.NewMemoryCache(cachePrefix, jsonEncoding, newObject)
-		return &cacheNameExampleCache{cache: c}
+		c = cache.NewMemoryCache(cachePrefix, jsonEncoding, nil)
+	default:
+		panic(fmt.Sprintf("unsupported cache type='%s'", cacheType.CType))
 	}
 
-	panic(fmt.Sprintf("unsupported cache type='%s'", cacheType.CType))
-}
-
-// cache key
-func (c *cacheNameExampleCache) getCacheKey(keyNameExample keyTypeExample) string {
-	return fmt.Sprintf("%s%v", cacheNameExampleCachePrefixKey, keyNameExample)
+	return &cacheNameExampleCache{
+		cache: cache.NewTyped[keyTypeExample, valueTypeExample](c, cacheNameExampleCachePrefixKey),
+	}
 }
 
 // Set cache
 func (c *cacheNameExampleCache) Set(ctx context.Context, keyNameExample keyTypeExample, valueNameExample valueTypeExample, duration time.Duration) error {
-	cacheKey := c.getCacheKey(keyNameExample)
-	return c.cache.Set(ctx, cacheKey, &valueNameExample, duration)
+	return c.cache.Set(ctx, keyNameExample, valueNameExample, duration)
 }
 
 // Get cache
 func (c *cacheNameExampleCache) Get(ctx context.Context, keyNameExample keyTypeExample) (valueTypeExample, error) {
-	var valueNameExample valueTypeExample
-	cacheKey := c.getCacheKey(keyNameExample)
-	err := c.cache.Get(ctx, cacheKey, &valueNameExample)
-	if err != nil {
-		return valueNameExample, err
-	}
-	return valueNameExample, nil
+	return c.cache.Get(ctx, keyNameExample)
 }
 
 // Del delete cache
 func (c *cacheNameExampleCache) Del(ctx context.Context, keyNameExample keyTypeExample) error {
-	cacheKey := c.getCacheKey(keyNameExample)
-	return c.cache.Del(ctx, cacheKey)
+	return c.cache.Del(ctx, keyNameExample)
 }
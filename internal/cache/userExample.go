@@ -9,7 +9,7 @@ import (
 
 	"github.com/zhufuyi/sponge/pkg/cache"
 	"github.com/zhufuyi/sponge/pkg/encoding"
-	"github.com/zhufuyi/sponge/pkg/utils"
+	"github.com/zhufuyi/sponge/pkg/gocrypto"
 )
 
 const (
@@ -17,8 +17,17 @@ const (
 	userExampleCachePrefixKey = "userExample:"
 	// UserExampleExpireTime expire time
 	UserExampleExpireTime = 5 * time.Minute
+
+	// cache prefix key of list
+	userExampleListCachePrefixKey = "userExample:list:"
+	// tag of all lists, used to delete all lists
+	userExampleListCacheTag = "userExample:list"
 )
 
+// UserExampleListExpireTime expire time of the paging list, if greater than 0, the list of GetByColumns is cached,
+// and all lists are deleted when any record is created, updated or deleted.
+var UserExampleListExpireTime time.Duration
+
 var _ UserExampleCache = (*userExampleCache)(nil)
 
 // UserExampleCache cache interface
@@ -29,11 +38,23 @@ type UserExampleCache interface {
 	MultiSet(ctx context.Context, data []*model.UserExample, duration time.Duration) error
 	Del(ctx context.Context, id uint64) error
 	SetCacheWithNotFound(ctx context.Context, id uint64) error
+	Load(ctx context.Context, id uint64, fn func(ctx context.Context) (*model.UserExample, error)) (*model.UserExample, error)
+
+	SetList(ctx context.Context, listKey string, records []*model.UserExample, total int64, duration time.Duration) error
+	GetList(ctx context.Context, listKey string) ([]*model.UserExample, int64, error)
+	DelList(ctx context.Context) error
+}
+
+// userExampleList paging list of records
+type userExampleList struct {
+	Records []*model.UserExample `json:"records"`
+	Total   int64                `json:"total"`
 }
 
 // userExampleCache define a cache struct
 type userExampleCache struct {
-	cache cache.Cache
+	cache     *cache.Typed[uint64, *model.UserExample]
+	listCache *cache.Typed[string, *userExampleList]
 }
 
 // NewUserExampleCache new a cache
@@ -41,26 +62,28 @@ func NewUserExampleCache(cacheType *model.CacheType) UserExampleCache {
 	jsonEncoding := encoding.JSONEncoding{}
 	cachePrefix := ""
 
+	var c cache.Cache
 	cType := strings.ToLower(cacheType.CType)
 	switch cType {
 	case "redis":
-		c := cache.NewRedisCache(cacheType.Rdb, cachePrefix, jsonEncoding, func() interface{} {
-			return &model.UserExample{}
-		})
-		return &userExampleCache{cache: c}
+		c = cache.NewRedisCache(cacheType.Rdb, cachePrefix, jsonEncoding, nil) // the values are decoded by Typed
+	case "multi":
+		c = cache.NewMultiCache(cacheType.Rdb, cachePrefix, jsonEncoding, nil)
 	case "memory":
-		c := cache.NewMemoryCache(cachePrefix, jsonEncoding, func() interface{} {
-			return &model.UserExample{}
-		})
-		return &userExampleCache{cache: c}
+		c = cache.NewMemoryCache(cachePrefix, jsonEncoding, nil)
+	default:
+		return nil // no cache
 	}
 
-	return nil // no cache
+	return &userExampleCache{
+		cache:     cache.NewTyped[uint64, *model.UserExample](c, userExampleCachePrefixKey, cache.WithNotFoundError(model.ErrRecordNotFound)),
+		listCache: cache.NewTyped[string, *userExampleList](c, userExampleListCachePrefixKey),
+	}
 }
 
 // GetUserExampleCacheKey cache key
 func (c *userExampleCache) GetUserExampleCacheKey(id uint64) string {
-	return userExampleCachePrefixKey + utils.Uint64ToStr(id)
+	return c.cache.CacheKey(id)
 }
 
 // Set write to cache
@@ -68,82 +91,66 @@ func (c *userExampleCache) Set(ctx context.Context, id uint64, data *model.UserE
 	if data == nil || id == 0 {
 		return nil
 	}
-	cacheKey := c.GetUserExampleCacheKey(id)
-	err := c.cache.Set(ctx, cacheKey, data, duration)
-	if err != nil {
-		return err
-	}
-	return nil
+	return c.cache.Set(ctx, id, data, duration)
 }
 
 // Get cache value
 func (c *userExampleCache) Get(ctx context.Context, id uint64) (*model.UserExample, error) {
-	var data *model.UserExample
-	cacheKey := c.GetUserExampleCacheKey(id)
-	err := c.cache.Get(ctx, cacheKey, &data)
-	if err != nil {
-		return nil, err
-	}
-	return data, nil
+	return c.cache.Get(ctx, id)
 }
 
 // MultiSet multiple set cache
 func (c *userExampleCache) MultiSet(ctx context.Context, data []*model.UserExample, duration time.Duration) error {
-	valMap := make(map[string]interface{})
+	valMap := make(map[uint64]*model.UserExample)
 	for _, v := range data {
-		cacheKey := c.GetUserExampleCacheKey(v.ID)
-		valMap[cacheKey] = v
-	}
-
-	err := c.cache.MultiSet(ctx, valMap, duration)
-	if err != nil {
-		return err
+		valMap[v.ID] = v
 	}
-
-	return nil
+	return c.cache.MultiSet(ctx, valMap, duration)
 }
 
 // MultiGet multiple get cache, return key in map is id value
 func (c *userExampleCache) MultiGet(ctx context.Context, ids []uint64) (map[uint64]*model.UserExample, error) {
-	var keys []string
-	for _, v := range ids {
-		cacheKey := c.GetUserExampleCacheKey(v)
-		keys = append(keys, cacheKey)
-	}
-
-	itemMap := make(map[string]*model.UserExample)
-	err := c.cache.MultiGet(ctx, keys, itemMap)
-	if err != nil {
-		return nil, err
-	}
-
-	retMap := make(map[uint64]*model.UserExample)
-	for _, id := range ids {
-		val, ok := itemMap[c.GetUserExampleCacheKey(id)]
-		if ok {
-			retMap[id] = val
-		}
-	}
-
-	return retMap, nil
+	return c.cache.MultiGet(ctx, ids)
 }
 
 // Del delete cache
 func (c *userExampleCache) Del(ctx context.Context, id uint64) error {
-	cacheKey := c.GetUserExampleCacheKey(id)
-	err := c.cache.Del(ctx, cacheKey)
-	if err != nil {
-		return err
-	}
-	return nil
+	return c.cache.Del(ctx, id)
 }
 
 // SetCacheWithNotFound set empty cache
 func (c *userExampleCache) SetCacheWithNotFound(ctx context.Context, id uint64) error {
-	cacheKey := c.GetUserExampleCacheKey(id)
-	err := c.cache.SetCacheWithNotFound(ctx, cacheKey)
+	return c.cache.SetCacheWithNotFound(ctx, id)
+}
+
+// Load get from cache, if the cache misses, call fn to get the record from database and set cache,
+// the concurrent loads of the same id are merged into one, if the record does not exist, cache a placeholder
+// and return model.ErrRecordNotFound, the expiry time is randomly increased and the record is refreshed before it expires.
+func (c *userExampleCache) Load(ctx context.Context, id uint64, fn func(ctx context.Context) (*model.UserExample, error)) (*model.UserExample, error) {
+	return c.cache.Load(ctx, id, UserExampleExpireTime, fn)
+}
+
+// GetUserExampleListCacheKey cache key of list, listKey is the unique key of query params, e.g. json of params
+func (c *userExampleCache) GetUserExampleListCacheKey(listKey string) string {
+	return c.listCache.CacheKey(gocrypto.Md5([]byte(listKey)))
+}
+
+// SetList write the paging list to cache
+func (c *userExampleCache) SetList(ctx context.Context, listKey string, records []*model.UserExample, total int64, duration time.Duration) error {
+	data := &userExampleList{Records: records, Total: total}
+	return c.listCache.SetWithTags(ctx, gocrypto.Md5([]byte(listKey)), data, duration, userExampleListCacheTag)
+}
+
+// GetList get the paging list from cache
+func (c *userExampleCache) GetList(ctx context.Context, listKey string) ([]*model.UserExample, int64, error) {
+	data, err := c.listCache.Get(ctx, gocrypto.Md5([]byte(listKey)))
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
-	return nil
+	return data.Records, data.Total, nil
+}
+
+// DelList delete all paging lists
+func (c *userExampleCache) DelList(ctx context.Context) error {
+	return c.listCache.DelByTag(ctx, userExampleListCacheTag)
 }
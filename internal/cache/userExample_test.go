@@ -1,11 +1,14 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/zhufuyi/sponge/internal/model"
 
+	"github.com/zhufuyi/sponge/pkg/cache"
 	"github.com/zhufuyi/sponge/pkg/gotest"
 	"github.com/zhufuyi/sponge/pkg/utils"
 
@@ -128,6 +131,62 @@ func Test_userExampleCache_SetCacheWithNotFound(t *testing.T) {
 	}
 }
 
+func Test_userExampleCache_Load(t *testing.T) {
+	c := newUserExampleCache()
+	defer c.Close()
+
+	record := c.TestDataSlice[0].(*model.UserExample)
+	got, err := c.ICache.(UserExampleCache).Load(c.Ctx, record.ID, func(ctx context.Context) (*model.UserExample, error) {
+		return record, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, record, got)
+
+	// hit cache
+	got, err = c.ICache.(UserExampleCache).Load(c.Ctx, record.ID, func(ctx context.Context) (*model.UserExample, error) {
+		return nil, errors.New("should not be called")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, record.ID, got.ID)
+
+	// not found
+	_, err = c.ICache.(UserExampleCache).Load(c.Ctx, 10, func(ctx context.Context) (*model.UserExample, error) {
+		return nil, model.ErrRecordNotFound
+	})
+	assert.ErrorIs(t, err, model.ErrRecordNotFound)
+	_, err = c.ICache.(UserExampleCache).Get(c.Ctx, 10)
+	assert.ErrorIs(t, err, cache.ErrPlaceholder)
+}
+
+func Test_userExampleCache_List(t *testing.T) {
+	c := newUserExampleCache()
+	defer c.Close()
+
+	var records []*model.UserExample
+	for _, v := range c.TestDataSlice {
+		records = append(records, v.(*model.UserExample))
+	}
+	listKey := `{"page":0,"limit":10}`
+	err := c.ICache.(UserExampleCache).SetList(c.Ctx, listKey, records, 2, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, total, err := c.ICache.(UserExampleCache).GetList(c.Ctx, listKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, len(records), len(got))
+
+	err = c.ICache.(UserExampleCache).DelList(c.Ctx)
+	assert.NoError(t, err)
+	_, _, err = c.ICache.(UserExampleCache).GetList(c.Ctx, listKey)
+	assert.ErrorIs(t, err, cache.CacheNotFound)
+}
+
 func TestNewUserExampleCache(t *testing.T) {
 	c := NewUserExampleCache(&model.CacheType{
 		CType: "",
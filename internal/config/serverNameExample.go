@@ -39,9 +39,39 @@ type Config struct {
 	Jaeger     Jaeger       `yaml:"jaeger" json:"jaeger"`
 	Logger     Logger       `yaml:"logger" json:"logger"`
 	NacosRd    NacosRd      `yaml:"nacosRd" json:"nacosRd"`
+	Policy     Policy       `yaml:"policy" json:"policy"`
 	Redis      Redis        `yaml:"redis" json:"redis"`
 }
 
+type Policy struct {
+	CircuitBreakers []CircuitBreakers `yaml:"circuitBreakers" json:"circuitBreakers"`
+	RateLimits      []RateLimits      `yaml:"rateLimits" json:"rateLimits"`
+}
+
+type RateLimits struct {
+	Bucket       int     `yaml:"bucket" json:"bucket"`
+	Burst        int     `yaml:"burst" json:"burst"`
+	CPUThreshold int64   `yaml:"cpuThreshold" json:"cpuThreshold"`
+	Limit        int     `yaml:"limit" json:"limit"`
+	Rate         float64 `yaml:"rate" json:"rate"`
+	Route        string  `yaml:"route" json:"route"`
+	Type         string  `yaml:"type" json:"type"`
+	Window       int     `yaml:"window" json:"window"`
+}
+
+type CircuitBreakers struct {
+	Bucket              int     `yaml:"bucket" json:"bucket"`
+	ConsecutiveFailures int64   `yaml:"consecutiveFailures" json:"consecutiveFailures"`
+	ErrorRate           float64 `yaml:"errorRate" json:"errorRate"`
+	HalfOpenProbes      int     `yaml:"halfOpenProbes" json:"halfOpenProbes"`
+	OpenDuration        int     `yaml:"openDuration" json:"openDuration"`
+	Request             int64   `yaml:"request" json:"request"`
+	Route               string  `yaml:"route" json:"route"`
+	Success             float64 `yaml:"success" json:"success"`
+	Type                string  `yaml:"type" json:"type"`
+	Window              int     `yaml:"window" json:"window"`
+}
+
 type Consul struct {
 	Addr string `yaml:"addr" json:"addr"`
 }
@@ -129,10 +159,15 @@ type Postgresql struct {
 }
 
 type Redis struct {
-	DialTimeout  int    `yaml:"dialTimeout" json:"dialTimeout"`
-	Dsn          string `yaml:"dsn" json:"dsn"`
-	ReadTimeout  int    `yaml:"readTimeout" json:"readTimeout"`
-	WriteTimeout int    `yaml:"writeTimeout" json:"writeTimeout"`
+	Addrs        []string `yaml:"addrs" json:"addrs"`
+	DialTimeout  int      `yaml:"dialTimeout" json:"dialTimeout"`
+	Dsn          string   `yaml:"dsn" json:"dsn"`
+	MasterName   string   `yaml:"masterName" json:"masterName"`
+	Mode         string   `yaml:"mode" json:"mode"`
+	Password     string   `yaml:"password" json:"password"`
+	ReadTimeout  int      `yaml:"readTimeout" json:"readTimeout"`
+	Username     string   `yaml:"username" json:"username"`
+	WriteTimeout int      `yaml:"writeTimeout" json:"writeTimeout"`
 }
 
 type Database struct {
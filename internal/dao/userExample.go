@@ -2,23 +2,38 @@ package dao
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/zhufuyi/sponge/internal/cache"
 	"github.com/zhufuyi/sponge/internal/model"
 
 	cacheBase "github.com/zhufuyi/sponge/pkg/cache"
+	"github.com/zhufuyi/sponge/pkg/ggorm"
 	"github.com/zhufuyi/sponge/pkg/ggorm/query"
-	"github.com/zhufuyi/sponge/pkg/utils"
 
-	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
 )
 
 var _ UserExampleDao = (*userExampleDao)(nil)
 
+// userExampleQueryValidator restrict the columns that can be used in the query conditions and sort,
+// the values of query conditions are converted to the types of columns, delete the columns that are not allowed to be queried.
+var userExampleQueryValidator = query.NewValidator(map[string]string{
+	"id":         query.TypeUint,
+	"created_at": query.TypeTime,
+	"updated_at": query.TypeTime,
+	"name":       query.TypeString,
+	"email":      query.TypeString,
+	"phone":      query.TypeString,
+	"avatar":     query.TypeString,
+	"age":        query.TypeInt,
+	"gender":     query.TypeInt,
+	"status":     query.TypeInt,
+	"login_at":   query.TypeInt,
+})
+
 // UserExampleDao defining the dao interface
 type UserExampleDao interface {
 	Create(ctx context.Context, table *model.UserExample) error
@@ -39,26 +54,32 @@ type UserExampleDao interface {
 type userExampleDao struct {
 	db    *gorm.DB
 	cache cache.UserExampleCache // if nil, the cache is not used.
-	sfg   *singleflight.Group    // if cache is nil, the sfg is not used.
 }
 
 // NewUserExampleDao creating the dao interface
 func NewUserExampleDao(db *gorm.DB, xCache cache.UserExampleCache) UserExampleDao {
-	if xCache == nil {
-		return &userExampleDao{db: db}
-	}
 	return &userExampleDao{
 		db:    db,
 		cache: xCache,
-		sfg:   new(singleflight.Group),
 	}
 }
 
-func (d *userExampleDao) deleteCache(ctx context.Context, id uint64) error {
-	if d.cache != nil {
-		return d.cache.Del(ctx, id)
+// delete the cache of the records, and the cached lists that may contain the records
+func (d *userExampleDao) deleteCache(ctx context.Context, ids ...uint64) error {
+	if d.cache == nil {
+		return nil
 	}
-	return nil
+
+	if cache.UserExampleListExpireTime > 0 {
+		_ = d.cache.DelList(ctx)
+	}
+	var err error
+	for _, id := range ids {
+		if e := d.cache.Del(ctx, id); e != nil {
+			err = e
+		}
+	}
+	return err
 }
 
 // Create a record, insert the record and the id value is written back to the table
@@ -89,9 +110,7 @@ func (d *userExampleDao) DeleteByIDs(ctx context.Context, ids []uint64) error {
 	}
 
 	// delete cache
-	for _, id := range ids {
-		_ = d.deleteCache(ctx, id)
-	}
+	_ = d.deleteCache(ctx, ids...)
 
 	return nil
 }
@@ -152,58 +171,23 @@ func (d *userExampleDao) GetByID(ctx context.Context, id uint64) (*model.UserExa
 		return record, err
 	}
 
-	// get from cache or database
-	record, err := d.cache.Get(ctx, id)
-	if err == nil {
-		return record, nil
-	}
-
-	if errors.Is(err, model.ErrCacheNotFound) {
-		// for the same id, prevent high concurrent simultaneous access to database
-		val, err, _ := d.sfg.Do(utils.Uint64ToStr(id), func() (interface{}, error) { //nolint
-			table := &model.UserExample{}
-			err = d.db.WithContext(ctx).Where("id = ?", id).First(table).Error
-			if err != nil {
-				// if data is empty, set not found cache to prevent cache penetration, default expiration time 10 minutes
-				if errors.Is(err, model.ErrRecordNotFound) {
-					err = d.cache.SetCacheWithNotFound(ctx, id)
-					if err != nil {
-						return nil, err
-					}
-					return nil, model.ErrRecordNotFound
-				}
-				return nil, err
-			}
-			// set cache
-			err = d.cache.Set(ctx, id, table, cache.UserExampleExpireTime)
-			if err != nil {
-				return nil, fmt.Errorf("cache.Set error: %v, id=%d", err, id)
-			}
-			return table, nil
-		})
-		if err != nil {
-			return nil, err
-		}
-		table, ok := val.(*model.UserExample)
-		if !ok {
-			return nil, model.ErrRecordNotFound
-		}
-		return table, nil
-	} else if errors.Is(err, cacheBase.ErrPlaceholder) {
-		return nil, model.ErrRecordNotFound
-	}
-
-	// fail fast, if cache error return, don't request to db
-	return nil, err
+	// get from cache, or get from database and set cache when the cache misses,
+	// for the same id, prevent high concurrent simultaneous access to database
+	return d.cache.Load(ctx, id, func(ctx context.Context) (*model.UserExample, error) {
+		record := &model.UserExample{}
+		err := d.db.WithContext(ctx).Where("id = ?", id).First(record).Error
+		return record, err
+	})
 }
 
 // GetByCondition get a record by condition
 // query conditions:
 //
 //	name: column name
-//	exp: expressions, which default is "=",  support =, !=, >, >=, <, <=, like, in
-//	value: column value, if exp=in, multiple values are separated by commas
+//	exp: expressions, which default is "=",  support =, !=, >, >=, <, <=, like, in, notin, between, isnull, notnull, prefix, suffix
+//	value: column value, if exp=in, notin or between, multiple values are separated by commas
 //	logic: logical type, defaults to and when value is null, only &(and), ||(or)
+//	columns: sub conditions in parentheses, if not empty, the name, exp and value are ignored
 //
 // example: find a male aged 20
 //
@@ -219,10 +203,14 @@ func (d *userExampleDao) GetByID(ctx context.Context, id uint64) (*model.UserExa
 //		},
 //	}
 func (d *userExampleDao) GetByCondition(ctx context.Context, c *query.Conditions) (*model.UserExample, error) {
-	queryStr, args, err := c.ConvertToGorm()
+	err := userExampleQueryValidator.ValidateColumns(c.Columns)
 	if err != nil {
 		return nil, err
 	}
+	queryStr, args, err := c.ConvertToGorm(query.WithDBDriver(d.db.Dialector.Name()))
+	if err != nil {
+		return nil, &query.FieldError{Field: "columns", Msg: err.Error()}
+	}
 
 	table := &model.UserExample{}
 	err = d.db.WithContext(ctx).Where(queryStr, args...).First(table).Error
@@ -304,10 +292,15 @@ func (d *userExampleDao) GetByIDs(ctx context.Context, ids []uint64) (map[uint64
 
 // GetByLastID get paging records by last id and limit
 func (d *userExampleDao) GetByLastID(ctx context.Context, lastID uint64, limit int, sort string) ([]*model.UserExample, error) {
+	if err := userExampleQueryValidator.ValidateSort(sort); err != nil {
+		return nil, err
+	}
+	// query by last id code start
 	page := query.NewPage(0, limit, sort)
 
 	records := []*model.UserExample{}
 	err := d.db.WithContext(ctx).Order(page.Sort()).Limit(page.Size()).Where("id < ?", lastID).Find(&records).Error
+	// query by last id code end
 	if err != nil {
 		return nil, err
 	}
@@ -323,13 +316,15 @@ func (d *userExampleDao) GetByLastID(ctx context.Context, lastID uint64, limit i
 //	page: page number, starting from 0
 //	size: lines per page
 //	sort: sort fields, default is id backwards, you can add - sign before the field to indicate reverse order, no - sign to indicate ascending order, multiple fields separated by comma
+//	cursor: cursor of keyset pagination returned by the previous page, if not empty, the page is ignored, the combination of sort fields must be unique
 //
 // query parameters (not required):
 //
 //	name: column name
-//	exp: expressions, which default is "=",  support =, !=, >, >=, <, <=, like, in
-//	value: column value, if exp=in, multiple values are separated by commas
+//	exp: expressions, which default is "=",  support =, !=, >, >=, <, <=, like, in, notin, between, isnull, notnull, prefix, suffix
+//	value: column value, if exp=in, notin or between, multiple values are separated by commas
 //	logic: logical type, defaults to and when value is null, only &(and), ||(or)
+//	columns: sub conditions in parentheses, if not empty, the name, exp and value are ignored
 //
 // example: search for a male over 20 years of age
 //
@@ -348,13 +343,51 @@ func (d *userExampleDao) GetByLastID(ctx context.Context, lastID uint64, limit i
 //		},
 //	}
 func (d *userExampleDao) GetByColumns(ctx context.Context, params *query.Params) ([]*model.UserExample, int64, error) {
-	queryStr, args, err := params.ConvertToGormConditions()
+	// no list cache
+	if d.cache == nil || cache.UserExampleListExpireTime <= 0 {
+		return d.getByColumns(ctx, params)
+	}
+
+	// build the list key before querying, the params are modified by validation
+	listKey, err := json.Marshal(params)
+	if err != nil {
+		return nil, 0, err
+	}
+	records, total, err := d.cache.GetList(ctx, string(listKey))
+	if err == nil {
+		return records, total, nil
+	}
+
+	records, total, err = d.getByColumns(ctx, params)
 	if err != nil {
-		return nil, 0, errors.New("query params error: " + err.Error())
+		return nil, 0, err
+	}
+	_ = d.cache.SetList(ctx, string(listKey), records, total, cache.UserExampleListExpireTime)
+
+	return records, total, nil
+}
+
+// get paging records from database
+func (d *userExampleDao) getByColumns(ctx context.Context, params *query.Params) ([]*model.UserExample, int64, error) {
+	// the sort "ignore count" skips the count and uses the default sort
+	isIgnoreCount := params.Sort == "ignore count"
+	if isIgnoreCount {
+		p := *params
+		p.Sort = ""
+		params = &p
+	}
+
+	err := userExampleQueryValidator.Validate(params)
+	if err != nil {
+		return nil, 0, err
+	}
+	queryStr, args, err := params.ConvertToGormConditions(query.WithDBDriver(d.db.Dialector.Name()))
+	if err != nil {
+		return nil, 0, &query.FieldError{Field: "columns", Msg: err.Error()}
 	}
 
 	var total int64
-	if params.Sort != "ignore count" { // determine if count is required
+	if !isIgnoreCount { // determine if count is required
 		err = d.db.WithContext(ctx).Model(&model.UserExample{}).Select([]string{"id"}).Where(queryStr, args...).Count(&total).Error
 		if err != nil {
 			return nil, 0, err
@@ -366,7 +399,15 @@ func (d *userExampleDao) GetByColumns(ctx context.Context, params *query.Params)
 
 	records := []*model.UserExample{}
 	order, limit, offset := params.ConvertToPage()
-	err = d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...).Find(&records).Error
+	cursorStr, cursorArgs, err := params.ConvertToCursorConditions()
+	if err != nil {
+		return nil, 0, &query.FieldError{Field: "cursor", Msg: err.Error()}
+	}
+	db := d.db.WithContext(ctx).Order(order).Limit(limit).Offset(offset).Where(queryStr, args...)
+	if cursorStr != "" {
+		db = db.Where(cursorStr, cursorArgs...)
+	}
+	err = db.Find(&records).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -374,13 +415,19 @@ func (d *userExampleDao) GetByColumns(ctx context.Context, params *query.Params)
 	return records, total, err
 }
 
-// CreateByTx create a record in the database using the provided transaction
+// CreateByTx create a record in the database using the provided transaction,
+// the cache is deleted after the transaction is committed if tx is started by ggorm.Transaction, otherwise it is deleted immediately.
 func (d *userExampleDao) CreateByTx(ctx context.Context, tx *gorm.DB, table *model.UserExample) (uint64, error) {
 	err := tx.WithContext(ctx).Create(table).Error
+
+	// delete cache after commit
+	ggorm.AfterCommit(tx, func() { _ = d.deleteCache(ctx, table.ID) })
+
 	return table.ID, err
 }
 
-// DeleteByTx delete a record by id in the database using the provided transaction
+// DeleteByTx delete a record by id in the database using the provided transaction,
+// the cache is deleted after the transaction is committed if tx is started by ggorm.Transaction, otherwise it is deleted immediately.
 func (d *userExampleDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64) error {
 	update := map[string]interface{}{
 		"deleted_at": time.Now(),
@@ -390,18 +437,19 @@ func (d *userExampleDao) DeleteByTx(ctx context.Context, tx *gorm.DB, id uint64)
 		return err
 	}
 
-	// delete cache
-	_ = d.deleteCache(ctx, id)
+	// delete cache after commit
+	ggorm.AfterCommit(tx, func() { _ = d.deleteCache(ctx, id) })
 
 	return nil
 }
 
-// UpdateByTx update a record by id in the database using the provided transaction
+// UpdateByTx update a record by id in the database using the provided transaction,
+// the cache is deleted after the transaction is committed if tx is started by ggorm.Transaction, otherwise it is deleted immediately.
 func (d *userExampleDao) UpdateByTx(ctx context.Context, tx *gorm.DB, table *model.UserExample) error {
 	err := d.updateDataByID(ctx, tx, table)
 
-	// delete cache
-	_ = d.deleteCache(ctx, table.ID)
+	// delete cache after commit
+	ggorm.AfterCommit(tx, func() { _ = d.deleteCache(ctx, table.ID) })
 
 	return err
 }
@@ -273,7 +273,8 @@ func Test_userExampleDao_GetByLastID(t *testing.T) {
 
 	// err test
 	_, err = d.IDao.(UserExampleDao).GetByLastID(d.Ctx, 0, 10, "unknown-column")
-	assert.Error(t, err)
+	var fieldErr *query.FieldError
+	assert.ErrorAs(t, err, &fieldErr)
 }
 
 func Test_userExampleDao_GetByColumns(t *testing.T) {
@@ -314,12 +315,72 @@ func Test_userExampleDao_GetByColumns(t *testing.T) {
 	})
 	assert.Error(t, err)
 
+	// column not allowed
+	_, _, err = d.IDao.(UserExampleDao).GetByColumns(d.Ctx, &query.Params{
+		Page: 0,
+		Size: 10,
+		Columns: []query.Column{
+			{
+				Name:  "password",
+				Value: "foo",
+			},
+		},
+	})
+	assert.Error(t, err)
+
 	// error test
 	dao := &userExampleDao{}
 	_, _, err = dao.GetByColumns(context.Background(), &query.Params{Columns: []query.Column{{}}})
 	t.Log(err)
 }
 
+func Test_userExampleDao_GetByColumnsWithListCache(t *testing.T) {
+	cache.UserExampleListExpireTime = time.Minute
+	defer func() { cache.UserExampleListExpireTime = 0 }()
+
+	d := newUserExampleDao()
+	defer d.Close()
+	testData := d.TestData.(*model.UserExample)
+	params := &query.Params{
+		Page: 0,
+		Size: 10,
+		Sort: "ignore count", // ignore test count(*)
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
+		AddRow(testData.ID, testData.CreatedAt, testData.UpdatedAt)
+	d.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(rows)
+	records, _, err := d.IDao.(UserExampleDao).GetByColumns(d.Ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(records))
+
+	// get from cache, no query
+	records, _, err = d.IDao.(UserExampleDao).GetByColumns(d.Ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(records))
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the lists are deleted after writing
+	_ = d.IDao.(*userExampleDao).deleteCache(d.Ctx, testData.ID)
+	d.SQLMock.ExpectQuery("SELECT .*").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	records, _, err = d.IDao.(UserExampleDao).GetByColumns(d.Ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(records))
+	err = d.SQLMock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func Test_userExampleDao_CreateByTx(t *testing.T) {
 	d := newUserExampleDao()
 	defer d.Close()
@@ -10,6 +10,7 @@ import (
 	"github.com/zhufuyi/sponge/internal/model"
 	"github.com/zhufuyi/sponge/internal/types"
 
+	"github.com/zhufuyi/sponge/pkg/ggorm/query"
 	"github.com/zhufuyi/sponge/pkg/gin/middleware"
 	"github.com/zhufuyi/sponge/pkg/gin/response"
 	"github.com/zhufuyi/sponge/pkg/logger"
@@ -251,9 +252,13 @@ func (h *userExampleHandler) GetByCondition(c *gin.Context) {
 	ctx := middleware.WrapCtx(c)
 	userExample, err := h.iDao.GetByCondition(ctx, &form.Conditions)
 	if err != nil {
+		var fieldErr *query.FieldError
 		if errors.Is(err, model.ErrRecordNotFound) {
 			logger.Warn("GetByCondition not found", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
 			response.Error(c, ecode.NotFound)
+		} else if errors.As(err, &fieldErr) {
+			logger.Warn("GetByCondition error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+			response.Out(c, ecode.InvalidParams.WithDetails(err.Error()))
 		} else {
 			logger.Error("GetByCondition error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
 			response.Output(c, ecode.InternalServerError.ToHTTPCode())
@@ -327,10 +332,12 @@ func (h *userExampleHandler) ListByIDs(c *gin.Context) {
 // @Success 200 {object} types.ListUserExamplesRespond{}
 // @Router /api/v1/userExample/list [get]
 func (h *userExampleHandler) ListByLastID(c *gin.Context) {
+	// get last id code start
 	lastID := utils.StrToUint64(c.Query("lastID"))
 	if lastID == 0 {
 		lastID = math.MaxInt32
 	}
+	// get last id code end
 	limit := utils.StrToInt(c.Query("limit"))
 	if limit == 0 {
 		limit = 10
@@ -340,6 +347,12 @@ func (h *userExampleHandler) ListByLastID(c *gin.Context) {
 	ctx := middleware.WrapCtx(c)
 	userExamples, err := h.iDao.GetByLastID(ctx, lastID, limit, sort)
 	if err != nil {
+		var fieldErr *query.FieldError
+		if errors.As(err, &fieldErr) {
+			logger.Warn("GetByLastID error", logger.Err(err), logger.String("sort", sort), middleware.GCtxRequestIDField(c))
+			response.Out(c, ecode.InvalidParams.WithDetails(err.Error()))
+			return
+		}
 		logger.Error("GetByLastID error", logger.Err(err), logger.Uint64("latsID", lastID), logger.Int("limit", limit), middleware.GCtxRequestIDField(c))
 		response.Output(c, ecode.InternalServerError.ToHTTPCode())
 		return
@@ -377,6 +390,12 @@ func (h *userExampleHandler) List(c *gin.Context) {
 	ctx := middleware.WrapCtx(c)
 	userExamples, total, err := h.iDao.GetByColumns(ctx, &form.Params)
 	if err != nil {
+		var fieldErr *query.FieldError
+		if errors.As(err, &fieldErr) {
+			logger.Warn("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
+			response.Out(c, ecode.InvalidParams.WithDetails(err.Error()))
+			return
+		}
 		logger.Error("GetByColumns error", logger.Err(err), logger.Any("form", form), middleware.GCtxRequestIDField(c))
 		response.Output(c, ecode.InternalServerError.ToHTTPCode())
 		return
@@ -388,12 +407,25 @@ func (h *userExampleHandler) List(c *gin.Context) {
 		return
 	}
 
+	// the cursor of next page is empty if there is no more data, the size is capped by the max size of page
+	nextCursor := ""
+	_, limit, _ := form.ConvertToPage()
+	if len(userExamples) > 0 && len(userExamples) >= limit {
+		nextCursor, err = form.NextCursor(userExamples[len(userExamples)-1])
+		if err != nil {
+			logger.Warn("NextCursor error", logger.Err(err), logger.String("sort", form.Sort), middleware.GCtxRequestIDField(c))
+		}
+	}
+
 	response.Success(c, gin.H{
 		"userExamples": data,
 		"total":        total,
+		"nextCursor":   nextCursor,
 	})
 }
 
+// get id from path code start
+
 func getUserExampleIDFromPath(c *gin.Context) (string, uint64, bool) {
 	idStr := c.Param("id")
 	id, err := utils.StrToUint64E(idStr)
@@ -405,6 +437,8 @@ func getUserExampleIDFromPath(c *gin.Context) (string, uint64, bool) {
 	return idStr, id, false
 }
 
+// get id from path code end
+
 func convertUserExample(userExample *model.UserExample) (*types.UserExampleObjDetail, error) {
 	data := &types.UserExampleObjDetail{}
 	err := copier.Copy(data, userExample)
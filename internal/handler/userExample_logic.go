@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"math"
-	"strings"
 
 	serverNameExampleV1 "github.com/zhufuyi/sponge/api/serverNameExample/v1"
 	"github.com/zhufuyi/sponge/internal/cache"
@@ -224,13 +223,20 @@ func (h *userExamplePbHandler) ListByLastID(ctx context.Context, req *serverName
 		logger.Warn("req.Validate error", logger.Err(err), logger.Any("req", req), middleware.CtxRequestIDField(ctx))
 		return nil, ecode.InvalidParams.Err()
 	}
+	// get last id code start
 	if req.LastID == 0 {
 		req.LastID = math.MaxInt32
 	}
+	// get last id code end
 
 	records, err := h.userExampleDao.GetByLastID(ctx, req.LastID, int(req.Limit), req.Sort)
 	if err != nil {
-		logger.Error("GetByColumns error", logger.Err(err), logger.Any("req", req), middleware.CtxRequestIDField(ctx))
+		var fieldErr *query.FieldError
+		if errors.As(err, &fieldErr) {
+			logger.Warn("GetByLastID error", logger.Err(err), logger.Any("req", req), middleware.CtxRequestIDField(ctx))
+			return nil, ecode.InvalidParams.Err()
+		}
+		logger.Error("GetByLastID error", logger.Err(err), logger.Any("req", req), middleware.CtxRequestIDField(ctx))
 		return nil, ecode.InternalServerError.Err()
 	}
 
@@ -266,7 +272,8 @@ func (h *userExamplePbHandler) List(ctx context.Context, req *serverNameExampleV
 
 	records, total, err := h.userExampleDao.GetByColumns(ctx, params)
 	if err != nil {
-		if strings.Contains(err.Error(), "query params error:") {
+		var fieldErr *query.FieldError
+		if errors.As(err, &fieldErr) {
 			logger.Warn("GetByColumns error", logger.Err(err), logger.Any("params", params), middleware.CtxRequestIDField(ctx))
 			return nil, ecode.InvalidParams.Err()
 		}
@@ -284,9 +291,20 @@ func (h *userExamplePbHandler) List(ctx context.Context, req *serverNameExampleV
 		userExamples = append(userExamples, data)
 	}
 
+	// the cursor of next page is empty if there is no more data, the size is capped by the max size of page
+	nextCursor := ""
+	_, limit, _ := params.ConvertToPage()
+	if len(records) > 0 && len(records) >= limit {
+		nextCursor, err = params.NextCursor(records[len(records)-1])
+		if err != nil {
+			logger.Warn("NextCursor error", logger.Err(err), logger.String("sort", params.Sort), middleware.CtxRequestIDField(ctx))
+		}
+	}
+
 	return &serverNameExampleV1.ListUserExampleReply{
 		Total:        total,
 		UserExamples: userExamples,
+		NextCursor:   nextCursor,
 	}, nil
 }
 
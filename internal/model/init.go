@@ -2,6 +2,7 @@
 package model
 
 import (
+	"errors"
 	"strings"
 	"sync"
 	"time"
@@ -29,8 +30,8 @@ var (
 	db    *gorm.DB
 	once1 sync.Once
 
-	redisCli *redis.Client
-	once2    sync.Once
+	redisCli redis.UniversalClient
+	redisMu  sync.Mutex
 
 	cacheType *CacheType
 	once3     sync.Once
@@ -38,8 +39,8 @@ var (
 
 // CacheType cache type
 type CacheType struct {
-	CType string        // cache type  memory or redis
-	Rdb   *redis.Client // if CType=redis, Rdb cannot be empty
+	CType string                // cache type  memory, redis or multi
+	Rdb   redis.UniversalClient // if CType=redis or multi, Rdb cannot be empty
 }
 
 // InitCache initial cache
@@ -48,7 +49,7 @@ func InitCache(cType string) {
 		CType: cType,
 	}
 
-	if cType == "redis" {
+	if cType == "redis" || cType == "multi" {
 		cacheType.Rdb = GetRedisCli()
 	}
 }
@@ -64,8 +65,17 @@ func GetCacheType() *CacheType {
 	return cacheType
 }
 
-// InitRedis connect redis
+// InitRedis connect redis, the mode of redis is single, sentinel or cluster, panic if failed
 func InitRedis() {
+	redisMu.Lock()
+	defer redisMu.Unlock()
+
+	if err := initRedis(); err != nil {
+		panic(err.Error())
+	}
+}
+
+func initRedis() error {
 	opts := []goredis.Option{
 		goredis.WithDialTimeout(time.Duration(config.Get().Redis.DialTimeout) * time.Second),
 		goredis.WithReadTimeout(time.Duration(config.Get().Redis.ReadTimeout) * time.Second),
@@ -75,22 +85,44 @@ func InitRedis() {
 		opts = append(opts, goredis.WithEnableTrace())
 	}
 
-	var err error
-	redisCli, err = goredis.Init(config.Get().Redis.Dsn, opts...)
-	if err != nil {
-		panic("goredis.Init error: " + err.Error())
+	cfg := config.Get().Redis
+	switch strings.ToLower(cfg.Mode) {
+	case "sentinel":
+		redisCli = goredis.InitSentinel(cfg.MasterName, cfg.Addrs, cfg.Username, cfg.Password, opts...)
+	case "cluster":
+		redisCli = goredis.InitCluster(cfg.Addrs, cfg.Username, cfg.Password, opts...)
+	case "", "single":
+		cli, err := goredis.Init(cfg.Dsn, opts...)
+		if err != nil {
+			return errors.New("goredis.Init error: " + err.Error())
+		}
+		redisCli = cli
+	default:
+		return errors.New("InitRedis error, unsupported redis mode: " + cfg.Mode)
 	}
+	return nil
 }
 
-// GetRedisCli get redis client
-func GetRedisCli() *redis.Client {
-	if redisCli == nil {
-		once2.Do(func() {
-			InitRedis()
-		})
+// GetRedisCli get redis client, panic if failed to connect redis
+func GetRedisCli() redis.UniversalClient {
+	cli, err := GetRedisCliE()
+	if err != nil {
+		panic(err.Error())
 	}
+	return cli
+}
+
+// GetRedisCliE get redis client, return an error instead of panic if failed to connect redis,
+// e.g. the redis configuration is invalid, it is used where panic is not acceptable, e.g. reload the configuration.
+func GetRedisCliE() (redis.UniversalClient, error) {
+	redisMu.Lock()
+	defer redisMu.Unlock()
 
-	return redisCli
+	if redisCli != nil {
+		return redisCli, nil
+	}
+	err := initRedis()
+	return redisCli, err
 }
 
 // CloseRedis close redis
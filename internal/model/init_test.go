@@ -107,6 +107,22 @@ func TestInitRedis(t *testing.T) {
 	_ = GetRedisCli()
 }
 
+func TestGetRedisCliE(t *testing.T) {
+	err := config.Init(configs.Path("serverNameExample.yml"))
+	if err != nil {
+		panic(err)
+	}
+
+	// invalid configuration returns an error instead of panic
+	mode := config.Get().Redis.Mode
+	defer func() { config.Get().Redis.Mode = mode }()
+	config.Get().Redis.Mode = "unknown"
+	_ = CloseRedis()
+	redisCli = nil
+	_, err = GetRedisCliE()
+	assert.Error(t, err)
+}
+
 func TestTableName(t *testing.T) {
 	t.Log(new(UserExample).TableName())
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/zhufuyi/sponge/pkg/gin/validator"
 	"github.com/zhufuyi/sponge/pkg/jwt"
 	"github.com/zhufuyi/sponge/pkg/logger"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -64,12 +65,18 @@ func NewRouter() *gin.Engine {
 
 	// limit middleware
 	if config.Get().App.EnableLimit {
-		r.Use(middleware.RateLimit())
+		r.Use(middleware.RateLimit(
+			// the routes matched by the policies of configuration file are limited by the policies
+			middleware.WithRateLimitPolicy(policy.Get()),
+		))
 	}
 
 	// circuit breaker middleware
 	if config.Get().App.EnableCircuitBreaker {
-		r.Use(middleware.CircuitBreaker())
+		r.Use(middleware.CircuitBreaker(
+			// the routes matched by the policies of configuration file use the breakers of the policies
+			middleware.WithCircuitBreakerPolicy(policy.Get()),
+		))
 	}
 
 	// trace middleware
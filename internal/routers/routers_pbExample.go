@@ -16,6 +16,7 @@ import (
 	"github.com/zhufuyi/sponge/pkg/gin/validator"
 	"github.com/zhufuyi/sponge/pkg/jwt"
 	"github.com/zhufuyi/sponge/pkg/logger"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -64,7 +65,10 @@ func NewRouter_pbExample() *gin.Engine { //nolint
 
 	// limit middleware
 	if config.Get().App.EnableLimit {
-		r.Use(middleware.RateLimit())
+		r.Use(middleware.RateLimit(
+			// the routes matched by the policies of configuration file are limited by the policies
+			middleware.WithRateLimitPolicy(policy.Get()),
+		))
 	}
 
 	// circuit breaker middleware
@@ -73,6 +77,8 @@ func NewRouter_pbExample() *gin.Engine { //nolint
 			// set http code for circuit breaker, default already includes 500 and 503
 			middleware.WithValidCode(errcode.InternalServerError.Code()),
 			middleware.WithValidCode(errcode.ServiceUnavailable.Code()),
+			// the routes matched by the policies of configuration file use the breakers of the policies
+			middleware.WithCircuitBreakerPolicy(policy.Get()),
 		))
 	}
 
@@ -12,6 +12,7 @@ import (
 	"github.com/zhufuyi/sponge/pkg/consulcli"
 	"github.com/zhufuyi/sponge/pkg/etcdcli"
 	"github.com/zhufuyi/sponge/pkg/grpc/grpccli"
+	"github.com/zhufuyi/sponge/pkg/grpc/interceptor"
 	"github.com/zhufuyi/sponge/pkg/logger"
 	"github.com/zhufuyi/sponge/pkg/nacoscli"
 	"github.com/zhufuyi/sponge/pkg/servicerd/registry/consul"
@@ -24,8 +25,42 @@ import (
 var (
 	serverNameExampleConn *grpc.ClientConn
 	serverNameExampleOnce sync.Once
+
+	// fallback functions of methods when the circuit breaker rejects the call
+	serverNameExampleFallbacks      = map[string]interceptor.FallbackFunc{}
+	serverNameExampleFallbacksMu    sync.Mutex
+	serverNameExampleFallbacksTaken bool // the fallback functions have been used by the connection
 )
 
+// RegisterServerNameExampleFallback register the fallback function of method, e.g. return cached value, static default
+// or call alternative backend when the circuit breaker rejects the call, method is the full method name, e.g.
+// /api.serverNameExample.v1.userExample/GetByID, if it ends with *, match the methods with the prefix.
+// it takes effect when enableCircuitBreaker is true, and must be called before the connection is created,
+// otherwise an error is returned.
+func RegisterServerNameExampleFallback(method string, fn interceptor.FallbackFunc) error {
+	serverNameExampleFallbacksMu.Lock()
+	defer serverNameExampleFallbacksMu.Unlock()
+
+	if serverNameExampleFallbacksTaken {
+		return fmt.Errorf("register fallback of method '%s' failed, the rpc connection has been created", method)
+	}
+	serverNameExampleFallbacks[method] = fn
+	return nil
+}
+
+// the registrations after the fallback options are taken return error until the connection is closed
+func takeServerNameExampleFallbackOptions() []grpccli.Option {
+	serverNameExampleFallbacksMu.Lock()
+	defer serverNameExampleFallbacksMu.Unlock()
+
+	serverNameExampleFallbacksTaken = true
+	opts := make([]grpccli.Option, 0, len(serverNameExampleFallbacks))
+	for method, fn := range serverNameExampleFallbacks {
+		opts = append(opts, grpccli.WithFallback(method, fn))
+	}
+	return opts
+}
+
 // NewServerNameExampleRPCConn instantiate rpc client connection
 func NewServerNameExampleRPCConn() {
 	cfg := config.Get()
@@ -116,6 +151,7 @@ func NewServerNameExampleRPCConn() {
 	}
 	if cfg.App.EnableCircuitBreaker {
 		cliOptions = append(cliOptions, grpccli.WithEnableCircuitBreaker())
+		cliOptions = append(cliOptions, takeServerNameExampleFallbackOptions()...)
 	}
 	if cfg.App.EnableMetrics {
 		cliOptions = append(cliOptions, grpccli.WithEnableMetrics())
@@ -148,6 +184,10 @@ func GetServerNameExampleRPCConn() *grpc.ClientConn {
 
 // CloseServerNameExampleRPCConn Close tears down the ClientConn and all underlying connections.
 func CloseServerNameExampleRPCConn() error {
+	serverNameExampleFallbacksMu.Lock()
+	serverNameExampleFallbacksTaken = false
+	serverNameExampleFallbacksMu.Unlock()
+
 	if serverNameExampleConn == nil {
 		return nil
 	}
@@ -20,6 +20,11 @@ func TestNewServerNameExampleRPCConn(t *testing.T) {
 	}
 	config.Get().App.EnableTrace = true
 	config.Get().App.EnableCircuitBreaker = true
+	err = RegisterServerNameExampleFallback("/api.serverNameExample.v1.userExample/*",
+		func(ctx context.Context, method string, req interface{}, err error) (interface{}, error) {
+			return nil, err
+		})
+	assert.NoError(t, err)
 
 	utils.SafeRunWithTimeout(time.Second*2, func(cancel context.CancelFunc) {
 		config.Get().GrpcClient[0].RegistryDiscoveryType = "consul"
@@ -70,3 +75,20 @@ func TestGetServerNameExampleRPCConn(t *testing.T) {
 	conn := GetServerNameExampleRPCConn()
 	assert.NotNil(t, conn)
 }
+
+func TestRegisterServerNameExampleFallback(t *testing.T) {
+	fn := func(ctx context.Context, method string, req interface{}, err error) (interface{}, error) {
+		return nil, err
+	}
+	err := RegisterServerNameExampleFallback("/api.serverNameExample.v1.userExample/GetByID", fn)
+	assert.NoError(t, err)
+
+	opts := takeServerNameExampleFallbackOptions()
+	assert.NotEmpty(t, opts)
+	err = RegisterServerNameExampleFallback("/api.serverNameExample.v1.userExample/List", fn)
+	assert.Error(t, err)
+
+	_ = CloseServerNameExampleRPCConn()
+	err = RegisterServerNameExampleFallback("/api.serverNameExample.v1.userExample/List", fn)
+	assert.NoError(t, err)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/zhufuyi/sponge/pkg/logger"
 	"github.com/zhufuyi/sponge/pkg/prof"
 	"github.com/zhufuyi/sponge/pkg/servicerd/registry"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"google.golang.org/grpc"
@@ -183,7 +184,10 @@ func (s *grpcServer) unaryServerOptions() grpc.ServerOption {
 
 	// limit interceptor
 	if config.Get().App.EnableLimit {
-		unaryServerInterceptors = append(unaryServerInterceptors, interceptor.UnaryServerRateLimit())
+		unaryServerInterceptors = append(unaryServerInterceptors, interceptor.UnaryServerRateLimit(
+			// the methods matched by the policies of configuration file are limited by the policies
+			interceptor.WithRateLimitPolicy(policy.Get()),
+		))
 	}
 
 	// circuit breaker interceptor
@@ -192,6 +196,8 @@ func (s *grpcServer) unaryServerOptions() grpc.ServerOption {
 			// set rpc code for circuit breaker, default already includes codes.Internal and codes.Unavailable
 			interceptor.WithValidCode(ecode.StatusInternalServerError.Code()),
 			interceptor.WithValidCode(ecode.StatusServiceUnavailable.Code()),
+			// the methods matched by the policies of configuration file use the breakers of the policies
+			interceptor.WithCircuitBreakerPolicy(policy.Get()),
 		))
 	}
 
@@ -242,7 +248,10 @@ func (s *grpcServer) streamServerOptions() grpc.ServerOption {
 
 	// limit interceptor
 	if config.Get().App.EnableLimit {
-		streamServerInterceptors = append(streamServerInterceptors, interceptor.StreamServerRateLimit())
+		streamServerInterceptors = append(streamServerInterceptors, interceptor.StreamServerRateLimit(
+			// the methods matched by the policies of configuration file are limited by the policies
+			interceptor.WithRateLimitPolicy(policy.Get()),
+		))
 	}
 
 	// circuit breaker interceptor
@@ -251,6 +260,8 @@ func (s *grpcServer) streamServerOptions() grpc.ServerOption {
 			// set rpc code for circuit breaker, default already includes codes.Internal and codes.Unavailable
 			interceptor.WithValidCode(ecode.StatusInternalServerError.Code()),
 			interceptor.WithValidCode(ecode.StatusServiceUnavailable.Code()),
+			// the methods matched by the policies of configuration file use the breakers of the policies
+			interceptor.WithCircuitBreakerPolicy(policy.Get()),
 		))
 	}
 
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"math"
-	"strings"
 
 	serverNameExampleV1 "github.com/zhufuyi/sponge/api/serverNameExample/v1"
 	"github.com/zhufuyi/sponge/internal/cache"
@@ -236,15 +235,22 @@ func (s *userExample) ListByLastID(ctx context.Context, req *serverNameExampleV1
 		logger.Warn("req.Validate error", logger.Err(err), logger.Any("req", req), interceptor.CtxRequestIDField(ctx))
 		return nil, ecode.StatusInvalidParams.Err()
 	}
+	// get last id code start
 	if req.LastID == 0 {
 		req.LastID = math.MaxInt32
 	}
+	// get last id code end
 	if req.Limit == 0 {
 		req.Limit = 10
 	}
 
 	records, err := s.iDao.GetByLastID(ctx, req.LastID, int(req.Limit), req.Sort)
 	if err != nil {
+		var fieldErr *query.FieldError
+		if errors.As(err, &fieldErr) {
+			logger.Warn("ListByLastID error", logger.Err(err), logger.Any("req", req), interceptor.ServerCtxRequestIDField(ctx))
+			return nil, ecode.StatusInvalidParams.Err()
+		}
 		logger.Error("ListByLastID error", logger.Err(err), interceptor.CtxRequestIDField(ctx))
 		return nil, ecode.StatusInternalServerError.ToRPCErr()
 	}
@@ -282,7 +288,8 @@ func (s *userExample) List(ctx context.Context, req *serverNameExampleV1.ListUse
 
 	records, total, err := s.iDao.GetByColumns(ctx, params)
 	if err != nil {
-		if strings.Contains(err.Error(), "query params error:") {
+		var fieldErr *query.FieldError
+		if errors.As(err, &fieldErr) {
 			logger.Warn("GetByColumns error", logger.Err(err), logger.Any("params", params), interceptor.ServerCtxRequestIDField(ctx))
 			return nil, ecode.StatusInvalidParams.Err()
 		}
@@ -300,9 +307,20 @@ func (s *userExample) List(ctx context.Context, req *serverNameExampleV1.ListUse
 		userExamples = append(userExamples, data)
 	}
 
+	// the cursor of next page is empty if there is no more data, the size is capped by the max size of page
+	nextCursor := ""
+	_, limit, _ := params.ConvertToPage()
+	if len(records) > 0 && len(records) >= limit {
+		nextCursor, err = params.NextCursor(records[len(records)-1])
+		if err != nil {
+			logger.Warn("NextCursor error", logger.Err(err), logger.String("sort", params.Sort), interceptor.ServerCtxRequestIDField(ctx))
+		}
+	}
+
 	return &serverNameExampleV1.ListUserExampleReply{
 		Total:        total,
 		UserExamples: userExamples,
+		NextCursor:   nextCursor,
 	}, nil
 }
 
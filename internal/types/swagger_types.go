@@ -18,18 +18,39 @@ type Params struct {
 	Size int    `json:"size"`           // lines per page
 	Sort string `json:"sort,omitempty"` // sorted fields, multi-column sorting separated by commas
 
+	Cursor string `json:"cursor,omitempty"` // cursor of keyset pagination returned by the previous page, if not empty, the page is ignored
+
 	Columns []Column `json:"columns,omitempty"` // query conditions
 }
 
 // Column information
 type Column struct {
 	Name  string      `json:"name"`  // column name
-	Exp   string      `json:"exp"`   // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like
+	Exp   string      `json:"exp"`   // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like, in, notin, between, isnull, notnull, prefix, suffix
 	Value interface{} `json:"value"` // column value
 	Logic string      `json:"logic"` // logical type, defaults to and when value is null, only &(and), ||(or)
+
+	Columns []Column `json:"columns,omitempty"` // sub conditions in parentheses, if not empty, the name, exp and value are ignored
 }
 
 // Conditions query conditions
 type Conditions struct {
 	Columns []Column `json:"columns"` // columns info
 }
+
+// Aggregation aggregate query parameters
+type Aggregation struct {
+	Columns    []Column    `json:"columns,omitempty"` // query conditions before grouping
+	GroupBy    []string    `json:"groupBy,omitempty"` // group by columns
+	Aggregates []Aggregate `json:"aggregates"`        // aggregate functions
+	Having     []Column    `json:"having,omitempty"`  // conditions of aggregate results, the name is the alias of aggregate
+	Sort       string      `json:"sort,omitempty"`    // sort by group by columns or aliases, e.g. -count,gender
+	Limit      int         `json:"limit,omitempty"`   // max number of results, 0 means no limit
+}
+
+// Aggregate aggregate function
+type Aggregate struct {
+	Func   string `json:"func"`            // aggregate function, have count, sum, avg, min, max
+	Column string `json:"column"`          // column name, count all records when func is count and column is empty
+	Alias  string `json:"alias,omitempty"` // name of the result, default is func_column, e.g. sum_age, count
+}
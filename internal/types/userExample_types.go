@@ -130,5 +130,7 @@ type ListUserExamplesRespond struct {
 	Msg  string `json:"msg"`  // return information description
 	Data struct {
 		UserExamples []UserExampleObjDetail `json:"userExamples"`
+		Total        int64                  `json:"total"`      // total number of records
+		NextCursor   string                 `json:"nextCursor"` // cursor of next page, empty if there is no more data
 	} `json:"data"` // return data
 }
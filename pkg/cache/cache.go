@@ -23,6 +23,8 @@ var (
 	ErrPlaceholder = errors.New("cache: placeholder")
 	// ErrSetMemoryWithNotFound .
 	ErrSetMemoryWithNotFound = errors.New("cache: set memory cache err for not found")
+
+	errNilNewObject = errors.New("cache: newObject is nil, MultiGet is not supported")
 )
 
 // Cache driver interface
@@ -33,6 +35,15 @@ type Cache interface {
 	MultiGet(ctx context.Context, keys []string, valueMap interface{}) error
 	Del(ctx context.Context, keys ...string) error
 	SetCacheWithNotFound(ctx context.Context, key string) error
+	SetWithTags(ctx context.Context, key string, val interface{}, expiration time.Duration, tags ...string) error
+	DelByTag(ctx context.Context, tags ...string) error
+}
+
+// rawCache get the encoded values, it is used by Typed to decode the values into the type of value
+type rawCache interface {
+	// get the encoded values of keys, the missed keys and the keys of not found placeholder are not in the result
+	multiGetRaw(ctx context.Context, keys []string) (map[string][]byte, error)
+	unmarshal(data []byte, val interface{}) error
 }
 
 // Set data
@@ -64,3 +75,18 @@ func Del(ctx context.Context, keys ...string) error {
 func SetCacheWithNotFound(ctx context.Context, key string) error {
 	return DefaultClient.SetCacheWithNotFound(ctx, key)
 }
+
+// SetWithTags set data and associate the key with tags
+func SetWithTags(ctx context.Context, key string, val interface{}, expiration time.Duration, tags ...string) error {
+	return DefaultClient.SetWithTags(ctx, key, val, expiration, tags...)
+}
+
+// DelByTag delete all data associated with the tags
+func DelByTag(ctx context.Context, tags ...string) error {
+	return DefaultClient.DelByTag(ctx, tags...)
+}
+
+// Load get data from cache, load data by fn and set cache when the cache misses
+func Load(ctx context.Context, key string, val interface{}, expiration time.Duration, fn LoadFunc) error {
+	return getDefaultLoader().Load(ctx, key, val, expiration, fn)
+}
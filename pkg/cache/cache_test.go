@@ -72,4 +72,11 @@ func TestCache(t *testing.T) {
 
 	err = SetCacheWithNotFound(c.Ctx, "not_found")
 	assert.NoError(t, err)
+
+	err = SetWithTags(c.Ctx, key, c.TestDataMap[key], time.Minute, "users")
+	assert.NoError(t, err)
+	err = DelByTag(c.Ctx, "users")
+	assert.NoError(t, err)
+	err = Get(c.Ctx, key, val)
+	assert.ErrorIs(t, err, CacheNotFound)
 }
@@ -0,0 +1,307 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// DefaultExpireJitter default ratio of the random expiry time that is added to the expiry time,
+	// prevent a large number of keys from expiring at the same time
+	DefaultExpireJitter = 0.1
+	// DefaultEarlyRefreshBeta default weight of early refresh, the larger the value, the earlier the refresh
+	DefaultEarlyRefreshBeta = 1.0
+	// DefaultLoadTimeout default timeout of loading the data from the data source
+	DefaultLoadTimeout = time.Second * 10
+
+	// max number of keys whose load information is saved for early refresh
+	maxLoadMetas = 100000
+)
+
+// LoadFunc load the data from the data source when the cache misses, e.g. query database
+type LoadFunc func(ctx context.Context) (interface{}, error)
+
+// LoaderOption set the loader options.
+type LoaderOption func(*loaderOptions)
+
+type loaderOptions struct {
+	jitter      float64
+	beta        float64
+	notFoundErr error
+	timeout     time.Duration
+}
+
+func (o *loaderOptions) apply(opts ...LoaderOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultLoaderOptions() *loaderOptions {
+	return &loaderOptions{
+		jitter:  DefaultExpireJitter,
+		beta:    DefaultEarlyRefreshBeta,
+		timeout: DefaultLoadTimeout,
+	}
+}
+
+// WithExpireJitter set the ratio of the random expiry time, e.g. 0.1 means the expiry time is increased by 0% to 10%,
+// 0 means no jitter.
+func WithExpireJitter(ratio float64) LoaderOption {
+	return func(o *loaderOptions) {
+		if ratio >= 0 {
+			o.jitter = ratio
+		}
+	}
+}
+
+// WithEarlyRefresh set the weight of probabilistic early refresh, the data is reloaded before it expires,
+// the closer to the expiry time and the longer the load time, the higher the probability, 0 means no early refresh.
+func WithEarlyRefresh(beta float64) LoaderOption {
+	return func(o *loaderOptions) {
+		if beta >= 0 {
+			o.beta = beta
+		}
+	}
+}
+
+// WithNotFoundError set the error of LoadFunc that means the data does not exist, e.g. gorm.ErrRecordNotFound,
+// a placeholder is cached when the error is returned, and the error is returned when the placeholder is hit,
+// prevent cache penetration.
+func WithNotFoundError(err error) LoaderOption {
+	return func(o *loaderOptions) {
+		o.notFoundErr = err
+	}
+}
+
+// WithLoadTimeout set the timeout of loading the data, the merged load is not canceled by the context of a caller,
+// it is canceled when the timeout is reached.
+func WithLoadTimeout(d time.Duration) LoaderOption {
+	return func(o *loaderOptions) {
+		if d > 0 {
+			o.timeout = d
+		}
+	}
+}
+
+// Loader cache-aside loader, get the data from cache, load the data from the data source and set cache when the cache misses.
+type Loader struct {
+	cache       Cache
+	sfg         *singleflight.Group
+	jitter      float64
+	beta        float64
+	notFoundErr error
+	timeout     time.Duration
+
+	mu    sync.Mutex
+	metas map[string]*loadMeta
+}
+
+// load information of key, used for early refresh
+type loadMeta struct {
+	expireAt time.Time
+	delta    time.Duration // time spent loading the data
+}
+
+// NewLoader create a loader
+func NewLoader(c Cache, opts ...LoaderOption) *Loader {
+	o := defaultLoaderOptions()
+	o.apply(opts...)
+
+	return &Loader{
+		cache:       c,
+		sfg:         new(singleflight.Group),
+		jitter:      o.jitter,
+		beta:        o.beta,
+		notFoundErr: o.notFoundErr,
+		timeout:     o.timeout,
+		metas:       make(map[string]*loadMeta),
+	}
+}
+
+// Load get the data from cache to val, val must be a pointer. if the cache misses, call fn to load the data
+// and set cache, the concurrent loads of the same key are merged into one. if fn returns the not found error,
+// a placeholder is cached and the not found error is returned.
+func (l *Loader) Load(ctx context.Context, key string, val interface{}, expiration time.Duration, fn LoadFunc) error {
+	err := l.cache.Get(ctx, key, val)
+	if err == nil {
+		if l.shouldRefresh(key) {
+			// keep the cached data if the refresh fails, unless the data has been deleted
+			err = l.load(ctx, key, val, expiration, fn)
+			if l.isNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if errors.Is(err, ErrPlaceholder) {
+		if l.notFoundErr != nil {
+			return l.notFoundErr
+		}
+		return err
+	}
+
+	// fail fast, if cache error return, don't request to data source
+	if !errors.Is(err, CacheNotFound) {
+		return err
+	}
+
+	return l.load(ctx, key, val, expiration, fn)
+}
+
+// the load is shared by the concurrent callers, it runs on a context detached from the caller with its own timeout,
+// a caller whose context is done returns early without canceling the load of the others.
+func (l *Loader) load(ctx context.Context, key string, val interface{}, expiration time.Duration, fn LoadFunc) error {
+	ch := l.sfg.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(detachedContext{parent: ctx}, l.timeout)
+		defer cancel()
+
+		start := time.Now()
+		data, err := fn(ctx)
+		if err != nil {
+			if l.isNotFound(err) {
+				l.delMeta(key)
+				if e := l.cache.SetCacheWithNotFound(ctx, key); e != nil {
+					return nil, e
+				}
+			}
+			return nil, err
+		}
+
+		ttl := l.jitterTTL(expiration)
+		err = l.cache.Set(ctx, key, data, ttl)
+		if err != nil {
+			return nil, fmt.Errorf("cache.Set error: %v, key=%s", err, key)
+		}
+		if ttl > 0 {
+			l.setMeta(key, &loadMeta{expireAt: time.Now().Add(ttl), delta: time.Since(start)})
+		}
+		return data, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case result := <-ch:
+		if result.Err != nil {
+			return result.Err
+		}
+		return setValue(val, result.Val)
+	}
+}
+
+// detachedContext keep the values of the parent context, but not the deadline and cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (c detachedContext) Done() <-chan struct{} { return nil }
+
+func (c detachedContext) Err() error { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+func (l *Loader) isNotFound(err error) bool {
+	return err != nil && l.notFoundErr != nil && errors.Is(err, l.notFoundErr)
+}
+
+// the expiry time is increased by a random time
+func (l *Loader) jitterTTL(expiration time.Duration) time.Duration {
+	if expiration <= 0 || l.jitter <= 0 {
+		return expiration
+	}
+	return expiration + time.Duration(rand.Float64()*l.jitter*float64(expiration)) //nolint
+}
+
+// probabilistic early refresh, refresh when now - delta * beta * ln(rand) >= expireAt
+func (l *Loader) shouldRefresh(key string) bool {
+	if l.beta <= 0 {
+		return false
+	}
+
+	l.mu.Lock()
+	meta, ok := l.metas[key]
+	l.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	gap := time.Duration(float64(meta.delta) * l.beta * -math.Log(1-rand.Float64())) //nolint
+	return !time.Now().Add(gap).Before(meta.expireAt)
+}
+
+func (l *Loader) setMeta(key string, meta *loadMeta) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.metas[key]; !ok && len(l.metas) >= maxLoadMetas {
+		now := time.Now()
+		for k, v := range l.metas {
+			if now.After(v.expireAt) {
+				delete(l.metas, k)
+			}
+		}
+		if len(l.metas) >= maxLoadMetas {
+			return
+		}
+	}
+	l.metas[key] = meta
+}
+
+func (l *Loader) delMeta(key string) {
+	l.mu.Lock()
+	delete(l.metas, key)
+	l.mu.Unlock()
+}
+
+// set data to val, data can be the same type as val or the type that val points to
+func setValue(val interface{}, data interface{}) error {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("val must be a non-nil pointer, type=%T", val)
+	}
+	elem := rv.Elem()
+
+	dv := reflect.ValueOf(data)
+	if !dv.IsValid() {
+		elem.Set(reflect.Zero(elem.Type()))
+		return nil
+	}
+	if dv.Type().AssignableTo(elem.Type()) {
+		elem.Set(dv)
+		return nil
+	}
+	if dv.Kind() == reflect.Ptr && !dv.IsNil() && dv.Elem().Type().AssignableTo(elem.Type()) {
+		elem.Set(dv.Elem())
+		return nil
+	}
+
+	return fmt.Errorf("cannot set %T to %T", data, val)
+}
+
+var (
+	defaultLoader   *Loader
+	defaultLoaderMu sync.Mutex
+)
+
+// the loader of DefaultClient, it is recreated when DefaultClient is changed
+func getDefaultLoader() *Loader {
+	defaultLoaderMu.Lock()
+	defer defaultLoaderMu.Unlock()
+
+	if defaultLoader == nil || defaultLoader.cache != DefaultClient {
+		defaultLoader = NewLoader(DefaultClient)
+	}
+	return defaultLoader
+}
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errRecordNotFound = errors.New("record not found")
+
+func TestLoader_Load(t *testing.T) {
+	c := newCache()
+	defer c.Close()
+	loader := NewLoader(c.ICache.(Cache), WithNotFoundError(errRecordNotFound), WithExpireJitter(0.2), WithEarlyRefresh(0))
+
+	var count int32
+	fn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(time.Millisecond * 50)
+		return &cacheUser{ID: 1, Name: "foo"}, nil
+	}
+
+	// concurrent loads of the same key are merged into one
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val := &cacheUser{}
+			err := loader.Load(c.Ctx, "1", val, time.Minute, fn)
+			assert.NoError(t, err)
+			assert.Equal(t, "foo", val.Name)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	// hit cache
+	var val *cacheUser
+	err := loader.Load(c.Ctx, "1", &val, time.Minute, fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", val.Name)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+	ttl := c.RedisClient.TTL(c.Ctx, "1").Val()
+	assert.True(t, ttl >= time.Minute && ttl <= time.Minute*12/10)
+
+	// negative cache
+	notFoundFn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&count, 1)
+		return nil, errRecordNotFound
+	}
+	err = loader.Load(c.Ctx, "2", &cacheUser{}, time.Minute, notFoundFn)
+	assert.ErrorIs(t, err, errRecordNotFound)
+	err = loader.Load(c.Ctx, "2", &cacheUser{}, time.Minute, notFoundFn)
+	assert.ErrorIs(t, err, errRecordNotFound)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+
+	// other errors are not cached
+	errFn := func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("db error")
+	}
+	err = loader.Load(c.Ctx, "3", &cacheUser{}, time.Minute, errFn)
+	assert.Error(t, err)
+	err = loader.Load(c.Ctx, "3", &cacheUser{}, time.Minute, fn)
+	assert.NoError(t, err)
+
+	// invalid val
+	err = loader.Load(c.Ctx, "4", cacheUser{}, time.Minute, fn)
+	assert.Error(t, err)
+}
+
+func TestLoader_EarlyRefresh(t *testing.T) {
+	c := newCache()
+	defer c.Close()
+	loader := NewLoader(c.ICache.(Cache), WithNotFoundError(errRecordNotFound))
+
+	name := "foo"
+	fn := func(ctx context.Context) (interface{}, error) {
+		return &cacheUser{ID: 1, Name: name}, nil
+	}
+	val := &cacheUser{}
+	err := loader.Load(c.Ctx, "1", val, time.Minute, fn)
+	assert.NoError(t, err)
+	assert.False(t, loader.shouldRefresh("1"))
+
+	// about to expire and the load time is long
+	loader.setMeta("1", &loadMeta{expireAt: time.Now().Add(time.Millisecond), delta: time.Hour})
+	assert.True(t, loader.shouldRefresh("1"))
+	name = "bar"
+	err = loader.Load(c.Ctx, "1", val, time.Minute, fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val.Name)
+
+	// the data is deleted when refreshing
+	loader.setMeta("1", &loadMeta{expireAt: time.Now(), delta: time.Hour})
+	err = loader.Load(c.Ctx, "1", val, time.Minute, func(ctx context.Context) (interface{}, error) {
+		return nil, errRecordNotFound
+	})
+	assert.ErrorIs(t, err, errRecordNotFound)
+	assert.False(t, loader.shouldRefresh("1"))
+}
+
+func TestLoader_LoadCanceled(t *testing.T) {
+	c := newCache()
+	defer c.Close()
+	loader := NewLoader(c.ICache.(Cache), WithLoadTimeout(time.Second))
+
+	type ctxKey struct{}
+	fn := func(ctx context.Context) (interface{}, error) {
+		time.Sleep(time.Millisecond * 100)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &cacheUser{ID: 1, Name: ctx.Value(ctxKey{}).(string)}, nil
+	}
+
+	// the first caller is canceled, the merged load is not canceled
+	ctx, cancel := context.WithTimeout(context.WithValue(c.Ctx, ctxKey{}, "foo"), time.Millisecond*20)
+	defer cancel()
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := loader.Load(ctx, "1", &cacheUser{}, time.Minute, fn)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	val := &cacheUser{}
+	err := loader.Load(c.Ctx, "1", val, time.Minute, fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", val.Name)
+	wg.Wait()
+
+	// the load is canceled when the timeout is reached
+	loader = NewLoader(c.ICache.(Cache), WithLoadTimeout(time.Millisecond*50))
+	err = loader.Load(c.Ctx, "2", &cacheUser{}, time.Minute, fn)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLoader_jitterTTL(t *testing.T) {
+	loader := NewLoader(nil)
+	for i := 0; i < 100; i++ {
+		ttl := loader.jitterTTL(time.Minute)
+		assert.True(t, ttl >= time.Minute && ttl <= time.Minute*11/10)
+	}
+	assert.Equal(t, time.Duration(0), loader.jitterTTL(0))
+
+	loader = NewLoader(nil, WithExpireJitter(0))
+	assert.Equal(t, time.Minute, loader.jitterTTL(time.Minute))
+}
+
+func Test_setValue(t *testing.T) {
+	data := &cacheUser{ID: 1, Name: "foo"}
+
+	val := &cacheUser{}
+	assert.NoError(t, setValue(val, data))
+	assert.Equal(t, "foo", val.Name)
+
+	var ptr *cacheUser
+	assert.NoError(t, setValue(&ptr, data))
+	assert.Equal(t, data, ptr)
+
+	assert.NoError(t, setValue(&ptr, nil))
+	assert.Nil(t, ptr)
+
+	assert.Error(t, setValue(*val, data))
+	assert.Error(t, setValue(&ptr, "foo"))
+}
+
+func TestLoad(t *testing.T) {
+	c := newCache()
+	defer c.Close()
+
+	val := &cacheUser{}
+	err := Load(c.Ctx, "1", val, time.Minute, func(ctx context.Context) (interface{}, error) {
+		return &cacheUser{ID: 1, Name: "foo"}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", val.Name)
+	assert.Equal(t, getDefaultLoader(), getDefaultLoader())
+}
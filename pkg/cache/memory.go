@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/zhufuyi/sponge/pkg/encoding"
 
 	"github.com/dgraph-io/ristretto"
+	"github.com/dgraph-io/ristretto/z"
 )
 
 type memoryCache struct {
@@ -18,24 +20,39 @@ type memoryCache struct {
 	encoding          encoding.Encoding
 	DefaultExpireTime time.Duration
 	newObject         func() interface{}
+
+	tagMu   sync.Mutex
+	tags    map[string]map[string]struct{} // tag --> cache keys
+	tagKeys map[uint64]*taggedKey          // hash of cache key --> tagged key
+}
+
+// the cache key associated with tags, it is removed from the tags when it is deleted, evicted or expired
+type taggedKey struct {
+	cacheKey string
+	conflict uint64
+	tags     map[string]struct{}
 }
 
-// NewMemoryCache create a memory cache
+// NewMemoryCache create a memory cache, newObject creates the values of MultiGet,
+// it can be nil if MultiGet is not used, e.g. the cache is used by Typed.
 func NewMemoryCache(keyPrefix string, encode encoding.Encoding, newObject func() interface{}) Cache {
 	// see: https://dgraph.io/blog/post/introducing-ristretto-high-perf-go-cache/
 	//		https://www.start.io/blog/we-chose-ristretto-cache-for-go-heres-why/
-	config := &ristretto.Config{
-		NumCounters: 1e7,     // number of keys to track frequency of (10M).
-		MaxCost:     1 << 30, // maximum cost of cache (1GB).
-		BufferItems: 64,      // number of keys per Get buffer.
-	}
-	store, _ := ristretto.NewCache(config)
-	return &memoryCache{
-		client:    store,
+	m := &memoryCache{
 		KeyPrefix: keyPrefix,
 		encoding:  encode,
 		newObject: newObject,
+		tags:      make(map[string]map[string]struct{}),
+		tagKeys:   make(map[uint64]*taggedKey),
+	}
+	config := &ristretto.Config{
+		NumCounters: 1e7,       // number of keys to track frequency of (10M).
+		MaxCost:     1 << 30,   // maximum cost of cache (1GB).
+		BufferItems: 64,        // number of keys per Get buffer.
+		OnEvict:     m.onEvict, // remove the evicted or expired keys from the tags
 	}
+	m.client, _ = ristretto.NewCache(config)
+	return m
 }
 
 // Set data
@@ -86,12 +103,14 @@ func (m *memoryCache) Del(_ context.Context, keys ...string) error {
 		return nil
 	}
 
-	key := keys[0]
-	cacheKey, err := BuildCacheKey(m.KeyPrefix, key)
-	if err != nil {
-		return fmt.Errorf("build cache key error, err=%v, key=%s", err, key)
+	for _, key := range keys {
+		cacheKey, err := BuildCacheKey(m.KeyPrefix, key)
+		if err != nil {
+			return fmt.Errorf("build cache key error, err=%v, key=%s", err, key)
+		}
+		m.client.Del(cacheKey)
+		m.untag(cacheKey)
 	}
-	m.client.Del(cacheKey)
 	return nil
 }
 
@@ -109,6 +128,9 @@ func (m *memoryCache) MultiSet(ctx context.Context, valueMap map[string]interfac
 
 // MultiGet multiple get data
 func (m *memoryCache) MultiGet(ctx context.Context, keys []string, value interface{}) error {
+	if m.newObject == nil {
+		return errNilNewObject
+	}
 	valueMap := reflect.ValueOf(value)
 	var err error
 	for _, key := range keys {
@@ -123,6 +145,26 @@ func (m *memoryCache) MultiGet(ctx context.Context, keys []string, value interfa
 	return nil
 }
 
+func (m *memoryCache) multiGetRaw(_ context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		cacheKey, err := BuildCacheKey(m.KeyPrefix, key)
+		if err != nil {
+			return nil, fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
+		}
+		data, ok := m.client.Get(cacheKey)
+		if !ok || string(data.([]byte)) == NotFoundPlaceholder {
+			continue
+		}
+		result[key] = data.([]byte)
+	}
+	return result, nil
+}
+
+func (m *memoryCache) unmarshal(data []byte, val interface{}) error {
+	return encoding.Unmarshal(m.encoding, data, val)
+}
+
 // SetCacheWithNotFound set not found
 func (m *memoryCache) SetCacheWithNotFound(_ context.Context, key string) error {
 	cacheKey, err := BuildCacheKey(m.KeyPrefix, key)
@@ -137,3 +179,107 @@ func (m *memoryCache) SetCacheWithNotFound(_ context.Context, key string) error
 
 	return nil
 }
+
+// SetWithTags set data and associate the key with tags
+func (m *memoryCache) SetWithTags(ctx context.Context, key string, val interface{}, expiration time.Duration, tags ...string) error {
+	err := m.Set(ctx, key, val, expiration)
+	if err != nil {
+		return err
+	}
+	cacheKey, _ := BuildCacheKey(m.KeyPrefix, key)
+	keyHash, conflict := z.KeyToHash(cacheKey)
+
+	m.tagMu.Lock()
+	defer m.tagMu.Unlock()
+	tk, ok := m.tagKeys[keyHash]
+	if !ok || tk.cacheKey != cacheKey {
+		tk = &taggedKey{cacheKey: cacheKey, conflict: conflict, tags: make(map[string]struct{})}
+		m.tagKeys[keyHash] = tk
+	}
+	for _, tag := range tags {
+		cacheKeys, ok := m.tags[tag]
+		if !ok {
+			cacheKeys = make(map[string]struct{})
+			m.tags[tag] = cacheKeys
+		}
+		cacheKeys[cacheKey] = struct{}{}
+		tk.tags[tag] = struct{}{}
+	}
+
+	return nil
+}
+
+// DelByTag delete all data associated with the tags
+func (m *memoryCache) DelByTag(_ context.Context, tags ...string) error {
+	m.delCacheKeys(m.popTags(tags...)...)
+	return nil
+}
+
+// remove the tags and return the cache keys associated with them
+func (m *memoryCache) popTags(tags ...string) []string {
+	m.tagMu.Lock()
+	defer m.tagMu.Unlock()
+
+	var cacheKeys []string
+	for _, tag := range tags {
+		for cacheKey := range m.tags[tag] {
+			cacheKeys = append(cacheKeys, cacheKey)
+			keyHash, _ := z.KeyToHash(cacheKey)
+			if tk, ok := m.tagKeys[keyHash]; ok && tk.cacheKey == cacheKey {
+				delete(tk.tags, tag)
+				if len(tk.tags) == 0 {
+					delete(m.tagKeys, keyHash)
+				}
+			}
+		}
+		delete(m.tags, tag)
+	}
+	return cacheKeys
+}
+
+// remove the cache keys from the tags
+func (m *memoryCache) untag(cacheKeys ...string) {
+	m.tagMu.Lock()
+	defer m.tagMu.Unlock()
+
+	for _, cacheKey := range cacheKeys {
+		keyHash, _ := z.KeyToHash(cacheKey)
+		if tk, ok := m.tagKeys[keyHash]; ok && tk.cacheKey == cacheKey {
+			m.removeTaggedKey(keyHash, tk)
+		}
+	}
+}
+
+// called by ristretto when the item is evicted or expired, only the hash of the key is known
+func (m *memoryCache) onEvict(item *ristretto.Item) {
+	m.tagMu.Lock()
+	defer m.tagMu.Unlock()
+
+	if tk, ok := m.tagKeys[item.Key]; ok && tk.conflict == item.Conflict {
+		m.removeTaggedKey(item.Key, tk)
+	}
+}
+
+func (m *memoryCache) removeTaggedKey(keyHash uint64, tk *taggedKey) {
+	for tag := range tk.tags {
+		cacheKeys := m.tags[tag]
+		delete(cacheKeys, tk.cacheKey)
+		if len(cacheKeys) == 0 {
+			delete(m.tags, tag)
+		}
+	}
+	delete(m.tagKeys, keyHash)
+}
+
+// set the encoded data of the cache key
+func (m *memoryCache) setWithTTL(cacheKey string, data []byte, expiration time.Duration) {
+	m.client.SetWithTTL(cacheKey, data, 0, expiration)
+}
+
+// delete the cache keys, the keys already contain the prefix
+func (m *memoryCache) delCacheKeys(cacheKeys ...string) {
+	for _, cacheKey := range cacheKeys {
+		m.client.Del(cacheKey)
+	}
+	m.untag(cacheKeys...)
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/zhufuyi/sponge/pkg/gotest"
 	"github.com/zhufuyi/sponge/pkg/utils"
 
+	"github.com/dgraph-io/ristretto"
+	"github.com/dgraph-io/ristretto/z"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -120,3 +122,61 @@ func TestMemoryCacheError(t *testing.T) {
 	err = iCache.SetCacheWithNotFound(c.Ctx, "")
 	assert.Error(t, err)
 }
+
+func TestMemoryCache_DelByTag(t *testing.T) {
+	c := newMemoryCache()
+	defer c.Close()
+	iCache := c.ICache.(Cache)
+
+	for key, val := range c.TestDataMap {
+		err := iCache.SetWithTags(c.Ctx, key, val, time.Minute, "users", "user:"+key)
+		assert.NoError(t, err)
+	}
+	err := iCache.SetWithTags(c.Ctx, "", &memoryUser{}, time.Minute, "users")
+	assert.Error(t, err)
+	time.Sleep(time.Millisecond * 10)
+
+	err = iCache.DelByTag(c.Ctx, "user:1")
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 10)
+	val := &memoryUser{}
+	assert.ErrorIs(t, iCache.Get(c.Ctx, "1", val), CacheNotFound)
+	assert.NoError(t, iCache.Get(c.Ctx, "2", val))
+
+	err = iCache.DelByTag(c.Ctx, "users", "not_exist")
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 10)
+	assert.ErrorIs(t, iCache.Get(c.Ctx, "2", val), CacheNotFound)
+}
+
+func TestMemoryCache_pruneTags(t *testing.T) {
+	c := newMemoryCache()
+	defer c.Close()
+	m := c.ICache.(*memoryCache)
+
+	for key, val := range c.TestDataMap {
+		err := m.SetWithTags(c.Ctx, key, val, time.Minute, "users", "user:"+key)
+		assert.NoError(t, err)
+	}
+	assert.Len(t, m.tagKeys, 2)
+
+	// deleted key
+	assert.NoError(t, m.Del(c.Ctx, "1"))
+	assert.Len(t, m.tagKeys, 1)
+	assert.Len(t, m.tags["users"], 1)
+	assert.NotContains(t, m.tags, "user:1")
+
+	// evicted or expired key
+	keyHash, conflict := z.KeyToHash("2")
+	m.onEvict(&ristretto.Item{Key: keyHash, Conflict: conflict + 1})
+	assert.Len(t, m.tagKeys, 1)
+	m.onEvict(&ristretto.Item{Key: keyHash, Conflict: conflict})
+	assert.Empty(t, m.tagKeys)
+	assert.Empty(t, m.tags)
+
+	// deleted tags
+	assert.NoError(t, m.SetWithTags(c.Ctx, "1", c.TestDataMap["1"], time.Minute, "users"))
+	assert.NoError(t, m.DelByTag(c.Ctx, "users"))
+	assert.Empty(t, m.tagKeys)
+	assert.Empty(t, m.tags)
+}
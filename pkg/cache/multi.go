@@ -0,0 +1,427 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/encoding"
+	"github.com/zhufuyi/sponge/pkg/krand"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// DefaultLocalExpireTime default max expiry time of the local cache in multi-level cache
+	DefaultLocalExpireTime = time.Minute
+	// DefaultInvalidationChannel default redis channel of the invalidation messages in multi-level cache
+	DefaultInvalidationChannel = "cache:invalidation"
+)
+
+// MultiOption set the multi-level cache options.
+type MultiOption func(*multiOptions)
+
+type multiOptions struct {
+	localExpireTime time.Duration
+	channel         string
+}
+
+func (o *multiOptions) apply(opts ...MultiOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultMultiOptions() *multiOptions {
+	return &multiOptions{
+		localExpireTime: DefaultLocalExpireTime,
+		channel:         DefaultInvalidationChannel,
+	}
+}
+
+// WithLocalExpireTime set the max expiry time of the local cache, the local expiry time is the smaller of it
+// and the expiry time of Set, it limits how long a local entry may be stale when the invalidation message is lost.
+func WithLocalExpireTime(d time.Duration) MultiOption {
+	return func(o *multiOptions) {
+		if d > 0 {
+			o.localExpireTime = d
+		}
+	}
+}
+
+// WithInvalidationChannel set the redis channel of the invalidation messages,
+// the instances sharing the same cache must use the same channel.
+func WithInvalidationChannel(channel string) MultiOption {
+	return func(o *multiOptions) {
+		if channel != "" {
+			o.channel = channel
+		}
+	}
+}
+
+// multiCache two-level cache, local memory cache in front of redis cache
+type multiCache struct {
+	id              string // identify the instance, ignore the invalidation messages published by itself
+	local           *memoryCache
+	remote          *redisCache
+	localExpireTime time.Duration
+	channel         string
+	subscription    *subscription // protected by subscriptionsMu
+}
+
+// NewMultiCache create a multi-level cache, read the local memory cache first, then the redis cache, and backfill the local cache.
+// Set, MultiSet, Del and SetCacheWithNotFound publish the keys to a redis channel,
+// the other instances delete the local entries of the keys when they receive the message.
+// the returned cache implements io.Closer, close it when it is no longer used, e.g. c.(io.Closer).Close()
+// newObject creates the values of MultiGet, it can be nil if MultiGet is not used, e.g. the cache is used by Typed.
+func NewMultiCache(client redis.UniversalClient, keyPrefix string, encode encoding.Encoding, newObject func() interface{}, opts ...MultiOption) Cache {
+	o := defaultMultiOptions()
+	o.apply(opts...)
+
+	c := &multiCache{
+		id:              krand.String(krand.R_All, 16),
+		local:           NewMemoryCache(keyPrefix, encode, newObject).(*memoryCache),
+		remote:          NewRedisCache(client, keyPrefix, encode, newObject).(*redisCache),
+		localExpireTime: o.localExpireTime,
+		channel:         o.channel,
+	}
+	subscribeInvalidation(client, o.channel, c)
+
+	return c
+}
+
+// Close stop receiving the invalidation messages and release the local cache, the redis client is not closed.
+func (c *multiCache) Close() error {
+	unsubscribeInvalidation(c)
+	c.local.client.Close()
+	return nil
+}
+
+// the local expiry time must be less than or equal to the local max expiry time
+func (c *multiCache) localTTL(expiration time.Duration) time.Duration {
+	if expiration <= 0 || expiration > c.localExpireTime {
+		return c.localExpireTime
+	}
+	return expiration
+}
+
+// Set data
+func (c *multiCache) Set(ctx context.Context, key string, val interface{}, expiration time.Duration) error {
+	err := c.remote.Set(ctx, key, val, expiration)
+	if err != nil {
+		return err
+	}
+	_ = c.local.Set(ctx, key, val, c.localTTL(expiration))
+
+	return c.publish(ctx, key)
+}
+
+// Get data
+func (c *multiCache) Get(ctx context.Context, key string, val interface{}) error {
+	err := c.local.Get(ctx, key, val)
+	if !errors.Is(err, CacheNotFound) {
+		return err
+	}
+
+	cacheKey, err := BuildCacheKey(c.remote.KeyPrefix, key)
+	if err != nil {
+		return fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
+	}
+	data, err := c.remote.client.Get(ctx, cacheKey).Bytes()
+	if err != nil {
+		return err
+	}
+
+	if string(data) == NotFoundPlaceholder {
+		c.local.setWithTTL(cacheKey, data, c.localTTL(DefaultNotFoundExpireTime))
+		return ErrPlaceholder
+	}
+	err = encoding.Unmarshal(c.remote.encoding, data, val)
+	if err != nil {
+		return fmt.Errorf("encoding.Unmarshal error: %v, key=%s, cacheKey=%s, type=%v, json=%+v ",
+			err, key, cacheKey, reflect.TypeOf(val), string(data))
+	}
+	c.local.setWithTTL(cacheKey, data, c.localTTL(0))
+
+	return nil
+}
+
+// MultiSet multiple set data
+func (c *multiCache) MultiSet(ctx context.Context, valueMap map[string]interface{}, expiration time.Duration) error {
+	if len(valueMap) == 0 {
+		return nil
+	}
+
+	err := c.remote.MultiSet(ctx, valueMap, expiration)
+	if err != nil {
+		return err
+	}
+	_ = c.local.MultiSet(ctx, valueMap, c.localTTL(expiration))
+
+	keys := make([]string, 0, len(valueMap))
+	for key := range valueMap {
+		keys = append(keys, key)
+	}
+	return c.publish(ctx, keys...)
+}
+
+// MultiGet multiple get data, the keys of valueMap are the keys of parameters
+func (c *multiCache) MultiGet(ctx context.Context, keys []string, value interface{}) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if c.remote.newObject == nil {
+		return errNilNewObject
+	}
+
+	values, err := c.multiGetRaw(ctx, keys)
+	if err != nil {
+		return err
+	}
+	valueMap := reflect.ValueOf(value)
+	for key, data := range values {
+		object := c.remote.newObject()
+		err = c.unmarshal(data, object)
+		if err != nil {
+			continue
+		}
+		valueMap.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(object))
+	}
+
+	return nil
+}
+
+// get the values from the local cache first, then get the missed values from redis and backfill the local cache
+func (c *multiCache) multiGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	var missedKeys, missedCacheKeys []string
+	for _, key := range keys {
+		cacheKey, err := BuildCacheKey(c.remote.KeyPrefix, key)
+		if err != nil {
+			return nil, fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
+		}
+		if data, ok := c.local.client.Get(cacheKey); ok {
+			if string(data.([]byte)) != NotFoundPlaceholder {
+				result[key] = data.([]byte)
+			}
+			continue
+		}
+		missedKeys = append(missedKeys, key)
+		missedCacheKeys = append(missedCacheKeys, cacheKey)
+	}
+	if len(missedKeys) == 0 {
+		return result, nil
+	}
+
+	values, err := c.remote.mget(ctx, missedCacheKeys...)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if str == NotFoundPlaceholder {
+			c.local.setWithTTL(missedCacheKeys[i], []byte(str), c.localTTL(DefaultNotFoundExpireTime))
+			continue
+		}
+		c.local.setWithTTL(missedCacheKeys[i], []byte(str), c.localTTL(0))
+		result[missedKeys[i]] = []byte(str)
+	}
+
+	return result, nil
+}
+
+func (c *multiCache) unmarshal(data []byte, val interface{}) error {
+	return encoding.Unmarshal(c.remote.encoding, data, val)
+}
+
+// Del multiple delete data
+func (c *multiCache) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err := c.remote.Del(ctx, keys...)
+	if err != nil {
+		return err
+	}
+	_ = c.local.Del(ctx, keys...)
+
+	return c.publish(ctx, keys...)
+}
+
+// SetCacheWithNotFound set not found
+func (c *multiCache) SetCacheWithNotFound(ctx context.Context, key string) error {
+	err := c.remote.SetCacheWithNotFound(ctx, key)
+	if err != nil {
+		return err
+	}
+	cacheKey, _ := BuildCacheKey(c.remote.KeyPrefix, key)
+	c.local.setWithTTL(cacheKey, []byte(NotFoundPlaceholder), c.localTTL(DefaultNotFoundExpireTime))
+
+	return c.publish(ctx, key)
+}
+
+// SetWithTags set data and associate the key with tags
+func (c *multiCache) SetWithTags(ctx context.Context, key string, val interface{}, expiration time.Duration, tags ...string) error {
+	err := c.remote.SetWithTags(ctx, key, val, expiration, tags...)
+	if err != nil {
+		return err
+	}
+	_ = c.local.SetWithTags(ctx, key, val, c.localTTL(expiration), tags...)
+
+	return c.publish(ctx, key)
+}
+
+// DelByTag delete all data associated with the tags
+func (c *multiCache) DelByTag(ctx context.Context, tags ...string) error {
+	cacheKeys, err := c.remote.delByTag(ctx, tags...)
+	_ = c.local.DelByTag(ctx, tags...)
+	c.local.delCacheKeys(cacheKeys...)
+
+	// the keys deleted before the error are published too, otherwise the other instances keep the stale local entries
+	publishErr := c.publishCacheKeys(ctx, cacheKeys...)
+	if err != nil {
+		return err
+	}
+	return publishErr
+}
+
+type invalidationMessage struct {
+	ID        string   `json:"id"`        // id of the instance that publishes the message
+	CacheKeys []string `json:"cacheKeys"` // the cache keys to be deleted
+}
+
+// publish the keys to other instances
+func (c *multiCache) publish(ctx context.Context, keys ...string) error {
+	var cacheKeys []string
+	for _, key := range keys {
+		cacheKey, err := BuildCacheKey(c.remote.KeyPrefix, key)
+		if err != nil {
+			continue
+		}
+		cacheKeys = append(cacheKeys, cacheKey)
+	}
+	return c.publishCacheKeys(ctx, cacheKeys...)
+}
+
+func (c *multiCache) publishCacheKeys(ctx context.Context, cacheKeys ...string) error {
+	if len(cacheKeys) == 0 {
+		return nil
+	}
+
+	msg := invalidationMessage{ID: c.id, CacheKeys: cacheKeys}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	err = c.remote.client.Publish(ctx, c.channel, data).Err()
+	if err != nil {
+		return fmt.Errorf("c.client.Publish error: %v, channel=%s", err, c.channel)
+	}
+	return nil
+}
+
+// the multi-level caches with the same redis client and channel share a subscription
+var (
+	subscriptions   = map[string]*subscription{}
+	subscriptionsMu sync.Mutex
+)
+
+type subscription struct {
+	name   string
+	pubSub *redis.PubSub
+
+	mu     sync.RWMutex
+	caches []*multiCache
+}
+
+func (s *subscription) add(c *multiCache) {
+	s.mu.Lock()
+	s.caches = append(s.caches, c)
+	s.mu.Unlock()
+}
+
+// remove the cache and return the number of remaining caches
+func (s *subscription) remove(c *multiCache) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, v := range s.caches {
+		if v == c {
+			s.caches = append(s.caches[:i], s.caches[i+1:]...)
+			break
+		}
+	}
+	return len(s.caches)
+}
+
+// delete the local entries of the caches, except the cache that publishes the message
+func (s *subscription) invalidate(msg *invalidationMessage) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range s.caches {
+		if c.id == msg.ID {
+			continue
+		}
+		c.local.delCacheKeys(msg.CacheKeys...)
+	}
+}
+
+func subscribeInvalidation(client redis.UniversalClient, channel string, c *multiCache) {
+	name := fmt.Sprintf("%p:%s", client, channel)
+
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+
+	if s, ok := subscriptions[name]; ok {
+		s.add(c)
+		c.subscription = s
+		return
+	}
+
+	pubSub := client.Subscribe(context.Background(), channel)
+	_, _ = pubSub.Receive(context.Background()) // wait for the confirmation of subscription
+	s := &subscription{name: name, pubSub: pubSub, caches: []*multiCache{c}}
+	subscriptions[name] = s
+	c.subscription = s
+	go func() {
+		// the channel is closed after the redis client is closed
+		for m := range pubSub.Channel() {
+			msg := &invalidationMessage{}
+			if err := json.Unmarshal([]byte(m.Payload), msg); err != nil {
+				continue
+			}
+			s.invalidate(msg)
+		}
+		subscriptionsMu.Lock()
+		if subscriptions[name] == s {
+			delete(subscriptions, name)
+		}
+		subscriptionsMu.Unlock()
+	}()
+}
+
+// remove the cache from the subscription, the subscription is closed when no cache uses it
+func unsubscribeInvalidation(c *multiCache) {
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+
+	s := c.subscription
+	if s == nil {
+		return
+	}
+	c.subscription = nil
+	if s.remove(c) > 0 {
+		return
+	}
+	if subscriptions[s.name] == s {
+		delete(subscriptions, s.name)
+	}
+	_ = s.pubSub.Close()
+}
@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/encoding"
+	"github.com/zhufuyi/sponge/pkg/gotest"
+	"github.com/zhufuyi/sponge/pkg/utils"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+type multiUser struct {
+	ID   uint64
+	Name string
+}
+
+func newMultiCache() *gotest.Cache {
+	record1 := &multiUser{
+		ID:   1,
+		Name: "foo",
+	}
+	record2 := &multiUser{
+		ID:   2,
+		Name: "bar",
+	}
+
+	testData := map[string]interface{}{
+		utils.Uint64ToStr(record1.ID): record1,
+		utils.Uint64ToStr(record2.ID): record2,
+	}
+
+	c := gotest.NewCache(testData)
+	cachePrefix := ""
+	c.ICache = NewMultiCache(c.RedisClient, cachePrefix, encoding.JSONEncoding{}, func() interface{} {
+		return &multiUser{}
+	}, WithLocalExpireTime(time.Minute), WithInvalidationChannel("test:invalidation"))
+
+	return c
+}
+
+func TestMultiCache(t *testing.T) {
+	c := newMultiCache()
+	defer c.Close()
+	testData := c.TestDataSlice[0].(*multiUser)
+	iCache := c.ICache.(Cache)
+
+	key := utils.Uint64ToStr(testData.ID)
+	err := iCache.Set(c.Ctx, key, c.TestDataMap[key], time.Minute)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 10)
+
+	val := &multiUser{}
+	err = iCache.Get(c.Ctx, key, val)
+	assert.NoError(t, err)
+	assert.Equal(t, testData.Name, val.Name)
+
+	err = iCache.Del(c.Ctx, key)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 10)
+	err = iCache.Get(c.Ctx, key, val)
+	assert.ErrorIs(t, err, CacheNotFound)
+
+	err = iCache.MultiSet(c.Ctx, c.TestDataMap, time.Minute)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 10)
+
+	var keys []string
+	for k := range c.TestDataMap {
+		keys = append(keys, k)
+	}
+	vals := make(map[string]*multiUser)
+	err = iCache.MultiGet(c.Ctx, keys, vals)
+	assert.NoError(t, err)
+	assert.Equal(t, len(c.TestDataSlice), len(vals))
+
+	err = iCache.SetCacheWithNotFound(c.Ctx, "not_found")
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 10)
+	err = iCache.Get(c.Ctx, "not_found", val)
+	assert.ErrorIs(t, err, ErrPlaceholder)
+}
+
+func TestMultiCache_Invalidation(t *testing.T) {
+	c := newMultiCache()
+	defer c.Close()
+	newObject := func() interface{} { return &multiUser{} }
+	// another instance using the same redis
+	otherCache := NewMultiCache(c.RedisClient, "", encoding.JSONEncoding{}, newObject, WithInvalidationChannel("test:invalidation"))
+	iCache := c.ICache.(Cache)
+
+	// read from redis and backfill the local cache of other instance
+	key := "1"
+	err := iCache.Set(c.Ctx, key, &multiUser{ID: 1, Name: "foo"}, time.Minute)
+	assert.NoError(t, err)
+	val := &multiUser{}
+	err = otherCache.Get(c.Ctx, key, val)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", val.Name)
+	time.Sleep(time.Millisecond * 10)
+
+	// the local entry of other instance is deleted after updating
+	err = iCache.Set(c.Ctx, key, &multiUser{ID: 1, Name: "bar"}, time.Minute)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 100)
+	err = otherCache.Get(c.Ctx, key, val)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val.Name)
+
+	// the local entry of other instance is deleted after deleting
+	time.Sleep(time.Millisecond * 10)
+	err = iCache.Del(c.Ctx, key)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 100)
+	err = otherCache.Get(c.Ctx, key, val)
+	assert.ErrorIs(t, err, CacheNotFound)
+
+	// placeholder from redis
+	err = iCache.SetCacheWithNotFound(c.Ctx, key)
+	assert.NoError(t, err)
+	vals := make(map[string]*multiUser)
+	err = otherCache.MultiGet(c.Ctx, []string{key, "2"}, vals)
+	assert.NoError(t, err)
+	assert.Empty(t, vals)
+	time.Sleep(time.Millisecond * 10)
+	err = otherCache.Get(c.Ctx, key, val)
+	assert.ErrorIs(t, err, ErrPlaceholder)
+}
+
+func TestMultiCache_DelByTag(t *testing.T) {
+	c := newMultiCache()
+	defer c.Close()
+	newObject := func() interface{} { return &multiUser{} }
+	otherCache := NewMultiCache(c.RedisClient, "", encoding.JSONEncoding{}, newObject, WithInvalidationChannel("test:invalidation"))
+	iCache := c.ICache.(Cache)
+
+	for key, val := range c.TestDataMap {
+		err := iCache.SetWithTags(c.Ctx, key, val, time.Minute, "users")
+		assert.NoError(t, err)
+	}
+	val := &multiUser{}
+	assert.NoError(t, otherCache.Get(c.Ctx, "1", val))
+	time.Sleep(time.Millisecond * 10)
+
+	// delete by tag in other instance, the local entries of all instances are deleted
+	err := otherCache.DelByTag(c.Ctx, "users")
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 100)
+	assert.ErrorIs(t, iCache.Get(c.Ctx, "1", val), CacheNotFound)
+	assert.ErrorIs(t, iCache.Get(c.Ctx, "2", val), CacheNotFound)
+	assert.ErrorIs(t, otherCache.Get(c.Ctx, "1", val), CacheNotFound)
+}
+
+func TestMultiCache_DelByTagError(t *testing.T) {
+	c := newMultiCache()
+	defer c.Close()
+	iCache := c.ICache.(Cache)
+
+	// the keys are deleted in redis, but fail to be removed from the set of tag
+	client := redis.NewClient(&redis.Options{Addr: c.RedisClient.Options().Addr})
+	defer client.Close()
+	client.AddHook(failDelHook{failSRem: true})
+	failedCache := NewMultiCache(client, "", encoding.JSONEncoding{}, nil, WithInvalidationChannel("test:invalidation"))
+	defer failedCache.(io.Closer).Close()
+
+	for key, val := range c.TestDataMap {
+		err := iCache.SetWithTags(c.Ctx, key, val, time.Minute, "users")
+		assert.NoError(t, err)
+	}
+	val := &multiUser{}
+	assert.NoError(t, iCache.Get(c.Ctx, "1", val))
+	time.Sleep(time.Millisecond * 10)
+
+	// the deleted keys are published even if the error is returned
+	err := failedCache.DelByTag(c.Ctx, "users")
+	assert.Error(t, err)
+	time.Sleep(time.Millisecond * 100)
+	assert.ErrorIs(t, iCache.Get(c.Ctx, "1", val), CacheNotFound)
+	assert.ErrorIs(t, iCache.Get(c.Ctx, "2", val), CacheNotFound)
+}
+
+func TestMultiCache_Close(t *testing.T) {
+	c := newMultiCache()
+	defer c.Close()
+	newObject := func() interface{} { return &multiUser{} }
+	otherCache := NewMultiCache(c.RedisClient, "", encoding.JSONEncoding{}, newObject, WithInvalidationChannel("test:invalidation"))
+	s := otherCache.(*multiCache).subscription
+	assert.Len(t, s.caches, 2)
+
+	assert.NoError(t, otherCache.(io.Closer).Close())
+	assert.NoError(t, otherCache.(io.Closer).Close())
+	assert.Len(t, s.caches, 1)
+	subscriptionsMu.Lock()
+	assert.Equal(t, s, subscriptions[s.name])
+	subscriptionsMu.Unlock()
+
+	// the subscription is closed after the last cache is closed
+	assert.NoError(t, c.ICache.(io.Closer).Close())
+	assert.Len(t, s.caches, 0)
+	subscriptionsMu.Lock()
+	_, ok := subscriptions[s.name]
+	subscriptionsMu.Unlock()
+	assert.False(t, ok)
+}
+
+func TestMultiCacheError(t *testing.T) {
+	c := newMultiCache()
+	defer c.Close()
+	iCache := c.ICache.(Cache)
+
+	err := iCache.Set(c.Ctx, "", &multiUser{}, time.Minute)
+	assert.Error(t, err)
+	err = iCache.Get(c.Ctx, "", &multiUser{})
+	assert.Error(t, err)
+	assert.NoError(t, iCache.MultiSet(c.Ctx, nil, time.Minute))
+	assert.NoError(t, iCache.MultiGet(c.Ctx, nil, nil))
+	assert.NoError(t, iCache.Del(c.Ctx))
+
+	mc := iCache.(*multiCache)
+	assert.Equal(t, time.Minute, mc.localTTL(0))
+	assert.Equal(t, time.Second, mc.localTTL(time.Second))
+	assert.Equal(t, time.Minute, mc.localTTL(time.Hour))
+}
@@ -18,15 +18,17 @@ var CacheNotFound = redis.Nil
 
 // redisCache redis cache object
 type redisCache struct {
-	client            *redis.Client
+	client            redis.UniversalClient
 	KeyPrefix         string
 	encoding          encoding.Encoding
 	DefaultExpireTime time.Duration
 	newObject         func() interface{}
 }
 
-// NewRedisCache new a cache, client parameter can be passed in for unit testing
-func NewRedisCache(client *redis.Client, keyPrefix string, encode encoding.Encoding, newObject func() interface{}) Cache {
+// NewRedisCache new a cache, the client can be *redis.Client, *redis.ClusterClient or the sentinel client,
+// client parameter can be passed in for unit testing, newObject creates the values of MultiGet,
+// it can be nil if MultiGet is not used, e.g. the cache is used by Typed.
+func NewRedisCache(client redis.UniversalClient, keyPrefix string, encode encoding.Encoding, newObject func() interface{}) Cache {
 	return &redisCache{
 		client:    client,
 		KeyPrefix: keyPrefix,
@@ -111,19 +113,26 @@ func (c *redisCache) MultiSet(ctx context.Context, valueMap map[string]interface
 		paris = append(paris, buf)
 	}
 	pipeline := c.client.Pipeline()
-	err := pipeline.MSet(ctx, paris...).Err()
-	if err != nil {
-		return fmt.Errorf("pipeline.MSet error: %v", err)
-	}
-	for i := 0; i < len(paris); i = i + 2 {
-		switch paris[i].(type) {
-		case []byte:
-			pipeline.Expire(ctx, string(paris[i].([]byte)), expiration)
-		default:
-			fmt.Printf("redis expire is unsupported key type: %+v\n", reflect.TypeOf(paris[i]))
+	if isClusterClient(c.client) {
+		// the keys of MSET must be in the same slot in cluster mode, set them one by one
+		for i := 0; i < len(paris); i = i + 2 {
+			pipeline.Set(ctx, string(paris[i].([]byte)), paris[i+1], expiration)
+		}
+	} else {
+		err := pipeline.MSet(ctx, paris...).Err()
+		if err != nil {
+			return fmt.Errorf("pipeline.MSet error: %v", err)
+		}
+		for i := 0; i < len(paris); i = i + 2 {
+			switch paris[i].(type) {
+			case []byte:
+				pipeline.Expire(ctx, string(paris[i].([]byte)), expiration)
+			default:
+				fmt.Printf("redis expire is unsupported key type: %+v\n", reflect.TypeOf(paris[i]))
+			}
 		}
 	}
-	_, err = pipeline.Exec(ctx)
+	_, err := pipeline.Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("pipeline.Exec error: %v", err)
 	}
@@ -135,6 +144,9 @@ func (c *redisCache) MultiGet(ctx context.Context, keys []string, value interfac
 	if len(keys) == 0 {
 		return nil
 	}
+	if c.newObject == nil {
+		return errNilNewObject
+	}
 	cacheKeys := make([]string, len(keys))
 	for index, key := range keys {
 		cacheKey, err := BuildCacheKey(c.KeyPrefix, key)
@@ -143,9 +155,9 @@ func (c *redisCache) MultiGet(ctx context.Context, keys []string, value interfac
 		}
 		cacheKeys[index] = cacheKey
 	}
-	values, err := c.client.MGet(ctx, cacheKeys...).Result()
+	values, err := c.mget(ctx, cacheKeys...)
 	if err != nil {
-		return fmt.Errorf("c.client.MGet error: %v, keys=%+v", err, cacheKeys)
+		return err
 	}
 
 	// Injection into map via reflection
@@ -165,6 +177,35 @@ func (c *redisCache) MultiGet(ctx context.Context, keys []string, value interfac
 	return nil
 }
 
+func (c *redisCache) multiGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	cacheKeys := make([]string, len(keys))
+	for index, key := range keys {
+		cacheKey, err := BuildCacheKey(c.KeyPrefix, key)
+		if err != nil {
+			return nil, fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
+		}
+		cacheKeys[index] = cacheKey
+	}
+	values, err := c.mget(ctx, cacheKeys...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]byte, len(keys))
+	for i, v := range values {
+		str, ok := v.(string)
+		if !ok || str == "" || str == NotFoundPlaceholder {
+			continue
+		}
+		result[keys[i]] = []byte(str)
+	}
+	return result, nil
+}
+
+func (c *redisCache) unmarshal(data []byte, val interface{}) error {
+	return encoding.Unmarshal(c.encoding, data, val)
+}
+
 // Del delete multiple values
 func (c *redisCache) Del(ctx context.Context, keys ...string) error {
 	if len(keys) == 0 {
@@ -179,11 +220,7 @@ func (c *redisCache) Del(ctx context.Context, keys ...string) error {
 		}
 		cacheKeys[index] = cacheKey
 	}
-	err := c.client.Del(ctx, cacheKeys...).Err()
-	if err != nil {
-		return fmt.Errorf("c.client.Del error: %v, keys=%+v", err, cacheKeys)
-	}
-	return nil
+	return c.del(ctx, cacheKeys...)
 }
 
 // SetCacheWithNotFound set value for notfound
@@ -196,6 +233,151 @@ func (c *redisCache) SetCacheWithNotFound(ctx context.Context, key string) error
 	return c.client.Set(ctx, cacheKey, NotFoundPlaceholder, DefaultNotFoundExpireTime).Err()
 }
 
+// get multiple values, the value of the key that does not exist is nil
+func (c *redisCache) mget(ctx context.Context, cacheKeys ...string) ([]interface{}, error) {
+	if !isClusterClient(c.client) {
+		values, err := c.client.MGet(ctx, cacheKeys...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("c.client.MGet error: %v, keys=%+v", err, cacheKeys)
+		}
+		return values, nil
+	}
+
+	// the keys of MGET must be in the same slot in cluster mode, get them one by one
+	pipeline := c.client.Pipeline()
+	cmds := make([]*redis.StringCmd, 0, len(cacheKeys))
+	for _, cacheKey := range cacheKeys {
+		cmds = append(cmds, pipeline.Get(ctx, cacheKey))
+	}
+	_, err := pipeline.Exec(ctx)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("pipeline.Exec error: %v, keys=%+v", err, cacheKeys)
+	}
+	values := make([]interface{}, len(cmds))
+	for i, cmd := range cmds {
+		if val, err := cmd.Result(); err == nil {
+			values[i] = val
+		}
+	}
+	return values, nil
+}
+
+// delete multiple keys
+func (c *redisCache) del(ctx context.Context, cacheKeys ...string) error {
+	if !isClusterClient(c.client) {
+		err := c.client.Del(ctx, cacheKeys...).Err()
+		if err != nil {
+			return fmt.Errorf("c.client.Del error: %v, keys=%+v", err, cacheKeys)
+		}
+		return nil
+	}
+
+	// the keys of DEL must be in the same slot in cluster mode, delete them one by one
+	pipeline := c.client.Pipeline()
+	for _, cacheKey := range cacheKeys {
+		pipeline.Del(ctx, cacheKey)
+	}
+	_, err := pipeline.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("pipeline.Exec error: %v, keys=%+v", err, cacheKeys)
+	}
+	return nil
+}
+
+func isClusterClient(client redis.UniversalClient) bool {
+	_, ok := client.(*redis.ClusterClient)
+	return ok
+}
+
+// add the cache key to the set of tag, the expiry time of the set is extended to the expiry time of the cache key
+var addTagScript = `
+redis.call('SADD', KEYS[1], ARGV[1])
+local expiration = tonumber(ARGV[2])
+if expiration <= 0 then
+	redis.call('PERSIST', KEYS[1])
+elseif redis.call('PTTL', KEYS[1]) < expiration then
+	redis.call('PEXPIRE', KEYS[1], expiration)
+end
+return 1
+`
+
+// SetWithTags set one value and add the key to the sets of tags
+func (c *redisCache) SetWithTags(ctx context.Context, key string, val interface{}, expiration time.Duration, tags ...string) error {
+	buf, err := encoding.Marshal(c.encoding, val)
+	if err != nil {
+		return fmt.Errorf("encoding.Marshal error: %v, key=%s, val=%+v ", err, key, val)
+	}
+	cacheKey, err := BuildCacheKey(c.KeyPrefix, key)
+	if err != nil {
+		return fmt.Errorf("BuildCacheKey error: %v, key=%s", err, key)
+	}
+
+	pipeline := c.client.Pipeline()
+	pipeline.Set(ctx, cacheKey, buf, expiration)
+	for _, tag := range tags {
+		pipeline.Eval(ctx, addTagScript, []string{buildTagKey(c.KeyPrefix, tag)}, cacheKey, expiration.Milliseconds())
+	}
+	_, err = pipeline.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("pipeline.Exec error: %v, cacheKey=%s", err, cacheKey)
+	}
+	return nil
+}
+
+// DelByTag delete all values whose keys are in the sets of tags
+func (c *redisCache) DelByTag(ctx context.Context, tags ...string) error {
+	_, err := c.delByTag(ctx, tags...)
+	return err
+}
+
+// scan the keys in the sets of tags, delete the keys and then remove them from the sets, return the deleted cache keys,
+// the keys are removed from the sets only after they are deleted, so the keys that fail to be deleted are deleted next time.
+func (c *redisCache) delByTag(ctx context.Context, tags ...string) ([]string, error) {
+	const batchSize = 1000
+	var deletedKeys []string
+	for _, tag := range tags {
+		tagKey := buildTagKey(c.KeyPrefix, tag)
+		var cursor uint64
+		for {
+			cacheKeys, next, err := c.client.SScan(ctx, tagKey, cursor, "", batchSize).Result()
+			if err != nil {
+				return deletedKeys, fmt.Errorf("c.client.SScan error: %v, tagKey=%s", err, tagKey)
+			}
+
+			if len(cacheKeys) > 0 {
+				pipeline := c.client.Pipeline()
+				members := make([]interface{}, 0, len(cacheKeys))
+				for _, cacheKey := range cacheKeys {
+					pipeline.Del(ctx, cacheKey)
+					members = append(members, cacheKey)
+				}
+				_, err = pipeline.Exec(ctx)
+				if err != nil {
+					return deletedKeys, fmt.Errorf("pipeline.Exec error: %v, tagKey=%s", err, tagKey)
+				}
+				deletedKeys = append(deletedKeys, cacheKeys...)
+
+				err = c.client.SRem(ctx, tagKey, members...).Err()
+				if err != nil {
+					return deletedKeys, fmt.Errorf("c.client.SRem error: %v, tagKey=%s", err, tagKey)
+				}
+			}
+
+			cursor = next
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+	return deletedKeys, nil
+}
+
+// the key of the set that saves the cache keys of tag
+func buildTagKey(keyPrefix string, tag string) string {
+	cacheKey, _ := BuildCacheKey(keyPrefix, "tag:"+tag)
+	return cacheKey
+}
+
 // BuildCacheKey construct a cache key with a prefix
 func BuildCacheKey(keyPrefix string, key string) (string, error) {
 	if key == "" {
@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -8,6 +10,7 @@ import (
 	"github.com/zhufuyi/sponge/pkg/gotest"
 	"github.com/zhufuyi/sponge/pkg/utils"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -127,3 +130,116 @@ func TestBuildCacheKey(t *testing.T) {
 	_, err = BuildCacheKey("foo", "bar")
 	assert.NoError(t, err)
 }
+
+func TestRedisCache_DelByTag(t *testing.T) {
+	c := newRedisCache()
+	defer c.Close()
+	iCache := c.ICache.(Cache)
+
+	for key, val := range c.TestDataMap {
+		err := iCache.SetWithTags(c.Ctx, key, val, time.Minute, "users", "user:"+key)
+		assert.NoError(t, err)
+	}
+	err := iCache.SetWithTags(c.Ctx, "3", &redisUser{ID: 3}, time.Hour, "users")
+	assert.NoError(t, err)
+	assert.True(t, c.RedisClient.TTL(c.Ctx, "tag:users").Val() > time.Minute)
+	err = iCache.SetWithTags(c.Ctx, "", &redisUser{}, time.Minute, "users")
+	assert.Error(t, err)
+
+	err = iCache.DelByTag(c.Ctx, "user:1")
+	assert.NoError(t, err)
+	val := &redisUser{}
+	assert.ErrorIs(t, iCache.Get(c.Ctx, "1", val), CacheNotFound)
+	assert.NoError(t, iCache.Get(c.Ctx, "2", val))
+
+	err = iCache.DelByTag(c.Ctx, "users", "not_exist")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, iCache.Get(c.Ctx, "2", val), CacheNotFound)
+	assert.ErrorIs(t, iCache.Get(c.Ctx, "3", val), CacheNotFound)
+	assert.Equal(t, int64(0), c.RedisClient.Exists(c.Ctx, "tag:users").Val())
+}
+
+// fail the DEL commands of pipeline, or the SREM command if failSRem is true
+type failDelHook struct {
+	failSRem bool
+}
+
+func (h failDelHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if h.failSRem && cmd.Name() == "srem" {
+		return ctx, errors.New("mock srem error")
+	}
+	return ctx, nil
+}
+
+func (failDelHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h failDelHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		if !h.failSRem && cmd.Name() == "del" {
+			return ctx, errors.New("mock del error")
+		}
+	}
+	return ctx, nil
+}
+
+func (failDelHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func TestRedisCache_DelByTagError(t *testing.T) {
+	c := newRedisCache()
+	defer c.Close()
+	iCache := c.ICache.(Cache)
+
+	for key, val := range c.TestDataMap {
+		err := iCache.SetWithTags(c.Ctx, key, val, time.Minute, "users")
+		assert.NoError(t, err)
+	}
+
+	// the keys are kept in the set if they fail to be deleted
+	client := redis.NewClient(&redis.Options{Addr: c.RedisClient.Options().Addr})
+	defer client.Close()
+	client.AddHook(failDelHook{})
+	err := NewRedisCache(client, "", encoding.JSONEncoding{}, nil).DelByTag(c.Ctx, "users")
+	assert.Error(t, err)
+	assert.Equal(t, int64(len(c.TestDataMap)), c.RedisClient.SCard(c.Ctx, "tag:users").Val())
+
+	err = iCache.DelByTag(c.Ctx, "users")
+	assert.NoError(t, err)
+	for key := range c.TestDataMap {
+		assert.ErrorIs(t, iCache.Get(c.Ctx, key, &redisUser{}), CacheNotFound)
+	}
+	assert.Equal(t, int64(0), c.RedisClient.Exists(c.Ctx, "tag:users").Val())
+}
+
+func TestRedisCache_Cluster(t *testing.T) {
+	c := newRedisCache()
+	defer c.Close()
+	// the miniredis serves all slots as a single node cluster
+	clusterClient := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{c.RedisClient.Options().Addr}})
+	defer clusterClient.Close()
+	iCache := NewRedisCache(clusterClient, "", encoding.JSONEncoding{}, func() interface{} {
+		return &redisUser{}
+	})
+
+	err := iCache.MultiSet(c.Ctx, c.TestDataMap, time.Minute)
+	assert.NoError(t, err)
+
+	var keys []string
+	for k := range c.TestDataMap {
+		keys = append(keys, k)
+	}
+	vals := make(map[string]*redisUser)
+	err = iCache.MultiGet(c.Ctx, append(keys, "not_exist"), vals)
+	assert.NoError(t, err)
+	assert.Equal(t, len(c.TestDataSlice), len(vals))
+
+	err = iCache.Del(c.Ctx, keys...)
+	assert.NoError(t, err)
+	vals = make(map[string]*redisUser)
+	err = iCache.MultiGet(c.Ctx, keys, vals)
+	assert.NoError(t, err)
+	assert.Empty(t, vals)
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/utils"
+)
+
+// Typed cache with the types of key and value checked at compile time, it is based on Cache,
+// the cache key is keyPrefix + string of key, e.g. "user:1", the value is encoded by the encoding of Cache,
+// the values are decoded into V by Typed, so the newObject of Cache is not required.
+type Typed[K comparable, V any] struct {
+	cache     Cache
+	keyPrefix string
+	loader    *Loader
+}
+
+// NewTyped create a typed cache, the opts are used by Load.
+func NewTyped[K comparable, V any](c Cache, keyPrefix string, opts ...LoaderOption) *Typed[K, V] {
+	return &Typed[K, V]{
+		cache:     c,
+		keyPrefix: keyPrefix,
+		loader:    NewLoader(c, opts...),
+	}
+}
+
+// CacheKey get the cache key of key
+func (t *Typed[K, V]) CacheKey(key K) string {
+	return t.keyPrefix + utils.AnyToStr(key)
+}
+
+// Set data
+func (t *Typed[K, V]) Set(ctx context.Context, key K, val V, expiration time.Duration) error {
+	return t.cache.Set(ctx, t.CacheKey(key), &val, expiration)
+}
+
+// Get data
+func (t *Typed[K, V]) Get(ctx context.Context, key K) (V, error) {
+	var val V
+	err := t.cache.Get(ctx, t.CacheKey(key), &val)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return val, nil
+}
+
+// MultiSet multiple set data
+func (t *Typed[K, V]) MultiSet(ctx context.Context, valMap map[K]V, expiration time.Duration) error {
+	if len(valMap) == 0 {
+		return nil
+	}
+
+	m := make(map[string]interface{}, len(valMap))
+	for key, val := range valMap {
+		v := val
+		m[t.CacheKey(key)] = &v
+	}
+	return t.cache.MultiSet(ctx, m, expiration)
+}
+
+// MultiGet multiple get data, the keys that are not found are not in the result
+func (t *Typed[K, V]) MultiGet(ctx context.Context, keys []K) (map[K]V, error) {
+	result := make(map[K]V, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	cacheKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		cacheKeys = append(cacheKeys, t.CacheKey(key))
+	}
+
+	rc, ok := t.cache.(rawCache)
+	if !ok {
+		// the cache does not provide the encoded values, get the values one by one
+		for i, key := range keys {
+			var val V
+			err := t.cache.Get(ctx, cacheKeys[i], &val)
+			if err != nil {
+				if errors.Is(err, CacheNotFound) || errors.Is(err, ErrPlaceholder) {
+					continue
+				}
+				return nil, err
+			}
+			result[key] = val
+		}
+		return result, nil
+	}
+
+	values, err := rc.multiGetRaw(ctx, cacheKeys)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		data, ok := values[cacheKeys[i]]
+		if !ok {
+			continue
+		}
+		var val V
+		if err = rc.unmarshal(data, &val); err != nil {
+			return nil, fmt.Errorf("unmarshal error: %v, key=%s, data=%s", err, cacheKeys[i], data)
+		}
+		result[key] = val
+	}
+	return result, nil
+}
+
+// Del multiple delete data
+func (t *Typed[K, V]) Del(ctx context.Context, keys ...K) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	cacheKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		cacheKeys = append(cacheKeys, t.CacheKey(key))
+	}
+	return t.cache.Del(ctx, cacheKeys...)
+}
+
+// SetCacheWithNotFound set not found
+func (t *Typed[K, V]) SetCacheWithNotFound(ctx context.Context, key K) error {
+	return t.cache.SetCacheWithNotFound(ctx, t.CacheKey(key))
+}
+
+// SetWithTags set data and associate the key with tags
+func (t *Typed[K, V]) SetWithTags(ctx context.Context, key K, val V, expiration time.Duration, tags ...string) error {
+	return t.cache.SetWithTags(ctx, t.CacheKey(key), &val, expiration, tags...)
+}
+
+// DelByTag delete all data associated with the tags
+func (t *Typed[K, V]) DelByTag(ctx context.Context, tags ...string) error {
+	return t.cache.DelByTag(ctx, tags...)
+}
+
+// Load get data from cache, if the cache misses, call fn to load the data and set cache, see Loader.Load
+func (t *Typed[K, V]) Load(ctx context.Context, key K, expiration time.Duration, fn func(ctx context.Context) (V, error)) (V, error) {
+	var val V
+	err := t.loader.Load(ctx, t.CacheKey(key), &val, expiration, func(ctx context.Context) (interface{}, error) {
+		v, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &v, nil // the encoding requires a pointer
+	})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return val, nil
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/encoding"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTyped(t *testing.T) {
+	c := newCache()
+	defer c.Close()
+	typed := NewTyped[uint64, *cacheUser](c.ICache.(Cache), "user:", WithNotFoundError(errRecordNotFound))
+
+	assert.Equal(t, "user:1", typed.CacheKey(1))
+
+	err := typed.Set(c.Ctx, 1, &cacheUser{ID: 1, Name: "foo"}, time.Minute)
+	assert.NoError(t, err)
+	val, err := typed.Get(c.Ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", val.Name)
+
+	err = typed.MultiSet(c.Ctx, map[uint64]*cacheUser{2: {ID: 2, Name: "bar"}, 3: {ID: 3, Name: "baz"}}, time.Minute)
+	assert.NoError(t, err)
+	vals, err := typed.MultiGet(c.Ctx, []uint64{1, 2, 3, 4})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(vals))
+	assert.Equal(t, "bar", vals[2].Name)
+
+	err = typed.Del(c.Ctx, 1, 2)
+	assert.NoError(t, err)
+	_, err = typed.Get(c.Ctx, 1)
+	assert.ErrorIs(t, err, CacheNotFound)
+
+	err = typed.SetCacheWithNotFound(c.Ctx, 5)
+	assert.NoError(t, err)
+	_, err = typed.Get(c.Ctx, 5)
+	assert.ErrorIs(t, err, ErrPlaceholder)
+
+	err = typed.SetWithTags(c.Ctx, 6, &cacheUser{ID: 6}, time.Minute, "users")
+	assert.NoError(t, err)
+	err = typed.DelByTag(c.Ctx, "users")
+	assert.NoError(t, err)
+	_, err = typed.Get(c.Ctx, 6)
+	assert.ErrorIs(t, err, CacheNotFound)
+
+	val, err = typed.Load(c.Ctx, 7, time.Minute, func(ctx context.Context) (*cacheUser, error) {
+		return &cacheUser{ID: 7, Name: "qux"}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "qux", val.Name)
+	_, err = typed.Load(c.Ctx, 8, time.Minute, func(ctx context.Context) (*cacheUser, error) {
+		return nil, errRecordNotFound
+	})
+	assert.ErrorIs(t, err, errRecordNotFound)
+
+	assert.NoError(t, typed.MultiSet(c.Ctx, nil, time.Minute))
+	assert.NoError(t, typed.Del(c.Ctx))
+	vals, err = typed.MultiGet(c.Ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, vals)
+}
+
+func TestTyped_ValueType(t *testing.T) {
+	c := newCache()
+	defer c.Close()
+	// the value is not a pointer, the values are decoded by Typed without newObject
+	rc := NewRedisCache(c.RedisClient, "", encoding.JSONEncoding{}, nil)
+	typed := NewTyped[string, string](rc, "name:")
+
+	err := typed.Set(c.Ctx, "foo", "bar", time.Minute)
+	assert.NoError(t, err)
+	val, err := typed.Get(c.Ctx, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", val)
+
+	vals, err := typed.MultiGet(c.Ctx, []string{"foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "bar"}, vals)
+
+	// the cached value can not be decoded into the type of value
+	typed2 := NewTyped[string, int](rc, "name:")
+	_, err = typed2.MultiGet(c.Ctx, []string{"foo"})
+	assert.Error(t, err)
+}
+
+func TestTyped_NilNewObject(t *testing.T) {
+	c := newCache()
+	defer c.Close()
+	mc := NewMultiCache(c.RedisClient, "", encoding.JSONEncoding{}, nil)
+	defer mc.(io.Closer).Close()
+	caches := map[string]Cache{
+		"redis":  NewRedisCache(c.RedisClient, "", encoding.JSONEncoding{}, nil),
+		"memory": NewMemoryCache("", encoding.JSONEncoding{}, nil),
+		"multi":  mc,
+	}
+
+	for name, ic := range caches {
+		t.Run(name, func(t *testing.T) {
+			users := NewTyped[uint64, *cacheUser](ic, name+":user:")
+			lists := NewTyped[string, []*cacheUser](ic, name+":list:")
+
+			err := users.MultiSet(c.Ctx, map[uint64]*cacheUser{1: {ID: 1, Name: "foo"}, 2: {ID: 2, Name: "bar"}}, time.Minute)
+			assert.NoError(t, err)
+			err = users.SetCacheWithNotFound(c.Ctx, 3)
+			assert.NoError(t, err)
+			err = lists.Set(c.Ctx, "page1", []*cacheUser{{ID: 1}, {ID: 2}}, time.Minute)
+			assert.NoError(t, err)
+			time.Sleep(time.Millisecond * 10) // wait for the memory cache to be written
+
+			vals, err := users.MultiGet(c.Ctx, []uint64{1, 2, 3, 4})
+			assert.NoError(t, err)
+			assert.Equal(t, 2, len(vals))
+			assert.Equal(t, "bar", vals[2].Name)
+			pages, err := lists.MultiGet(c.Ctx, []string{"page1"})
+			assert.NoError(t, err)
+			assert.Equal(t, 2, len(pages["page1"]))
+
+			// the untyped MultiGet requires newObject
+			err = ic.MultiGet(c.Ctx, []string{name + ":user:1"}, map[string]*cacheUser{})
+			assert.ErrorIs(t, err, errNilNewObject)
+		})
+	}
+}
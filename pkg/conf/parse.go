@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/fsnotify/fsnotify"
@@ -49,7 +50,7 @@ func Parse(configFile string, obj interface{}, fs ...func()) error {
 func watchConfig(obj interface{}, fs ...func()) {
 	viper.WatchConfig()
 	viper.OnConfigChange(func(e fsnotify.Event) {
-		err := viper.Unmarshal(obj)
+		err := reload(obj)
 		if err != nil {
 			fmt.Println("viper.Unmarshal error: ", err)
 		} else {
@@ -60,6 +61,22 @@ func watchConfig(obj interface{}, fs ...func()) {
 	})
 }
 
+// decode to a new object and replace obj, the elements removed from the slices of the file are not retained
+func reload(obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return viper.Unmarshal(obj)
+	}
+
+	newObj := reflect.New(rv.Elem().Type())
+	err := viper.Unmarshal(newObj.Interface())
+	if err != nil {
+		return err
+	}
+	rv.Elem().Set(newObj.Elem())
+	return nil
+}
+
 // Show print configuration information (hide sensitive fields)
 func Show(obj interface{}, fields ...string) string {
 	var out string
@@ -85,3 +85,26 @@ func TestParseConfigData(t *testing.T) {
 	}
 	t.Log(conf)
 }
+
+func Test_reload(t *testing.T) {
+	type app struct {
+		Name string
+	}
+	type config struct {
+		App   app
+		Extra []string
+	}
+
+	obj := &config{Extra: []string{"foo"}}
+	err := reload(obj)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if obj.App.Name != "serverNameExample" || obj.Extra != nil {
+		t.Errorf("reload error, got %+v", obj)
+	}
+
+	// not a pointer
+	_ = reload(config{})
+}
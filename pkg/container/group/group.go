@@ -56,6 +56,17 @@ func (g *Group) Reset(new func() interface{}) {
 	g.Clear()
 }
 
+// Range calls fn for each key and object, if fn returns false, range stops the iteration.
+func (g *Group) Range(fn func(key string, val interface{}) bool) {
+	g.RLock()
+	defer g.RUnlock()
+	for k, v := range g.vals {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
 // Clear deletes all objects.
 func (g *Group) Clear() {
 	g.Lock()
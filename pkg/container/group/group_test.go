@@ -77,3 +77,29 @@ func TestGroupClear(t *testing.T) {
 		t.Errorf("expect length 0, actual %v", length)
 	}
 }
+
+func TestGroupRange(t *testing.T) {
+	g := NewGroup(func() interface{} {
+		return 1
+	})
+	g.Get("key_0")
+	g.Get("key_1")
+
+	keys := map[string]interface{}{}
+	g.Range(func(key string, val interface{}) bool {
+		keys[key] = val
+		return true
+	})
+	if !reflect.DeepEqual(keys, map[string]interface{}{"key_0": 1, "key_1": 1}) {
+		t.Errorf("expect 2 keys, actual %v", keys)
+	}
+
+	count := 0
+	g.Range(func(key string, val interface{}) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Errorf("expect count 1, actual %v", count)
+	}
+}
@@ -0,0 +1,24 @@
+// Package dlock is a distributed lock library, supports redis and etcd.
+package dlock
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNotObtained the lock is held by others
+	ErrNotObtained = errors.New("dlock: lock not obtained")
+	// ErrNotHeld the lock is not held, it has not been acquired, has been released or has expired
+	ErrNotHeld = errors.New("dlock: lock not held")
+)
+
+// Locker distributed lock, a Locker is used by one goroutine at a time, create a new Locker for each owner.
+type Locker interface {
+	// Lock acquire the lock, block until the lock is acquired or ctx is done
+	Lock(ctx context.Context) error
+	// TryLock try to acquire the lock once, return false if the lock is held by others
+	TryLock(ctx context.Context) (bool, error)
+	// Unlock release the lock, return ErrNotHeld if the lock is not held
+	Unlock(ctx context.Context) error
+}
@@ -0,0 +1,113 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+type etcdLock struct {
+	client *clientv3.Client
+	key    string
+	ttl    int // lease ttl, unit(second)
+
+	mu      sync.Mutex
+	session *concurrency.Session
+	mutex   *concurrency.Mutex
+}
+
+// NewEtcdLock create an etcd lock, the client can be created by etcdcli.Init. the lock is bound to a lease,
+// the lease is kept alive until the lock is released, and expires after the client is disconnected.
+func NewEtcdLock(client *clientv3.Client, key string, opts ...Option) Locker {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	ttl := int((o.expiration + time.Second - 1) / time.Second)
+	return &etcdLock{
+		client: client,
+		key:    key,
+		ttl:    ttl,
+	}
+}
+
+// Lock acquire the lock, wait until the lock is acquired or ctx is done
+func (l *etcdLock) Lock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	session, err := l.newSession(ctx)
+	if err != nil {
+		return err
+	}
+	mutex := concurrency.NewMutex(session, l.key)
+	if err = mutex.Lock(ctx); err != nil {
+		_ = session.Close()
+		return err
+	}
+
+	l.setHeld(session, mutex)
+	return nil
+}
+
+// TryLock try to acquire the lock once
+func (l *etcdLock) TryLock(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	session, err := l.newSession(ctx)
+	if err != nil {
+		return false, err
+	}
+	mutex := concurrency.NewMutex(session, l.key)
+	if err = mutex.TryLock(ctx); err != nil {
+		_ = session.Close()
+		if errors.Is(err, concurrency.ErrLocked) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	l.setHeld(session, mutex)
+	return true, nil
+}
+
+// Unlock release the lock
+func (l *etcdLock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.session == nil {
+		return ErrNotHeld
+	}
+	session, mutex := l.session, l.mutex
+	l.session, l.mutex = nil, nil
+	defer session.Close() //nolint
+
+	select {
+	case <-session.Done():
+		return ErrNotHeld // the lease has expired
+	default:
+	}
+	return mutex.Unlock(ctx)
+}
+
+// the lease is granted with ctx, and kept alive by the session until it is closed
+func (l *etcdLock) newSession(ctx context.Context) (*concurrency.Session, error) {
+	resp, err := l.client.Grant(ctx, int64(l.ttl))
+	if err != nil {
+		return nil, err
+	}
+	return concurrency.NewSession(l.client, concurrency.WithTTL(l.ttl), concurrency.WithLease(resp.ID))
+}
+
+func (l *etcdLock) setHeld(session *concurrency.Session, mutex *concurrency.Mutex) {
+	if l.session != nil {
+		_ = l.session.Close()
+	}
+	l.session = session
+	l.mutex = mutex
+}
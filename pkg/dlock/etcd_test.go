@@ -0,0 +1,78 @@
+package dlock
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/etcdcli"
+
+	"github.com/stretchr/testify/assert"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// start an embedded etcd server listening on random ports, and return the client connected to it
+func newEtcdClient(t *testing.T) *clientv3.Client {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	clientURL, _ := url.Parse("http://127.0.0.1:0")
+	peerURL, _ := url.Parse("http://127.0.0.1:0")
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{*clientURL}, []url.URL{*clientURL}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{*peerURL}, []url.URL{*peerURL}
+	cfg.InitialCluster = cfg.Name + "=" + peerURL.String()
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(time.Second * 10):
+		t.Fatal("start embedded etcd timeout")
+	}
+
+	endpoints := []string{e.Clients[0].Addr().String()}
+	cli, err := etcdcli.Init(endpoints, etcdcli.WithDialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cli
+}
+
+func TestEtcdLock(t *testing.T) {
+	cli := newEtcdClient(t)
+	defer cli.Close()
+	ctx := context.Background()
+
+	l1 := NewEtcdLock(cli, "/dlock/foo", WithExpiration(time.Second*5))
+	l2 := NewEtcdLock(cli, "/dlock/foo", WithExpiration(time.Second*5))
+
+	ok, err := l1.TryLock(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = l2.TryLock(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.ErrorIs(t, l2.Unlock(ctx), ErrNotHeld)
+
+	// context cancellation
+	ctx2, cancel := context.WithTimeout(ctx, time.Millisecond*200)
+	defer cancel()
+	assert.Error(t, l2.Lock(ctx2))
+
+	assert.NoError(t, l1.Unlock(ctx))
+	assert.ErrorIs(t, l1.Unlock(ctx), ErrNotHeld)
+	assert.NoError(t, l2.Lock(ctx))
+	assert.NoError(t, l2.Unlock(ctx))
+}
+
+func TestNewEtcdLock(t *testing.T) {
+	l := NewEtcdLock(nil, "/dlock/foo", WithExpiration(time.Millisecond*1500))
+	assert.Equal(t, 2, l.(*etcdLock).ttl)
+	l = NewEtcdLock(nil, "/dlock/foo")
+	assert.Equal(t, 10, l.(*etcdLock).ttl)
+}
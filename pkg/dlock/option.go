@@ -0,0 +1,56 @@
+package dlock
+
+import (
+	"time"
+)
+
+// Option set the lock options.
+type Option func(*options)
+
+type options struct {
+	expiration    time.Duration
+	retryInterval time.Duration
+	watchdog      bool
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// default settings
+func defaultOptions() *options {
+	return &options{
+		expiration:    time.Second * 10,
+		retryInterval: time.Millisecond * 100,
+		watchdog:      true, // renew the lock automatically until it is released
+	}
+}
+
+// WithExpiration set the expiry time of the lock, the lock is released automatically after it expires,
+// if watchdog is enabled, the lock is renewed every 1/3 of the expiry time. etcd lock is rounded up to seconds.
+func WithExpiration(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.expiration = d
+		}
+	}
+}
+
+// WithRetryInterval set the interval of retrying to acquire the lock, only used by redis lock.
+func WithRetryInterval(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.retryInterval = d
+		}
+	}
+}
+
+// WithoutWatchdog disable the automatic renewal of the lock, the lock is released after it expires,
+// only used by redis lock, etcd lock is always kept alive by the lease until it is released.
+func WithoutWatchdog() Option {
+	return func(o *options) {
+		o.watchdog = false
+	}
+}
@@ -0,0 +1,159 @@
+package dlock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/krand"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// delete the key only if the value is the token of owner
+	releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+	// extend the expiry time only if the value is the token of owner
+	renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+)
+
+type redisLock struct {
+	client        redis.UniversalClient
+	key           string
+	expiration    time.Duration
+	retryInterval time.Duration
+	watchdog      bool
+
+	mu     sync.Mutex
+	token  string        // the value of key, identifies the owner of the lock
+	stopCh chan struct{} // stop the watchdog
+	doneCh chan struct{} // the watchdog has exited
+}
+
+// NewRedisLock create a redis lock, the value of key is a random token, only the owner can renew and release the lock.
+func NewRedisLock(client redis.UniversalClient, key string, opts ...Option) Locker {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	return &redisLock{
+		client:        client,
+		key:           key,
+		expiration:    o.expiration,
+		retryInterval: o.retryInterval,
+		watchdog:      o.watchdog,
+	}
+}
+
+// Lock acquire the lock, retry at intervals until the lock is acquired or ctx is done
+func (l *redisLock) Lock(ctx context.Context) error {
+	ticker := time.NewTicker(l.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := l.TryLock(ctx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// TryLock try to acquire the lock once
+func (l *redisLock) TryLock(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	token := krand.String(krand.R_All, 24)
+	ok, err := l.client.SetNX(ctx, l.key, token, l.expiration).Result()
+	if err != nil || !ok {
+		return false, err
+	}
+
+	l.stopWatchdog()
+	l.token = token
+	if l.watchdog {
+		l.startWatchdog(token)
+	}
+	return true, nil
+}
+
+// Unlock release the lock
+func (l *redisLock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stopWatchdog()
+	if l.token == "" {
+		return ErrNotHeld
+	}
+	token := l.token
+	l.token = ""
+
+	n, err := releaseScript.Run(ctx, l.client, []string{l.key}, token).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// renew the lock every 1/3 of the expiry time, stop when the lock is released or lost
+func (l *redisLock) startWatchdog(token string) {
+	l.stopCh = make(chan struct{})
+	l.doneCh = make(chan struct{})
+
+	go func(stopCh <-chan struct{}, doneCh chan<- struct{}) {
+		defer close(doneCh)
+		interval := l.expiration / 3
+		if interval <= 0 {
+			interval = time.Millisecond
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				n, err := renewScript.Run(ctx, l.client, []string{l.key}, token, l.expiration.Milliseconds()).Int()
+				cancel()
+				if err == nil && n == 0 {
+					return // the lock has expired or been taken by others
+				}
+			}
+		}
+	}(l.stopCh, l.doneCh)
+}
+
+func (l *redisLock) stopWatchdog() {
+	if l.stopCh == nil {
+		return
+	}
+	close(l.stopCh)
+	<-l.doneCh
+	l.stopCh = nil
+	l.doneCh = nil
+}
@@ -0,0 +1,121 @@
+package dlock
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRedisClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, redis.NewClient(&redis.Options{Addr: s.Addr()})
+}
+
+func TestRedisLock(t *testing.T) {
+	s, client := newRedisClient(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	l1 := NewRedisLock(client, "lock:foo")
+	l2 := NewRedisLock(client, "lock:foo")
+
+	ok, err := l1.TryLock(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = l2.TryLock(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// the lock can only be released by the owner
+	assert.ErrorIs(t, l2.Unlock(ctx), ErrNotHeld)
+	assert.True(t, s.Exists("lock:foo"))
+	assert.NoError(t, l1.Unlock(ctx))
+	assert.ErrorIs(t, l1.Unlock(ctx), ErrNotHeld)
+	assert.False(t, s.Exists("lock:foo"))
+
+	ok, err = l2.TryLock(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, l2.Unlock(ctx))
+}
+
+func TestRedisLock_Lock(t *testing.T) {
+	s, client := newRedisClient(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	var count, maxCount int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := NewRedisLock(client, "lock:bar", WithRetryInterval(time.Millisecond*5))
+			if err := l.Lock(ctx); err != nil {
+				t.Error(err)
+				return
+			}
+			n := atomic.AddInt32(&count, 1)
+			if n > atomic.LoadInt32(&maxCount) {
+				atomic.StoreInt32(&maxCount, n)
+			}
+			time.Sleep(time.Millisecond * 10)
+			atomic.AddInt32(&count, -1)
+			assert.NoError(t, l.Unlock(ctx))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxCount))
+
+	// context cancellation
+	l1 := NewRedisLock(client, "lock:bar")
+	assert.NoError(t, l1.Lock(ctx))
+	ctx2, cancel := context.WithTimeout(ctx, time.Millisecond*200)
+	defer cancel()
+	err := NewRedisLock(client, "lock:bar").Lock(ctx2)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, l1.Unlock(ctx))
+}
+
+func TestRedisLock_Watchdog(t *testing.T) {
+	s, client := newRedisClient(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	// the lock is renewed by watchdog
+	l := NewRedisLock(client, "lock:foo", WithExpiration(time.Millisecond*300))
+	assert.NoError(t, l.Lock(ctx))
+	time.Sleep(time.Millisecond * 250)
+	s.FastForward(time.Millisecond * 200)
+	assert.True(t, s.Exists("lock:foo"))
+	assert.NoError(t, l.Unlock(ctx))
+
+	// the lock expires without watchdog
+	l = NewRedisLock(client, "lock:foo", WithExpiration(time.Millisecond*300), WithoutWatchdog())
+	assert.NoError(t, l.Lock(ctx))
+	time.Sleep(time.Millisecond * 250)
+	s.FastForward(time.Millisecond * 400)
+	assert.False(t, s.Exists("lock:foo"))
+	assert.ErrorIs(t, l.Unlock(ctx), ErrNotHeld)
+}
+
+func TestRedisLock_Error(t *testing.T) {
+	s, client := newRedisClient(t)
+	ctx := context.Background()
+	l := NewRedisLock(client, "lock:foo")
+	s.Close()
+
+	ok, err := l.TryLock(ctx)
+	assert.Error(t, err)
+	assert.False(t, ok)
+	assert.Error(t, l.Lock(ctx))
+}
@@ -0,0 +1,385 @@
+// Package migrate is a database schema migration library based on gorm,
+// the migration files are versioned sql files, and the applied versions are recorded in a table.
+//
+// migration file naming: <version>_<name>.up.sql and <version>_<name>.down.sql, e.g.
+//
+//	20230801120000_add_user_phone.up.sql
+//	20230801120000_add_user_phone.down.sql
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultTableName default table name for recording applied versions
+	DefaultTableName = "schema_migrations"
+
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+
+	versionLayout = "20060102150405"
+)
+
+var fileNameRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// SchemaMigration record of applied version
+type SchemaMigration struct {
+	Version   string    `gorm:"column:version;type:varchar(64);primary_key" json:"version"`
+	Name      string    `gorm:"column:name;type:varchar(255)" json:"name"`
+	AppliedAt time.Time `gorm:"column:applied_at" json:"appliedAt"`
+}
+
+// Migration versioned migration sql
+type Migration struct {
+	Version string
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status migration status
+type Status struct {
+	Version   string
+	Name      string
+	IsApplied bool
+	AppliedAt time.Time
+}
+
+// Option set the migrator options.
+type Option func(*options)
+
+type options struct {
+	tableName string
+	fsys      fs.FS
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultOptions() *options {
+	return &options{
+		tableName: DefaultTableName,
+	}
+}
+
+// WithTableName set the table name for recording applied versions
+func WithTableName(name string) Option {
+	return func(o *options) {
+		if name != "" {
+			o.tableName = name
+		}
+	}
+}
+
+// WithFS read migration files from fs, e.g. embed.FS, the dir parameter is the path in fs
+func WithFS(fsys fs.FS) Option {
+	return func(o *options) {
+		o.fsys = fsys
+	}
+}
+
+// Migrator execute migrations
+type Migrator struct {
+	db        *gorm.DB
+	dir       string
+	fsys      fs.FS
+	tableName string
+}
+
+// New create a migrator, dir is the directory where the migration files are located
+func New(db *gorm.DB, dir string, opts ...Option) *Migrator {
+	o := defaultOptions()
+	o.apply(opts...)
+
+	fsys := o.fsys
+	if fsys == nil {
+		fsys = os.DirFS(dir)
+		dir = "."
+	}
+
+	return &Migrator{
+		db:        db,
+		dir:       dir,
+		fsys:      fsys,
+		tableName: o.tableName,
+	}
+}
+
+func (m *Migrator) table(ctx context.Context) *gorm.DB {
+	return m.db.WithContext(ctx).Table(m.tableName)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	err := m.db.WithContext(ctx).Table(m.tableName).AutoMigrate(&SchemaMigration{})
+	if err != nil {
+		return fmt.Errorf("create table %s error: %v", m.tableName, err)
+	}
+	return nil
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context) (map[string]*SchemaMigration, error) {
+	var records []*SchemaMigration
+	err := m.table(ctx).Order("version ASC").Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]*SchemaMigration, len(records))
+	for _, record := range records {
+		versions[record.Version] = record
+	}
+	return versions, nil
+}
+
+// Migrations read all migrations from the migration directory, sorted by version in ascending order
+func (m *Migrator) Migrations() ([]*Migration, error) {
+	entries, err := fs.ReadDir(m.fsys, m.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migration dir error: %v", err)
+	}
+
+	migrationMap := make(map[string]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := fileNameRegexp.FindStringSubmatch(entry.Name())
+		if len(matches) != 4 {
+			continue
+		}
+		data, err := fs.ReadFile(m.fsys, filepath.ToSlash(filepath.Join(m.dir, entry.Name())))
+		if err != nil {
+			return nil, err
+		}
+
+		version, name, direction := matches[1], matches[2], matches[3]
+		migration, ok := migrationMap[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			migrationMap[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("duplicate migration version %s, names: %s, %s", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(migrationMap))
+	for _, migration := range migrationMap {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up apply all pending migrations, return the applied versions
+func (m *Migrator) Up(ctx context.Context) ([]string, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err = m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := execSQL(tx, migration.Up); err != nil {
+				return err
+			}
+			return tx.Table(m.tableName).Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return versions, fmt.Errorf("apply migration %s_%s error: %v", migration.Version, migration.Name, err)
+		}
+		versions = append(versions, migration.Version)
+	}
+
+	return versions, nil
+}
+
+// Down roll back the latest applied migrations, steps is the number of migrations to roll back,
+// if steps <= 0, all applied migrations are rolled back, return the rolled back versions
+func (m *Migrator) Down(ctx context.Context, steps int) ([]string, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	migrationMap := make(map[string]*Migration, len(migrations))
+	for _, migration := range migrations {
+		migrationMap[migration.Version] = migration
+	}
+
+	var records []*SchemaMigration
+	query := m.table(ctx).Order("version DESC")
+	if steps > 0 {
+		query = query.Limit(steps)
+	}
+	if err = query.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, record := range records {
+		migration, ok := migrationMap[record.Version]
+		if !ok {
+			return versions, fmt.Errorf("migration file of version %s not found", record.Version)
+		}
+		err = m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := execSQL(tx, migration.Down); err != nil {
+				return err
+			}
+			return tx.Table(m.tableName).Where("version = ?", record.Version).Delete(&SchemaMigration{}).Error
+		})
+		if err != nil {
+			return versions, fmt.Errorf("roll back migration %s_%s error: %v", migration.Version, migration.Name, err)
+		}
+		versions = append(versions, migration.Version)
+	}
+
+	return versions, nil
+}
+
+// Status get the status of all migrations
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*Status, 0, len(migrations))
+	for _, migration := range migrations {
+		status := &Status{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+		if record, ok := applied[migration.Version]; ok {
+			status.IsApplied = true
+			status.AppliedAt = record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// NewVersion generate a migration version based on the current time
+func NewVersion() string {
+	return time.Now().Format(versionLayout)
+}
+
+// WriteFiles write the up and down migration sql to files in the directory, return the file paths
+func WriteFiles(dir string, version string, name string, up string, down string) (string, string, error) {
+	if version == "" {
+		version = NewVersion()
+	}
+	name = strings.ReplaceAll(strings.TrimSpace(name), " ", "_")
+	if name == "" {
+		return "", "", errors.New("migration name cannot be empty")
+	}
+
+	if err := os.MkdirAll(dir, 0766); err != nil {
+		return "", "", err
+	}
+
+	prefix := filepath.Join(dir, version+"_"+name)
+	upFile, downFile := prefix+upSuffix, prefix+downSuffix
+	if err := os.WriteFile(upFile, []byte(up), 0666); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(downFile, []byte(down), 0666); err != nil {
+		return "", "", err
+	}
+
+	return upFile, downFile, nil
+}
+
+func execSQL(db *gorm.DB, sql string) error {
+	for _, stmt := range splitStatements(sql) {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements split sql into statements by semicolon, ignoring semicolons in quotes and comments
+func splitStatements(sql string) []string {
+	var (
+		stmts   []string
+		builder strings.Builder
+		quote   rune
+	)
+
+	lines := strings.Split(sql, "\n")
+	for _, line := range lines {
+		if quote == 0 && strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		for _, r := range line {
+			switch {
+			case quote != 0:
+				if r == quote {
+					quote = 0
+				}
+			case r == '\'' || r == '"' || r == '`':
+				quote = r
+			case r == ';':
+				if stmt := strings.TrimSpace(builder.String()); stmt != "" {
+					stmts = append(stmts, stmt)
+				}
+				builder.Reset()
+				continue
+			}
+			builder.WriteRune(r)
+		}
+		builder.WriteByte('\n')
+	}
+	if stmt := strings.TrimSpace(builder.String()); stmt != "" {
+		stmts = append(stmts, stmt)
+	}
+
+	return stmts
+}
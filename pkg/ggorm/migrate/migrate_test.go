@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zhufuyi/sponge/pkg/ggorm"
+)
+
+func TestMigrator(t *testing.T) {
+	dir := filepath.Join(os.TempDir(), "sponge_migrations")
+	defer os.RemoveAll(dir)
+
+	_, _, err := WriteFiles(dir, "20230801120000", "create user",
+		"CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT);\nINSERT INTO user (name) VALUES ('foo;bar');",
+		"DROP TABLE user;")
+	assert.NoError(t, err)
+	_, _, err = WriteFiles(dir, "20230801120001", "add_user_age",
+		"-- add column\nALTER TABLE user ADD COLUMN age INTEGER;",
+		"ALTER TABLE user DROP COLUMN age;")
+	assert.NoError(t, err)
+
+	dbFile := filepath.Join(dir, "test_migrate.db")
+	db, err := ggorm.InitSqlite(dbFile)
+	if err != nil {
+		// ignore test error about not being able to connect to real sqlite
+		t.Logf("connect to sqlite failed, err=%v, dbFile=%s", err, dbFile)
+		return
+	}
+	defer ggorm.CloseDB(db)
+
+	ctx := context.Background()
+	m := New(db, dir)
+
+	versions, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"20230801120000", "20230801120001"}, versions)
+
+	versions, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+
+	statuses, err := m.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(statuses))
+	assert.True(t, statuses[1].IsApplied)
+
+	versions, err = m.Down(ctx, 1)
+	if err != nil {
+		// older sqlite versions do not support DROP COLUMN
+		t.Log(err)
+		return
+	}
+	assert.Equal(t, []string{"20230801120001"}, versions)
+
+	versions, err = m.Down(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"20230801120000"}, versions)
+}
+
+func TestWriteFiles(t *testing.T) {
+	_, _, err := WriteFiles(os.TempDir(), "", " ", "", "")
+	assert.Error(t, err)
+}
+
+func Test_splitStatements(t *testing.T) {
+	sql := "-- comment;\nCREATE TABLE `t` (`a` varchar(10) DEFAULT ';');\n\nALTER TABLE t ADD COLUMN b INT COMMENT 'x;y';\n"
+	stmts := splitStatements(sql)
+	assert.Equal(t, 2, len(stmts))
+	assert.Equal(t, "ALTER TABLE t ADD COLUMN b INT COMMENT 'x;y'", stmts[1])
+}
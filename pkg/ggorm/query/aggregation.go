@@ -0,0 +1,233 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// AggCount count of records, count all records when the column is empty or *
+	AggCount = "count"
+	// AggSum sum of the column values
+	AggSum = "sum"
+	// AggAvg average of the column values
+	AggAvg = "avg"
+	// AggMin minimum of the column values
+	AggMin = "min"
+	// AggMax maximum of the column values
+	AggMax = "max"
+)
+
+var aggFuncMap = map[string]string{
+	AggCount: "COUNT",
+	AggSum:   "SUM",
+	AggAvg:   "AVG",
+	AggMin:   "MIN",
+	AggMax:   "MAX",
+}
+
+var identifierRegexp = regexp.MustCompile(`^[a-zA-Z_]\w*$`)
+
+// Aggregate aggregate function of the column
+type Aggregate struct {
+	Func   string `json:"func" form:"func"`             // aggregate function, have count, sum, avg, min, max
+	Column string `json:"column" form:"column"`         // column name, count all records when func is count and column is empty
+	Alias  string `json:"alias,omitempty" form:"alias"` // name of the result, default is func_column, e.g. sum_age, count
+}
+
+// the name of the result
+func (a *Aggregate) name() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+	if a.Column == "" || a.Column == "*" {
+		return strings.ToLower(a.Func)
+	}
+	return strings.ToLower(a.Func) + "_" + a.Column
+}
+
+func (a *Aggregate) checkValid() error {
+	if _, ok := aggFuncMap[strings.ToLower(a.Func)]; !ok {
+		return fmt.Errorf("unknown aggregate function '%s'", a.Func)
+	}
+	if a.Column == "" || a.Column == "*" {
+		if strings.ToLower(a.Func) != AggCount {
+			return fmt.Errorf("the column of aggregate function '%s' cannot be empty", a.Func)
+		}
+	} else if !identifierRegexp.MatchString(a.Column) {
+		return fmt.Errorf("invalid aggregate column '%s'", a.Column)
+	}
+	if !identifierRegexp.MatchString(a.name()) {
+		return fmt.Errorf("invalid aggregate alias '%s'", a.name())
+	}
+	return nil
+}
+
+// the sql expression, e.g. COUNT(*), SUM(age)
+func (a *Aggregate) expr() string {
+	column := a.Column
+	if column == "" {
+		column = "*"
+	}
+	return aggFuncMap[strings.ToLower(a.Func)] + "(" + column + ")"
+}
+
+// Aggregation aggregate query parameters, e.g. count the records and average the age of each gender,
+// groupBy=["gender"], aggregates=[{"func":"count"},{"func":"avg","column":"age"}], having: count > 10, sort=-count
+type Aggregation struct {
+	Columns    []Column    `json:"columns,omitempty" form:"columns"`             // query conditions before grouping, not required
+	GroupBy    []string    `json:"groupBy,omitempty" form:"groupBy"`             // group by columns, not required
+	Aggregates []Aggregate `json:"aggregates" form:"aggregates" binding:"min=1"` // aggregate functions
+	Having     []Column    `json:"having,omitempty" form:"having"`               // conditions of aggregate results, the name is the alias of aggregate, not required
+	Sort       string      `json:"sort,omitempty" form:"sort"`                   // sort by group by columns or aliases, e.g. -count,gender, not required
+	Limit      int         `json:"limit,omitempty" form:"limit" binding:"gte=0"` // max number of results, 0 means no limit
+}
+
+// GormAggregation the gorm clauses converted from Aggregation
+type GormAggregation struct {
+	Select     string
+	Where      string
+	WhereArgs  []interface{}
+	Group      string
+	Having     string
+	HavingArgs []interface{}
+	Order      string
+	Limit      int
+}
+
+// names of the result columns, group by column names and aggregate aliases
+func (a *Aggregation) resultNames() map[string]bool {
+	names := map[string]bool{}
+	for _, name := range a.GroupBy {
+		names[name] = true
+	}
+	for _, agg := range a.Aggregates {
+		names[agg.name()] = true
+	}
+	return names
+}
+
+// CheckValid check valid
+func (a *Aggregation) CheckValid() error {
+	if len(a.Aggregates) == 0 {
+		return fmt.Errorf("field 'aggregates' cannot be empty")
+	}
+	if a.Limit < 0 {
+		return fmt.Errorf("field 'limit' cannot be negative")
+	}
+
+	names := map[string]bool{}
+	for _, name := range a.GroupBy {
+		if !identifierRegexp.MatchString(name) {
+			return fmt.Errorf("invalid group by column '%s'", name)
+		}
+		names[name] = true
+	}
+	for _, agg := range a.Aggregates {
+		if err := agg.checkValid(); err != nil {
+			return err
+		}
+		if names[agg.name()] {
+			return fmt.Errorf("duplicate aggregate alias '%s'", agg.name())
+		}
+		names[agg.name()] = true
+	}
+
+	if err := checkColumns(a.Columns); err != nil {
+		return err
+	}
+	if err := checkColumns(a.Having); err != nil {
+		return err
+	}
+	return a.checkSort()
+}
+
+func (a *Aggregation) checkSort() error {
+	if strings.Replace(a.Sort, " ", "", -1) == "" {
+		return nil
+	}
+	names := a.resultNames()
+	for _, column := range parseSortColumns(a.Sort) {
+		if !names[column.name] {
+			return fmt.Errorf("sort column '%s' is not a group by column or aggregate alias", column.name)
+		}
+	}
+	return nil
+}
+
+// ConvertToGorm conversion to gorm clauses, the having conditions are converted to the aggregate expressions,
+// e.g. db.Model(&model.User{}).Select(g.Select).Where(g.Where, g.WhereArgs...).Group(g.Group).Having(g.Having, g.HavingArgs...)
+func (a *Aggregation) ConvertToGorm(opts ...ConditionOption) (*GormAggregation, error) {
+	if err := a.CheckValid(); err != nil {
+		return nil, err
+	}
+
+	g := &GormAggregation{Group: strings.Join(a.GroupBy, ", "), Limit: a.Limit}
+
+	selects := append([]string{}, a.GroupBy...)
+	exprs := map[string]string{}
+	for _, agg := range a.Aggregates {
+		exprs[agg.name()] = agg.expr()
+		selects = append(selects, agg.expr()+" AS "+agg.name())
+	}
+	g.Select = strings.Join(selects, ", ")
+
+	var err error
+	p := &Params{Columns: a.Columns}
+	g.Where, g.WhereArgs, err = p.ConvertToGormConditions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// postgresql does not support alias in having, use the aggregate expressions instead
+	having, err := replaceColumnNames(a.Having, exprs)
+	if err != nil {
+		return nil, err
+	}
+	p = &Params{Columns: having}
+	g.Having, g.HavingArgs, err = p.ConvertToGormConditions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Replace(a.Sort, " ", "", -1) != "" {
+		var orders []string
+		for _, column := range parseSortColumns(a.Sort) {
+			if column.desc {
+				orders = append(orders, column.name+" DESC")
+			} else {
+				orders = append(orders, column.name+" ASC")
+			}
+		}
+		g.Order = strings.Join(orders, ", ")
+	}
+
+	return g, nil
+}
+
+// replace the column names with the values of names, return new columns
+func replaceColumnNames(columns []Column, names map[string]string) ([]Column, error) {
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	newColumns := make([]Column, 0, len(columns))
+	for _, column := range columns {
+		if column.isGroup() {
+			subColumns, err := replaceColumnNames(column.Columns, names)
+			if err != nil {
+				return nil, err
+			}
+			column.Columns = subColumns
+		} else {
+			name, ok := names[column.Name]
+			if !ok {
+				return nil, fmt.Errorf("having column '%s' is not an aggregate alias", column.Name)
+			}
+			column.Name = name
+		}
+		newColumns = append(newColumns, column)
+	}
+	return newColumns, nil
+}
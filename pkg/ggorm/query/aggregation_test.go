@@ -0,0 +1,83 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregation_ConvertToGorm(t *testing.T) {
+	a := &Aggregation{
+		Columns: []Column{{Name: "status", Value: 1}},
+		GroupBy: []string{"gender"},
+		Aggregates: []Aggregate{
+			{Func: "count"},
+			{Func: "avg", Column: "age"},
+			{Func: "max", Column: "age", Alias: "oldest"},
+		},
+		Having: []Column{
+			{Name: "count", Exp: ">", Value: 10, Logic: "||"},
+			{Columns: []Column{
+				{Name: "avg_age", Exp: "gte", Value: 18},
+				{Name: "oldest", Exp: "lt", Value: 60},
+			}},
+		},
+		Sort:  "-count, gender",
+		Limit: 10,
+	}
+	g, err := a.ConvertToGorm()
+	assert.NoError(t, err)
+	assert.Equal(t, &GormAggregation{
+		Select:     "gender, COUNT(*) AS count, AVG(age) AS avg_age, MAX(age) AS oldest",
+		Where:      "status = ?",
+		WhereArgs:  []interface{}{1},
+		Group:      "gender",
+		Having:     "COUNT(*) > ? OR (AVG(age) >= ? AND MAX(age) < ?)",
+		HavingArgs: []interface{}{10, 18, 60},
+		Order:      "count DESC, gender ASC",
+		Limit:      10,
+	}, g)
+	// the input is not modified
+	assert.Equal(t, "count", a.Having[0].Name)
+
+	// without group by
+	a = &Aggregation{Aggregates: []Aggregate{{Func: "SUM", Column: "age"}}}
+	g, err = a.ConvertToGorm()
+	assert.NoError(t, err)
+	assert.Equal(t, "SUM(age) AS sum_age", g.Select)
+	assert.Empty(t, g.Where)
+	assert.Empty(t, g.Group)
+	assert.Empty(t, g.Having)
+	assert.Empty(t, g.Order)
+}
+
+func TestAggregation_CheckValid(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *Aggregation
+	}{
+		{"empty aggregates", &Aggregation{}},
+		{"negative limit", &Aggregation{Aggregates: []Aggregate{{Func: "count"}}, Limit: -1}},
+		{"unknown func", &Aggregation{Aggregates: []Aggregate{{Func: "median", Column: "age"}}}},
+		{"empty column", &Aggregation{Aggregates: []Aggregate{{Func: "sum"}}}},
+		{"invalid column", &Aggregation{Aggregates: []Aggregate{{Func: "sum", Column: "age); drop table t"}}}},
+		{"invalid alias", &Aggregation{Aggregates: []Aggregate{{Func: "count", Alias: "a b"}}}},
+		{"invalid group by", &Aggregation{GroupBy: []string{"1=1"}, Aggregates: []Aggregate{{Func: "count"}}}},
+		{"duplicate alias", &Aggregation{GroupBy: []string{"age"}, Aggregates: []Aggregate{{Func: "count", Alias: "age"}}}},
+		{"invalid sort", &Aggregation{Aggregates: []Aggregate{{Func: "count"}}, Sort: "age"}},
+		{"invalid columns", &Aggregation{Aggregates: []Aggregate{{Func: "count"}}, Columns: []Column{{Name: "age"}}}},
+		{"invalid having", &Aggregation{Aggregates: []Aggregate{{Func: "count"}}, Having: []Column{{Name: "count", Exp: "unknown", Value: 1}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.a.CheckValid())
+			_, err := tt.a.ConvertToGorm()
+			assert.Error(t, err)
+		})
+	}
+
+	// having column is not an alias
+	a := &Aggregation{Aggregates: []Aggregate{{Func: "count"}}, Having: []Column{{Name: "age", Value: 1}}}
+	_, err := a.ConvertToGorm()
+	assert.Error(t, err)
+}
@@ -0,0 +1,303 @@
+package query
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huandu/xstrings"
+)
+
+// keyset pagination, the next page is queried by the values of the sort columns of the last record of current page,
+// the values are encoded into an opaque cursor, the client passes the cursor back as is to get the next page.
+// Note: the combination of sort columns must be unique, e.g. -created_at,-id, otherwise records with the same values may be skipped.
+
+const (
+	valueTypeInt    = "i"
+	valueTypeUint   = "u"
+	valueTypeFloat  = "f"
+	valueTypeBool   = "b"
+	valueTypeString = "s"
+	valueTypeTime   = "t"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v"`
+}
+
+type cursorInfo struct {
+	Sort   string        `json:"s"`
+	Values []cursorValue `json:"v"`
+}
+
+type sortColumn struct {
+	name string
+	desc bool
+}
+
+// parse sort columns, the default is id descending
+func parseSortColumns(columnNames string) []sortColumn {
+	columnNames = strings.Replace(columnNames, " ", "", -1)
+	if columnNames == "" {
+		return []sortColumn{{name: "id", desc: true}}
+	}
+
+	var columns []sortColumn
+	for _, name := range strings.Split(columnNames, ",") {
+		if name == "" || name == "-" {
+			continue
+		}
+		if name[0] == '-' {
+			columns = append(columns, sortColumn{name: name[1:], desc: true})
+		} else {
+			columns = append(columns, sortColumn{name: name})
+		}
+	}
+	return columns
+}
+
+// EncodeCursor encode the values of the sort columns into a cursor, the values must be in the same order as the sort columns
+func EncodeCursor(sort string, values ...interface{}) (string, error) {
+	columns := parseSortColumns(sort)
+	if len(columns) != len(values) {
+		return "", fmt.Errorf("the number of values %d does not match the number of sort columns %d", len(values), len(columns))
+	}
+
+	info := cursorInfo{Sort: strings.Replace(sort, " ", "", -1)}
+	for i, value := range values {
+		cv, err := toCursorValue(value)
+		if err != nil {
+			return "", fmt.Errorf("column '%s' %v", columns[i].name, err)
+		}
+		info.Values = append(info.Values, cv)
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor decode the cursor into the values of the sort columns, the sort must be the same as the sort when encoding
+func DecodeCursor(sort string, cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	info := cursorInfo{}
+	if err = json.Unmarshal(data, &info); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	if info.Sort != strings.Replace(sort, " ", "", -1) {
+		return nil, fmt.Errorf("cursor does not match the sort '%s'", sort)
+	}
+	if len(info.Values) != len(parseSortColumns(sort)) {
+		return nil, errors.New("invalid cursor")
+	}
+
+	values := make([]interface{}, 0, len(info.Values))
+	for _, cv := range info.Values {
+		value, err := fromCursorValue(cv)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func toCursorValue(value interface{}) (cursorValue, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return cursorValue{}, err
+		}
+		value = v
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return cursorValue{}, errors.New("value is null, cannot be used as cursor")
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cursorValue{Type: valueTypeInt, Value: strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cursorValue{Type: valueTypeUint, Value: strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return cursorValue{Type: valueTypeFloat, Value: strconv.FormatFloat(rv.Float(), 'g', -1, 64)}, nil
+	case reflect.Bool:
+		return cursorValue{Type: valueTypeBool, Value: strconv.FormatBool(rv.Bool())}, nil
+	case reflect.String:
+		return cursorValue{Type: valueTypeString, Value: rv.String()}, nil
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return cursorValue{Type: valueTypeTime, Value: rv.Interface().(time.Time).Format(time.RFC3339Nano)}, nil
+		}
+	case reflect.Invalid:
+		return cursorValue{}, errors.New("value is null, cannot be used as cursor")
+	}
+
+	return cursorValue{}, fmt.Errorf("unsupported value type %s", rv.Type())
+}
+
+func fromCursorValue(cv cursorValue) (interface{}, error) {
+	var (
+		value interface{}
+		err   error
+	)
+	switch cv.Type {
+	case valueTypeInt:
+		value, err = strconv.ParseInt(cv.Value, 10, 64)
+	case valueTypeUint:
+		value, err = strconv.ParseUint(cv.Value, 10, 64)
+	case valueTypeFloat:
+		value, err = strconv.ParseFloat(cv.Value, 64)
+	case valueTypeBool:
+		value, err = strconv.ParseBool(cv.Value)
+	case valueTypeString:
+		value = cv.Value
+	case valueTypeTime:
+		value, err = time.Parse(time.RFC3339Nano, cv.Value)
+	default:
+		err = fmt.Errorf("unknown type '%s'", cv.Type)
+	}
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return value, nil
+}
+
+// get the values of the columns from the record, record is a struct or map, the column name of a struct field is
+// the column name in gorm tag, if not set, it is the snake case of the field name.
+func getColumnValues(record interface{}, columns []sortColumn) ([]interface{}, error) {
+	rv := reflect.ValueOf(record)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("record is nil")
+		}
+		rv = rv.Elem()
+	}
+
+	fields := map[string]interface{}{}
+	switch rv.Kind() {
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			fields[fmt.Sprintf("%v", iter.Key().Interface())] = iter.Value().Interface()
+		}
+	case reflect.Struct:
+		getStructFields(rv, fields)
+	default:
+		return nil, fmt.Errorf("unsupported record type %s", rv.Type())
+	}
+
+	values := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		value, ok := fields[column.name]
+		if !ok {
+			return nil, fmt.Errorf("column '%s' not found in record", column.name)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func getStructFields(rv reflect.Value, fields map[string]interface{}) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("gorm")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous || strings.Contains(tag, "embedded") {
+			fv := rv.Field(i)
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+				getStructFields(fv, fields)
+				continue
+			}
+		}
+
+		name := xstrings.ToSnakeCase(field.Name)
+		for _, s := range strings.Split(tag, ";") {
+			if strings.HasPrefix(strings.ToLower(s), "column:") {
+				name = s[len("column:"):]
+				break
+			}
+		}
+		fields[name] = rv.Field(i).Interface()
+	}
+}
+
+// ConvertToCursorConditions converted to the gorm conditions of keyset pagination based on the sort and cursor parameters,
+// if the cursor is empty, the returned conditions are empty.
+// e.g. sort=-created_at,-id, conditions: (created_at < ? OR (created_at = ? AND id < ?))
+func (p *Params) ConvertToCursorConditions() (string, []interface{}, error) {
+	if p.Cursor == "" {
+		return "", nil, nil
+	}
+
+	values, err := DecodeCursor(p.Sort, p.Cursor)
+	if err != nil {
+		return "", nil, err
+	}
+	columns := parseSortColumns(p.Sort)
+
+	ors := make([]string, 0, len(columns))
+	args := []interface{}{}
+	for i, column := range columns {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, columns[j].name+" = ?")
+			args = append(args, values[j])
+		}
+		if column.desc {
+			ands = append(ands, column.name+" < ?")
+		} else {
+			ands = append(ands, column.name+" > ?")
+		}
+		args = append(args, values[i])
+
+		if len(ands) == 1 {
+			ors = append(ors, ands[0])
+		} else {
+			ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		}
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args, nil
+}
+
+// NextCursor get the cursor of the next page from the last record of current page, record is a struct or map,
+// return empty if record is nil.
+func (p *Params) NextCursor(record interface{}) (string, error) {
+	if record == nil {
+		return "", nil
+	}
+	values, err := getColumnValues(record, parseSortColumns(p.Sort))
+	if err != nil {
+		return "", err
+	}
+	return EncodeCursor(p.Sort, values...)
+}
@@ -0,0 +1,81 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cursorModel struct {
+	Model `gorm:"embedded"`
+	Name  string `gorm:"column:name"`
+	Age   *int
+}
+
+type Model struct {
+	ID        uint64    `gorm:"column:id;AUTO_INCREMENT;primary_key"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func TestCursor(t *testing.T) {
+	now := time.Now()
+	cursor, err := EncodeCursor("-created_at, -id", now, uint64(10))
+	assert.NoError(t, err)
+
+	values, err := DecodeCursor("-created_at,-id", cursor)
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(values[0].(time.Time)))
+	assert.Equal(t, uint64(10), values[1])
+
+	_, err = DecodeCursor("-id", cursor)
+	assert.Error(t, err)
+	_, err = DecodeCursor("-created_at,-id", "abc")
+	assert.Error(t, err)
+	_, err = EncodeCursor("-created_at,-id", now)
+	assert.Error(t, err)
+	_, err = EncodeCursor("name", []int{1})
+	assert.Error(t, err)
+}
+
+func TestParams_NextCursor(t *testing.T) {
+	age := 18
+	record := &cursorModel{Model: Model{ID: 3, CreatedAt: time.Now()}, Name: "foo", Age: &age}
+
+	p := &Params{Size: 10, Sort: "name,-age,-id"}
+	cursor, err := p.NextCursor(record)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cursor)
+
+	p.Cursor = cursor
+	str, args, err := p.ConvertToCursorConditions()
+	assert.NoError(t, err)
+	assert.Equal(t, "(name > ? OR (name = ? AND age < ?) OR (name = ? AND age = ? AND id < ?))", str)
+	assert.Equal(t, []interface{}{"foo", "foo", int64(18), "foo", int64(18), uint64(3)}, args)
+	_, _, offset := p.ConvertToPage()
+	assert.Equal(t, 0, offset)
+
+	// default sort
+	p = &Params{}
+	cursor, err = p.NextCursor(map[string]interface{}{"id": 5})
+	assert.NoError(t, err)
+	p.Cursor = cursor
+	str, args, err = p.ConvertToCursorConditions()
+	assert.NoError(t, err)
+	assert.Equal(t, "(id < ?)", str)
+	assert.Equal(t, []interface{}{int64(5)}, args)
+
+	// error
+	p = &Params{Sort: "unknown"}
+	_, err = p.NextCursor(record)
+	assert.Error(t, err)
+	p = &Params{Sort: "age"}
+	_, err = p.NextCursor(&cursorModel{})
+	assert.Error(t, err)
+	cursor, err = p.NextCursor(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, cursor)
+	p = &Params{Sort: "-id", Cursor: "abc"}
+	_, _, err = p.ConvertToCursorConditions()
+	assert.Error(t, err)
+}
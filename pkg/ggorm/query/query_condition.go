@@ -3,6 +3,7 @@ package query
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -23,11 +24,26 @@ const (
 	Like = "like"
 	// In include
 	In = "in"
+	// NotIn not include
+	NotIn = "notin"
+	// Between between two values, including the two values
+	Between = "between"
+	// IsNull is null, the value is ignored
+	IsNull = "isnull"
+	// NotNull is not null, the value is ignored
+	NotNull = "notnull"
+	// PrefixLike fuzzy lookup by prefix
+	PrefixLike = "prefix"
+	// SuffixLike fuzzy lookup by suffix
+	SuffixLike = "suffix"
 
 	// AND logic and
 	AND string = "and"
 	// OR logic or
 	OR string = "or"
+
+	// JSONPathSep separator of the json column name and the path, e.g. attrs->color, attrs->size.width
+	JSONPathSep = "->"
 )
 
 var expMap = map[string]string{
@@ -40,6 +56,13 @@ var expMap = map[string]string{
 	Like: " LIKE ",
 	In:   " IN ",
 
+	NotIn:      " NOT IN ",
+	Between:    " BETWEEN ",
+	IsNull:     " IS NULL",
+	NotNull:    " IS NOT NULL",
+	PrefixLike: " LIKE ",
+	SuffixLike: " LIKE ",
+
 	"=":  " = ",
 	"!=": " <> ",
 	">":  " > ",
@@ -48,6 +71,29 @@ var expMap = map[string]string{
 	"<=": " <= ",
 }
 
+var jsonKeyRegexp = regexp.MustCompile(`^\w+$`)
+
+// ConditionOption set the options of converting conditions.
+type ConditionOption func(*conditionOptions)
+
+type conditionOptions struct {
+	dbDriver string
+}
+
+func (o *conditionOptions) apply(opts ...ConditionOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithDBDriver set the database driver, it determines the sql of the json path, default is mysql,
+// supported mysql, tidb, postgresql(postgres), sqlite, e.g. WithDBDriver(db.Dialector.Name())
+func WithDBDriver(driver string) ConditionOption {
+	return func(o *conditionOptions) {
+		o.dbDriver = strings.ToLower(driver)
+	}
+}
+
 var logicMap = map[string]string{
 	AND: " AND ",
 	OR:  " OR ",
@@ -66,38 +112,71 @@ type Params struct {
 	Size int    `json:"size" form:"size" binding:"gt=0"`
 	Sort string `json:"sort,omitempty" form:"sort" binding:""`
 
+	// cursor of keyset pagination, returned by the previous page, if not empty, the page is ignored
+	Cursor string `json:"cursor,omitempty" form:"cursor" binding:""`
+
 	Columns []Column `json:"columns,omitempty" form:"columns"` // not required
 }
 
 // Column query info
 type Column struct {
-	Name  string      `json:"name" form:"name"`   // column name
-	Exp   string      `json:"exp" form:"exp"`     // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like, in
+	Name  string      `json:"name" form:"name"`   // column name, the json column supports path, e.g. attrs->size.width
+	Exp   string      `json:"exp" form:"exp"`     // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like, in, notin, between, isnull, notnull, prefix, suffix
 	Value interface{} `json:"value" form:"value"` // column value
 	Logic string      `json:"logic" form:"logic"` // logical type, defaults to and when the value is null, with &(and), ||(or)
+
+	// sub conditions in parentheses, if not empty, the name, exp and value are ignored, e.g. (a=1 OR b=2) AND c>3
+	Columns []Column `json:"columns,omitempty" form:"columns"`
+}
+
+func (c *Column) isGroup() bool {
+	return len(c.Columns) > 0
 }
 
 func (c *Column) checkValid() error {
+	if c.isGroup() {
+		for _, column := range c.Columns {
+			if err := column.checkValid(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	if c.Name == "" {
 		return fmt.Errorf("field 'name' cannot be empty")
 	}
 	if c.Value == nil {
-		return fmt.Errorf("field 'value' cannot be nil")
+		if exp := strings.ToLower(c.Exp); exp != IsNull && exp != NotNull {
+			return fmt.Errorf("field 'value' cannot be nil")
+		}
 	}
 	return nil
 }
 
 // converting ExpType to sql expressions and LogicType to sql using characters
 func (c *Column) convert() error {
+	if c.isGroup() {
+		return c.convertLogic()
+	}
+
 	if c.Exp == "" {
 		c.Exp = Eq
 	}
-	if v, ok := expMap[strings.ToLower(c.Exp)]; ok { //nolint
+	exp := strings.ToLower(c.Exp)
+	if v, ok := expMap[exp]; ok { //nolint
 		c.Exp = v
-		if c.Exp == " LIKE " {
+		switch exp {
+		case Like:
 			c.Value = fmt.Sprintf("%%%v%%", c.Value)
-		}
-		if c.Exp == " IN " {
+		case PrefixLike:
+			c.Value = fmt.Sprintf("%v%%", c.Value)
+		case SuffixLike:
+			c.Value = fmt.Sprintf("%%%v", c.Value)
+		case In, NotIn:
+			if _, ok := c.Value.([]interface{}); ok {
+				break
+			}
 			val, ok := c.Value.(string)
 			if !ok {
 				return fmt.Errorf("invalid value type '%s'", c.Value)
@@ -108,11 +187,21 @@ func (c *Column) convert() error {
 				iVal = append(iVal, s)
 			}
 			c.Value = iVal
+		case Between:
+			values, err := toBetweenValues(c.Value)
+			if err != nil {
+				return err
+			}
+			c.Value = values
 		}
 	} else {
 		return fmt.Errorf("unknown exp type '%s'", c.Exp)
 	}
 
+	return c.convertLogic()
+}
+
+func (c *Column) convertLogic() error {
 	if c.Logic == "" {
 		c.Logic = AND
 	}
@@ -125,18 +214,96 @@ func (c *Column) convert() error {
 	return nil
 }
 
-// ConvertToPage converted to conform to gorm rules based on the page size sort parameter
+// the sql condition of the converted column, e.g. age > ?, (name = ? OR age > ?)
+func (c *Column) condition(o *conditionOptions) (string, []interface{}, error) {
+	if c.isGroup() {
+		p := &Params{Columns: c.Columns}
+		str, args, err := p.convertToGormConditions(o)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + str + ")", args, nil
+	}
+
+	name, args, err := columnExpr(c.Name, o.dbDriver)
+	if err != nil {
+		return "", nil, err
+	}
+	switch c.Exp {
+	case expMap[IsNull], expMap[NotNull]:
+		return name + c.Exp, args, nil
+	case expMap[In], expMap[NotIn]:
+		return name + c.Exp + "(?)", append(args, c.Value), nil
+	case expMap[Between]:
+		return name + c.Exp + "? AND ?", append(args, c.Value.([]interface{})...), nil
+	}
+	return name + c.Exp + "?", append(args, c.Value), nil
+}
+
+// the column expression, if the name contains json path, convert to the json extraction expression of the db driver,
+// e.g. attrs->size.width --> JSON_UNQUOTE(JSON_EXTRACT(attrs, '$.size.width'))
+func columnExpr(name string, dbDriver string) (string, []interface{}, error) {
+	ss := strings.SplitN(name, JSONPathSep, 2)
+	if len(ss) == 1 {
+		return name, nil, nil
+	}
+
+	column, path := ss[0], ss[1]
+	keys := strings.Split(path, ".")
+	for _, key := range append([]string{column}, keys...) {
+		if !jsonKeyRegexp.MatchString(key) {
+			return "", nil, fmt.Errorf("invalid json path '%s'", name)
+		}
+	}
+
+	switch dbDriver {
+	case "postgresql", "postgres":
+		return column + " #>> ?", []interface{}{"{" + strings.Join(keys, ",") + "}"}, nil
+	case "sqlite":
+		return "JSON_EXTRACT(" + column + ", ?)", []interface{}{"$." + path}, nil
+	}
+	return "JSON_UNQUOTE(JSON_EXTRACT(" + column + ", ?))", []interface{}{"$." + path}, nil
+}
+
+// the value of between is two values separated by comma or an array of two values
+func toBetweenValues(value interface{}) ([]interface{}, error) {
+	var values []interface{}
+	switch v := value.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			values = append(values, s)
+		}
+	case []interface{}:
+		values = v
+	}
+	if len(values) != 2 {
+		return nil, fmt.Errorf("invalid value '%v' of between, must be two values", value)
+	}
+	return values, nil
+}
+
+// ConvertToPage converted to conform to gorm rules based on the page size sort parameter,
+// if the cursor is not empty, the offset is 0, use it with ConvertToCursorConditions.
 func (p *Params) ConvertToPage() (order string, limit int, offset int) { //nolint
 	page := NewPage(p.Page, p.Size, p.Sort)
 	order = page.sort
 	limit = page.size
 	offset = page.page * page.size
+	if p.Cursor != "" {
+		offset = 0
+	}
 	return //nolint
 }
 
 // ConvertToGormConditions conversion to gorm-compliant parameters based on the Columns parameter
 // ignore the logical type of the last column, whether it is a one-column or multi-column query
-func (p *Params) ConvertToGormConditions() (string, []interface{}, error) {
+func (p *Params) ConvertToGormConditions(opts ...ConditionOption) (string, []interface{}, error) {
+	o := &conditionOptions{}
+	o.apply(opts...)
+	return p.convertToGormConditions(o)
+}
+
+func (p *Params) convertToGormConditions(o *conditionOptions) (string, []interface{}, error) {
 	str := ""
 	args := []interface{}{}
 	l := len(p.Columns)
@@ -160,20 +327,20 @@ func (p *Params) ConvertToGormConditions() (string, []interface{}, error) {
 			return "", nil, err
 		}
 
-		symbol := "?"
-		if column.Exp == " IN " {
-			symbol = "(?)"
+		condition, values, err := column.condition(o)
+		if err != nil {
+			return "", nil, err
 		}
 		if i == l-1 { // ignore the logical type of the last column
-			str += column.Name + column.Exp + symbol
+			str += condition
 		} else {
-			str += column.Name + column.Exp + symbol + column.Logic
+			str += condition + column.Logic
 		}
-		args = append(args, column.Value)
+		args = append(args, values...)
 
 		// when multiple columns are the same, determine whether the use of IN
 		if isUseIN {
-			if field != column.Name {
+			if field != column.Name || column.isGroup() || strings.Contains(column.Name, JSONPathSep) {
 				isUseIN = false
 				continue
 			}
@@ -202,12 +369,20 @@ func (c *Conditions) CheckValid() error {
 		return fmt.Errorf("field 'columns' cannot be empty")
 	}
 
-	for _, column := range c.Columns {
+	return checkColumns(c.Columns)
+}
+
+func checkColumns(columns []Column) error {
+	for _, column := range columns {
 		err := column.checkValid()
 		if err != nil {
 			return err
 		}
-		if column.Exp != "" {
+		if column.isGroup() {
+			if err = checkColumns(column.Columns); err != nil {
+				return err
+			}
+		} else if column.Exp != "" {
 			if _, ok := expMap[column.Exp]; !ok {
 				return fmt.Errorf("unknown exp type '%s'", column.Exp)
 			}
@@ -224,7 +399,7 @@ func (c *Conditions) CheckValid() error {
 
 // ConvertToGorm conversion to gorm-compliant parameters based on the Columns parameter
 // ignore the logical type of the last column, whether it is a one-column or multi-column query
-func (c *Conditions) ConvertToGorm() (string, []interface{}, error) {
+func (c *Conditions) ConvertToGorm(opts ...ConditionOption) (string, []interface{}, error) {
 	p := &Params{Columns: c.Columns}
-	return p.ConvertToGormConditions()
+	return p.ConvertToGormConditions(opts...)
 }
@@ -394,7 +394,194 @@ func TestParams_ConvertToGormConditions(t *testing.T) {
 			wantErr: false,
 		},
 
+		// ------------------------------ operators ------------------------------------------
+		{
+			name: "notin and between",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Value: "LiSi,ZhangSan",
+						Exp:   NotIn,
+					},
+					{
+						Name:  "age",
+						Value: []interface{}{10, 20},
+						Exp:   Between,
+					},
+				},
+			},
+			want:    "name NOT IN (?) AND age BETWEEN ? AND ?",
+			want1:   []interface{}{[]interface{}{"LiSi", "ZhangSan"}, 10, 20},
+			wantErr: false,
+		},
+		{
+			name: "isnull or notnull",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "deleted_at",
+						Exp:   IsNull,
+						Logic: OR,
+					},
+					{
+						Name:  "email",
+						Exp:   NotNull,
+						Value: "ignored",
+					},
+				},
+			},
+			want:    "deleted_at IS NULL OR email IS NOT NULL",
+			want1:   []interface{}{},
+			wantErr: false,
+		},
+		{
+			name: "prefix and suffix",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Value: "Zhang",
+						Exp:   PrefixLike,
+					},
+					{
+						Name:  "email",
+						Value: "@foo.com",
+						Exp:   SuffixLike,
+					},
+				},
+			},
+			want:    "name LIKE ? AND email LIKE ?",
+			want1:   []interface{}{"Zhang%", "%@foo.com"},
+			wantErr: false,
+		},
+		{
+			name: "json path",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "attrs->size.width",
+						Value: 10,
+						Exp:   Gt,
+					},
+				},
+			},
+			want:    "JSON_UNQUOTE(JSON_EXTRACT(attrs, ?)) > ?",
+			want1:   []interface{}{"$.size.width", 10},
+			wantErr: false,
+		},
+
+		// ------------------------------ group ----------------------------------------------
+		{
+			name: "(a or b) and c",
+			args: args{
+				columns: []Column{
+					{
+						Columns: []Column{
+							{
+								Name:  "name",
+								Value: "LiSi",
+								Logic: OR,
+							},
+							{
+								Name:  "name",
+								Value: "ZhangSan",
+							},
+						},
+					},
+					{
+						Name:  "age",
+						Value: 20,
+						Exp:   Gt,
+					},
+				},
+			},
+			want:    "(name IN (?)) AND age > ?",
+			want1:   []interface{}{[]interface{}{"LiSi", "ZhangSan"}, 20},
+			wantErr: false,
+		},
+		{
+			name: "a or (b and (c or d))",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "gender",
+						Value: "female",
+						Logic: OR,
+					},
+					{
+						Columns: []Column{
+							{
+								Name:  "age",
+								Value: 20,
+								Exp:   Gt,
+							},
+							{
+								Columns: []Column{
+									{
+										Name:  "name",
+										Value: "Li",
+										Exp:   PrefixLike,
+										Logic: OR,
+									},
+									{
+										Name: "email",
+										Exp:  IsNull,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			want:    "gender = ? OR (age > ? AND (name LIKE ? OR email IS NULL))",
+			want1:   []interface{}{"female", 20, "Li%"},
+			wantErr: false,
+		},
+
 		// ---------------------------- error ----------------------------------------------
+		{
+			name: "between value err",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Value: "10",
+						Exp:   Between,
+					},
+				},
+			},
+			want:    "",
+			want1:   nil,
+			wantErr: true,
+		},
+		{
+			name: "json path err",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "attrs->size' OR 1=1",
+						Value: 1,
+					},
+				},
+			},
+			want:    "",
+			want1:   nil,
+			wantErr: true,
+		},
+		{
+			name: "group err",
+			args: args{
+				columns: []Column{
+					{
+						Columns: []Column{{Name: "age"}},
+					},
+				},
+			},
+			want:    "",
+			want1:   nil,
+			wantErr: true,
+		},
 		{
 			name: "exp type err",
 			args: args{
@@ -457,3 +644,40 @@ func TestParams_ConvertToGormConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestColumnExpr(t *testing.T) {
+	name, args, err := columnExpr("attrs->size.width", "postgresql")
+	if err != nil || name != "attrs #>> ?" || args[0] != "{size,width}" {
+		t.Errorf("columnExpr() got = %v, %v, %v", name, args, err)
+	}
+
+	name, args, err = columnExpr("attrs->color", "sqlite")
+	if err != nil || name != "JSON_EXTRACT(attrs, ?)" || args[0] != "$.color" {
+		t.Errorf("columnExpr() got = %v, %v, %v", name, args, err)
+	}
+
+	params := &Params{Columns: []Column{{Name: "attrs->color", Value: "red"}}}
+	str, args, err := params.ConvertToGormConditions(WithDBDriver("postgres"))
+	if err != nil || str != "attrs #>> ? = ?" || args[0] != "{color}" {
+		t.Errorf("ConvertToGormConditions() got = %v, %v, %v", str, args, err)
+	}
+	c := &Conditions{Columns: params.Columns}
+	str, _, err = c.ConvertToGorm()
+	if err != nil || str != "JSON_UNQUOTE(JSON_EXTRACT(attrs, ?)) = ?" {
+		t.Errorf("ConvertToGorm() got = %v, %v", str, err)
+	}
+}
+
+func TestConditions_CheckValid(t *testing.T) {
+	c := &Conditions{Columns: []Column{
+		{Columns: []Column{{Name: "name", Value: "foo", Exp: PrefixLike}, {Name: "age", Exp: IsNull}}},
+	}}
+	if err := c.CheckValid(); err != nil {
+		t.Error(err)
+	}
+
+	c = &Conditions{Columns: []Column{{Columns: []Column{{Name: "name", Value: "foo", Exp: "unknown"}}}}}
+	if err := c.CheckValid(); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,354 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// value types of the columns in Validator
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeUint   = "uint"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+	TypeTime   = "time"
+	TypeAny    = "any" // the value is not converted
+)
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// FieldError error of the field in query parameters
+type FieldError struct {
+	Field string // e.g. sort, columns[0].name, columns[1].columns[0].value
+	Msg   string
+}
+
+// Error return error message
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+// ValidatorOption set the validator options.
+type ValidatorOption func(*validatorOptions)
+
+type validatorOptions struct {
+	sortColumns []string
+}
+
+func (o *validatorOptions) apply(opts ...ValidatorOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithSortColumns set the columns that can be sorted, default is all columns of the validator
+func WithSortColumns(names ...string) ValidatorOption {
+	return func(o *validatorOptions) {
+		o.sortColumns = names
+	}
+}
+
+// Validator restrict the columns that can be used in query conditions and sort,
+// and convert the values of query conditions to the types of columns.
+type Validator struct {
+	columns     map[string]string // column name --> value type
+	sortColumns map[string]bool
+}
+
+// NewValidator create a validator, columns is a map of column name and value type, e.g. {"id": TypeUint, "name": TypeString}
+func NewValidator(columns map[string]string, opts ...ValidatorOption) *Validator {
+	o := &validatorOptions{}
+	o.apply(opts...)
+
+	v := &Validator{columns: columns, sortColumns: map[string]bool{}}
+	if len(o.sortColumns) == 0 {
+		for name := range columns {
+			v.sortColumns[name] = true
+		}
+	} else {
+		for _, name := range o.sortColumns {
+			v.sortColumns[name] = true
+		}
+	}
+	return v
+}
+
+// Validate check the sort and query conditions of params, the values of conditions are converted to the types of columns
+func (v *Validator) Validate(p *Params) error {
+	if err := v.ValidateSort(p.Sort); err != nil {
+		return err
+	}
+	return v.ValidateColumns(p.Columns)
+}
+
+// ValidateSort check whether the sort columns are allowed, empty sort means the default sort
+func (v *Validator) ValidateSort(sort string) error {
+	if strings.Replace(sort, " ", "", -1) == "" {
+		return nil
+	}
+	for _, column := range parseSortColumns(sort) {
+		if !v.sortColumns[column.name] {
+			return &FieldError{Field: "sort", Msg: fmt.Sprintf("column '%s' is not allowed to sort", column.name)}
+		}
+	}
+	return nil
+}
+
+// ValidateColumns check the query conditions, the values of conditions are converted to the types of columns
+func (v *Validator) ValidateColumns(columns []Column) error {
+	return v.validateColumns("columns", columns)
+}
+
+// ValidateAggregation check whether the group by columns, aggregate columns and query conditions are allowed,
+// the values of query conditions are converted to the types of columns
+func (v *Validator) ValidateAggregation(a *Aggregation) error {
+	for i, name := range a.GroupBy {
+		if _, ok := v.columns[name]; !ok {
+			return &FieldError{Field: fmt.Sprintf("groupBy[%d]", i), Msg: fmt.Sprintf("column '%s' is not allowed", name)}
+		}
+	}
+	for i, agg := range a.Aggregates {
+		if agg.Column == "" || agg.Column == "*" {
+			continue
+		}
+		if _, ok := v.columns[agg.Column]; !ok {
+			return &FieldError{Field: fmt.Sprintf("aggregates[%d].column", i), Msg: fmt.Sprintf("column '%s' is not allowed", agg.Column)}
+		}
+	}
+	return v.ValidateColumns(a.Columns)
+}
+
+func (v *Validator) validateColumns(field string, columns []Column) error {
+	for i := range columns {
+		if err := v.validateColumn(fmt.Sprintf("%s[%d]", field, i), &columns[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validateColumn(field string, c *Column) error {
+	if c.Logic != "" {
+		if _, ok := logicMap[strings.ToLower(c.Logic)]; !ok {
+			return &FieldError{Field: field + ".logic", Msg: fmt.Sprintf("unknown logic type '%s'", c.Logic)}
+		}
+	}
+	if c.isGroup() {
+		return v.validateColumns(field+".columns", c.Columns)
+	}
+
+	name := strings.SplitN(c.Name, JSONPathSep, 2)[0]
+	typ, ok := v.columns[name]
+	if !ok {
+		return &FieldError{Field: field + ".name", Msg: fmt.Sprintf("column '%s' is not allowed", c.Name)}
+	}
+	if name != c.Name { // the value of json path is not converted
+		typ = TypeAny
+	}
+
+	exp := strings.ToLower(c.Exp)
+	if exp == "" {
+		exp = Eq
+	}
+	if _, ok = expMap[exp]; !ok {
+		return &FieldError{Field: field + ".exp", Msg: fmt.Sprintf("unknown exp type '%s'", c.Exp)}
+	}
+
+	var err error
+	switch exp {
+	case IsNull, NotNull:
+		return nil
+	case Like, PrefixLike, SuffixLike:
+		c.Value, err = convertValue(c.Value, TypeString)
+	case In, NotIn:
+		c.Value, err = convertValues(splitValues(c.Value), typ)
+	case Between:
+		var values []interface{}
+		if values, err = toBetweenValues(c.Value); err == nil {
+			c.Value, err = convertValues(values, typ)
+		}
+	default:
+		c.Value, err = convertValue(c.Value, typ)
+	}
+	if err != nil {
+		return &FieldError{Field: field + ".value", Msg: err.Error()}
+	}
+	return nil
+}
+
+func splitValues(value interface{}) []interface{} {
+	if str, ok := value.(string); ok {
+		var values []interface{}
+		for _, s := range strings.Split(str, ",") {
+			values = append(values, s)
+		}
+		return values
+	}
+	if values, ok := value.([]interface{}); ok {
+		return values
+	}
+	return []interface{}{value}
+}
+
+func convertValues(values []interface{}, typ string) ([]interface{}, error) {
+	newValues := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		v, err := convertValue(value, typ)
+		if err != nil {
+			return nil, err
+		}
+		newValues = append(newValues, v)
+	}
+	return newValues, nil
+}
+
+// convert the value to the type, the value comes from json, e.g. string, float64, bool
+func convertValue(value interface{}, typ string) (interface{}, error) {
+	if typ == TypeAny || typ == "" {
+		return value, nil
+	}
+	if n, ok := value.(json.Number); ok {
+		value = n.String()
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || rv.Kind() == reflect.Ptr {
+		return nil, fmt.Errorf("value cannot be nil")
+	}
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch typ {
+	case TypeString:
+		result, err = toString(rv)
+	case TypeInt:
+		result, err = toInt(rv)
+	case TypeUint:
+		result, err = toUint(rv)
+	case TypeFloat:
+		result, err = toFloat(rv)
+	case TypeBool:
+		result, err = toBool(rv)
+	case TypeTime:
+		result, err = toTime(rv)
+	default:
+		return nil, fmt.Errorf("unknown value type '%s'", typ)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value '%v'", typ, value)
+	}
+	return result, nil
+}
+
+func toString(rv reflect.Value) (string, error) {
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", rv.Type())
+}
+
+func toInt(rv reflect.Value) (int64, error) {
+	switch rv.Kind() {
+	case reflect.String:
+		return strconv.ParseInt(strings.TrimSpace(rv.String()), 10, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("out of range")
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+			return 0, fmt.Errorf("not an integer")
+		}
+		return int64(f), nil
+	}
+	return 0, fmt.Errorf("unsupported type %s", rv.Type())
+}
+
+func toUint(rv reflect.Value) (uint64, error) {
+	switch rv.Kind() {
+	case reflect.String:
+		return strconv.ParseUint(strings.TrimSpace(rv.String()), 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	}
+	i, err := toInt(rv)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("not an unsigned integer")
+	}
+	return uint64(i), nil
+}
+
+func toFloat(rv reflect.Value) (float64, error) {
+	switch rv.Kind() {
+	case reflect.String:
+		return strconv.ParseFloat(strings.TrimSpace(rv.String()), 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+	return 0, fmt.Errorf("unsupported type %s", rv.Type())
+}
+
+func toBool(rv reflect.Value) (bool, error) {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return strconv.ParseBool(strings.TrimSpace(rv.String()))
+	}
+	i, err := toInt(rv)
+	if err != nil || (i != 0 && i != 1) {
+		return false, fmt.Errorf("not a bool")
+	}
+	return i == 1, nil
+}
+
+// the time value is a string in the format of RFC3339, 2006-01-02 15:04:05, 2006-01-02, or a unix timestamp in seconds
+func toTime(rv reflect.Value) (time.Time, error) {
+	if rv.Type() == timeType {
+		return rv.Interface().(time.Time), nil
+	}
+	if rv.Kind() == reflect.String {
+		str := strings.TrimSpace(rv.String())
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, str, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		if _, err := strconv.ParseInt(str, 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("invalid time format")
+		}
+	}
+	sec, err := toInt(rv)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
@@ -0,0 +1,125 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testValidator = NewValidator(map[string]string{
+	"id":         TypeUint,
+	"name":       TypeString,
+	"age":        TypeInt,
+	"score":      TypeFloat,
+	"is_vip":     TypeBool,
+	"created_at": TypeTime,
+	"attrs":      TypeString,
+}, WithSortColumns("id", "age", "created_at"))
+
+func TestValidator_Validate(t *testing.T) {
+	p := &Params{
+		Sort: "-created_at,id",
+		Columns: []Column{
+			{Name: "id", Value: float64(1), Logic: OR},
+			{Name: "age", Value: "10,20", Exp: Between},
+			{Columns: []Column{
+				{Name: "is_vip", Value: "true"},
+				{Name: "score", Value: "9.5", Exp: Gte},
+				{Name: "name", Value: 1, Exp: PrefixLike},
+				{Name: "age", Value: []interface{}{float64(18), "20"}, Exp: In},
+				{Name: "created_at", Value: "2023-01-02 03:04:05", Exp: Lt},
+				{Name: "attrs->size.width", Value: float64(10)},
+				{Name: "name", Exp: IsNull},
+			}},
+		},
+	}
+	err := testValidator.Validate(p)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), p.Columns[0].Value)
+	assert.Equal(t, []interface{}{int64(10), int64(20)}, p.Columns[1].Value)
+	sub := p.Columns[2].Columns
+	assert.Equal(t, true, sub[0].Value)
+	assert.Equal(t, 9.5, sub[1].Value)
+	assert.Equal(t, "1", sub[2].Value)
+	assert.Equal(t, []interface{}{int64(18), int64(20)}, sub[3].Value)
+	assert.Equal(t, time.Date(2023, 1, 2, 3, 4, 5, 0, time.Local), sub[4].Value)
+	assert.Equal(t, float64(10), sub[5].Value)
+
+	_, _, err = p.ConvertToGormConditions()
+	assert.NoError(t, err)
+	assert.NoError(t, testValidator.ValidateSort(""))
+}
+
+func TestValidator_ValidateError(t *testing.T) {
+	tests := []struct {
+		params *Params
+		field  string
+	}{
+		{&Params{Sort: "name"}, "sort"},
+		{&Params{Columns: []Column{{Name: "password", Value: "123456"}}}, "columns[0].name"},
+		{&Params{Columns: []Column{{Name: "id", Value: "1' OR 1=1"}}}, "columns[0].value"},
+		{&Params{Columns: []Column{{Name: "id", Value: float64(-1)}}}, "columns[0].value"},
+		{&Params{Columns: []Column{{Name: "age", Value: 1.5}}}, "columns[0].value"},
+		{&Params{Columns: []Column{{Name: "is_vip", Value: float64(2)}}}, "columns[0].value"},
+		{&Params{Columns: []Column{{Name: "created_at", Value: "yesterday"}}}, "columns[0].value"},
+		{&Params{Columns: []Column{{Name: "age", Value: "1", Exp: Between}}}, "columns[0].value"},
+		{&Params{Columns: []Column{{Name: "age", Value: 1, Exp: "unknown"}}}, "columns[0].exp"},
+		{&Params{Columns: []Column{{Name: "age", Value: 1, Logic: "unknown"}}}, "columns[0].logic"},
+		{&Params{Columns: []Column{{Name: "id", Value: 1}, {Columns: []Column{{Name: "age", Value: nil}}}}}, "columns[1].columns[0].value"},
+	}
+	for _, tt := range tests {
+		err := testValidator.Validate(tt.params)
+		if assert.Error(t, err) {
+			assert.Equal(t, tt.field, err.(*FieldError).Field, err.Error())
+		}
+	}
+}
+
+func TestValidator_ValidateAggregation(t *testing.T) {
+	a := &Aggregation{
+		Columns:    []Column{{Name: "age", Value: "18", Exp: Gte}},
+		GroupBy:    []string{"is_vip"},
+		Aggregates: []Aggregate{{Func: AggCount}, {Func: AggAvg, Column: "score"}},
+	}
+	assert.NoError(t, testValidator.ValidateAggregation(a))
+	assert.Equal(t, int64(18), a.Columns[0].Value)
+
+	tests := []struct {
+		a     *Aggregation
+		field string
+	}{
+		{&Aggregation{GroupBy: []string{"password"}}, "groupBy[0]"},
+		{&Aggregation{Aggregates: []Aggregate{{Func: AggCount}, {Func: AggSum, Column: "password"}}}, "aggregates[1].column"},
+		{&Aggregation{Columns: []Column{{Name: "password", Value: "123456"}}}, "columns[0].name"},
+	}
+	for _, tt := range tests {
+		err := testValidator.ValidateAggregation(tt.a)
+		if assert.Error(t, err) {
+			assert.Equal(t, tt.field, err.(*FieldError).Field, err.Error())
+		}
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	v, err := convertValue(int64(1700000000), TypeTime)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000000, 0), v)
+	v, err = convertValue("2023-01-02T03:04:05Z", TypeTime)
+	assert.NoError(t, err)
+	assert.Equal(t, 2023, v.(time.Time).Year())
+	v, err = convertValue(float64(1), TypeBool)
+	assert.NoError(t, err)
+	assert.Equal(t, true, v)
+	v, err = convertValue(uint8(3), TypeFloat)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), v)
+	v, err = convertValue(map[string]int{}, TypeAny)
+	assert.NoError(t, err)
+	assert.NotNil(t, v)
+
+	_, err = convertValue(map[string]int{}, TypeString)
+	assert.Error(t, err)
+	_, err = convertValue(1, "unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package ggorm
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const afterCommitKey = "ggorm:after_commit"
+
+// the functions called after the transaction is committed
+type afterCommitHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+func (h *afterCommitHooks) add(fns ...func()) {
+	h.mu.Lock()
+	h.fns = append(h.fns, fns...)
+	h.mu.Unlock()
+}
+
+func getAfterCommitHooks(db *gorm.DB) *afterCommitHooks {
+	if v, ok := db.Get(afterCommitKey); ok {
+		if hooks, ok := v.(*afterCommitHooks); ok {
+			return hooks
+		}
+	}
+	return nil
+}
+
+// Transaction run fn in a transaction, the functions registered by AfterCommit in fn are called after
+// the transaction is committed, and they are discarded if the transaction is rolled back.
+// if db is a transaction started by Transaction, the functions are called after the outer transaction is committed.
+func Transaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	outer := getAfterCommitHooks(db)
+	hooks := &afterCommitHooks{}
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(tx.Set(afterCommitKey, hooks).Session(&gorm.Session{}))
+	})
+	if err != nil {
+		return err
+	}
+
+	if outer != nil {
+		outer.add(hooks.fns...)
+		return nil
+	}
+	for _, f := range hooks.fns {
+		f()
+	}
+	return nil
+}
+
+// AfterCommit register fn to be called after the transaction of tx is committed, e.g. delete the cache of the changed records,
+// tx must be started by Transaction, otherwise fn is called immediately.
+func AfterCommit(tx *gorm.DB, fn func()) {
+	if hooks := getAfterCommitHooks(tx); hooks != nil {
+		hooks.add(fn)
+		return
+	}
+	fn()
+}
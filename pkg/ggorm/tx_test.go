@@ -0,0 +1,71 @@
+package ggorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type txExample struct {
+	ID   uint64 `gorm:"primaryKey"`
+	Name string
+}
+
+func TestTransaction(t *testing.T) {
+	db, err := InitSqlite("file::memory:")
+	if err != nil {
+		t.Logf("connect to sqlite failed, err=%v", err)
+		return
+	}
+	defer CloseDB(db)
+	assert.NoError(t, db.AutoMigrate(&txExample{}))
+	ctx := context.Background()
+
+	// called after commit
+	var called []string
+	err = Transaction(ctx, db, func(tx *gorm.DB) error {
+		if err := tx.WithContext(ctx).Create(&txExample{ID: 1, Name: "foo"}).Error; err != nil {
+			return err
+		}
+		AfterCommit(tx.WithContext(ctx), func() {
+			var count int64
+			db.Model(&txExample{}).Where("id = ?", 1).Count(&count)
+			assert.Equal(t, int64(1), count) // the record is visible
+			called = append(called, "create")
+		})
+		assert.Empty(t, called)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"create"}, called)
+
+	// discarded after rollback
+	called = nil
+	err = Transaction(ctx, db, func(tx *gorm.DB) error {
+		AfterCommit(tx, func() { called = append(called, "rollback") })
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+	assert.Empty(t, called)
+
+	// nested transaction, called after the outer transaction is committed
+	err = Transaction(ctx, db, func(tx *gorm.DB) error {
+		err := Transaction(ctx, tx, func(tx2 *gorm.DB) error {
+			AfterCommit(tx2, func() { called = append(called, "inner") })
+			return nil
+		})
+		assert.Empty(t, called)
+		AfterCommit(tx, func() { called = append(called, "outer") })
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"inner", "outer"}, called)
+
+	// not in a transaction started by Transaction, called immediately
+	called = nil
+	AfterCommit(db, func() { called = append(called, "now") })
+	assert.Equal(t, []string{"now"}, called)
+}
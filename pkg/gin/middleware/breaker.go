@@ -4,8 +4,10 @@ import (
 	"net/http"
 
 	"github.com/zhufuyi/sponge/pkg/container/group"
-	"github.com/zhufuyi/sponge/pkg/gin/response"
 	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	"github.com/zhufuyi/sponge/pkg/shield/fallback"
+	shieldmetrics "github.com/zhufuyi/sponge/pkg/shield/metrics"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +22,8 @@ type circuitBreakerOptions struct {
 	group *group.Group
 	// http code for circuit breaker, default already includes 500 and 503
 	validCodes map[int]struct{}
+	policy     *policy.Manager
+	fallbacks  *fallback.Registry[FallbackFunc]
 }
 
 func defaultCircuitBreakerOptions() *circuitBreakerOptions {
@@ -31,6 +35,7 @@ func defaultCircuitBreakerOptions() *circuitBreakerOptions {
 			http.StatusInternalServerError: {},
 			http.StatusServiceUnavailable:  {},
 		},
+		fallbacks: fallback.NewRegistry[FallbackFunc](),
 	}
 }
 
@@ -50,6 +55,18 @@ func WithGroup(g *group.Group) CircuitBreakerOption {
 	}
 }
 
+// WithBreaker set the function to create the breaker of each route, the breaker can be
+// circuitbreaker.NewBreaker(sre breaker, default) or circuitbreaker.NewClassicBreaker(classic three-state breaker).
+func WithBreaker(fn func() circuitbreaker.CircuitBreaker) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		if fn != nil {
+			o.group = group.NewGroup(func() interface{} {
+				return fn()
+			})
+		}
+	}
+}
+
 // WithValidCode http code to mark failed
 func WithValidCode(code ...int) CircuitBreakerOption {
 	return func(o *circuitBreakerOptions) {
@@ -59,30 +76,70 @@ func WithValidCode(code ...int) CircuitBreakerOption {
 	}
 }
 
+// WithCircuitBreakerPolicy use the circuit breaker policies of routes, the routes not matched by the policies use
+// the breakers of group, the policies can be updated at runtime, e.g. policy.Get().
+func WithCircuitBreakerPolicy(m *policy.Manager) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		o.policy = m
+	}
+}
+
+// WithCircuitBreakerFallback set the fallback function of route when the request is rejected by the breaker,
+// if route ends with *, match the routes with the prefix, e.g. /api/v1/*
+func WithCircuitBreakerFallback(route string, fn FallbackFunc) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		if fn != nil {
+			o.fallbacks.Register(route, fn)
+		}
+	}
+}
+
+// the stats of breakers are collected by gin metrics
+func (o *circuitBreakerOptions) registerMetrics(source string) {
+	shieldmetrics.RegisterBreakerGroup(source, o.group)
+	if o.policy != nil {
+		shieldmetrics.RegisterPolicy(o.policy)
+	}
+}
+
+func (o *circuitBreakerOptions) getBreaker(route string) circuitbreaker.CircuitBreaker {
+	if o.policy != nil {
+		if breaker := o.policy.Breaker(route); breaker != nil {
+			return breaker
+		}
+	}
+	return o.group.Get(route).(circuitbreaker.CircuitBreaker)
+}
+
 // CircuitBreaker a circuit breaker middleware
 func CircuitBreaker(opts ...CircuitBreakerOption) gin.HandlerFunc {
 	o := defaultCircuitBreakerOptions()
 	o.apply(opts...)
+	o.registerMetrics("gin")
 
 	return func(c *gin.Context) {
-		breaker := o.group.Get(c.FullPath()).(circuitbreaker.CircuitBreaker)
-		if err := breaker.Allow(); err != nil {
-			// NOTE: when client reject request locally, keep adding counter let the drop ratio higher.
-			breaker.MarkFailed()
-			response.Output(c, http.StatusServiceUnavailable, err.Error())
-			c.Abort()
+		breaker := o.getBreaker(c.FullPath())
+		done, err := circuitbreaker.Acquire(breaker)
+		if err != nil {
+			// NOTE: when client reject request locally, the sre breaker keeps adding counter let the drop ratio higher.
+			circuitbreaker.MarkRejected(breaker)
+			rejectRequest(c, o.fallbacks, http.StatusServiceUnavailable, err)
 			return
 		}
 
+		// mark failed if the handler panics, otherwise the probe of half open breaker is never marked
+		defer func() {
+			if e := recover(); e != nil {
+				done(false)
+				panic(e)
+			}
+		}()
+
 		c.Next()
 
 		code := c.Writer.Status()
 		// NOTE: need to check internal and service unavailable error
 		_, isHit := o.validCodes[code]
-		if isHit {
-			breaker.MarkFailed()
-		} else {
-			breaker.MarkSuccess()
-		}
+		done(!isHit)
 	}
 }
@@ -3,6 +3,7 @@ package middleware
 import (
 	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,6 +14,7 @@ import (
 	"github.com/zhufuyi/sponge/pkg/gin/response"
 	"github.com/zhufuyi/sponge/pkg/gohttp"
 	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
 	"github.com/zhufuyi/sponge/pkg/utils"
 
 	"github.com/gin-gonic/gin"
@@ -75,3 +77,77 @@ func TestCircuitBreaker(t *testing.T) {
 			time.Now().Format(time.RFC3339Nano), success, failures, countBreaker)
 	}
 }
+
+func TestCircuitBreakerPolicy(t *testing.T) {
+	m := policy.NewManager()
+	err := m.Update(&policy.Config{CircuitBreakers: []policy.CircuitBreaker{{Route: "/api/v1/*", Request: 10}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := defaultCircuitBreakerOptions()
+	o.apply(WithCircuitBreakerPolicy(m))
+	if o.getBreaker("/api/v1/user") != m.Breaker("/api/v1/user") {
+		t.Error("the breaker of policy is not used")
+	}
+	if o.getBreaker("/hello") != o.group.Get("/hello") {
+		t.Error("the breaker of group is not used")
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(CircuitBreaker(WithCircuitBreakerPolicy(m)))
+	r.GET("/api/v1/user", func(c *gin.Context) { response.Success(c) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/user", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("got code %d", w.Code)
+	}
+}
+
+func TestCircuitBreakerClassic(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(CircuitBreaker(WithBreaker(func() circuitbreaker.CircuitBreaker {
+		return circuitbreaker.NewClassicBreaker(circuitbreaker.WithConsecutiveFailures(3))
+	})))
+	r.GET("/hello", func(c *gin.Context) { response.Output(c, http.StatusInternalServerError) })
+
+	codes := make([]int, 0, 5)
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello", nil))
+		codes = append(codes, w.Code)
+	}
+	expected := []int{500, 500, 500, 503, 503}
+	for i, code := range codes {
+		if code != expected[i] {
+			t.Fatalf("got codes %v, expected %v", codes, expected)
+		}
+	}
+}
+
+func TestCircuitBreakerPanic(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, err interface{}) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	r.Use(CircuitBreaker(WithBreaker(func() circuitbreaker.CircuitBreaker {
+		return circuitbreaker.NewClassicBreaker(circuitbreaker.WithConsecutiveFailures(3))
+	})))
+	r.GET("/hello", func(c *gin.Context) { panic("test panic") })
+
+	codes := make([]int, 0, 4)
+	for i := 0; i < 4; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello", nil))
+		codes = append(codes, w.Code)
+	}
+	expected := []int{500, 500, 500, 503}
+	for i, code := range codes {
+		if code != expected[i] {
+			t.Fatalf("got codes %v, expected %v", codes, expected)
+		}
+	}
+}
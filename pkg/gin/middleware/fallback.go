@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/zhufuyi/sponge/pkg/gin/response"
+	"github.com/zhufuyi/sponge/pkg/shield/fallback"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FallbackFunc handle the request rejected by circuit breaker or rate limiter instead of the error response,
+// e.g. response cached value, static default or the result of alternative backend, err is ErrNotAllowed or ErrLimitExceed.
+type FallbackFunc func(c *gin.Context, err error)
+
+// call the fallback function matched by route if it is registered, otherwise response the error with code
+func rejectRequest(c *gin.Context, fallbacks *fallback.Registry[FallbackFunc], code int, err error) {
+	if fn, ok := fallbacks.Get(c.FullPath()); ok {
+		fn(c, err)
+	} else {
+		response.Output(c, code, err.Error())
+	}
+	c.Abort()
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zhufuyi/sponge/pkg/gin/response"
+	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	rl "github.com/zhufuyi/sponge/pkg/shield/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type rejectLimiter struct{}
+
+func (rejectLimiter) Allow(ctx context.Context, key string) (*rl.Result, error) {
+	return &rl.Result{Allowed: false, Limit: 1}, nil
+}
+
+func TestCircuitBreakerFallback(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(CircuitBreaker(
+		WithBreaker(func() circuitbreaker.CircuitBreaker {
+			return circuitbreaker.NewClassicBreaker(circuitbreaker.WithConsecutiveFailures(1))
+		}),
+		WithCircuitBreakerFallback("/api/v1/*", func(c *gin.Context, err error) {
+			assert.True(t, errors.Is(err, ErrNotAllowed))
+			response.Success(c, gin.H{"name": "default"})
+		}),
+	))
+	r.GET("/api/v1/user", func(c *gin.Context) { response.Output(c, http.StatusInternalServerError) })
+	r.GET("/hello", func(c *gin.Context) { response.Output(c, http.StatusInternalServerError) })
+
+	for _, path := range []string{"/api/v1/user", "/hello"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	}
+
+	// the breaker is open
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/user", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "default")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestRateLimitFallback(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(RateLimit(
+		WithKeyLimiter(rejectLimiter{}),
+		WithRateLimitFallback("/api/v1/user", func(c *gin.Context, err error) {
+			assert.True(t, errors.Is(err, ErrLimitExceed))
+			response.Success(c, gin.H{"name": "cached"})
+		}),
+	))
+	r.GET("/api/v1/user", func(c *gin.Context) { response.Success(c) })
+	r.GET("/hello", func(c *gin.Context) { response.Success(c) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/user", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "cached")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
@@ -1,5 +1,6 @@
 // Package metrics is gin metrics library, collect five metrics, "uptime", "http_request_count_total",
-// "http_request_duration_seconds", "http_request_size_bytes", "http_response_size_bytes".
+// "http_request_duration_seconds", "http_request_size_bytes", "http_response_size_bytes",
+// and the metrics of rate limiters and circuit breakers, see pkg/shield/metrics.
 package metrics
 
 import (
@@ -7,6 +8,8 @@ import (
 	"strconv"
 	"time"
 
+	shieldmetrics "github.com/zhufuyi/sponge/pkg/shield/metrics"
+
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -61,6 +64,8 @@ var (
 // init registers the prometheus metrics
 func initPrometheus() {
 	prometheus.MustRegister(uptime, reqCount, reqDuration, reqSizeBytes, respSizeBytes)
+	// the stats of rate limiters and circuit breakers
+	prometheus.MustRegister(shieldmetrics.Collector())
 	go recordUptime()
 }
 
@@ -1,10 +1,14 @@
 package middleware
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
-	"github.com/zhufuyi/sponge/pkg/gin/response"
+	"github.com/zhufuyi/sponge/pkg/shield/fallback"
+	shieldmetrics "github.com/zhufuyi/sponge/pkg/shield/metrics"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
 	rl "github.com/zhufuyi/sponge/pkg/shield/ratelimit"
 
 	"github.com/gin-gonic/gin"
@@ -22,6 +26,11 @@ type rateLimitOptions struct {
 	bucket       int
 	cpuThreshold int64
 	cpuQuota     float64
+
+	keyLimiter rl.KeyLimiter
+	keyFn      RateLimitKeyFunc
+	policy     *policy.Manager
+	fallbacks  *fallback.Registry[FallbackFunc]
 }
 
 func defaultRatelimitOptions() *rateLimitOptions {
@@ -29,6 +38,8 @@ func defaultRatelimitOptions() *rateLimitOptions {
 		window:       time.Second * 10,
 		bucket:       100,
 		cpuThreshold: 800,
+		keyFn:        KeyByIP(),
+		fallbacks:    fallback.NewRegistry[FallbackFunc](),
 	}
 }
 
@@ -66,22 +77,122 @@ func WithCPUQuota(quota float64) RateLimitOption {
 	}
 }
 
-// RateLimit an adaptive rate limiter middleware
+// WithKeyLimiter use the rate limiter by key instead of the adaptive rate limiter,
+// e.g. rl.NewTokenBucket, rl.NewSlidingWindow, the key is extracted by WithKeyFunc, default is client ip.
+func WithKeyLimiter(limiter rl.KeyLimiter) RateLimitOption {
+	return func(o *rateLimitOptions) {
+		o.keyLimiter = limiter
+	}
+}
+
+// WithKeyFunc set the function to extract the key of rate limiter by key
+func WithKeyFunc(fn RateLimitKeyFunc) RateLimitOption {
+	return func(o *rateLimitOptions) {
+		if fn != nil {
+			o.keyFn = fn
+		}
+	}
+}
+
+// WithRateLimitPolicy use the rate limit policies of routes, the routes not matched by the policies are limited by default,
+// the policies can be updated at runtime, e.g. policy.Get().
+func WithRateLimitPolicy(m *policy.Manager) RateLimitOption {
+	return func(o *rateLimitOptions) {
+		o.policy = m
+	}
+}
+
+// WithRateLimitFallback set the fallback function of route when the request is rejected by the limiter,
+// if route ends with *, match the routes with the prefix, e.g. /api/v1/*
+func WithRateLimitFallback(route string, fn FallbackFunc) RateLimitOption {
+	return func(o *rateLimitOptions) {
+		if fn != nil {
+			o.fallbacks.Register(route, fn)
+		}
+	}
+}
+
+// RateLimitKeyFunc extract the key of rate limiter by key from request, the request is not limited if the key is empty
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// KeyByIP use client ip as key
+func KeyByIP() RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		return c.ClientIP()
+	}
+}
+
+// KeyByHeader use the value of header as key, e.g. X-API-Key
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		return c.GetHeader(name)
+	}
+}
+
+// KeyByContext use the value of gin context as key, e.g. "uid" set by Auth
+func KeyByContext(name string) RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		return c.GetString(name)
+	}
+}
+
+// KeyByPath use the route path as key, limit the total requests of each route
+func KeyByPath() RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		return c.FullPath()
+	}
+}
+
+// RateLimit an adaptive rate limiter middleware, if WithKeyLimiter is set, limit requests by key,
+// if WithRateLimitPolicy is set, the routes matched by the policies are limited by the policies.
 func RateLimit(opts ...RateLimitOption) gin.HandlerFunc {
 	o := defaultRatelimitOptions()
 	o.apply(opts...)
+
+	handler := defaultRateLimit(o)
+	if o.policy == nil {
+		return handler
+	}
+	shieldmetrics.RegisterPolicy(o.policy)
+
+	return func(c *gin.Context) {
+		limiter := o.policy.Limiter(c.FullPath())
+		if limiter == nil {
+			handler(c)
+			return
+		}
+
+		done, result, err := limiter.Allow(c.Request.Context(), o.keyFn(c))
+		if result != nil {
+			setRateLimitHeaders(c, result)
+		}
+		if err != nil {
+			rejectRequest(c, o.fallbacks, http.StatusTooManyRequests, err)
+			return
+		}
+
+		c.Next()
+
+		done(rl.DoneInfo{Err: c.Request.Context().Err()})
+	}
+}
+
+func defaultRateLimit(o *rateLimitOptions) gin.HandlerFunc {
+	if o.keyLimiter != nil {
+		return keyRateLimit(o)
+	}
 	limiter := rl.NewLimiter(
 		rl.WithWindow(o.window),
 		rl.WithBucket(o.bucket),
 		rl.WithCPUThreshold(o.cpuThreshold),
 		rl.WithCPUQuota(o.cpuQuota),
 	)
+	shieldmetrics.ReplaceLimiter("gin", "default", limiter)
 
 	return func(c *gin.Context) {
 		done, err := limiter.Allow()
 		if err != nil {
-			response.Output(c, http.StatusTooManyRequests, err.Error())
-			c.Abort()
+			rejectRequest(c, o.fallbacks, http.StatusTooManyRequests, err)
 			return
 		}
 
@@ -90,3 +201,41 @@ func RateLimit(opts ...RateLimitOption) gin.HandlerFunc {
 		done(rl.DoneInfo{Err: c.Request.Context().Err()})
 	}
 }
+
+func keyRateLimit(o *rateLimitOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := o.keyFn(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		result, err := o.keyLimiter.Allow(c.Request.Context(), key)
+		if err != nil {
+			// let the request pass if the limiter is unavailable
+			c.Next()
+			return
+		}
+
+		setRateLimitHeaders(c, result)
+		if !result.Allowed {
+			rejectRequest(c, o.fallbacks, http.StatusTooManyRequests, ErrLimitExceed)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func setRateLimitHeaders(c *gin.Context, result *rl.Result) {
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -10,9 +11,14 @@ import (
 
 	"github.com/zhufuyi/sponge/pkg/gin/response"
 	"github.com/zhufuyi/sponge/pkg/gohttp"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
+	rl "github.com/zhufuyi/sponge/pkg/shield/ratelimit"
 	"github.com/zhufuyi/sponge/pkg/utils"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
 )
 
 func runRateLimiterHTTPServer() string {
@@ -75,3 +81,130 @@ func TestRateLimiter(t *testing.T) {
 			time.Now().Format(time.RFC3339Nano), success, failures)
 	}
 }
+
+func runKeyRateLimiterHTTPServer(limiter rl.KeyLimiter) string {
+	serverAddr, requestAddr := utils.GetLocalHTTPAddrPairs()
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(RateLimit(
+		WithKeyLimiter(limiter),
+		WithKeyFunc(KeyByHeader("X-API-Key")),
+	))
+	r.GET("/hello", func(c *gin.Context) {
+		response.Success(c, "hello "+c.ClientIP())
+	})
+
+	go func() {
+		err := r.Run(serverAddr)
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	time.Sleep(time.Millisecond * 200)
+	return requestAddr
+}
+
+func TestKeyRateLimiter(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	limiter, err := rl.NewSlidingWindow(client, 2, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestAddr := runKeyRateLimiterHTTPServer(limiter)
+
+	get := func(apiKey string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, requestAddr+"/hello", nil)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	for i := 0; i < 2; i++ {
+		resp := get("foo")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("X-RateLimit-Limit"))
+	}
+	resp := get("foo")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "60", resp.Header.Get("Retry-After"))
+
+	// other keys are not affected, empty key is not limited
+	assert.Equal(t, http.StatusOK, get("bar").StatusCode)
+	for i := 0; i < 3; i++ {
+		resp = get("")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-RateLimit-Limit"))
+	}
+
+	// let the request pass if redis is unavailable
+	s.Close()
+	assert.Equal(t, http.StatusOK, get("foo").StatusCode)
+}
+
+func TestRateLimitKeyFunc(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/hello", nil)
+	c.Request.Header.Set("X-API-Key", "foo")
+	c.Set("uid", "100")
+	assert.Equal(t, "foo", KeyByHeader("X-API-Key")(c))
+	assert.Equal(t, "100", KeyByContext("uid")(c))
+	assert.Equal(t, "", KeyByPath()(c))
+	assert.Equal(t, "192.0.2.1", KeyByIP()(c))
+}
+
+func TestRateLimitPolicy(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	m := policy.NewManager(policy.WithRedisClient(redis.NewClient(&redis.Options{Addr: s.Addr()})))
+	err = m.Update(&policy.Config{RateLimits: []policy.RateLimit{
+		{Route: "/hello", Type: policy.LimiterSlidingWindow, Limit: 1, Window: 60},
+	}})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(RateLimit(WithRateLimitPolicy(m)))
+	r.GET("/hello", func(c *gin.Context) { response.Success(c) })
+	r.GET("/ping", func(c *gin.Context) { response.Success(c) })
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/hello")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, http.StatusTooManyRequests, get("/hello").Code)
+
+	// not matched by the policies
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, get("/ping").Code)
+	}
+
+	// update policies at runtime
+	err = m.Update(&policy.Config{RateLimits: []policy.RateLimit{{Route: "/ping", Type: policy.LimiterSlidingWindow, Limit: 1, Window: 60}}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, get("/hello").Code)
+	assert.Equal(t, http.StatusOK, get("/ping").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get("/ping").Code)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/zhufuyi/sponge/pkg/krand"
+	"github.com/zhufuyi/sponge/pkg/requestid"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -42,9 +43,11 @@ func (o *requestIDOptions) apply(opts ...RequestIDOption) {
 func (o *requestIDOptions) setRequestIDKey() {
 	if o.contextRequestIDKey != ContextRequestIDKey {
 		ContextRequestIDKey = o.contextRequestIDKey
+		requestid.ContextKey = o.contextRequestIDKey
 	}
 	if o.headerXRequestIDKey != HeaderXRequestIDKey {
 		HeaderXRequestIDKey = o.headerXRequestIDKey
+		requestid.HeaderKey = o.headerXRequestIDKey
 	}
 }
 
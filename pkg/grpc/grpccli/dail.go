@@ -5,13 +5,17 @@ import (
 	"context"
 	"errors"
 
+	"github.com/zhufuyi/sponge/pkg/container/group"
 	"github.com/zhufuyi/sponge/pkg/grpc/gtls"
 	"github.com/zhufuyi/sponge/pkg/grpc/interceptor"
 	"github.com/zhufuyi/sponge/pkg/logger"
 	"github.com/zhufuyi/sponge/pkg/servicerd/discovery"
+	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	shieldmetrics "github.com/zhufuyi/sponge/pkg/shield/metrics"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
@@ -59,7 +63,36 @@ func Dial(ctx context.Context, endpoint string, opts ...Option) (*grpc.ClientCon
 	// custom options
 	clientOptions = append(clientOptions, o.dialOptions...)
 
-	return grpc.DialContext(ctx, endpoint, clientOptions...)
+	conn, err := grpc.DialContext(ctx, endpoint, clientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.breakerGroups) > 0 {
+		go unregisterMetricsOnClose(conn, o.breakerGroups)
+	}
+	return conn, nil
+}
+
+// the metrics of circuit breakers are no longer collected after the connection is closed
+func unregisterMetricsOnClose(conn *grpc.ClientConn, groups []*group.Group) {
+	for {
+		state := conn.GetState()
+		if state == connectivity.Shutdown {
+			break
+		}
+		conn.WaitForStateChange(context.Background(), state)
+	}
+	for _, g := range groups {
+		shieldmetrics.Unregister(g)
+	}
+}
+
+func newBreakerGroup(o *options) *group.Group {
+	g := group.NewGroup(func() interface{} {
+		return circuitbreaker.NewBreaker()
+	})
+	o.breakerGroups = append(o.breakerGroups, g)
+	return g
 }
 
 func secureOption(o *options) (grpc.DialOption, error) {
@@ -121,12 +154,15 @@ func unaryClientOptions(o *options) grpc.DialOption {
 	}
 
 	// circuit breaker
-	//if o.enableCircuitBreaker {
-	//	unaryClientInterceptors = append(unaryClientInterceptors, interceptor.UnaryClientCircuitBreaker(
-	//	// set rpc code for circuit breaker, default already includes codes.Internal and codes.Unavailable
-	//	//interceptor.WithValidCode(codes.PermissionDenied),
-	//	))
-	//}
+	if o.enableCircuitBreaker {
+		// set rpc code for circuit breaker, default already includes codes.Internal and codes.Unavailable
+		//cbOptions := []interceptor.CircuitBreakerOption{interceptor.WithValidCode(codes.PermissionDenied)}
+		cbOptions := []interceptor.CircuitBreakerOption{interceptor.WithGroup(newBreakerGroup(o))}
+		for method, fn := range o.fallbacks {
+			cbOptions = append(cbOptions, interceptor.WithCircuitBreakerFallback(method, fn))
+		}
+		unaryClientInterceptors = append(unaryClientInterceptors, interceptor.UnaryClientCircuitBreaker(cbOptions...))
+	}
 
 	// retry
 	if o.enableRetry {
@@ -167,12 +203,13 @@ func streamClientOptions(o *options) grpc.DialOption {
 	}
 
 	// circuit breaker
-	//if o.enableCircuitBreaker {
-	//	streamClientInterceptors = append(streamClientInterceptors, interceptor.StreamClientCircuitBreaker(
-	//	// set rpc code for circuit breaker, default already includes codes.Internal and codes.Unavailable
-	//	//interceptor.WithValidCode(codes.PermissionDenied),
-	//	))
-	//}
+	if o.enableCircuitBreaker {
+		streamClientInterceptors = append(streamClientInterceptors, interceptor.StreamClientCircuitBreaker(
+			interceptor.WithGroup(newBreakerGroup(o)),
+			// set rpc code for circuit breaker, default already includes codes.Internal and codes.Unavailable
+			//interceptor.WithValidCode(codes.PermissionDenied),
+		))
+	}
 
 	// retry
 	if o.enableRetry {
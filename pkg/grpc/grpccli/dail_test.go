@@ -5,9 +5,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/zhufuyi/sponge/pkg/container/group"
 	"github.com/zhufuyi/sponge/pkg/grpc/gtls/certfile"
+	"github.com/zhufuyi/sponge/pkg/grpc/interceptor"
 	"github.com/zhufuyi/sponge/pkg/servicerd/registry/etcd"
+	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	shieldmetrics "github.com/zhufuyi/sponge/pkg/shield/metrics"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
@@ -32,6 +37,32 @@ func TestDial2(t *testing.T) {
 	time.Sleep(time.Millisecond * 50)
 }
 
+func Test_unregisterMetricsOnClose(t *testing.T) {
+	conn, err := Dial(context.Background(), "localhost:8282")
+	assert.NoError(t, err)
+
+	g := group.NewGroup(func() interface{} {
+		return circuitbreaker.NewBreaker()
+	})
+	g.Get("/api.test.v1.Test/Unregister")
+	shieldmetrics.RegisterBreakerGroup("grpc_client", g)
+	count := testutil.CollectAndCount(shieldmetrics.Collector())
+	assert.GreaterOrEqual(t, count, 4)
+
+	done := make(chan struct{})
+	go func() {
+		unregisterMetricsOnClose(conn, []*group.Group{g})
+		close(done)
+	}()
+	_ = conn.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	assert.Equal(t, count-4, testutil.CollectAndCount(shieldmetrics.Collector())) // 4 metrics of a breaker
+}
+
 func Test_unaryClientOptions(t *testing.T) {
 	o := &options{
 		enableToken:          true,
@@ -42,6 +73,11 @@ func Test_unaryClientOptions(t *testing.T) {
 		enableRetry:          true,
 		enableLoadBalance:    true,
 		enableCircuitBreaker: true,
+		fallbacks: map[string]interceptor.FallbackFunc{
+			"/api.user.v1.User/*": func(ctx context.Context, method string, req interface{}, err error) (interface{}, error) {
+				return nil, err
+			},
+		},
 	}
 	scOpt := unaryClientOptions(o)
 	assert.NotNil(t, scOpt)
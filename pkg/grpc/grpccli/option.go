@@ -3,6 +3,8 @@ package grpccli
 import (
 	"time"
 
+	"github.com/zhufuyi/sponge/pkg/container/group"
+	"github.com/zhufuyi/sponge/pkg/grpc/interceptor"
 	"github.com/zhufuyi/sponge/pkg/servicerd/registry"
 
 	"go.uber.org/zap"
@@ -44,6 +46,11 @@ type options struct {
 	enableCircuitBreaker bool               // whether to turn on circuit breaker
 	discovery            registry.Discovery // if not nil means use service discovery
 
+	// fallback functions of methods when the circuit breaker rejects
+	fallbacks map[string]interceptor.FallbackFunc
+	// the groups of circuit breakers created when dialing, their metrics are unregistered when the connection is closed
+	breakerGroups []*group.Group
+
 	// custom setting
 	dialOptions        []grpc.DialOption              // custom options
 	unaryInterceptors  []grpc.UnaryClientInterceptor  // custom unary interceptor
@@ -135,6 +142,20 @@ func WithEnableCircuitBreaker() Option {
 	}
 }
 
+// WithFallback set the fallback function of method when the unary call is rejected by the circuit breaker,
+// if method ends with *, match the methods with the prefix, it takes effect when circuit breaker is enabled.
+func WithFallback(method string, fn interceptor.FallbackFunc) Option {
+	return func(o *options) {
+		if fn == nil {
+			return
+		}
+		if o.fallbacks == nil {
+			o.fallbacks = make(map[string]interceptor.FallbackFunc)
+		}
+		o.fallbacks[method] = fn
+	}
+}
+
 func (o *options) isSecure() bool {
 	if o.secureType == secureOneWay || o.secureType == secureTwoWay {
 		return true
@@ -1,6 +1,7 @@
 package grpccli
 
 import (
+	"context"
 	"go.uber.org/zap"
 	"testing"
 	"time"
@@ -36,6 +37,16 @@ func TestWithEnableCircuitBreaker(t *testing.T) {
 	assert.Equal(t, true, o.enableCircuitBreaker)
 }
 
+func TestWithFallback(t *testing.T) {
+	fn := func(ctx context.Context, method string, req interface{}, err error) (interface{}, error) {
+		return nil, err
+	}
+	o := new(options)
+	o.apply(WithFallback("/api.user.v1.User/*", fn), WithFallback("/foo", nil))
+	assert.Len(t, o.fallbacks, 1)
+	assert.NotNil(t, o.fallbacks["/api.user.v1.User/*"])
+}
+
 func TestWithEnableLoadBalance(t *testing.T) {
 	opt := WithEnableLoadBalance()
 	o := new(options)
@@ -7,6 +7,9 @@ import (
 	"github.com/zhufuyi/sponge/pkg/container/group"
 	"github.com/zhufuyi/sponge/pkg/errcode"
 	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	"github.com/zhufuyi/sponge/pkg/shield/fallback"
+	shieldmetrics "github.com/zhufuyi/sponge/pkg/shield/metrics"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -23,6 +26,8 @@ type circuitBreakerOptions struct {
 	group *group.Group
 	// rpc code for circuit breaker, default already includes codes.Internal and codes.Unavailable
 	validCodes map[codes.Code]struct{}
+	policy     *policy.Manager
+	fallbacks  *fallback.Registry[FallbackFunc]
 }
 
 func defaultCircuitBreakerOptions() *circuitBreakerOptions {
@@ -34,6 +39,7 @@ func defaultCircuitBreakerOptions() *circuitBreakerOptions {
 			codes.Internal:    {},
 			codes.Unavailable: {},
 		},
+		fallbacks: fallback.NewRegistry[FallbackFunc](),
 	}
 }
 
@@ -53,6 +59,18 @@ func WithGroup(g *group.Group) CircuitBreakerOption {
 	}
 }
 
+// WithBreaker set the function to create the breaker of each method, the breaker can be
+// circuitbreaker.NewBreaker(sre breaker, default) or circuitbreaker.NewClassicBreaker(classic three-state breaker).
+func WithBreaker(fn func() circuitbreaker.CircuitBreaker) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		if fn != nil {
+			o.group = group.NewGroup(func() interface{} {
+				return fn()
+			})
+		}
+	}
+}
+
 // WithValidCode rpc code to mark failed
 func WithValidCode(code ...codes.Code) CircuitBreakerOption {
 	return func(o *circuitBreakerOptions) {
@@ -62,31 +80,85 @@ func WithValidCode(code ...codes.Code) CircuitBreakerOption {
 	}
 }
 
+// WithCircuitBreakerPolicy use the circuit breaker policies of methods, the methods not matched by the policies use
+// the breakers of group, the policies can be updated at runtime, e.g. policy.Get().
+func WithCircuitBreakerPolicy(m *policy.Manager) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		o.policy = m
+	}
+}
+
+// WithCircuitBreakerFallback set the fallback function of method when the unary call is rejected by the breaker,
+// if method ends with *, match the methods with the prefix, e.g. /api.user.v1.User/*
+func WithCircuitBreakerFallback(method string, fn FallbackFunc) CircuitBreakerOption {
+	return func(o *circuitBreakerOptions) {
+		if fn != nil {
+			o.fallbacks.Register(method, fn)
+		}
+	}
+}
+
+// the stats of breakers are collected by grpc server metrics
+func (o *circuitBreakerOptions) registerMetrics(source string) {
+	shieldmetrics.RegisterBreakerGroup(source, o.group)
+	if o.policy != nil {
+		shieldmetrics.RegisterPolicy(o.policy)
+	}
+}
+
+func (o *circuitBreakerOptions) getBreaker(method string) circuitbreaker.CircuitBreaker {
+	if o.policy != nil {
+		if breaker := o.policy.Breaker(method); breaker != nil {
+			return breaker
+		}
+	}
+	return o.group.Get(method).(circuitbreaker.CircuitBreaker)
+}
+
+// NOTE: need to check internal and service unavailable error
+func (o *circuitBreakerOptions) markResult(done func(success bool), err error) {
+	if err != nil {
+		s, ok := status.FromError(err)
+		_, isHit := o.validCodes[s.Code()]
+		if ok && isHit {
+			done(false)
+			return
+		}
+	}
+	done(true)
+}
+
+// mark failed if the call panics, otherwise the probe of half open breaker is never marked
+func markPanic(done func(success bool)) {
+	if e := recover(); e != nil {
+		done(false)
+		panic(e)
+	}
+}
+
 // UnaryClientCircuitBreaker client-side unary circuit breaker interceptor
 func UnaryClientCircuitBreaker(opts ...CircuitBreakerOption) grpc.UnaryClientInterceptor {
 	o := defaultCircuitBreakerOptions()
 	o.apply(opts...)
+	o.registerMetrics("grpc_client")
 
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		breaker := o.group.Get(method).(circuitbreaker.CircuitBreaker)
-		if err := breaker.Allow(); err != nil {
-			// NOTE: when client reject request locally, keep adding counter let the drop ratio higher.
-			breaker.MarkFailed()
-			return errcode.StatusServiceUnavailable.ToRPCErr(err.Error())
-		}
-
-		err := invoker(ctx, method, req, reply, cc, opts...)
+		breaker := o.getBreaker(method)
+		done, err := circuitbreaker.Acquire(breaker)
 		if err != nil {
-			// NOTE: need to check internal and service unavailable error
-			s, ok := status.FromError(err)
-			_, isHit := o.validCodes[s.Code()]
-			if ok && isHit {
-				breaker.MarkFailed()
-			} else {
-				breaker.MarkSuccess()
+			// NOTE: when client reject request locally, the sre breaker keeps adding counter let the drop ratio higher.
+			circuitbreaker.MarkRejected(breaker)
+			value, err := callFallback(ctx, o.fallbacks, method, req, err, errcode.StatusServiceUnavailable.ToRPCErr(err.Error()))
+			if err != nil {
+				return err
 			}
+			return setReply(reply, value)
 		}
 
+		defer markPanic(done)
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		o.markResult(done, err)
+
 		return err
 	}
 }
@@ -95,26 +167,20 @@ func UnaryClientCircuitBreaker(opts ...CircuitBreakerOption) grpc.UnaryClientInt
 func StreamClientCircuitBreaker(opts ...CircuitBreakerOption) grpc.StreamClientInterceptor {
 	o := defaultCircuitBreakerOptions()
 	o.apply(opts...)
+	o.registerMetrics("grpc_client")
 
 	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-		breaker := o.group.Get(method).(circuitbreaker.CircuitBreaker)
-		if err := breaker.Allow(); err != nil {
-			// NOTE: when client reject request locally, keep adding counter let the drop ratio higher.
-			breaker.MarkFailed()
+		breaker := o.getBreaker(method)
+		done, err := circuitbreaker.Acquire(breaker)
+		if err != nil {
+			// NOTE: when client reject request locally, the sre breaker keeps adding counter let the drop ratio higher.
+			circuitbreaker.MarkRejected(breaker)
 			return nil, errcode.StatusServiceUnavailable.ToRPCErr(err.Error())
 		}
 
+		defer markPanic(done)
 		clientStream, err := streamer(ctx, desc, cc, method, opts...)
-		if err != nil {
-			// NOTE: need to check internal and service unavailable error
-			s, ok := status.FromError(err)
-			_, isHit := o.validCodes[s.Code()]
-			if ok && isHit {
-				breaker.MarkFailed()
-			} else {
-				breaker.MarkSuccess()
-			}
-		}
+		o.markResult(done, err)
 
 		return clientStream, err
 	}
@@ -124,26 +190,20 @@ func StreamClientCircuitBreaker(opts ...CircuitBreakerOption) grpc.StreamClientI
 func UnaryServerCircuitBreaker(opts ...CircuitBreakerOption) grpc.UnaryServerInterceptor {
 	o := defaultCircuitBreakerOptions()
 	o.apply(opts...)
+	o.registerMetrics("grpc_server")
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		breaker := o.group.Get(info.FullMethod).(circuitbreaker.CircuitBreaker)
-		if err := breaker.Allow(); err != nil {
-			// NOTE: when client reject request locally, keep adding let the drop ratio higher.
-			breaker.MarkFailed()
-			return nil, errcode.StatusServiceUnavailable.ToRPCErr(err.Error())
+		breaker := o.getBreaker(info.FullMethod)
+		done, err := circuitbreaker.Acquire(breaker)
+		if err != nil {
+			// NOTE: when client reject request locally, the sre breaker keeps adding counter let the drop ratio higher.
+			circuitbreaker.MarkRejected(breaker)
+			return callFallback(ctx, o.fallbacks, info.FullMethod, req, err, errcode.StatusServiceUnavailable.ToRPCErr(err.Error()))
 		}
 
+		defer markPanic(done)
 		reply, err := handler(ctx, req)
-		if err != nil {
-			// NOTE: need to check internal and service unavailable error
-			s, ok := status.FromError(err)
-			_, isHit := o.validCodes[s.Code()]
-			if ok && isHit {
-				breaker.MarkFailed()
-			} else {
-				breaker.MarkSuccess()
-			}
-		}
+		o.markResult(done, err)
 
 		return reply, err
 	}
@@ -153,26 +213,20 @@ func UnaryServerCircuitBreaker(opts ...CircuitBreakerOption) grpc.UnaryServerInt
 func StreamServerCircuitBreaker(opts ...CircuitBreakerOption) grpc.StreamServerInterceptor {
 	o := defaultCircuitBreakerOptions()
 	o.apply(opts...)
+	o.registerMetrics("grpc_server")
 
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		breaker := o.group.Get(info.FullMethod).(circuitbreaker.CircuitBreaker)
-		if err := breaker.Allow(); err != nil {
-			// NOTE: when client reject request locally, keep adding counter let the drop ratio higher.
-			breaker.MarkFailed()
+		breaker := o.getBreaker(info.FullMethod)
+		done, err := circuitbreaker.Acquire(breaker)
+		if err != nil {
+			// NOTE: when client reject request locally, the sre breaker keeps adding counter let the drop ratio higher.
+			circuitbreaker.MarkRejected(breaker)
 			return errcode.StatusServiceUnavailable.ToRPCErr(err.Error())
 		}
 
-		err := handler(srv, ss)
-		if err != nil {
-			// NOTE: need to check internal and service unavailable error
-			s, ok := status.FromError(err)
-			_, isHit := o.validCodes[s.Code()]
-			if ok && isHit {
-				breaker.MarkFailed()
-			} else {
-				breaker.MarkSuccess()
-			}
-		}
+		defer markPanic(done)
+		err = handler(srv, ss)
+		o.markResult(done, err)
 
 		return err
 	}
@@ -3,14 +3,17 @@ package interceptor
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/zhufuyi/sponge/pkg/container/group"
 	"github.com/zhufuyi/sponge/pkg/errcode"
 	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
 	"google.golang.org/grpc/codes"
 
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
 func TestUnaryClientCircuitBreaker(t *testing.T) {
@@ -94,3 +97,64 @@ func TestSteamServerCircuitBreaker(t *testing.T) {
 	err := interceptor(nil, nil, &grpc.StreamServerInfo{FullMethod: "/test"}, handler)
 	assert.Error(t, err)
 }
+
+func TestCircuitBreakerPolicy(t *testing.T) {
+	m := policy.NewManager()
+	err := m.Update(&policy.Config{CircuitBreakers: []policy.CircuitBreaker{{Route: "/api.user.v1.User/*", Request: 10}}})
+	assert.NoError(t, err)
+
+	o := defaultCircuitBreakerOptions()
+	o.apply(WithCircuitBreakerPolicy(m))
+	assert.True(t, o.getBreaker("/api.user.v1.User/GetByID") == m.Breaker("/api.user.v1.User/GetByID"))
+	assert.True(t, o.getBreaker("/test") == o.group.Get("/test"))
+
+	interceptor := UnaryServerCircuitBreaker(WithCircuitBreakerPolicy(m))
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/api.user.v1.User/GetByID"}, unaryServerHandler)
+	assert.NoError(t, err)
+}
+
+func TestCircuitBreakerClassic(t *testing.T) {
+	interceptor := UnaryClientCircuitBreaker(WithBreaker(func() circuitbreaker.CircuitBreaker {
+		return circuitbreaker.NewClassicBreaker(
+			circuitbreaker.WithConsecutiveFailures(3),
+			circuitbreaker.WithOpenDuration(50*time.Millisecond),
+		)
+	}))
+
+	ivoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return errcode.StatusInternalServerError.ToRPCErr()
+	}
+	for i := 0; i < 3; i++ {
+		err := interceptor(context.Background(), "/test", nil, nil, nil, ivoker)
+		assert.Equal(t, codes.Internal, status.Code(err))
+	}
+	err := interceptor(context.Background(), "/test", nil, nil, nil, ivoker)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// the probe succeeds, the breaker is closed
+	time.Sleep(60 * time.Millisecond)
+	ivoker = func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		err = interceptor(context.Background(), "/test", nil, nil, nil, ivoker)
+		assert.NoError(t, err)
+	}
+}
+
+func TestCircuitBreakerPanic(t *testing.T) {
+	interceptor := UnaryServerCircuitBreaker(WithBreaker(func() circuitbreaker.CircuitBreaker {
+		return circuitbreaker.NewClassicBreaker(circuitbreaker.WithConsecutiveFailures(3))
+	}))
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("test panic")
+	}
+	for i := 0; i < 3; i++ {
+		assert.Panics(t, func() {
+			_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test"}, handler)
+		})
+	}
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test"}, handler)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
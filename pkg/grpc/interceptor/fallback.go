@@ -0,0 +1,55 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/zhufuyi/sponge/pkg/shield/fallback"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// FallbackFunc handle the unary call rejected by circuit breaker or rate limiter instead of the error,
+// e.g. return cached value, static default or the reply of alternative backend, err is ErrNotAllowed or ErrLimitExceed.
+// on the client side, the returned value is copied to reply, it must be the same type as reply.
+type FallbackFunc func(ctx context.Context, method string, req interface{}, err error) (interface{}, error)
+
+// call the fallback function matched by method, return rpcErr if no fallback function is registered
+func callFallback(ctx context.Context, fallbacks *fallback.Registry[FallbackFunc],
+	method string, req interface{}, err error, rpcErr error) (interface{}, error) {
+	fn, ok := fallbacks.Get(method)
+	if !ok {
+		return nil, rpcErr
+	}
+	return fn(ctx, method, req, err)
+}
+
+// copy the value returned by the fallback function to reply of client
+func setReply(reply interface{}, value interface{}) error {
+	if value == nil || reply == nil {
+		return nil
+	}
+
+	if dst, ok := reply.(proto.Message); ok {
+		if src, ok := value.(proto.Message); ok &&
+			dst.ProtoReflect().Descriptor().FullName() == src.ProtoReflect().Descriptor().FullName() {
+			proto.Reset(dst)
+			proto.Merge(dst, src)
+			return nil
+		}
+	}
+
+	dst, src := reflect.ValueOf(reply), reflect.ValueOf(value)
+	if dst.Kind() == reflect.Ptr && !dst.IsNil() {
+		if src.Type() == dst.Type() && !src.IsNil() {
+			dst.Elem().Set(src.Elem())
+			return nil
+		}
+		if src.Type().AssignableTo(dst.Elem().Type()) {
+			dst.Elem().Set(src)
+			return nil
+		}
+	}
+	return fmt.Errorf("fallback: the type of value %T does not match the type of reply %T", value, reply)
+}
@@ -0,0 +1,87 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zhufuyi/sponge/pkg/errcode"
+	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	rl "github.com/zhufuyi/sponge/pkg/shield/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type rejectLimiter struct{}
+
+func (rejectLimiter) Allow(ctx context.Context, key string) (*rl.Result, error) {
+	return &rl.Result{Allowed: false, Limit: 1}, nil
+}
+
+func TestUnaryClientCircuitBreakerFallback(t *testing.T) {
+	interceptor := UnaryClientCircuitBreaker(
+		WithBreaker(func() circuitbreaker.CircuitBreaker {
+			return circuitbreaker.NewClassicBreaker(circuitbreaker.WithConsecutiveFailures(1))
+		}),
+		WithCircuitBreakerFallback("/api.user.v1.User/*", func(ctx context.Context, method string, req interface{}, err error) (interface{}, error) {
+			assert.True(t, errors.Is(err, ErrNotAllowed))
+			return wrapperspb.String("default"), nil
+		}),
+	)
+
+	ivoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return errcode.StatusInternalServerError.ToRPCErr()
+	}
+	for _, method := range []string{"/api.user.v1.User/GetByID", "/test"} {
+		err := interceptor(context.Background(), method, nil, &wrapperspb.StringValue{}, nil, ivoker)
+		assert.Equal(t, codes.Internal, status.Code(err))
+	}
+
+	// the breaker is open
+	reply := &wrapperspb.StringValue{}
+	err := interceptor(context.Background(), "/api.user.v1.User/GetByID", nil, reply, nil, ivoker)
+	assert.NoError(t, err)
+	assert.Equal(t, "default", reply.Value)
+
+	err = interceptor(context.Background(), "/test", nil, reply, nil, ivoker)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestUnaryServerRateLimitFallback(t *testing.T) {
+	interceptor := UnaryServerRateLimit(
+		WithKeyLimiter(rejectLimiter{}),
+		WithKeyFunc(KeyByMethod()),
+		WithRateLimitFallback("/api.user.v1.User/GetByID", func(ctx context.Context, method string, req interface{}, err error) (interface{}, error) {
+			assert.True(t, errors.Is(err, ErrLimitExceed))
+			return "cached", nil
+		}),
+	)
+
+	reply, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/api.user.v1.User/GetByID"}, unaryServerHandler)
+	assert.NoError(t, err)
+	assert.Equal(t, "cached", reply)
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test"}, unaryServerHandler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func Test_setReply(t *testing.T) {
+	reply := &wrapperspb.StringValue{Value: "old"}
+	assert.NoError(t, setReply(reply, wrapperspb.String("new")))
+	assert.Equal(t, "new", reply.Value)
+	assert.NoError(t, setReply(reply, nil))
+
+	type user struct{ Name string }
+	u := &user{}
+	assert.NoError(t, setReply(u, &user{Name: "foo"}))
+	assert.Equal(t, "foo", u.Name)
+	assert.NoError(t, setReply(u, user{Name: "bar"}))
+	assert.Equal(t, "bar", u.Name)
+
+	assert.Error(t, setReply(u, wrapperspb.String("foo")))
+	assert.Error(t, setReply(reply, wrapperspb.Int64(1)))
+}
@@ -2,12 +2,20 @@ package interceptor
 
 import (
 	"context"
+	"math"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/zhufuyi/sponge/pkg/errcode"
+	"github.com/zhufuyi/sponge/pkg/shield/fallback"
+	shieldmetrics "github.com/zhufuyi/sponge/pkg/shield/metrics"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
 	rl "github.com/zhufuyi/sponge/pkg/shield/ratelimit"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
 // ---------------------------------- server interceptor ----------------------------------
@@ -24,6 +32,11 @@ type ratelimitOptions struct {
 	bucket       int
 	cpuThreshold int64
 	cpuQuota     float64
+
+	keyLimiter rl.KeyLimiter
+	keyFn      RateLimitKeyFunc
+	policy     *policy.Manager
+	fallbacks  *fallback.Registry[FallbackFunc]
 }
 
 func defaultRatelimitOptions() *ratelimitOptions {
@@ -31,6 +44,8 @@ func defaultRatelimitOptions() *ratelimitOptions {
 		window:       time.Second * 10,
 		bucket:       100,
 		cpuThreshold: 800,
+		keyFn:        KeyByPeerIP(),
+		fallbacks:    fallback.NewRegistry[FallbackFunc](),
 	}
 }
 
@@ -68,21 +83,129 @@ func WithCPUQuota(quota float64) RatelimitOption {
 	}
 }
 
-// UnaryServerRateLimit server-side unary circuit breaker interceptor
+// WithKeyLimiter use the rate limiter by key instead of the adaptive rate limiter,
+// e.g. rl.NewTokenBucket, rl.NewSlidingWindow, the key is extracted by WithKeyFunc, default is peer ip.
+func WithKeyLimiter(limiter rl.KeyLimiter) RatelimitOption {
+	return func(o *ratelimitOptions) {
+		o.keyLimiter = limiter
+	}
+}
+
+// WithKeyFunc set the function to extract the key of rate limiter by key
+func WithKeyFunc(fn RateLimitKeyFunc) RatelimitOption {
+	return func(o *ratelimitOptions) {
+		if fn != nil {
+			o.keyFn = fn
+		}
+	}
+}
+
+// WithRateLimitPolicy use the rate limit policies of methods, the methods not matched by the policies are limited by default,
+// the policies can be updated at runtime, e.g. policy.Get().
+func WithRateLimitPolicy(m *policy.Manager) RatelimitOption {
+	return func(o *ratelimitOptions) {
+		o.policy = m
+	}
+}
+
+// WithRateLimitFallback set the fallback function of method when the unary call is rejected by the limiter,
+// if method ends with *, match the methods with the prefix, e.g. /api.user.v1.User/*
+func WithRateLimitFallback(method string, fn FallbackFunc) RatelimitOption {
+	return func(o *ratelimitOptions) {
+		if fn != nil {
+			o.fallbacks.Register(method, fn)
+		}
+	}
+}
+
+// RateLimitKeyFunc extract the key of rate limiter by key from request, the request is not limited if the key is empty
+type RateLimitKeyFunc func(ctx context.Context, fullMethod string) string
+
+// KeyByPeerIP use the ip of client as key
+func KeyByPeerIP() RateLimitKeyFunc {
+	return func(ctx context.Context, fullMethod string) string {
+		p, ok := peer.FromContext(ctx)
+		if !ok || p.Addr == nil {
+			return ""
+		}
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			return p.Addr.String()
+		}
+		return host
+	}
+}
+
+// KeyByMetadata use the value of incoming metadata as key, e.g. x-api-key
+func KeyByMetadata(name string) RateLimitKeyFunc {
+	return func(ctx context.Context, fullMethod string) string {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return ""
+		}
+		values := md.Get(name)
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+}
+
+// KeyByMethod use the full method name as key, limit the total requests of each method
+func KeyByMethod() RateLimitKeyFunc {
+	return func(ctx context.Context, fullMethod string) string {
+		return fullMethod
+	}
+}
+
+// UnaryServerRateLimit server-side unary circuit breaker interceptor, if WithKeyLimiter is set, limit requests by key,
+// if WithRateLimitPolicy is set, the methods matched by the policies are limited by the policies.
 func UnaryServerRateLimit(opts ...RatelimitOption) grpc.UnaryServerInterceptor {
 	o := defaultRatelimitOptions()
 	o.apply(opts...)
+
+	interceptor := unaryServerDefaultRateLimit(o)
+	if o.policy == nil {
+		return interceptor
+	}
+	shieldmetrics.RegisterPolicy(o.policy)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		limiter := o.policy.Limiter(info.FullMethod)
+		if limiter == nil {
+			return interceptor(ctx, req, info, handler)
+		}
+
+		done, result, err := limiter.Allow(ctx, o.keyFn(ctx, info.FullMethod))
+		if result != nil {
+			_ = grpc.SetHeader(ctx, rateLimitMD(result))
+		}
+		if err != nil {
+			return callFallback(ctx, o.fallbacks, info.FullMethod, req, err, errcode.StatusLimitExceed.ToRPCErr(err.Error()))
+		}
+
+		reply, err := handler(ctx, req)
+		done(rl.DoneInfo{Err: err})
+		return reply, err
+	}
+}
+
+func unaryServerDefaultRateLimit(o *ratelimitOptions) grpc.UnaryServerInterceptor {
+	if o.keyLimiter != nil {
+		return unaryServerKeyRateLimit(o)
+	}
 	limiter := rl.NewLimiter(
 		rl.WithWindow(o.window),
 		rl.WithBucket(o.bucket),
 		rl.WithCPUThreshold(o.cpuThreshold),
 		rl.WithCPUQuota(o.cpuQuota),
 	)
+	shieldmetrics.ReplaceLimiter("grpc_server", "unary", limiter)
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 		done, err := limiter.Allow()
 		if err != nil {
-			return nil, errcode.StatusLimitExceed.ToRPCErr(err.Error())
+			return callFallback(ctx, o.fallbacks, info.FullMethod, req, err, errcode.StatusLimitExceed.ToRPCErr(err.Error()))
 		}
 
 		reply, err := handler(ctx, req)
@@ -91,16 +214,50 @@ func UnaryServerRateLimit(opts ...RatelimitOption) grpc.UnaryServerInterceptor {
 	}
 }
 
-// StreamServerRateLimit server-side stream circuit breaker interceptor
+// StreamServerRateLimit server-side stream circuit breaker interceptor, if WithKeyLimiter is set, limit requests by key,
+// if WithRateLimitPolicy is set, the methods matched by the policies are limited by the policies.
 func StreamServerRateLimit(opts ...RatelimitOption) grpc.StreamServerInterceptor {
 	o := defaultRatelimitOptions()
 	o.apply(opts...)
+
+	interceptor := streamServerDefaultRateLimit(o)
+	if o.policy == nil {
+		return interceptor
+	}
+	shieldmetrics.RegisterPolicy(o.policy)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		limiter := o.policy.Limiter(info.FullMethod)
+		if limiter == nil {
+			return interceptor(srv, ss, info, handler)
+		}
+
+		ctx := ss.Context()
+		done, result, err := limiter.Allow(ctx, o.keyFn(ctx, info.FullMethod))
+		if result != nil {
+			_ = ss.SetHeader(rateLimitMD(result))
+		}
+		if err != nil {
+			return errcode.StatusLimitExceed.ToRPCErr(err.Error())
+		}
+
+		err = handler(srv, ss)
+		done(rl.DoneInfo{Err: err})
+		return err
+	}
+}
+
+func streamServerDefaultRateLimit(o *ratelimitOptions) grpc.StreamServerInterceptor {
+	if o.keyLimiter != nil {
+		return streamServerKeyRateLimit(o.keyLimiter, o.keyFn)
+	}
 	limiter := rl.NewLimiter(
 		rl.WithWindow(o.window),
 		rl.WithBucket(o.bucket),
 		rl.WithCPUThreshold(o.cpuThreshold),
 		rl.WithCPUQuota(o.cpuQuota),
 	)
+	shieldmetrics.ReplaceLimiter("grpc_server", "stream", limiter)
 
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		done, err := limiter.Allow()
@@ -113,3 +270,64 @@ func StreamServerRateLimit(opts ...RatelimitOption) grpc.StreamServerInterceptor
 		return err
 	}
 }
+
+func unaryServerKeyRateLimit(o *ratelimitOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		fullMethod := ""
+		if info != nil {
+			fullMethod = info.FullMethod
+		}
+		result := allowByKey(ctx, o.keyLimiter, o.keyFn(ctx, fullMethod))
+		if result != nil {
+			_ = grpc.SetHeader(ctx, rateLimitMD(result))
+			if !result.Allowed {
+				return callFallback(ctx, o.fallbacks, fullMethod, req, ErrLimitExceed, errcode.StatusLimitExceed.ToRPCErr(ErrLimitExceed.Error()))
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func streamServerKeyRateLimit(limiter rl.KeyLimiter, keyFn RateLimitKeyFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		fullMethod := ""
+		if info != nil {
+			fullMethod = info.FullMethod
+		}
+		ctx := ss.Context()
+		result := allowByKey(ctx, limiter, keyFn(ctx, fullMethod))
+		if result != nil {
+			_ = ss.SetHeader(rateLimitMD(result))
+			if !result.Allowed {
+				return errcode.StatusLimitExceed.ToRPCErr(ErrLimitExceed.Error())
+			}
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// return nil if the request is not limited, the request passes if the key is empty or the limiter is unavailable
+func allowByKey(ctx context.Context, limiter rl.KeyLimiter, key string) *rl.Result {
+	if key == "" {
+		return nil
+	}
+	result, err := limiter.Allow(ctx, key)
+	if err != nil {
+		return nil
+	}
+	return result
+}
+
+func rateLimitMD(result *rl.Result) metadata.MD {
+	md := metadata.Pairs(
+		"x-ratelimit-limit", strconv.Itoa(result.Limit),
+		"x-ratelimit-remaining", strconv.Itoa(result.Remaining),
+		"x-ratelimit-reset", strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds()))),
+	)
+	if !result.Allowed {
+		md.Set("retry-after", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+	}
+	return md
+}
@@ -2,11 +2,19 @@ package interceptor
 
 import (
 	"context"
+	"net"
 	"testing"
 	"time"
 
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
+	rl "github.com/zhufuyi/sponge/pkg/shield/ratelimit"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
 func TestUnaryServerRateLimit(t *testing.T) {
@@ -35,3 +43,103 @@ func TestStreamServerRateLimit(t *testing.T) {
 	err := interceptor(nil, nil, nil, handler)
 	assert.NoError(t, err)
 }
+
+func TestUnaryServerKeyRateLimit(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+
+	limiter, err := rl.NewTokenBucket(client, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := UnaryServerRateLimit(
+		WithKeyLimiter(limiter),
+		WithKeyFunc(KeyByMetadata("x-api-key")),
+	)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "foo"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/api.user.v1.User/GetByID"}
+
+	for i := 0; i < 2; i++ {
+		_, err = interceptor(ctx, nil, info, unaryServerHandler)
+		assert.NoError(t, err)
+	}
+	_, err = interceptor(ctx, nil, info, unaryServerHandler)
+	assert.Error(t, err)
+
+	// empty key is not limited
+	for i := 0; i < 3; i++ {
+		_, err = interceptor(context.Background(), nil, info, unaryServerHandler)
+		assert.NoError(t, err)
+	}
+
+	// let the request pass if redis is unavailable
+	s.Close()
+	_, err = interceptor(ctx, nil, info, unaryServerHandler)
+	assert.NoError(t, err)
+}
+
+func TestStreamServerKeyRateLimit(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+
+	limiter, err := rl.NewSlidingWindow(client, 1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := StreamServerRateLimit(
+		WithKeyLimiter(limiter),
+		WithKeyFunc(KeyByMethod()),
+	)
+	ss := newStreamServer(context.Background())
+	err = interceptor(nil, ss, streamServerInfo, streamServerHandler)
+	assert.NoError(t, err)
+	err = interceptor(nil, ss, streamServerInfo, streamServerHandler)
+	assert.Error(t, err)
+}
+
+func TestRateLimitKeyFunc(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8282}})
+	assert.Equal(t, "127.0.0.1", KeyByPeerIP()(ctx, ""))
+	assert.Equal(t, "", KeyByPeerIP()(context.Background(), ""))
+	assert.Equal(t, "", KeyByMetadata("x-api-key")(ctx, ""))
+	assert.Equal(t, "/test", KeyByMethod()(ctx, "/test"))
+}
+
+func TestRateLimitPolicy(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	m := policy.NewManager(policy.WithRedisClient(redis.NewClient(&redis.Options{Addr: s.Addr()})))
+	err = m.Update(&policy.Config{RateLimits: []policy.RateLimit{
+		{Route: "/api.user.v1.User/*", Type: policy.LimiterTokenBucket, Rate: 1, Burst: 1},
+	}})
+	assert.NoError(t, err)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8282}})
+
+	unaryInterceptor := UnaryServerRateLimit(WithRateLimitPolicy(m))
+	info := &grpc.UnaryServerInfo{FullMethod: "/api.user.v1.User/GetByID"}
+	_, err = unaryInterceptor(ctx, nil, info, unaryServerHandler)
+	assert.NoError(t, err)
+	_, err = unaryInterceptor(ctx, nil, info, unaryServerHandler)
+	assert.Error(t, err)
+	// not matched by the policies
+	_, err = unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test"}, unaryServerHandler)
+	assert.NoError(t, err)
+
+	streamInterceptor := StreamServerRateLimit(WithRateLimitPolicy(m))
+	err = streamInterceptor(nil, newStreamServer(ctx), &grpc.StreamServerInfo{FullMethod: "/api.user.v1.User/List"}, streamServerHandler)
+	assert.Error(t, err)
+	err = streamInterceptor(nil, newStreamServer(ctx), streamServerInfo, streamServerHandler)
+	assert.NoError(t, err)
+}
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/zhufuyi/sponge/pkg/krand"
+	"github.com/zhufuyi/sponge/pkg/requestid"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
 	"go.uber.org/zap"
@@ -25,6 +26,7 @@ func SetContextRequestIDKey(key string) {
 	}
 	once.Do(func() {
 		ContextRequestIDKey = key
+		requestid.ContextKey = key
 	})
 }
 
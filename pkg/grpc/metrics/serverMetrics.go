@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"sync"
 
+	shieldmetrics "github.com/zhufuyi/sponge/pkg/shield/metrics"
+
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
@@ -90,6 +92,9 @@ func srvRegisterMetrics() {
 		// register metrics to capture, custom metrics also need to be registered
 		srvReg.MustRegister(grpcServerMetrics)
 
+		// register the metrics of rate limiters and circuit breakers
+		srvReg.MustRegister(shieldmetrics.Collector())
+
 		// register custom Counter metrics
 		for _, metric := range customizedCounterMetrics {
 			srvReg.MustRegister(metric)
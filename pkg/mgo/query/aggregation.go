@@ -0,0 +1,237 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// AggCount count of documents, count all documents when the column is empty or *
+	AggCount = "count"
+	// AggSum sum of the column values
+	AggSum = "sum"
+	// AggAvg average of the column values
+	AggAvg = "avg"
+	// AggMin minimum of the column values
+	AggMin = "min"
+	// AggMax maximum of the column values
+	AggMax = "max"
+)
+
+var aggFuncMap = map[string]string{
+	AggCount: "$sum",
+	AggSum:   "$sum",
+	AggAvg:   "$avg",
+	AggMin:   "$min",
+	AggMax:   "$max",
+}
+
+var identifierRegexp = regexp.MustCompile(`^[a-zA-Z_]\w*$`)
+
+// Aggregate aggregate function of the column
+type Aggregate struct {
+	Func   string `json:"func" form:"func"`             // aggregate function, have count, sum, avg, min, max
+	Column string `json:"column" form:"column"`         // column name, count all documents when func is count and column is empty
+	Alias  string `json:"alias,omitempty" form:"alias"` // name of the result, default is func_column, e.g. sum_age, count
+}
+
+// the name of the result
+func (a *Aggregate) name() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+	if a.Column == "" || a.Column == "*" {
+		return strings.ToLower(a.Func)
+	}
+	return strings.ToLower(a.Func) + "_" + a.Column
+}
+
+func (a *Aggregate) checkValid() error {
+	if _, ok := aggFuncMap[strings.ToLower(a.Func)]; !ok {
+		return fmt.Errorf("unknown aggregate function '%s'", a.Func)
+	}
+	if a.Column == "" || a.Column == "*" {
+		if strings.ToLower(a.Func) != AggCount {
+			return fmt.Errorf("the column of aggregate function '%s' cannot be empty", a.Func)
+		}
+	} else if !identifierRegexp.MatchString(a.Column) {
+		return fmt.Errorf("invalid aggregate column '%s'", a.Column)
+	}
+	if name := a.name(); !identifierRegexp.MatchString(name) || name == "id" || name == oidName {
+		return fmt.Errorf("invalid aggregate alias '%s'", name)
+	}
+	return nil
+}
+
+// the accumulator of $group, e.g. {"$sum": 1}, {"$avg": "$age"}
+func (a *Aggregate) accumulator() bson.M {
+	fn := strings.ToLower(a.Func)
+	if fn == AggCount {
+		if a.Column == "" || a.Column == "*" {
+			return bson.M{"$sum": 1}
+		}
+		// count the documents whose column is not null or missing
+		return bson.M{"$sum": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$" + a.Column, nil}}, nil}}, 0, 1,
+		}}}
+	}
+	return bson.M{aggFuncMap[fn]: "$" + a.Column}
+}
+
+// Aggregation aggregate query parameters, e.g. count the documents and average the age of each gender,
+// groupBy=["gender"], aggregates=[{"func":"count"},{"func":"avg","column":"age"}], having: count > 10, sort=-count
+type Aggregation struct {
+	Columns    []Column    `json:"columns,omitempty" form:"columns"`             // query conditions before grouping, not required
+	GroupBy    []string    `json:"groupBy,omitempty" form:"groupBy"`             // group by columns, not required
+	Aggregates []Aggregate `json:"aggregates" form:"aggregates" binding:"min=1"` // aggregate functions
+	Having     []Column    `json:"having,omitempty" form:"having"`               // conditions of aggregate results, the name is the alias of aggregate, not required
+	Sort       string      `json:"sort,omitempty" form:"sort"`                   // sort by group by columns or aliases, e.g. -count,gender, not required
+	Limit      int         `json:"limit,omitempty" form:"limit" binding:"gte=0"` // max number of results, 0 means no limit
+}
+
+// names of the result columns, group by column names and aggregate aliases
+func (a *Aggregation) resultNames() map[string]bool {
+	names := map[string]bool{}
+	for _, name := range a.GroupBy {
+		names[name] = true
+	}
+	for _, agg := range a.Aggregates {
+		names[agg.name()] = true
+	}
+	return names
+}
+
+// CheckValid check valid
+func (a *Aggregation) CheckValid() error {
+	if len(a.Aggregates) == 0 {
+		return fmt.Errorf("field 'aggregates' cannot be empty")
+	}
+	if a.Limit < 0 {
+		return fmt.Errorf("field 'limit' cannot be negative")
+	}
+
+	names := map[string]bool{}
+	for _, name := range a.GroupBy {
+		if !identifierRegexp.MatchString(name) || name == oidName {
+			return fmt.Errorf("invalid group by column '%s'", name)
+		}
+		names[name] = true
+	}
+	for _, agg := range a.Aggregates {
+		if err := agg.checkValid(); err != nil {
+			return err
+		}
+		if names[agg.name()] {
+			return fmt.Errorf("duplicate aggregate alias '%s'", agg.name())
+		}
+		names[agg.name()] = true
+	}
+
+	if err := checkColumns(a.Columns); err != nil {
+		return err
+	}
+	if err := checkColumns(a.Having); err != nil {
+		return err
+	}
+	if err := checkHavingNames(a.Having, a.resultNames()); err != nil {
+		return err
+	}
+	return a.checkSort()
+}
+
+func (a *Aggregation) checkSort() error {
+	if strings.Replace(a.Sort, " ", "", -1) == "" {
+		return nil
+	}
+	names := a.resultNames()
+	for _, e := range getSort(a.Sort) {
+		if !names[e.Key] {
+			return fmt.Errorf("sort column '%s' is not a group by column or aggregate alias", e.Key)
+		}
+	}
+	return nil
+}
+
+func checkHavingNames(columns []Column, names map[string]bool) error {
+	for _, column := range columns {
+		if column.isGroup() {
+			if err := checkHavingNames(column.Columns, names); err != nil {
+				return err
+			}
+			continue
+		}
+		if !names[column.Name] {
+			return fmt.Errorf("having column '%s' is not a group by column or aggregate alias", column.Name)
+		}
+	}
+	return nil
+}
+
+// ConvertToMongoPipeline conversion to the stages of mongo aggregation pipeline: $match, $group, $project, $match (having), $sort, $limit,
+// the group by columns are flattened into the result documents, e.g. {"gender": 1, "count": 10, "avg_age": 20.5}
+func (a *Aggregation) ConvertToMongoPipeline() ([]bson.M, error) {
+	if err := a.CheckValid(); err != nil {
+		return nil, err
+	}
+
+	var pipeline []bson.M
+
+	if len(a.Columns) > 0 {
+		p := &Params{Columns: copyColumns(a.Columns)}
+		filter, err := p.ConvertToMongoFilter()
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, bson.M{"$match": filter})
+	}
+
+	var groupID interface{}
+	project := bson.M{oidName: 0}
+	if len(a.GroupBy) > 0 {
+		id := bson.M{}
+		for _, name := range a.GroupBy {
+			id[name] = "$" + name
+			project[name] = "$" + oidName + "." + name
+		}
+		groupID = id
+	}
+	group := bson.M{oidName: groupID}
+	for _, agg := range a.Aggregates {
+		group[agg.name()] = agg.accumulator()
+		project[agg.name()] = 1
+	}
+	pipeline = append(pipeline, bson.M{"$group": group}, bson.M{"$project": project})
+
+	if len(a.Having) > 0 {
+		p := &Params{Columns: copyColumns(a.Having)}
+		filter, err := p.ConvertToMongoFilter()
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, bson.M{"$match": filter})
+	}
+
+	if strings.Replace(a.Sort, " ", "", -1) != "" {
+		pipeline = append(pipeline, bson.M{"$sort": getSort(a.Sort)})
+	}
+	if a.Limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": a.Limit})
+	}
+
+	return pipeline, nil
+}
+
+// the conversion of filter modifies the columns
+func copyColumns(columns []Column) []Column {
+	newColumns := make([]Column, 0, len(columns))
+	for _, column := range columns {
+		if column.isGroup() {
+			column.Columns = copyColumns(column.Columns)
+		}
+		newColumns = append(newColumns, column)
+	}
+	return newColumns
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAggregation_ConvertToMongoPipeline(t *testing.T) {
+	a := &Aggregation{
+		Columns: []Column{{Name: "status", Value: 1}},
+		GroupBy: []string{"gender"},
+		Aggregates: []Aggregate{
+			{Func: "count"},
+			{Func: "count", Column: "email"},
+			{Func: "avg", Column: "age"},
+			{Func: "max", Column: "age", Alias: "oldest"},
+		},
+		Having: []Column{{Name: "count", Exp: ">", Value: 10}},
+		Sort:   "-count, gender",
+		Limit:  10,
+	}
+	pipeline, err := a.ConvertToMongoPipeline()
+	assert.NoError(t, err)
+	assert.Equal(t, []bson.M{
+		{"$match": bson.M{"status": 1}},
+		{"$group": bson.M{
+			"_id":   bson.M{"gender": "$gender"},
+			"count": bson.M{"$sum": 1},
+			"count_email": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$email", nil}}, nil}}, 0, 1,
+			}}},
+			"avg_age": bson.M{"$avg": "$age"},
+			"oldest":  bson.M{"$max": "$age"},
+		}},
+		{"$project": bson.M{"_id": 0, "gender": "$_id.gender", "count": 1, "count_email": 1, "avg_age": 1, "oldest": 1}},
+		{"$match": bson.M{"count": bson.M{"$gt": 10}}},
+		{"$sort": bson.D{primitive.E{Key: "count", Value: -1}, primitive.E{Key: "gender", Value: 1}}},
+		{"$limit": 10},
+	}, pipeline)
+	// the input is not modified
+	assert.Equal(t, ">", a.Having[0].Exp)
+
+	// without group by and conditions
+	a = &Aggregation{Aggregates: []Aggregate{{Func: "SUM", Column: "age"}}}
+	pipeline, err = a.ConvertToMongoPipeline()
+	assert.NoError(t, err)
+	assert.Equal(t, []bson.M{
+		{"$group": bson.M{"_id": nil, "sum_age": bson.M{"$sum": "$age"}}},
+		{"$project": bson.M{"_id": 0, "sum_age": 1}},
+	}, pipeline)
+}
+
+func TestAggregation_CheckValid(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *Aggregation
+	}{
+		{"empty aggregates", &Aggregation{}},
+		{"negative limit", &Aggregation{Aggregates: []Aggregate{{Func: "count"}}, Limit: -1}},
+		{"unknown func", &Aggregation{Aggregates: []Aggregate{{Func: "median", Column: "age"}}}},
+		{"empty column", &Aggregation{Aggregates: []Aggregate{{Func: "sum"}}}},
+		{"invalid column", &Aggregation{Aggregates: []Aggregate{{Func: "sum", Column: "$age"}}}},
+		{"invalid alias", &Aggregation{Aggregates: []Aggregate{{Func: "count", Alias: "_id"}}}},
+		{"invalid group by", &Aggregation{GroupBy: []string{"a.b"}, Aggregates: []Aggregate{{Func: "count"}}}},
+		{"duplicate alias", &Aggregation{GroupBy: []string{"age"}, Aggregates: []Aggregate{{Func: "count", Alias: "age"}}}},
+		{"invalid sort", &Aggregation{Aggregates: []Aggregate{{Func: "count"}}, Sort: "age"}},
+		{"invalid columns", &Aggregation{Aggregates: []Aggregate{{Func: "count"}}, Columns: []Column{{Name: "age"}}}},
+		{"invalid having", &Aggregation{Aggregates: []Aggregate{{Func: "count"}}, Having: []Column{{Name: "age", Value: 1}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.a.CheckValid())
+			_, err := tt.a.ConvertToMongoPipeline()
+			assert.Error(t, err)
+		})
+	}
+}
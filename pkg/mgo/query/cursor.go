@@ -0,0 +1,275 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// keyset pagination, the next page is queried by the values of the sort fields of the last document of current page,
+// the values are encoded into an opaque cursor, the client passes the cursor back as is to get the next page.
+// Note: the combination of sort fields must be unique, e.g. -created_at,-id, otherwise documents with the same values may be skipped.
+
+const (
+	valueTypeInt      = "i"
+	valueTypeUint     = "u"
+	valueTypeFloat    = "f"
+	valueTypeBool     = "b"
+	valueTypeString   = "s"
+	valueTypeTime     = "t"
+	valueTypeObjectID = "o"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v"`
+}
+
+type cursorInfo struct {
+	Sort   string        `json:"s"`
+	Values []cursorValue `json:"v"`
+}
+
+// EncodeCursor encode the values of the sort fields into a cursor, the values must be in the same order as the sort fields
+func EncodeCursor(sort string, values ...interface{}) (string, error) {
+	fields := getSort(sort)
+	if len(fields) != len(values) {
+		return "", fmt.Errorf("the number of values %d does not match the number of sort fields %d", len(values), len(fields))
+	}
+
+	info := cursorInfo{Sort: strings.Replace(sort, " ", "", -1)}
+	for i, value := range values {
+		cv, err := toCursorValue(value)
+		if err != nil {
+			return "", fmt.Errorf("field '%s' %v", fields[i].Key, err)
+		}
+		info.Values = append(info.Values, cv)
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor decode the cursor into the values of the sort fields, the sort must be the same as the sort when encoding
+func DecodeCursor(sort string, cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	info := cursorInfo{}
+	if err = json.Unmarshal(data, &info); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	if info.Sort != strings.Replace(sort, " ", "", -1) {
+		return nil, fmt.Errorf("cursor does not match the sort '%s'", sort)
+	}
+	if len(info.Values) != len(getSort(sort)) {
+		return nil, errors.New("invalid cursor")
+	}
+
+	values := make([]interface{}, 0, len(info.Values))
+	for _, cv := range info.Values {
+		value, err := fromCursorValue(cv)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func toCursorValue(value interface{}) (cursorValue, error) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return cursorValue{}, errors.New("value is null, cannot be used as cursor")
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if dt, ok := rv.Interface().(primitive.DateTime); ok {
+			return cursorValue{Type: valueTypeTime, Value: dt.Time().Format(time.RFC3339Nano)}, nil
+		}
+		return cursorValue{Type: valueTypeInt, Value: strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cursorValue{Type: valueTypeUint, Value: strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return cursorValue{Type: valueTypeFloat, Value: strconv.FormatFloat(rv.Float(), 'g', -1, 64)}, nil
+	case reflect.Bool:
+		return cursorValue{Type: valueTypeBool, Value: strconv.FormatBool(rv.Bool())}, nil
+	case reflect.String:
+		return cursorValue{Type: valueTypeString, Value: rv.String()}, nil
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return cursorValue{Type: valueTypeTime, Value: rv.Interface().(time.Time).Format(time.RFC3339Nano)}, nil
+		}
+	case reflect.Array:
+		if rv.Type() == objectIDType {
+			return cursorValue{Type: valueTypeObjectID, Value: rv.Interface().(primitive.ObjectID).Hex()}, nil
+		}
+	case reflect.Invalid:
+		return cursorValue{}, errors.New("value is null, cannot be used as cursor")
+	}
+
+	return cursorValue{}, fmt.Errorf("unsupported value type %s", rv.Type())
+}
+
+func fromCursorValue(cv cursorValue) (interface{}, error) {
+	var (
+		value interface{}
+		err   error
+	)
+	switch cv.Type {
+	case valueTypeInt:
+		value, err = strconv.ParseInt(cv.Value, 10, 64)
+	case valueTypeUint:
+		value, err = strconv.ParseUint(cv.Value, 10, 64)
+	case valueTypeFloat:
+		value, err = strconv.ParseFloat(cv.Value, 64)
+	case valueTypeBool:
+		value, err = strconv.ParseBool(cv.Value)
+	case valueTypeString:
+		value = cv.Value
+	case valueTypeTime:
+		value, err = time.Parse(time.RFC3339Nano, cv.Value)
+	case valueTypeObjectID:
+		value, err = primitive.ObjectIDFromHex(cv.Value)
+	default:
+		err = fmt.Errorf("unknown type '%s'", cv.Type)
+	}
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return value, nil
+}
+
+// get the values of the fields from the document, document is a struct or map, the field name of a struct field is
+// the name in bson tag, if not set, it is the lowercase of the field name.
+func getFieldValues(document interface{}, sort bson.D) ([]interface{}, error) {
+	rv := reflect.ValueOf(document)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("document is nil")
+		}
+		rv = rv.Elem()
+	}
+
+	fields := map[string]interface{}{}
+	switch rv.Kind() {
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			fields[fmt.Sprintf("%v", iter.Key().Interface())] = iter.Value().Interface()
+		}
+	case reflect.Struct:
+		getStructFields(rv, fields)
+	default:
+		return nil, fmt.Errorf("unsupported document type %s", rv.Type())
+	}
+
+	values := make([]interface{}, 0, len(sort))
+	for _, e := range sort {
+		value, ok := fields[e.Key]
+		if !ok {
+			return nil, fmt.Errorf("field '%s' not found in document", e.Key)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func getStructFields(rv reflect.Value, fields map[string]interface{}) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("bson")
+		if tag == "-" {
+			continue
+		}
+		ss := strings.Split(tag, ",")
+		if field.Anonymous || strings.Contains(tag, "inline") {
+			fv := rv.Field(i)
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+				getStructFields(fv, fields)
+				continue
+			}
+		}
+
+		name := strings.ToLower(field.Name)
+		if ss[0] != "" {
+			name = ss[0]
+		}
+		fields[name] = rv.Field(i).Interface()
+	}
+}
+
+// ConvertToCursorFilter converted to the mongo filter of keyset pagination based on the sort and cursor parameters,
+// if the cursor is empty, the returned filter is empty.
+// e.g. sort=-created_at,-id, filter: {"$or": [{"created_at": {"$lt": v1}}, {"created_at": v1, "_id": {"$lt": v2}}]}
+func (p *Params) ConvertToCursorFilter() (bson.M, error) {
+	if p.Cursor == "" {
+		return bson.M{}, nil
+	}
+
+	values, err := DecodeCursor(p.Sort, p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	sort := getSort(p.Sort)
+
+	ors := make([]bson.M, 0, len(sort))
+	for i, e := range sort {
+		m := bson.M{}
+		for j := 0; j < i; j++ {
+			m[sort[j].Key] = values[j]
+		}
+		if e.Value == -1 {
+			m[e.Key] = bson.M{"$lt": values[i]}
+		} else {
+			m[e.Key] = bson.M{"$gt": values[i]}
+		}
+		ors = append(ors, m)
+	}
+
+	if len(ors) == 1 {
+		return ors[0], nil
+	}
+	return bson.M{"$or": ors}, nil
+}
+
+// NextCursor get the cursor of the next page from the last document of current page, document is a struct or map,
+// return empty if document is nil.
+func (p *Params) NextCursor(document interface{}) (string, error) {
+	if document == nil {
+		return "", nil
+	}
+	values, err := getFieldValues(document, getSort(p.Sort))
+	if err != nil {
+		return "", err
+	}
+	return EncodeCursor(p.Sort, values...)
+}
@@ -0,0 +1,87 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type cursorModel struct {
+	Model `bson:",inline"`
+	Name  string `bson:"name"`
+	Age   *int
+}
+
+type Model struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+func TestCursor(t *testing.T) {
+	now := time.Now()
+	oid := primitive.NewObjectID()
+	cursor, err := EncodeCursor("-created_at, -id", now, oid)
+	assert.NoError(t, err)
+
+	values, err := DecodeCursor("-created_at,-id", cursor)
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(values[0].(time.Time)))
+	assert.Equal(t, oid, values[1])
+
+	_, err = DecodeCursor("-id", cursor)
+	assert.Error(t, err)
+	_, err = DecodeCursor("-created_at,-id", "abc")
+	assert.Error(t, err)
+	_, err = EncodeCursor("-created_at,-id", now)
+	assert.Error(t, err)
+	_, err = EncodeCursor("name", []int{1})
+	assert.Error(t, err)
+}
+
+func TestParams_NextCursor(t *testing.T) {
+	age := 18
+	oid := primitive.NewObjectID()
+	record := &cursorModel{Model: Model{ID: oid, CreatedAt: time.Now()}, Name: "foo", Age: &age}
+
+	p := &Params{Size: 10, Sort: "name,-age,-id"}
+	cursor, err := p.NextCursor(record)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cursor)
+
+	p.Cursor = cursor
+	filter, err := p.ConvertToCursorFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$or": []bson.M{
+		{"name": bson.M{"$gt": "foo"}},
+		{"name": "foo", "age": bson.M{"$lt": int64(18)}},
+		{"name": "foo", "age": int64(18), "_id": bson.M{"$lt": oid}},
+	}}, filter)
+	_, _, skip := p.ConvertToPage()
+	assert.Equal(t, 0, skip)
+
+	// default sort
+	p = &Params{}
+	cursor, err = p.NextCursor(bson.M{"_id": oid})
+	assert.NoError(t, err)
+	p.Cursor = cursor
+	filter, err = p.ConvertToCursorFilter()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"_id": bson.M{"$lt": oid}}, filter)
+
+	// error
+	p = &Params{Sort: "unknown"}
+	_, err = p.NextCursor(record)
+	assert.Error(t, err)
+	p = &Params{Sort: "age"}
+	_, err = p.NextCursor(&cursorModel{})
+	assert.Error(t, err)
+	cursor, err = p.NextCursor(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, cursor)
+	p = &Params{Sort: "-id", Cursor: "abc"}
+	_, err = p.ConvertToCursorFilter()
+	assert.Error(t, err)
+}
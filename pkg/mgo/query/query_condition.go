@@ -3,6 +3,7 @@ package query
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -32,6 +33,18 @@ const (
 	Like = "like"
 	// In include
 	In = "in"
+	// NotIn not include
+	NotIn = "notin"
+	// Between between two values, including the two values
+	Between = "between"
+	// IsNull is null, the value is ignored
+	IsNull = "isnull"
+	// NotNull is not null, the value is ignored
+	NotNull = "notnull"
+	// PrefixLike fuzzy lookup by prefix
+	PrefixLike = "prefix"
+	// SuffixLike fuzzy lookup by suffix
+	SuffixLike = "suffix"
 
 	// AND logic and
 	AND        string = "and" //nolint
@@ -44,6 +57,9 @@ const (
 
 	allLogicAnd = 1
 	allLogicOr  = 2
+
+	// JSONPathSep separator of the field name and the path of embedded document, e.g. attrs->color, attrs->size.width
+	JSONPathSep = "->"
 )
 
 var expMap = map[string]string{
@@ -61,8 +77,17 @@ var expMap = map[string]string{
 	lteSymbol: lteSymbol,
 	Like:      Like,
 	In:        In,
+
+	NotIn:      NotIn,
+	Between:    Between,
+	IsNull:     IsNull,
+	NotNull:    NotNull,
+	PrefixLike: PrefixLike,
+	SuffixLike: SuffixLike,
 }
 
+var jsonKeyRegexp = regexp.MustCompile(`^\w+$`)
+
 var logicMap = map[string]string{
 	AND:        andSymbol1,
 	andSymbol1: andSymbol1,
@@ -78,27 +103,58 @@ type Params struct {
 	Size int    `json:"size" form:"size" binding:"gt=0"`
 	Sort string `json:"sort,omitempty" form:"sort" binding:""`
 
+	// cursor of keyset pagination, returned by the previous page, if not empty, the page is ignored
+	Cursor string `json:"cursor,omitempty" form:"cursor" binding:""`
+
 	Columns []Column `json:"columns,omitempty" form:"columns"` // not required
 }
 
 // Column query info
 type Column struct {
-	Name  string      `json:"name" form:"name"`   // column name
-	Exp   string      `json:"exp" form:"exp"`     // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like, in
+	Name  string      `json:"name" form:"name"`   // column name, the embedded document supports path, e.g. attrs->size.width
+	Exp   string      `json:"exp" form:"exp"`     // expressions, which default to = when the value is null, have =, !=, >, >=, <, <=, like, in, notin, between, isnull, notnull, prefix, suffix
 	Value interface{} `json:"value" form:"value"` // column value
 	Logic string      `json:"logic" form:"logic"` // logical type, defaults to and when the value is null, with &(and), ||(or)
+
+	// sub conditions in parentheses, if not empty, the name, exp and value are ignored, e.g. (a=1 OR b=2) AND c>3
+	Columns []Column `json:"columns,omitempty" form:"columns"`
+
+	filter bson.M // filter of sub conditions
+}
+
+func (c *Column) isGroup() bool {
+	return len(c.Columns) > 0
 }
 
 func (c *Column) checkValid() error {
+	if c.isGroup() {
+		for _, column := range c.Columns {
+			if err := column.checkValid(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	if c.Name == "" {
 		return fmt.Errorf("field 'name' cannot be empty")
 	}
 	if c.Value == nil {
-		return fmt.Errorf("field 'value' cannot be nil")
+		if exp := strings.ToLower(c.Exp); exp != IsNull && exp != NotNull {
+			return fmt.Errorf("field 'value' cannot be nil")
+		}
 	}
 	return nil
 }
 
+// the mongo filter of the converted column
+func (c *Column) toFilter() bson.M {
+	if c.isGroup() {
+		return c.filter
+	}
+	return bson.M{c.Name: c.Value}
+}
+
 func (c *Column) convertLogic() error {
 	if c.Logic == "" {
 		c.Logic = AND
@@ -116,6 +172,25 @@ func (c *Column) convert() error {
 		return err
 	}
 
+	if c.isGroup() {
+		p := &Params{Columns: c.Columns}
+		filter, err := p.ConvertToMongoFilter()
+		if err != nil {
+			return err
+		}
+		c.filter = filter
+		return c.convertLogic()
+	}
+
+	if strings.Contains(c.Name, JSONPathSep) {
+		for _, key := range strings.Split(strings.Replace(c.Name, JSONPathSep, ".", 1), ".") {
+			if !jsonKeyRegexp.MatchString(key) {
+				return fmt.Errorf("invalid json path '%s'", c.Name)
+			}
+		}
+		c.Name = strings.Replace(c.Name, JSONPathSep, ".", 1)
+	}
+
 	if c.Name == "id" || c.Name == "_id" {
 		if str, ok := c.Value.(string); ok {
 			c.Name = "_id"
@@ -147,17 +222,37 @@ func (c *Column) convert() error {
 			c.Value = bson.M{"$lte": c.Value}
 		case Like:
 			c.Value = bson.M{"$regex": fmt.Sprintf("%v", c.Value)}
-		case In:
-			val, ok := c.Value.(string)
+		case PrefixLike:
+			c.Value = bson.M{"$regex": "^" + regexp.QuoteMeta(fmt.Sprintf("%v", c.Value))}
+		case SuffixLike:
+			c.Value = bson.M{"$regex": regexp.QuoteMeta(fmt.Sprintf("%v", c.Value)) + "$"}
+		case In, NotIn:
+			values, ok := c.Value.([]interface{})
 			if !ok {
-				return fmt.Errorf("invalid value type '%s'", c.Value)
+				val, ok := c.Value.(string)
+				if !ok {
+					return fmt.Errorf("invalid value type '%s'", c.Value)
+				}
+				ss := strings.Split(val, ",")
+				for _, s := range ss {
+					values = append(values, s)
+				}
 			}
-			values := []interface{}{}
-			ss := strings.Split(val, ",")
-			for _, s := range ss {
-				values = append(values, s)
+			if c.Exp == In {
+				c.Value = bson.M{"$in": values}
+			} else {
+				c.Value = bson.M{"$nin": values}
+			}
+		case Between:
+			values, err := toBetweenValues(c.Value)
+			if err != nil {
+				return err
 			}
-			c.Value = bson.M{"$in": values}
+			c.Value = bson.M{"$gte": values[0], "$lte": values[1]}
+		case IsNull:
+			c.Value = nil
+		case NotNull:
+			c.Value = bson.M{"$ne": nil}
 		}
 	} else {
 		return fmt.Errorf("unknown exp type '%s'", c.Exp)
@@ -166,12 +261,16 @@ func (c *Column) convert() error {
 	return c.convertLogic()
 }
 
-// ConvertToPage converted to conform to mongo rules based on the page size sort parameter
+// ConvertToPage converted to conform to mongo rules based on the page size sort parameter,
+// if the cursor is not empty, the skip is 0, use it with ConvertToCursorFilter.
 func (p *Params) ConvertToPage() (sort bson.D, limit int, skip int) { //nolint
 	page := NewPage(p.Page, p.Size, p.Sort)
 	sort = page.sort
 	limit = page.size
 	skip = page.page * page.size
+	if p.Cursor != "" {
+		skip = 0
+	}
 	return //nolint
 }
 
@@ -189,8 +288,7 @@ func (p *Params) ConvertToMongoFilter() (bson.M, error) {
 		if err != nil {
 			return nil, err
 		}
-		filter[p.Columns[0].Name] = p.Columns[0].Value
-		return filter, nil
+		return p.Columns[0].toFilter(), nil
 
 	case 2: // l == 2
 		err := p.Columns[0].convert()
@@ -203,12 +301,12 @@ func (p *Params) ConvertToMongoFilter() (bson.M, error) {
 		}
 		if p.Columns[0].Logic == andSymbol1 {
 			filter = bson.M{"$and": []bson.M{
-				{p.Columns[0].Name: p.Columns[0].Value},
-				{p.Columns[1].Name: p.Columns[1].Value}}}
+				p.Columns[0].toFilter(),
+				p.Columns[1].toFilter()}}
 		} else {
 			filter = bson.M{"$or": []bson.M{
-				{p.Columns[0].Name: p.Columns[0].Value},
-				{p.Columns[1].Name: p.Columns[1].Value}}}
+				p.Columns[0].toFilter(),
+				p.Columns[1].toFilter()}}
 		}
 		return filter, nil
 
@@ -230,10 +328,10 @@ func (p *Params) convertMultiColumns() (bson.M, error) {
 				return nil, err
 			}
 			if v, ok := filter["$and"]; !ok {
-				filter["$and"] = []bson.M{{column.Name: column.Value}}
+				filter["$and"] = []bson.M{column.toFilter()}
 			} else {
 				if cols, ok1 := v.([]bson.M); ok1 {
-					cols = append(cols, bson.M{column.Name: column.Value})
+					cols = append(cols, column.toFilter())
 					filter["$and"] = cols
 				}
 			}
@@ -246,10 +344,10 @@ func (p *Params) convertMultiColumns() (bson.M, error) {
 				return nil, err
 			}
 			if v, ok := filter["$or"]; !ok {
-				filter["$or"] = []bson.M{{column.Name: column.Value}}
+				filter["$or"] = []bson.M{column.toFilter()}
 			} else {
 				if cols, ok1 := v.([]bson.M); ok1 {
-					cols = append(cols, bson.M{column.Name: column.Value})
+					cols = append(cols, column.toFilter())
 					filter["$or"] = cols
 				}
 			}
@@ -264,7 +362,7 @@ func (p *Params) convertMultiColumns() (bson.M, error) {
 			if err != nil {
 				return nil, err
 			}
-			orConditions = append(orConditions, bson.M{column.Name: column.Value})
+			orConditions = append(orConditions, column.toFilter())
 		} else {
 			andConditions := []bson.M{}
 			for _, index := range indexes {
@@ -273,7 +371,7 @@ func (p *Params) convertMultiColumns() (bson.M, error) {
 				if err != nil {
 					return nil, err
 				}
-				andConditions = append(andConditions, bson.M{column.Name: column.Value})
+				andConditions = append(andConditions, column.toFilter())
 			}
 			orConditions = append(orConditions, bson.M{"$and": andConditions})
 		}
@@ -344,12 +442,20 @@ func (c *Conditions) CheckValid() error {
 		return fmt.Errorf("field 'columns' cannot be empty")
 	}
 
-	for _, column := range c.Columns {
+	return checkColumns(c.Columns)
+}
+
+func checkColumns(columns []Column) error {
+	for _, column := range columns {
 		err := column.checkValid()
 		if err != nil {
 			return err
 		}
-		if column.Exp != "" {
+		if column.isGroup() {
+			if err = checkColumns(column.Columns); err != nil {
+				return err
+			}
+		} else if column.Exp != "" {
 			if _, ok := expMap[column.Exp]; !ok {
 				return fmt.Errorf("unknown exp type '%s'", column.Exp)
 			}
@@ -370,3 +476,20 @@ func (c *Conditions) ConvertToMongo() (bson.M, error) {
 	p := &Params{Columns: c.Columns}
 	return p.ConvertToMongoFilter()
 }
+
+// the value of between is two values separated by comma or an array of two values
+func toBetweenValues(value interface{}) ([]interface{}, error) {
+	var values []interface{}
+	switch v := value.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			values = append(values, s)
+		}
+	case []interface{}:
+		values = v
+	}
+	if len(values) != 2 {
+		return nil, fmt.Errorf("invalid value '%v' of between, must be two values", value)
+	}
+	return values, nil
+}
@@ -486,6 +486,206 @@ func TestParams_ConvertToMongoFilter(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		// ------------------------------ operators ------------------------------------------
+		{
+			name: "notin and between",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Value: "LiSi,ZhangSan",
+						Exp:   NotIn,
+					},
+					{
+						Name:  "age",
+						Value: []interface{}{10, 20},
+						Exp:   Between,
+					},
+				},
+			},
+			want: bson.M{"$and": []bson.M{
+				{"name": bson.M{"$nin": []interface{}{"LiSi", "ZhangSan"}}},
+				{"age": bson.M{"$gte": 10, "$lte": 20}},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "isnull or notnull",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "deleted_at",
+						Exp:   IsNull,
+						Logic: OR,
+					},
+					{
+						Name: "email",
+						Exp:  NotNull,
+					},
+				},
+			},
+			want: bson.M{"$or": []bson.M{
+				{"deleted_at": nil},
+				{"email": bson.M{"$ne": nil}},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "prefix and suffix",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "name",
+						Value: "Zhang",
+						Exp:   PrefixLike,
+					},
+					{
+						Name:  "email",
+						Value: "@foo.com",
+						Exp:   SuffixLike,
+					},
+				},
+			},
+			want: bson.M{"$and": []bson.M{
+				{"name": bson.M{"$regex": "^Zhang"}},
+				{"email": bson.M{"$regex": "@foo\\.com$"}},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "json path",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "attrs->size.width",
+						Value: 10,
+						Exp:   Gt,
+					},
+				},
+			},
+			want:    bson.M{"attrs.size.width": bson.M{"$gt": 10}},
+			wantErr: false,
+		},
+
+		// ------------------------------ group ----------------------------------------------
+		{
+			name: "(a or b) and c",
+			args: args{
+				columns: []Column{
+					{
+						Columns: []Column{
+							{
+								Name:  "name",
+								Value: "LiSi",
+								Logic: OR,
+							},
+							{
+								Name:  "name",
+								Value: "ZhangSan",
+							},
+						},
+					},
+					{
+						Name:  "age",
+						Value: 20,
+						Exp:   Gt,
+					},
+				},
+			},
+			want: bson.M{"$and": []bson.M{
+				{"$or": []bson.M{{"name": "LiSi"}, {"name": "ZhangSan"}}},
+				{"age": bson.M{"$gt": 20}},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "a or (b and (c or d)) or e",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "gender",
+						Value: "female",
+						Logic: OR,
+					},
+					{
+						Columns: []Column{
+							{
+								Name:  "age",
+								Value: 20,
+								Exp:   Gt,
+							},
+							{
+								Columns: []Column{
+									{
+										Name:  "name",
+										Value: "Li",
+										Exp:   PrefixLike,
+										Logic: OR,
+									},
+									{
+										Name: "email",
+										Exp:  IsNull,
+									},
+								},
+							},
+						},
+						Logic: OR,
+					},
+					{
+						Name:  "name",
+						Value: "WangWu",
+					},
+				},
+			},
+			want: bson.M{"$or": []bson.M{
+				{"gender": "female"},
+				{"$and": []bson.M{
+					{"age": bson.M{"$gt": 20}},
+					{"$or": []bson.M{{"name": bson.M{"$regex": "^Li"}}, {"email": nil}}},
+				}},
+				{"name": "WangWu"},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "between value err",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "age",
+						Value: "10",
+						Exp:   Between,
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "json path err",
+			args: args{
+				columns: []Column{
+					{
+						Name:  "attrs->$where",
+						Value: 1,
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "group err",
+			args: args{
+				columns: []Column{
+					{
+						Columns: []Column{{Name: "age"}},
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "name empty",
 			args: args{
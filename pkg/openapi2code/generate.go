@@ -0,0 +1,588 @@
+package openapi2code
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Service operations grouped by tag
+type Service struct {
+	Name      string // User
+	LowerName string // user
+	Methods   []*Method
+}
+
+// RandNumber rand number 1~100
+func (s *Service) RandNumber() int {
+	return randNumber()
+}
+
+// Method operation
+type Method struct {
+	Name     string // GetUser
+	Comment  string // e.g. // GetUser get user detail
+	Request  string // GetUserRequest
+	Reply    string // GetUserReply
+	Path     string // gin path, e.g. /users/:id
+	Method   string // http method
+	Num      int    // handler number
+	BodyName string // request body field, bind the request body to this field when the body is not an object
+
+	HasPathParams  bool
+	HasQueryParams bool
+	HasBody        bool
+
+	ServiceName      string // User
+	LowerServiceName string // user
+}
+
+// HandlerName gin handler name
+func (m *Method) HandlerName() string {
+	return fmt.Sprintf("%s_%d", m.Name, m.Num)
+}
+
+// AddOne counter
+func (m *Method) AddOne(i int) int {
+	return i + 1
+}
+
+// goStruct go type definition
+type goStruct struct {
+	Name       string
+	Comment    string
+	Alias      string // type Name = Alias
+	Fields     []*goField
+	IsValidate bool // whether to generate Validate method
+}
+
+type goField struct {
+	Name    string
+	Type    string
+	Tag     string
+	Comment string
+}
+
+type generator struct {
+	doc         *Document
+	defaultName string
+	structs     []*goStruct
+	typeNames   map[string]bool
+	services    []*Service
+	methodNames map[string]int
+}
+
+func newGenerator(doc *Document, defaultServiceName string) *generator {
+	return &generator{
+		doc:         doc,
+		defaultName: defaultServiceName,
+		typeNames:   map[string]bool{},
+		methodNames: map[string]int{},
+	}
+}
+
+func (g *generator) parse() error {
+	// components schemas
+	for _, s := range g.doc.Components.Schemas {
+		g.typeNames[camelCase(s.Name)] = true
+	}
+	for _, s := range g.doc.Components.Schemas {
+		g.addSchemaType(camelCase(s.Name), s.Schema)
+	}
+
+	serviceMap := map[string]*Service{}
+	for _, item := range g.doc.Paths {
+		methods, operations := item.Operations()
+		for i, op := range operations {
+			serviceName := g.defaultName
+			if len(op.Tags) > 0 && op.Tags[0] != "" {
+				serviceName = camelCase(op.Tags[0])
+			}
+			service, ok := serviceMap[serviceName]
+			if !ok {
+				service = &Service{Name: serviceName, LowerName: lowerFirst(serviceName)}
+				serviceMap[serviceName] = service
+				g.services = append(g.services, service)
+			}
+			m, err := g.parseOperation(service, item, methods[i], op)
+			if err != nil {
+				return err
+			}
+			service.Methods = append(service.Methods, m)
+		}
+	}
+	if len(g.services) == 0 {
+		return fmt.Errorf("no operations found in openapi document")
+	}
+
+	return nil
+}
+
+func (g *generator) parseOperation(service *Service, item *PathItem, httpMethod string, op *Operation) (*Method, error) {
+	name := camelCase(op.OperationID)
+	if name == "" {
+		name = methodNameFromPath(httpMethod, item.Path)
+	}
+	if n := g.methodNames[name]; n > 0 {
+		g.methodNames[name]++
+		name += strconv.Itoa(n + 1)
+	} else {
+		g.methodNames[name] = 1
+	}
+
+	m := &Method{
+		Name:             name,
+		Comment:          methodComment(name, httpMethod, item.Path, op),
+		Request:          g.uniqueTypeName(name + "Request"),
+		Reply:            g.uniqueTypeName(name + "Reply"),
+		Path:             ginPath(item.Path),
+		Method:           httpMethod,
+		ServiceName:      service.Name,
+		LowerServiceName: service.LowerName,
+	}
+
+	// request
+	req := &goStruct{Name: m.Request, Comment: fmt.Sprintf("// %s request params", m.Request), IsValidate: true}
+	params := map[string]*Parameter{}
+	var paramNames []string
+	for _, p := range append(item.Parameters, op.Parameters...) {
+		p = g.doc.resolveParameter(p)
+		key := p.In + ":" + p.Name
+		if _, ok := params[key]; !ok {
+			paramNames = append(paramNames, key)
+		}
+		params[key] = p // operation parameters override path item parameters
+	}
+	for _, key := range paramNames {
+		p := params[key]
+		field := &goField{
+			Name:    camelCase(p.Name),
+			Type:    g.goType(p.Schema, m.Request, p.Name),
+			Comment: comment(p.Description),
+		}
+		validateTag := validateTag(g.doc.resolveSchema(p.Schema), p.Required || p.In == "path")
+		switch p.In {
+		case "path":
+			m.HasPathParams = true
+			field.Tag = fmt.Sprintf(`uri:"%s" form:"-" json:"-"%s`, p.Name, validateTag)
+		case "query":
+			m.HasQueryParams = true
+			field.Tag = fmt.Sprintf(`form:"%s" json:"-"%s`, p.Name, validateTag)
+		default: // header and cookie parameters need to be obtained manually
+			field.Tag = fmt.Sprintf(`form:"-" json:"-"`)
+			field.Comment = strings.TrimSpace(fmt.Sprintf("in %s, obtain it manually. %s", p.In, p.Description))
+		}
+		req.Fields = append(req.Fields, field)
+	}
+
+	if body := g.doc.resolveRequestBody(op.RequestBody); body != nil && isBodyMethod(httpMethod) {
+		schema := jsonSchema(body.Content)
+		m.HasBody = schema != nil
+		resolved := g.resolveObject(schema)
+		switch {
+		case schema == nil:
+		case schema.Ref != "" && resolved != nil:
+			req.Fields = append(req.Fields, &goField{Name: camelCase(refName(schema.Ref)), Comment: "request body"})
+		case resolved != nil:
+			req.Fields = append(req.Fields, g.structFields(m.Request, resolved)...)
+		default:
+			m.BodyName = "Body"
+			req.Fields = append(req.Fields, &goField{
+				Name:    m.BodyName,
+				Type:    g.goType(schema, m.Request, "body"),
+				Tag:     `form:"-" json:"-"`,
+				Comment: "request body",
+			})
+		}
+	}
+	g.addStruct(req)
+
+	// reply, the first 2xx response
+	reply := &goStruct{Name: m.Reply, Comment: fmt.Sprintf("// %s reply", m.Reply)}
+	if schema := jsonSchema(g.successResponse(op)); schema != nil {
+		if resolved := g.resolveObject(schema); resolved != nil && schema.Ref == "" {
+			reply.Fields = g.structFields(m.Reply, resolved)
+		} else {
+			reply.Alias = strings.TrimPrefix(g.goType(schema, m.Reply, ""), "*")
+		}
+	}
+	g.addStruct(reply)
+
+	return m, nil
+}
+
+func (g *generator) successResponse(op *Operation) map[string]*MediaType {
+	var codes []string
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if resp := g.doc.resolveResponse(op.Responses[code]); resp != nil && len(resp.Content) > 0 {
+			return resp.Content
+		}
+	}
+	return nil
+}
+
+func (g *generator) addSchemaType(name string, schema *Schema) {
+	if obj := g.resolveObject(schema); obj != nil && schema.Ref == "" {
+		g.addStruct(&goStruct{
+			Name:    name,
+			Comment: typeComment(name, schema.Description, "schema"),
+			Fields:  g.structFields(name, obj),
+		})
+		return
+	}
+	g.addStruct(&goStruct{
+		Name:    name,
+		Comment: typeComment(name, schema.Description, "schema"),
+		Alias:   strings.TrimPrefix(g.goType(schema, name, ""), "*"),
+	})
+}
+
+func (g *generator) addStruct(s *goStruct) {
+	g.typeNames[s.Name] = true
+	g.structs = append(g.structs, s)
+}
+
+// resolveObject get the object schema, allOf is merged into one object, return nil if it is not an object
+func (g *generator) resolveObject(schema *Schema) *Schema {
+	s := g.doc.resolveSchema(schema)
+	if s == nil {
+		return nil
+	}
+	if len(s.AllOf) > 0 {
+		merged := &Schema{Type: "object", Description: s.Description}
+		for _, sub := range s.AllOf {
+			obj := g.resolveObject(sub)
+			if obj == nil {
+				continue
+			}
+			merged.Properties = append(merged.Properties, obj.Properties...)
+			merged.Required = append(merged.Required, obj.Required...)
+		}
+		return merged
+	}
+	if len(s.Properties) > 0 || (s.Type == "object" && s.AdditionalProperties.Kind == 0) {
+		return s
+	}
+	return nil
+}
+
+func (g *generator) structFields(structName string, obj *Schema) []*goField {
+	required := map[string]bool{}
+	for _, name := range obj.Required {
+		required[name] = true
+	}
+
+	var fields []*goField
+	for _, p := range obj.Properties {
+		fields = append(fields, &goField{
+			Name:    camelCase(p.Name),
+			Type:    g.goType(p.Schema, structName, p.Name),
+			Tag:     fmt.Sprintf(`json:"%s"%s`, p.Name, validateTag(g.doc.resolveSchema(p.Schema), required[p.Name])),
+			Comment: comment(p.Schema.Description),
+		})
+	}
+	return fields
+}
+
+// goType convert schema to go type, the inline object is defined as a new struct named parentName+fieldName
+func (g *generator) goType(schema *Schema, parentName string, fieldName string) string {
+	if schema == nil {
+		return "interface{}"
+	}
+	if schema.Ref != "" {
+		name := camelCase(refName(schema.Ref))
+		if g.resolveObject(schema) != nil {
+			return "*" + name
+		}
+		return name
+	}
+	if len(schema.AllOf) == 1 {
+		return g.goType(schema.AllOf[0], parentName, fieldName)
+	}
+
+	if obj := g.resolveObject(schema); obj != nil {
+		name := g.uniqueTypeName(parentName + camelCase(fieldName))
+		g.addStruct(&goStruct{
+			Name:    name,
+			Comment: typeComment(name, schema.Description, "object of field "+fieldName+" in "+parentName),
+			Fields:  g.structFields(name, obj),
+		})
+		return "*" + name
+	}
+
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer":
+		if schema.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(schema.Items, parentName, fieldName+"Item")
+	case "object":
+		if schema.AdditionalProperties.Kind == yaml.MappingNode {
+			valueSchema := &Schema{}
+			if err := schema.AdditionalProperties.Decode(valueSchema); err == nil {
+				return "map[string]" + g.goType(valueSchema, parentName, fieldName+"Value")
+			}
+		}
+		return "map[string]interface{}"
+	}
+
+	return "interface{}"
+}
+
+func (g *generator) uniqueTypeName(name string) string {
+	newName := name
+	for i := 2; g.typeNames[newName]; i++ {
+		newName = name + strconv.Itoa(i)
+	}
+	g.typeNames[newName] = true
+	return newName
+}
+
+// validateTag convert the schema constraints to validator tag
+func validateTag(schema *Schema, required bool) string {
+	var rules []string
+	if required && (schema == nil || schema.Type != "boolean") {
+		rules = append(rules, "required")
+	}
+
+	if schema != nil {
+		switch schema.Type {
+		case "string":
+			if schema.MinLength != nil {
+				rules = append(rules, fmt.Sprintf("min=%d", *schema.MinLength))
+			}
+			if schema.MaxLength != nil {
+				rules = append(rules, fmt.Sprintf("max=%d", *schema.MaxLength))
+			}
+			switch schema.Format {
+			case "email", "uuid", "uri", "ipv4", "ipv6", "hostname":
+				rules = append(rules, schema.Format)
+			}
+		case "integer", "number":
+			if schema.Minimum != nil {
+				rule := "gte"
+				if isExclusive(schema.ExclusiveMinimum) {
+					rule = "gt"
+				}
+				rules = append(rules, rule+"="+formatFloat(*schema.Minimum))
+			} else if v, ok := exclusiveValue(schema.ExclusiveMinimum); ok {
+				rules = append(rules, "gt="+v)
+			}
+			if schema.Maximum != nil {
+				rule := "lte"
+				if isExclusive(schema.ExclusiveMaximum) {
+					rule = "lt"
+				}
+				rules = append(rules, rule+"="+formatFloat(*schema.Maximum))
+			} else if v, ok := exclusiveValue(schema.ExclusiveMaximum); ok {
+				rules = append(rules, "lt="+v)
+			}
+		case "array":
+			if schema.MinItems != nil {
+				rules = append(rules, fmt.Sprintf("min=%d", *schema.MinItems))
+			}
+			if schema.MaxItems != nil {
+				rules = append(rules, fmt.Sprintf("max=%d", *schema.MaxItems))
+			}
+		}
+
+		if len(schema.Enum) > 0 {
+			var values []string
+			for _, v := range schema.Enum {
+				if v.Value != "" && !strings.ContainsAny(v.Value, " ,|") {
+					values = append(values, v.Value)
+				}
+			}
+			if len(values) == len(schema.Enum) {
+				rules = append(rules, "oneof="+strings.Join(values, " "))
+			}
+		}
+	}
+
+	if len(rules) == 0 {
+		return ""
+	}
+	if !required {
+		rules = append([]string{"omitempty"}, rules...)
+	}
+	return fmt.Sprintf(` validate:"%s"`, strings.Join(rules, ","))
+}
+
+// openapi 3.0 exclusiveMinimum is a bool
+func isExclusive(node yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Value == "true"
+}
+
+// openapi 3.1 exclusiveMinimum is a number
+func exclusiveValue(node yaml.Node) (string, bool) {
+	if node.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	if _, err := strconv.ParseFloat(node.Value, 64); err != nil {
+		return "", false
+	}
+	return node.Value, true
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func jsonSchema(content map[string]*MediaType) *Schema {
+	if len(content) == 0 {
+		return nil
+	}
+	if mt, ok := content["application/json"]; ok && mt != nil {
+		return mt.Schema
+	}
+	var types []string
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		if strings.Contains(t, "json") && content[t] != nil {
+			return content[t].Schema
+		}
+	}
+	return nil
+}
+
+// ginPath convert path parameters {id} --> :id
+func ginPath(path string) string {
+	ss := strings.Split(path, "/")
+	for i, s := range ss {
+		if len(s) > 2 && s[0] == '{' && s[len(s)-1] == '}' {
+			ss[i] = ":" + s[1:len(s)-1]
+		}
+	}
+	return strings.Join(ss, "/")
+}
+
+// methodNameFromPath e.g. GET /users/{id} --> GetUsersByID
+func methodNameFromPath(httpMethod string, path string) string {
+	name := camelCase(strings.ToLower(httpMethod))
+	var params []string
+	for _, s := range strings.Split(path, "/") {
+		if s == "" {
+			continue
+		}
+		if s[0] == '{' {
+			params = append(params, camelCase(strings.Trim(s, "{}")))
+			continue
+		}
+		name += camelCase(s)
+	}
+	if len(params) > 0 {
+		name += "By" + strings.Join(params, "And")
+	}
+	return name
+}
+
+func methodComment(name string, httpMethod string, path string, op *Operation) string {
+	desc := op.Summary
+	if desc == "" {
+		desc = op.Description
+	}
+	desc = lowerFirst(strings.Join(strings.Fields(desc), " "))
+	if desc == "" {
+		desc = httpMethod + " " + path
+	}
+	c := "// " + name + " " + desc
+	if op.Deprecated {
+		c += "\n// Deprecated: Do not use."
+	}
+	return c
+}
+
+// the fallback is used when the schema has no description
+func typeComment(name string, desc string, fallback string) string {
+	desc = strings.Join(strings.Fields(desc), " ")
+	if desc == "" {
+		desc = fallback
+	}
+	return "// " + name + " " + lowerFirst(desc)
+}
+
+func comment(desc string) string {
+	return strings.Join(strings.Fields(desc), " ")
+}
+
+var commonInitialisms = map[string]string{
+	"id": "ID", "ids": "IDs", "url": "URL", "uri": "URI", "uuid": "UUID", "api": "API",
+	"http": "HTTP", "ip": "IP", "json": "JSON", "sql": "SQL", "html": "HTML",
+}
+
+// camelCase convert to upper camel case, e.g. user_id --> UserID, get-user --> GetUser, listUsers --> ListUsers
+func camelCase(s string) string {
+	var words []string
+	var word []rune
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			words = append(words, string(word))
+			word = nil
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+
+	var b strings.Builder
+	for _, w := range words {
+		if v, ok := commonInitialisms[strings.ToLower(w)]; ok {
+			b.WriteString(v)
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	name := b.String()
+	if name != "" && unicode.IsDigit([]rune(name)[0]) {
+		name = "N" + name
+	}
+	return name
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	if len(s) > 1 && strings.ToUpper(s[:2]) == s[:2] {
+		return s // e.g. ID, URL
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func isBodyMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
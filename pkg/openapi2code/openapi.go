@@ -0,0 +1,263 @@
+package openapi2code
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document openapi 3 document, only the fields used for generating code are parsed
+type Document struct {
+	OpenAPI    string     `yaml:"openapi"`
+	Info       Info       `yaml:"info"`
+	Paths      Paths      `yaml:"paths"`
+	Components Components `yaml:"components"`
+
+	raw interface{} // original document
+}
+
+// Info document info
+type Info struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Version     string `yaml:"version"`
+}
+
+// Components reusable objects
+type Components struct {
+	Schemas       SchemaMap               `yaml:"schemas"`
+	Parameters    map[string]*Parameter   `yaml:"parameters"`
+	RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+	Responses     map[string]*Response    `yaml:"responses"`
+}
+
+// Paths path items in the order of the document
+type Paths []*PathItem
+
+// UnmarshalYAML keep the order of paths
+func (p *Paths) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return errors.New("paths must be an object")
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		item := &PathItem{}
+		if err := value.Content[i+1].Decode(item); err != nil {
+			return err
+		}
+		item.Path = value.Content[i].Value
+		*p = append(*p, item)
+	}
+	return nil
+}
+
+// PathItem operations of a path
+type PathItem struct {
+	Path       string       `yaml:"-"`
+	Parameters []*Parameter `yaml:"parameters"`
+	Get        *Operation   `yaml:"get"`
+	Put        *Operation   `yaml:"put"`
+	Post       *Operation   `yaml:"post"`
+	Delete     *Operation   `yaml:"delete"`
+	Patch      *Operation   `yaml:"patch"`
+}
+
+// Operations http method:operation, in the order of GET, POST, PUT, PATCH, DELETE
+func (p *PathItem) Operations() ([]string, []*Operation) {
+	var methods []string
+	var operations []*Operation
+	for _, v := range []struct {
+		method string
+		op     *Operation
+	}{
+		{"GET", p.Get}, {"POST", p.Post}, {"PUT", p.Put}, {"PATCH", p.Patch}, {"DELETE", p.Delete},
+	} {
+		if v.op != nil {
+			methods = append(methods, v.method)
+			operations = append(operations, v.op)
+		}
+	}
+	return methods, operations
+}
+
+// Operation api operation
+type Operation struct {
+	OperationID string               `yaml:"operationId"`
+	Summary     string               `yaml:"summary"`
+	Description string               `yaml:"description"`
+	Tags        []string             `yaml:"tags"`
+	Parameters  []*Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody         `yaml:"requestBody"`
+	Responses   map[string]*Response `yaml:"responses"`
+	Deprecated  bool                 `yaml:"deprecated"`
+}
+
+// Parameter operation parameter
+type Parameter struct {
+	Ref         string  `yaml:"$ref"`
+	Name        string  `yaml:"name"`
+	In          string  `yaml:"in"` // path, query, header, cookie
+	Description string  `yaml:"description"`
+	Required    bool    `yaml:"required"`
+	Schema      *Schema `yaml:"schema"`
+}
+
+// RequestBody request body
+type RequestBody struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Required    bool                  `yaml:"required"`
+	Content     map[string]*MediaType `yaml:"content"`
+}
+
+// Response operation response
+type Response struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Content     map[string]*MediaType `yaml:"content"`
+}
+
+// MediaType media type of content
+type MediaType struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+// Schema data type
+type Schema struct {
+	Ref                  string      `yaml:"$ref"`
+	Type                 SchemaType  `yaml:"type"`
+	Format               string      `yaml:"format"`
+	Description          string      `yaml:"description"`
+	Properties           SchemaMap   `yaml:"properties"`
+	Required             []string    `yaml:"required"`
+	Items                *Schema     `yaml:"items"`
+	AllOf                []*Schema   `yaml:"allOf"`
+	AdditionalProperties yaml.Node   `yaml:"additionalProperties"`
+	Enum                 []yaml.Node `yaml:"enum"`
+	Pattern              string      `yaml:"pattern"`
+	MinLength            *int        `yaml:"minLength"`
+	MaxLength            *int        `yaml:"maxLength"`
+	MinItems             *int        `yaml:"minItems"`
+	MaxItems             *int        `yaml:"maxItems"`
+	Minimum              *float64    `yaml:"minimum"`
+	Maximum              *float64    `yaml:"maximum"`
+	// openapi 3.0 is a bool, openapi 3.1 is a number
+	ExclusiveMinimum yaml.Node `yaml:"exclusiveMinimum"`
+	ExclusiveMaximum yaml.Node `yaml:"exclusiveMaximum"`
+}
+
+// SchemaType type of schema, openapi 3.1 type can be an array, e.g. [string, "null"]
+type SchemaType string
+
+// UnmarshalYAML support string and array
+func (t *SchemaType) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*t = SchemaType(value.Value)
+	case yaml.SequenceNode:
+		for _, node := range value.Content {
+			if node.Value != "null" {
+				*t = SchemaType(node.Value)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// NamedSchema schema with name
+type NamedSchema struct {
+	Name   string
+	Schema *Schema
+}
+
+// SchemaMap schemas in the order of the document
+type SchemaMap []*NamedSchema
+
+// UnmarshalYAML keep the order of schemas
+func (m *SchemaMap) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return errors.New("schemas must be an object")
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		schema := &Schema{}
+		if err := value.Content[i+1].Decode(schema); err != nil {
+			return err
+		}
+		*m = append(*m, &NamedSchema{Name: value.Content[i].Value, Schema: schema})
+	}
+	return nil
+}
+
+// Get schema by name
+func (m SchemaMap) Get(name string) *Schema {
+	for _, s := range m {
+		if s.Name == name {
+			return s.Schema
+		}
+	}
+	return nil
+}
+
+// ParseDocument parse openapi 3 document, support yaml and json
+func ParseDocument(data []byte) (*Document, error) {
+	doc := &Document{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("parse openapi document error: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version '%s', only openapi 3.x is supported", doc.OpenAPI)
+	}
+	if err := yaml.Unmarshal(data, &doc.raw); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// JSON the original document in json format
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d.raw, "", "  ")
+}
+
+// refName get the name of reference, e.g. #/components/schemas/User --> User
+func refName(ref string) string {
+	ss := strings.Split(ref, "/")
+	return ss[len(ss)-1]
+}
+
+// resolveSchema get the referenced schema
+func (d *Document) resolveSchema(s *Schema) *Schema {
+	for i := 0; s != nil && s.Ref != "" && i < 10; i++ {
+		s = d.Components.Schemas.Get(refName(s.Ref))
+	}
+	return s
+}
+
+func (d *Document) resolveParameter(p *Parameter) *Parameter {
+	if p.Ref != "" {
+		if v, ok := d.Components.Parameters[refName(p.Ref)]; ok {
+			return v
+		}
+	}
+	return p
+}
+
+func (d *Document) resolveRequestBody(b *RequestBody) *RequestBody {
+	if b != nil && b.Ref != "" {
+		if v, ok := d.Components.RequestBodies[refName(b.Ref)]; ok {
+			return v
+		}
+	}
+	return b
+}
+
+func (d *Document) resolveResponse(r *Response) *Response {
+	if r != nil && r.Ref != "" {
+		if v, ok := d.Components.Responses[refName(r.Ref)]; ok {
+			return v
+		}
+	}
+	return r
+}
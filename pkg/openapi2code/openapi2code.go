@@ -0,0 +1,158 @@
+// Package openapi2code generate gin router, handler, types and error code according to the openapi 3 document,
+// the layout of the generated code is the same as that generated by protoc-gen-go-gin from protobuf.
+package openapi2code
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const (
+	// CodeTypeTypes request and reply types, saved in api/<serverName>/v1/<name>_types.go
+	CodeTypeTypes = "types"
+	// CodeTypeRouter router registration code, saved in api/<serverName>/v1/<name>_router.go
+	CodeTypeRouter = "router"
+	// CodeTypeHandler handler logic template code, saved in internal/handler/<name>.go
+	CodeTypeHandler = "handler"
+	// CodeTypeHandlerRouter router code, saved in internal/routers/<name>_router.go
+	CodeTypeHandlerRouter = "handlerRouter"
+	// CodeTypeErrCode http error code, saved in internal/ecode/<name>_http.go
+	CodeTypeErrCode = "errCode"
+	// CodeTypeSwagger openapi document in json format, saved in docs/apis.swagger.json
+	CodeTypeSwagger = "swagger"
+)
+
+// Args generate code arguments
+type Args struct {
+	File string // openapi 3 document file, yaml or json
+	Data []byte // openapi 3 document content, the priority is higher than File
+
+	ModuleName  string // module name in go.mod
+	ServerName  string // server name
+	ServiceName string // service name of operations without tags, default is the file name
+}
+
+func (a *Args) checkValid() error {
+	if len(a.Data) == 0 && a.File == "" {
+		return errors.New("you must specify openapi document file or content")
+	}
+	if a.ModuleName == "" {
+		return errors.New("module name cannot be empty")
+	}
+	if a.ServerName == "" {
+		return errors.New("server name cannot be empty")
+	}
+	return nil
+}
+
+// GetFilenamePrefix get the file name prefix of the generated code, e.g. api/petstore.yaml --> petstore
+func (a *Args) GetFilenamePrefix() string {
+	if a.File == "" {
+		return lowerFirst(a.ServiceName)
+	}
+	name := filepath.Base(a.File)
+	return strings.ReplaceAll(strings.TrimSuffix(name, filepath.Ext(name)), ".", "_")
+}
+
+// Generate code according to the openapi 3 document, return map of code type and code content
+func Generate(args *Args) (map[string]string, error) {
+	if err := args.checkValid(); err != nil {
+		return nil, err
+	}
+
+	data := args.Data
+	if len(data) == 0 {
+		var err error
+		data, err = os.ReadFile(args.File)
+		if err != nil {
+			return nil, fmt.Errorf("read %s failed, %s", args.File, err)
+		}
+	}
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := args.GetFilenamePrefix()
+	serviceName := camelCase(args.ServiceName)
+	if serviceName == "" {
+		serviceName = camelCase(prefix)
+	}
+	if serviceName == "" {
+		serviceName = "Default"
+	}
+
+	g := newGenerator(doc, serviceName)
+	if err = g.parse(); err != nil {
+		return nil, err
+	}
+
+	source := filepath.Base(args.File)
+	if args.File == "" {
+		source = "openapi document"
+	}
+	tmplData := map[string]interface{}{
+		"Source":        source,
+		"Services":      g.services,
+		"Structs":       g.structs,
+		"HasValidate":   hasValidate(g.structs),
+		"ValidatorName": lowerFirst(camelCase(prefix)) + "Validator",
+	}
+
+	codes := make(map[string]string)
+	for codeType, tmpl := range map[string]*template.Template{
+		CodeTypeTypes:         typesTmpl,
+		CodeTypeRouter:        routerTmpl,
+		CodeTypeHandler:       handlerLogicTmpl,
+		CodeTypeHandlerRouter: handlerRouterTmpl,
+		CodeTypeErrCode:       httpErrCodeTmpl,
+	} {
+		code, err := executeTmpl(tmpl, tmplData)
+		if err != nil {
+			return nil, fmt.Errorf("generate %s code error: %v", codeType, err)
+		}
+		code = bytes.ReplaceAll(code, []byte("moduleNameExample"), []byte(args.ModuleName))
+		code = bytes.ReplaceAll(code, []byte("serverNameExample"), []byte(args.ServerName))
+		codes[codeType] = string(code)
+	}
+
+	swaggerJSON, err := doc.JSON()
+	if err != nil {
+		return nil, err
+	}
+	codes[CodeTypeSwagger] = string(swaggerJSON)
+
+	return codes, nil
+}
+
+func hasValidate(structs []*goStruct) bool {
+	for _, s := range structs {
+		if s.IsValidate {
+			return true
+		}
+	}
+	return false
+}
+
+var splitLineMark = []byte(`// ---------- Do not delete or move this split line, this is the merge code marker ----------`)
+
+func executeTmpl(tmpl *template.Template, data interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return nil, err
+	}
+	code := bytes.ReplaceAll(buf.Bytes(), []byte("// --blank line--"), []byte{})
+
+	// remove the last split line mark
+	if i := bytes.LastIndex(code, splitLineMark); i >= 0 {
+		code = append(code[:i:i], code[i+len(splitLineMark):]...)
+	}
+
+	return format.Source(code)
+}
@@ -0,0 +1,56 @@
+package openapi2code
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	codes, err := Generate(&Args{
+		File:       "testdata/petstore.yaml",
+		ModuleName: "github.com/foo/bar",
+		ServerName: "petstore",
+	})
+	assert.NoError(t, err)
+	for _, codeType := range []string{CodeTypeTypes, CodeTypeRouter, CodeTypeHandler, CodeTypeHandlerRouter, CodeTypeErrCode, CodeTypeSwagger} {
+		assert.NotEmpty(t, codes[codeType])
+	}
+	assert.Contains(t, codes[CodeTypeTypes], `validate:"omitempty,gte=1,lte=100"`)
+	assert.Contains(t, codes[CodeTypeRouter], `"/pets/:petId"`)
+	assert.Contains(t, codes[CodeTypeHandler], "ListPets(ctx context.Context, req *petstoreV1.ListPetsRequest) (*petstoreV1.ListPetsReply, error)")
+	for _, codeType := range []string{CodeTypeTypes, CodeTypeRouter, CodeTypeHandler} {
+		assert.NotContains(t, codes[codeType], "......") // no placeholder comments
+	}
+	t.Log(codes[CodeTypeTypes])
+	t.Log(codes[CodeTypeRouter])
+}
+
+func TestGenerateError(t *testing.T) {
+	_, err := Generate(&Args{})
+	assert.Error(t, err)
+
+	_, err = Generate(&Args{Data: []byte(`swagger: "2.0"`), ModuleName: "foo", ServerName: "bar"})
+	assert.Error(t, err)
+
+	_, err = Generate(&Args{File: "not_found.yaml", ModuleName: "foo", ServerName: "bar"})
+	assert.Error(t, err)
+
+	_, err = Generate(&Args{Data: []byte(`openapi: 3.0.0`), ModuleName: "foo", ServerName: "bar"})
+	assert.Error(t, err)
+}
+
+func Test_camelCase(t *testing.T) {
+	tests := map[string]string{
+		"user_id":    "UserID",
+		"get-user":   "GetUser",
+		"listUsers":  "ListUsers",
+		"HTTPServer": "HTTPServer",
+		"pets":       "Pets",
+		"1st":        "N1st",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, camelCase(in))
+	}
+	assert.Equal(t, "GetStoresTagsByStoreID", methodNameFromPath("GET", "/stores/{storeId}/tags"))
+}
@@ -0,0 +1,427 @@
+package openapi2code
+
+import (
+	"math/rand"
+	"text/template"
+	"time"
+)
+
+func init() {
+	typesTmpl = template.Must(template.New("types").Parse(typesTmplRaw))
+	routerTmpl = template.Must(template.New("router").Parse(routerTmplRaw))
+	handlerLogicTmpl = template.Must(template.New("handlerLogic").Parse(handlerLogicTmplRaw))
+	handlerRouterTmpl = template.Must(template.New("handlerRouter").Parse(handlerRouterTmplRaw))
+	httpErrCodeTmpl = template.Must(template.New("httpErrCode").Parse(httpErrCodeTmplRaw))
+
+	rand.Seed(time.Now().UnixNano())
+}
+
+func randNumber() int {
+	return rand.Intn(99) + 1
+}
+
+var (
+	typesTmpl    *template.Template
+	typesTmplRaw = `// Code generated by https://github.com/zhufuyi/sponge, DO NOT EDIT.
+// source: {{.Source}}
+
+package v1
+
+{{- if .HasValidate}}
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+var {{.ValidatorName}} = validator.New()
+{{- end}}
+
+{{- range .Structs}}
+
+{{.Comment}}
+{{- if .Alias}}
+type {{.Name}} = {{.Alias}}
+{{- else}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}} {{if .Tag}}` + "`{{.Tag}}`" + `{{end}} {{if .Comment}}// {{.Comment}}{{end}}
+{{- end}}
+}
+{{- end}}
+
+{{- if .IsValidate}}
+
+// Validate checks the field values on {{.Name}} with the rules defined in the openapi document
+func (m *{{.Name}}) Validate() error {
+	return {{$.ValidatorName}}.Struct(m)
+}
+{{- end}}
+{{- end}}
+`
+
+	routerTmpl    *template.Template
+	routerTmplRaw = `// Code generated by https://github.com/zhufuyi/sponge, DO NOT EDIT.
+// source: {{.Source}}
+
+package v1
+
+import (
+	"context"
+	"strings"
+
+	"github.com/zhufuyi/sponge/pkg/errcode"
+	"github.com/zhufuyi/sponge/pkg/gin/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+{{- range .Services}}
+
+type {{.Name}}Logicer interface {
+{{- range .Methods}}
+	{{.Name}}(ctx context.Context, req *{{.Request}}) (*{{.Reply}}, error)
+{{- end}}
+}
+
+type {{.Name}}Option func(*{{.LowerName}}Options)
+
+type {{.LowerName}}Options struct {
+	isFromRPC  bool
+	responser  errcode.Responser
+	zapLog     *zap.Logger
+	httpErrors []*errcode.Error
+	rpcStatus  []*errcode.RPCStatus
+	wrapCtxFn  func(c *gin.Context) context.Context
+}
+
+func (o *{{.LowerName}}Options) apply(opts ...{{.Name}}Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func With{{.Name}}HTTPResponse() {{.Name}}Option {
+	return func(o *{{.LowerName}}Options) {
+		o.isFromRPC = false
+	}
+}
+
+func With{{.Name}}RPCResponse() {{.Name}}Option {
+	return func(o *{{.LowerName}}Options) {
+		o.isFromRPC = true
+	}
+}
+
+func With{{.Name}}Responser(responser errcode.Responser) {{.Name}}Option {
+	return func(o *{{.LowerName}}Options) {
+		o.responser = responser
+	}
+}
+
+func With{{.Name}}Logger(zapLog *zap.Logger) {{.Name}}Option {
+	return func(o *{{.LowerName}}Options) {
+		o.zapLog = zapLog
+	}
+}
+
+func With{{.Name}}ErrorToHTTPCode(e ...*errcode.Error) {{.Name}}Option {
+	return func(o *{{.LowerName}}Options) {
+		o.httpErrors = e
+	}
+}
+
+func With{{.Name}}RPCStatusToHTTPCode(s ...*errcode.RPCStatus) {{.Name}}Option {
+	return func(o *{{.LowerName}}Options) {
+		o.rpcStatus = s
+	}
+}
+
+func With{{.Name}}WrapCtx(wrapCtxFn func(c *gin.Context) context.Context) {{.Name}}Option {
+	return func(o *{{.LowerName}}Options) {
+		o.wrapCtxFn = wrapCtxFn
+	}
+}
+
+func Register{{.Name}}Router(
+	iRouter gin.IRouter,
+	groupPathMiddlewares map[string][]gin.HandlerFunc,
+	singlePathMiddlewares map[string][]gin.HandlerFunc,
+	iLogic {{.Name}}Logicer,
+	opts ...{{.Name}}Option) {
+
+	o := &{{.LowerName}}Options{}
+	o.apply(opts...)
+
+	if o.responser == nil {
+		o.responser = errcode.NewResponser(o.isFromRPC, o.httpErrors, o.rpcStatus)
+	}
+	if o.zapLog == nil {
+		o.zapLog, _ = zap.NewProduction()
+	}
+
+	r := &{{.LowerName}}Router{
+		iRouter:               iRouter,
+		groupPathMiddlewares:  groupPathMiddlewares,
+		singlePathMiddlewares: singlePathMiddlewares,
+		iLogic:                iLogic,
+		iResponse:             o.responser,
+		zapLog:                o.zapLog,
+		wrapCtxFn:             o.wrapCtxFn,
+	}
+	r.register()
+}
+
+type {{.LowerName}}Router struct {
+	iRouter               gin.IRouter
+	groupPathMiddlewares  map[string][]gin.HandlerFunc
+	singlePathMiddlewares map[string][]gin.HandlerFunc
+	iLogic                {{.Name}}Logicer
+	iResponse             errcode.Responser
+	zapLog                *zap.Logger
+	wrapCtxFn             func(c *gin.Context) context.Context
+}
+
+func (r *{{.LowerName}}Router) register() {
+{{- range .Methods}}
+	r.iRouter.Handle("{{.Method}}", "{{.Path}}", r.withMiddleware("{{.Method}}", "{{.Path}}", r.{{.HandlerName}})...)
+{{- end}}
+}
+
+func (r *{{.LowerName}}Router) withMiddleware(method string, path string, fn gin.HandlerFunc) []gin.HandlerFunc {
+	handlerFns := []gin.HandlerFunc{}
+
+	// determine if a route group is hit or miss, left prefix rule
+	for groupPath, fns := range r.groupPathMiddlewares {
+		if groupPath == "" || groupPath == "/" {
+			handlerFns = append(handlerFns, fns...)
+			continue
+		}
+		size := len(groupPath)
+		if len(path) < size {
+			continue
+		}
+		if groupPath == path[:size] {
+			handlerFns = append(handlerFns, fns...)
+		}
+	}
+
+	// determine if a single route has been hit
+	key := strings.ToUpper(method) + "->" + path
+	if fns, ok := r.singlePathMiddlewares[key]; ok {
+		handlerFns = append(handlerFns, fns...)
+	}
+
+	return append(handlerFns, fn)
+}
+{{- $lowerName := .LowerName}}
+{{- range .Methods}}
+
+func (r *{{$lowerName}}Router) {{.HandlerName}}(c *gin.Context) {
+	req := &{{.Request}}{}
+	var err error
+{{- if .HasPathParams}}
+
+	if err = c.ShouldBindUri(req); err != nil {
+		r.zapLog.Warn("ShouldBindUri error", zap.Error(err), middleware.GCtxRequestIDField(c))
+		r.iResponse.ParamError(c, err)
+		return
+	}
+{{- end}}
+{{- if or .HasQueryParams (eq .Method "GET" "DELETE")}}
+
+	if err = c.ShouldBindQuery(req); err != nil {
+		r.zapLog.Warn("ShouldBindQuery error", zap.Error(err), middleware.GCtxRequestIDField(c))
+		r.iResponse.ParamError(c, err)
+		return
+	}
+{{- end}}
+{{- if .HasBody}}
+
+	if err = c.ShouldBindJSON({{if .BodyName}}&req.{{.BodyName}}{{else}}req{{end}}); err != nil {
+		r.zapLog.Warn("ShouldBindJSON error", zap.Error(err), middleware.GCtxRequestIDField(c))
+		r.iResponse.ParamError(c, err)
+		return
+	}
+{{- end}}
+
+	if err = req.Validate(); err != nil {
+		r.zapLog.Warn("Validate error", zap.Error(err), middleware.GCtxRequestIDField(c))
+		r.iResponse.ParamError(c, err)
+		return
+	}
+
+	var ctx context.Context
+	if r.wrapCtxFn != nil {
+		ctx = r.wrapCtxFn(c)
+	} else {
+		ctx = middleware.WrapCtx(c)
+	}
+
+	out, err := r.iLogic.{{.Name}}(ctx, req)
+	if err != nil {
+		r.iResponse.Error(c, err)
+		return
+	}
+
+	r.iResponse.Success(c, out)
+}
+{{- end}}
+{{- end}}
+`
+
+	handlerLogicTmpl    *template.Template
+	handlerLogicTmplRaw = `// Code generated by https://github.com/zhufuyi/sponge
+
+package handler
+
+import (
+	"context"
+
+	serverNameExampleV1 "moduleNameExample/api/serverNameExample/v1"
+)
+
+{{- range .Services}}
+
+var _ serverNameExampleV1.{{.Name}}Logicer = (*{{.LowerName}}Handler)(nil)
+
+type {{.LowerName}}Handler struct {
+	// example:
+	// 	{{.LowerName}}Dao dao.{{.Name}}Dao
+}
+
+// New{{.Name}}Handler create a handler
+func New{{.Name}}Handler() serverNameExampleV1.{{.Name}}Logicer {
+	return &{{.LowerName}}Handler{
+		// example:
+		// 	{{.LowerName}}Dao: dao.New{{.Name}}Dao(
+		// 		model.GetDB(),
+		// 		cache.New{{.Name}}Cache(model.GetCacheType()),
+		// 	),
+	}
+}
+
+{{- range .Methods}}
+
+{{.Comment}}
+func (h *{{.LowerServiceName}}Handler) {{.Name}}(ctx context.Context, req *serverNameExampleV1.{{.Request}}) (*serverNameExampleV1.{{.Reply}}, error) {
+	panic("implement me")
+
+	// fill in the business logic code here, the request params have been validated
+	// example:
+	//	    reply, err := h.{{.LowerServiceName}}Dao.{{.Name}}(ctx, req)
+	//	    if err != nil {
+	//		    logger.Warn("{{.Name}} error", logger.Err(err), middleware.CtxRequestIDField(ctx))
+	//		    return nil, ecode.InternalServerError.Err()
+	//	    }
+	//
+	//	    return reply, nil
+}
+
+{{- end}}
+
+// ---------- Do not delete or move this split line, this is the merge code marker ----------
+
+{{- end}}
+`
+
+	handlerRouterTmpl    *template.Template
+	handlerRouterTmplRaw = `// Code generated by https://github.com/zhufuyi/sponge
+
+package routers
+
+import (
+	serverNameExampleV1 "moduleNameExample/api/serverNameExample/v1"
+	"moduleNameExample/internal/handler"
+
+	"github.com/zhufuyi/sponge/pkg/logger"
+	//"github.com/zhufuyi/sponge/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	allMiddlewareFns = append(allMiddlewareFns, func(c *middlewareConfig) {
+{{- range .Services}}
+		{{.LowerName}}Middlewares(c)
+{{- end}}
+	})
+
+	allRouteFns = append(allRouteFns,
+		func(r *gin.Engine, groupPathMiddlewares map[string][]gin.HandlerFunc, singlePathMiddlewares map[string][]gin.HandlerFunc) {
+{{- range .Services}}
+			{{.LowerName}}Router(r, groupPathMiddlewares, singlePathMiddlewares, handler.New{{.Name}}Handler())
+{{- end}}
+		})
+}
+
+{{- range .Services}}
+
+func {{.LowerName}}Router(
+	r *gin.Engine,
+	groupPathMiddlewares map[string][]gin.HandlerFunc,
+	singlePathMiddlewares map[string][]gin.HandlerFunc,
+	iService serverNameExampleV1.{{.Name}}Logicer) {
+	serverNameExampleV1.Register{{.Name}}Router(
+		r,
+		groupPathMiddlewares,
+		singlePathMiddlewares,
+		iService,
+		serverNameExampleV1.With{{.Name}}HTTPResponse(),
+		serverNameExampleV1.With{{.Name}}Logger(logger.Get()),
+		serverNameExampleV1.With{{.Name}}ErrorToHTTPCode(
+			// Set some error codes to standard http return codes,
+			// by default there is already ecode.InternalServerError and ecode.ServiceUnavailable
+			// example:
+			// 	ecode.Forbidden, ecode.LimitExceed,
+		),
+	)
+}
+
+// you can set the middleware of a route group, or set the middleware of a single route,
+// or you can mix them, pay attention to the duplication of middleware when mixing them,
+// it is recommended to set the middleware of a single route in preference
+func {{.LowerName}}Middlewares(c *middlewareConfig) {
+	// set up group route middleware, group path is left prefix rules,
+	// if the left prefix is hit, the middleware will take effect, e.g. group route is /api/v1, route /api/v1/{{.LowerName}}/:id  will take effect
+	// c.setGroupPath("/api/v1/{{.LowerName}}", middleware.Auth())
+
+	// set up single route middleware, just uncomment the code and fill in the middlewares, nothing else needs to be changed
+{{- range .Methods}}
+	//c.setSinglePath("{{.Method}}", "{{.Path}}", middleware.Auth())
+{{- end}}
+}
+
+// ---------- Do not delete or move this split line, this is the merge code marker ----------
+
+{{- end}}
+`
+
+	httpErrCodeTmpl    *template.Template
+	httpErrCodeTmplRaw = `// Code generated by https://github.com/zhufuyi/sponge
+
+package ecode
+
+import (
+	"github.com/zhufuyi/sponge/pkg/errcode"
+)
+
+{{- range .Services}}
+
+// {{.LowerName}} business-level http error codes.
+// the {{.LowerName}}NO value range is 1~100, if the same number appears, it will cause a failure to start the service.
+var (
+	{{.LowerName}}NO       = {{.RandNumber}}
+	{{.LowerName}}Name     = "{{.LowerName}}"
+	{{.LowerName}}BaseCode = errcode.HCode({{.LowerName}}NO)
+// --blank line--
+{{- range $i, $v := .Methods}}
+	Err{{.Name}}{{.ServiceName}} = errcode.NewError({{.LowerServiceName}}BaseCode+{{$v.AddOne $i}}, "failed to {{.Name}} "+{{.LowerServiceName}}Name)
+{{- end}}
+	// error codes are globally unique, adding 1 to the previous error code
+)
+
+// ---------- Do not delete or move this split line, this is the merge code marker ----------
+
+{{- end}}
+`
+)
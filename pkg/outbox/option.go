@@ -0,0 +1,131 @@
+package outbox
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RelayOption set the relay options.
+type RelayOption func(*relayOptions)
+
+type relayOptions struct {
+	interval       time.Duration
+	batchSize      int
+	lockTimeout    time.Duration
+	publishTimeout time.Duration
+
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	cleanupInterval time.Duration
+	retention       time.Duration
+
+	zapLog *zap.Logger
+}
+
+func (o *relayOptions) apply(opts ...RelayOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// default relay settings
+func defaultRelayOptions() *relayOptions {
+	zapLog, _ := zap.NewProduction()
+	return &relayOptions{
+		interval:        time.Second,
+		batchSize:       100,
+		lockTimeout:     0, // batchSize * publishTimeout
+		publishTimeout:  time.Second * 3,
+		maxAttempts:     10,
+		initialBackoff:  time.Second,
+		maxBackoff:      time.Minute * 5,
+		cleanupInterval: time.Hour,
+		retention:       time.Hour * 24 * 7,
+		zapLog:          zapLog,
+	}
+}
+
+// WithRelayInterval set the interval of polling the pending messages, default is 1s.
+func WithRelayInterval(d time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		if d > 0 {
+			o.interval = d
+		}
+	}
+}
+
+// WithRelayBatchSize set the maximum number of messages claimed at a time, default is 100.
+func WithRelayBatchSize(size int) RelayOption {
+	return func(o *relayOptions) {
+		if size > 0 {
+			o.batchSize = size
+		}
+	}
+}
+
+// WithRelayLockTimeout set the time of the claimed messages locked by the relay, the messages can be claimed by
+// other relays after the lock expires, e.g. the relay crashes, it is at least batchSize * publishTimeout,
+// default is batchSize * publishTimeout.
+func WithRelayLockTimeout(d time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		if d > 0 {
+			o.lockTimeout = d
+		}
+	}
+}
+
+// WithRelayPublishTimeout set the timeout of publishing a message, default is 3s.
+func WithRelayPublishTimeout(d time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		if d > 0 {
+			o.publishTimeout = d
+		}
+	}
+}
+
+// WithRelayMaxAttempts set the maximum number of publishing attempts, the message is marked as dead after max attempts,
+// default is 10, 0 means unlimited.
+func WithRelayMaxAttempts(n int) RelayOption {
+	return func(o *relayOptions) {
+		if n >= 0 {
+			o.maxAttempts = n
+		}
+	}
+}
+
+// WithRelayBackoff set the exponential backoff of retrying the failed message, default is initial 1s and max 5m.
+func WithRelayBackoff(initial time.Duration, max time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		if initial > 0 {
+			o.initialBackoff = initial
+		}
+		if max > 0 {
+			o.maxBackoff = max
+		}
+	}
+}
+
+// WithRelayCleanup set the interval of deleting the published messages older than retention,
+// default interval is 1h and retention is 7 days, retention 0 means the published messages are not deleted.
+func WithRelayCleanup(interval time.Duration, retention time.Duration) RelayOption {
+	return func(o *relayOptions) {
+		if interval > 0 {
+			o.cleanupInterval = interval
+		}
+		if retention >= 0 {
+			o.retention = retention
+		}
+	}
+}
+
+// WithRelayLogger set logger option.
+func WithRelayLogger(zapLog *zap.Logger) RelayOption {
+	return func(o *relayOptions) {
+		if zapLog != nil {
+			o.zapLog = zapLog
+		}
+	}
+}
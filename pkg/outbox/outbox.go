@@ -0,0 +1,94 @@
+// Package outbox is the transactional outbox for publishing events with gorm writes, the events are saved into
+// the outbox table in the same transaction as the business data, and published to the message broker by the relay,
+// so the events are not lost if the service crashes between writing and publishing, the delivery is at least once.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// StatusPending the message is waiting to be published
+	StatusPending = 0
+	// StatusPublished the message has been published
+	StatusPublished = 1
+	// StatusDead the message failed to be published after max attempts
+	StatusDead = 2
+)
+
+// Message outbox table
+type Message struct {
+	ID          uint64     `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`
+	Topic       string     `gorm:"column:topic;type:varchar(255);not null" json:"topic"`
+	Headers     string     `gorm:"column:headers;type:text" json:"headers"` // json object
+	Payload     []byte     `gorm:"column:payload" json:"payload"`
+	Status      int        `gorm:"column:status;not null;index:idx_outbox_message_status,priority:1" json:"status"`
+	NextRetryAt time.Time  `gorm:"column:next_retry_at;index:idx_outbox_message_status,priority:2" json:"nextRetryAt"`
+	Attempts    int        `gorm:"column:attempts;not null" json:"attempts"`
+	LastError   string     `gorm:"column:last_error;type:text" json:"lastError"`
+	LockedBy    string     `gorm:"column:locked_by;type:varchar(64);index" json:"lockedBy"`
+	LockedUntil *time.Time `gorm:"column:locked_until" json:"lockedUntil"`
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"createdAt"`
+	PublishedAt *time.Time `gorm:"column:published_at" json:"publishedAt"`
+}
+
+// TableName get table name
+func (m *Message) TableName() string {
+	return "outbox_message"
+}
+
+// GetHeaders get the headers of message
+func (m *Message) GetHeaders() map[string]string {
+	headers := make(map[string]string)
+	if m.Headers != "" {
+		_ = json.Unmarshal([]byte(m.Headers), &headers)
+	}
+	return headers
+}
+
+// Event to be published
+type Event struct {
+	Topic   string            // e.g. the routing key of rabbitmq
+	Headers map[string]string // optional
+	Payload []byte
+}
+
+// Migrate create or update the outbox table
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Message{})
+}
+
+// Save insert the events into the outbox table using the provided transaction, it should be called in the same
+// transaction as the business data, e.g. after the dao methods CreateByTx and UpdateByTx.
+func Save(ctx context.Context, tx *gorm.DB, events ...*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	messages := make([]*Message, 0, len(events))
+	for _, event := range events {
+		var headers string
+		if len(event.Headers) > 0 {
+			data, err := json.Marshal(event.Headers)
+			if err != nil {
+				return err
+			}
+			headers = string(data)
+		}
+		messages = append(messages, &Message{
+			Topic:       event.Topic,
+			Headers:     headers,
+			Payload:     event.Payload,
+			Status:      StatusPending,
+			NextRetryAt: now,
+			CreatedAt:   now,
+		})
+	}
+
+	return tx.WithContext(ctx).Create(&messages).Error
+}
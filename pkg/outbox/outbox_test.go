@@ -0,0 +1,85 @@
+package outbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zhufuyi/sponge/pkg/ggorm"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type order struct {
+	ggorm.Model `gorm:"embedded"`
+	Name        string `gorm:"column:name"`
+}
+
+func initDB(t *testing.T) (*gorm.DB, func()) {
+	dir, err := os.MkdirTemp("", "sponge_outbox")
+	if err != nil {
+		t.Log(err)
+		return nil, nil
+	}
+	dbFile := filepath.Join(dir, "test_outbox.db")
+	db, err := ggorm.InitSqlite(dbFile)
+	if err != nil {
+		// ignore test error about not being able to connect to real sqlite
+		t.Logf("connect to sqlite failed, err=%v, dbFile=%s", err, dbFile)
+		_ = os.RemoveAll(dir)
+		return nil, nil
+	}
+	err = db.AutoMigrate(&order{})
+	assert.NoError(t, err)
+	err = Migrate(db)
+	assert.NoError(t, err)
+
+	return db, func() {
+		_ = ggorm.CloseDB(db)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestSave(t *testing.T) {
+	db, closeDB := initDB(t)
+	if db == nil {
+		return
+	}
+	defer closeDB()
+	ctx := context.Background()
+
+	// committed with business data
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&order{Name: "foo"}).Error; err != nil {
+			return err
+		}
+		return Save(ctx, tx, &Event{
+			Topic:   "order.created",
+			Headers: map[string]string{"foo": "bar"},
+			Payload: []byte(`{"name":"foo"}`),
+		}, &Event{Topic: "order.notify"})
+	})
+	assert.NoError(t, err)
+
+	// rollback with business data
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := Save(ctx, tx, &Event{Topic: "order.created"}); err != nil {
+			return err
+		}
+		return gorm.ErrInvalidData
+	})
+	assert.Error(t, err)
+
+	var messages []*Message
+	err = db.Order("id ASC").Find(&messages).Error
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "order.created", messages[0].Topic)
+	assert.Equal(t, StatusPending, messages[0].Status)
+	assert.Equal(t, map[string]string{"foo": "bar"}, messages[0].GetHeaders())
+	assert.Equal(t, map[string]string{}, messages[1].GetHeaders())
+
+	assert.NoError(t, Save(ctx, db))
+}
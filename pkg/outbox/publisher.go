@@ -0,0 +1,43 @@
+package outbox
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/zhufuyi/sponge/pkg/rabbitmq"
+)
+
+// HeaderMessageID the id of outbox message, which can be used by the consumer for deduplication
+const HeaderMessageID = "x-outbox-id"
+
+// Publisher publish the outbox message to the message broker, returns nil only if the broker has received the message.
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// PublisherFunc is an adapter to use the ordinary function as Publisher.
+type PublisherFunc func(ctx context.Context, msg *Message) error
+
+// Publish call f(ctx, msg)
+func (f PublisherFunc) Publish(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// NewRabbitmqPublisher create a publisher of rabbitmq, the topic of message is the routing key, empty means the
+// routing key of exchange. the producer must be created with rabbitmq.WithProducerConfirm(), so that the message
+// is marked as published only after the broker confirms it.
+func NewRabbitmqPublisher(producer *rabbitmq.Producer) Publisher {
+	return PublisherFunc(func(ctx context.Context, msg *Message) error {
+		headers := make(map[string]interface{})
+		for k, v := range msg.GetHeaders() {
+			headers[k] = v
+		}
+		headers[HeaderMessageID] = strconv.FormatUint(msg.ID, 10)
+
+		confirmation, err := producer.PublishAsync(ctx, msg.Topic, headers, msg.Payload)
+		if err != nil {
+			return err
+		}
+		return confirmation.Wait(ctx)
+	})
+}
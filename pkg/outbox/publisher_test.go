@@ -0,0 +1,29 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zhufuyi/sponge/pkg/rabbitmq"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublisherFunc(t *testing.T) {
+	var topic string
+	p := PublisherFunc(func(ctx context.Context, msg *Message) error {
+		topic = msg.Topic
+		return nil
+	})
+	err := p.Publish(context.Background(), &Message{Topic: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", topic)
+}
+
+func TestNewRabbitmqPublisher(t *testing.T) {
+	// the producer without confirm mode is not supported
+	producer := &rabbitmq.Producer{Exchange: rabbitmq.NewDirectExchange("foo", "bar")}
+	p := NewRabbitmqPublisher(producer)
+	err := p.Publish(context.Background(), &Message{ID: 1, Topic: "foo", Headers: `{"foo":"bar"}`})
+	assert.Error(t, err)
+}
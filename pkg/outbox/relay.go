@@ -0,0 +1,325 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/krand"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	publishedDesc = prometheus.NewDesc("outbox_published_total",
+		"Total number of outbox messages published.", nil, nil)
+	failedDesc = prometheus.NewDesc("outbox_publish_failed_total",
+		"Total number of failures of publishing outbox messages.", nil, nil)
+	deadDesc = prometheus.NewDesc("outbox_dead_total",
+		"Total number of outbox messages failed after max attempts.", nil, nil)
+	cleanedDesc = prometheus.NewDesc("outbox_cleaned_total",
+		"Total number of published outbox messages deleted.", nil, nil)
+	lockLostDesc = prometheus.NewDesc("outbox_lock_lost_total",
+		"Total number of outbox messages whose lock expired and was claimed by others before the result was saved.", nil, nil)
+	pendingDesc = prometheus.NewDesc("outbox_pending",
+		"Number of pending outbox messages.", nil, nil)
+)
+
+// Stat is the statistics of relay
+type Stat struct {
+	Published int64 // total number of messages published
+	Failed    int64 // total number of publishing failures
+	Dead      int64 // total number of messages failed after max attempts
+	Cleaned   int64 // total number of published messages deleted
+	LockLost  int64 // total number of messages whose lock was lost before the result was saved, they are published again
+	Pending   int64 // number of pending messages counted at the last collection of metrics
+}
+
+// Relay poll the pending messages from the outbox table and publish them, multiple relays can run at the same time,
+// the messages are claimed by locking rows, the order of messages is not guaranteed strictly.
+// it implements app.IServer, and prometheus.Collector for metrics.
+type Relay struct {
+	db        *gorm.DB
+	publisher Publisher
+	opts      *relayOptions
+
+	id  string // the identity of relay, used to lock rows
+	seq uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+
+	published int64
+	failed    int64
+	dead      int64
+	cleaned   int64
+	lockLost  int64
+	pending   int64
+}
+
+// NewRelay create a relay
+func NewRelay(db *gorm.DB, publisher Publisher, opts ...RelayOption) *Relay {
+	o := defaultRelayOptions()
+	o.apply(opts...)
+	// the messages of a batch are published one by one, the lock must not expire before the batch is published
+	if minLockTimeout := time.Duration(o.batchSize) * o.publishTimeout; o.lockTimeout < minLockTimeout {
+		if o.lockTimeout > 0 {
+			o.zapLog.Warn("[outbox relay] lock timeout is less than batch size * publish timeout, use the minimum value",
+				zap.Duration("lockTimeout", o.lockTimeout), zap.Duration("minLockTimeout", minLockTimeout))
+		}
+		o.lockTimeout = minLockTimeout
+	}
+
+	hostname, _ := os.Hostname()
+	if len(hostname) > 32 {
+		hostname = hostname[:32]
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		opts:      o,
+		id:        hostname + "-" + krand.String(krand.R_All, 8),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start polling and publishing messages, block until Stop is called
+func (r *Relay) Start() error {
+	r.mu.Lock()
+	if r.ctx.Err() != nil {
+		r.mu.Unlock()
+		return nil
+	}
+	r.wg.Add(1)
+	r.mu.Unlock()
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.opts.interval)
+	defer ticker.Stop()
+	var cleanupC <-chan time.Time
+	if r.opts.retention > 0 {
+		cleanupTicker := time.NewTicker(r.opts.cleanupInterval)
+		defer cleanupTicker.Stop()
+		cleanupC = cleanupTicker.C
+	}
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return nil
+		case <-ticker.C:
+			for {
+				n, err := r.relay(r.ctx)
+				if err != nil && !errors.Is(err, context.Canceled) {
+					r.opts.zapLog.Warn("[outbox relay] relay messages error", zap.String("err", err.Error()))
+				}
+				if err != nil || n < r.opts.batchSize {
+					break // continue if the batch is full
+				}
+			}
+		case <-cleanupC:
+			if _, err := r.cleanup(r.ctx); err != nil {
+				r.opts.zapLog.Warn("[outbox relay] cleanup messages error", zap.String("err", err.Error()))
+			}
+		}
+	}
+}
+
+// Stop the relay, wait for the messages being published
+func (r *Relay) Stop() error {
+	r.mu.Lock()
+	r.cancel()
+	r.mu.Unlock()
+	r.wg.Wait()
+	return nil
+}
+
+// String comment
+func (r *Relay) String() string {
+	return "outbox relay, id: " + r.id
+}
+
+// Stat takes a snapshot of the relay.
+func (r *Relay) Stat() Stat {
+	return Stat{
+		Published: atomic.LoadInt64(&r.published),
+		Failed:    atomic.LoadInt64(&r.failed),
+		Dead:      atomic.LoadInt64(&r.dead),
+		Cleaned:   atomic.LoadInt64(&r.cleaned),
+		LockLost:  atomic.LoadInt64(&r.lockLost),
+		Pending:   atomic.LoadInt64(&r.pending),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (r *Relay) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{publishedDesc, failedDesc, deadDesc, cleanedDesc, lockLostDesc, pendingDesc} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector, the pending messages are counted when collecting.
+func (r *Relay) Collect(ch chan<- prometheus.Metric) {
+	r.countPending()
+	stat := r.Stat()
+	ch <- prometheus.MustNewConstMetric(publishedDesc, prometheus.CounterValue, float64(stat.Published))
+	ch <- prometheus.MustNewConstMetric(failedDesc, prometheus.CounterValue, float64(stat.Failed))
+	ch <- prometheus.MustNewConstMetric(deadDesc, prometheus.CounterValue, float64(stat.Dead))
+	ch <- prometheus.MustNewConstMetric(cleanedDesc, prometheus.CounterValue, float64(stat.Cleaned))
+	ch <- prometheus.MustNewConstMetric(lockLostDesc, prometheus.CounterValue, float64(stat.LockLost))
+	ch <- prometheus.MustNewConstMetric(pendingDesc, prometheus.GaugeValue, float64(stat.Pending))
+}
+
+// claim a batch of pending messages and publish them, returns the number of messages handled
+func (r *Relay) relay(ctx context.Context) (int, error) {
+	messages, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, msg := range messages {
+		if ctx.Err() != nil {
+			// the lock of unpublished messages expires, they are claimed again later
+			return i, ctx.Err()
+		}
+		// stop publishing if the lock may expire before the message is published, otherwise the message may be
+		// claimed and published by others at the same time, the rest messages are claimed again after the lock expires.
+		if msg.LockedUntil == nil || time.Until(*msg.LockedUntil) < r.opts.publishTimeout {
+			r.opts.zapLog.Warn("[outbox relay] lock of batch is about to expire, stop publishing the batch",
+				zap.Int("published", i), zap.Int("batch", len(messages)))
+			return i, nil
+		}
+		r.publish(ctx, msg)
+	}
+
+	return len(messages), nil
+}
+
+func (r *Relay) claim(ctx context.Context) ([]*Message, error) {
+	db := r.db.WithContext(ctx)
+	now := time.Now()
+
+	var ids []uint64
+	err := db.Model(&Message{}).
+		Where("status = ? AND next_retry_at <= ? AND (locked_until IS NULL OR locked_until < ?)", StatusPending, now, now).
+		Order("id ASC").Limit(r.opts.batchSize).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	// the rows may be claimed by other relays at the same time, only the rows updated successfully belong to this relay
+	token := r.id + "-" + strconv.FormatUint(atomic.AddUint64(&r.seq, 1), 10)
+	lockedUntil := now.Add(r.opts.lockTimeout)
+	err = db.Model(&Message{}).
+		Where("id IN ? AND status = ? AND (locked_until IS NULL OR locked_until < ?)", ids, StatusPending, now).
+		Updates(map[string]interface{}{"locked_by": token, "locked_until": lockedUntil}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*Message
+	err = db.Where("locked_by = ? AND status = ?", token, StatusPending).Order("id ASC").Find(&messages).Error
+	return messages, err
+}
+
+func (r *Relay) publish(ctx context.Context, msg *Message) {
+	publishCtx, cancel := context.WithTimeout(ctx, r.opts.publishTimeout)
+	publishErr := r.publisher.Publish(publishCtx, msg)
+	cancel()
+
+	now := time.Now()
+	attempts := msg.Attempts + 1
+	isDead := false
+	update := map[string]interface{}{"locked_until": nil}
+	if publishErr == nil {
+		update["status"] = StatusPublished
+		update["published_at"] = now
+	} else {
+		update["attempts"] = attempts
+		update["last_error"] = publishErr.Error()
+		update["next_retry_at"] = now.Add(r.backoff(attempts))
+		if r.opts.maxAttempts > 0 && attempts >= r.opts.maxAttempts {
+			update["status"] = StatusDead
+			isDead = true
+		}
+	}
+
+	// use a new context, the result must be saved even if the relay is stopping
+	result := r.db.WithContext(context.Background()).Model(&Message{}).
+		Where("id = ? AND locked_by = ?", msg.ID, msg.LockedBy).Updates(update)
+	if result.Error != nil {
+		// the message is published again after the lock expires
+		r.opts.zapLog.Warn("[outbox relay] update message error", zap.Uint64("id", msg.ID), zap.String("err", result.Error.Error()))
+		return
+	}
+	if result.RowsAffected == 0 {
+		// the lock expired and the message was claimed by others, the result is discarded
+		atomic.AddInt64(&r.lockLost, 1)
+		r.opts.zapLog.Warn("[outbox relay] lock of message is lost, increase the lock timeout if it happens frequently",
+			zap.Uint64("id", msg.ID), zap.String("topic", msg.Topic), zap.Bool("published", publishErr == nil))
+		return
+	}
+
+	if publishErr == nil {
+		atomic.AddInt64(&r.published, 1)
+		return
+	}
+	atomic.AddInt64(&r.failed, 1)
+	if isDead {
+		atomic.AddInt64(&r.dead, 1)
+		r.opts.zapLog.Error("[outbox relay] message is dead after max attempts", zap.Uint64("id", msg.ID),
+			zap.String("topic", msg.Topic), zap.String("err", publishErr.Error()))
+	} else {
+		r.opts.zapLog.Warn("[outbox relay] publish message error", zap.Uint64("id", msg.ID),
+			zap.String("topic", msg.Topic), zap.Int("attempts", attempts), zap.String("err", publishErr.Error()))
+	}
+}
+
+// count the pending messages, it is called when collecting metrics instead of each polling
+func (r *Relay) countPending() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.opts.publishTimeout)
+	defer cancel()
+
+	var count int64
+	err := r.db.WithContext(ctx).Model(&Message{}).Where("status = ?", StatusPending).Count(&count).Error
+	if err != nil {
+		r.opts.zapLog.Warn("[outbox relay] count pending messages error", zap.String("err", err.Error()))
+		return
+	}
+	atomic.StoreInt64(&r.pending, count)
+}
+
+// the delay before the nth retry
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.opts.initialBackoff
+	for i := 1; i < attempts && d < r.opts.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.opts.maxBackoff {
+		d = r.opts.maxBackoff
+	}
+	return d
+}
+
+// delete the published messages older than retention
+func (r *Relay) cleanup(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status = ? AND published_at < ?", StatusPublished, time.Now().Add(-r.opts.retention)).
+		Delete(&Message{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	atomic.AddInt64(&r.cleaned, result.RowsAffected)
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,257 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type testPublisher struct {
+	mu     sync.Mutex
+	topics []string
+	fail   map[string]bool
+	delay  time.Duration
+}
+
+func (p *testPublisher) Publish(ctx context.Context, msg *Message) error {
+	time.Sleep(p.delay)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail[msg.Topic] {
+		return errors.New("publish failed")
+	}
+	p.topics = append(p.topics, msg.Topic)
+	return nil
+}
+
+func (p *testPublisher) getTopics() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string{}, p.topics...)
+}
+
+func TestRelayOptions(t *testing.T) {
+	o := defaultRelayOptions()
+	o.apply(
+		WithRelayInterval(time.Millisecond*100),
+		WithRelayBatchSize(10),
+		WithRelayLockTimeout(time.Second),
+		WithRelayPublishTimeout(time.Second*2),
+		WithRelayMaxAttempts(3),
+		WithRelayBackoff(time.Millisecond*10, time.Second),
+		WithRelayCleanup(time.Minute, 0),
+		WithRelayLogger(zap.NewNop()),
+	)
+	assert.Equal(t, time.Millisecond*100, o.interval)
+	assert.Equal(t, 10, o.batchSize)
+	assert.Equal(t, time.Second, o.lockTimeout)
+	assert.Equal(t, time.Second*2, o.publishTimeout)
+	assert.Equal(t, 3, o.maxAttempts)
+	assert.Equal(t, time.Millisecond*10, o.initialBackoff)
+	assert.Equal(t, time.Second, o.maxBackoff)
+	assert.Equal(t, time.Minute, o.cleanupInterval)
+	assert.Equal(t, time.Duration(0), o.retention)
+
+	r := &Relay{opts: o}
+	assert.Equal(t, time.Millisecond*10, r.backoff(1))
+	assert.Equal(t, time.Millisecond*40, r.backoff(3))
+	assert.Equal(t, time.Second, r.backoff(20))
+
+	// the lock timeout is at least batch size * publish timeout
+	r = NewRelay(nil, &testPublisher{}, WithRelayBatchSize(10), WithRelayPublishTimeout(time.Second),
+		WithRelayLockTimeout(time.Second), WithRelayLogger(zap.NewNop()))
+	assert.Equal(t, time.Second*10, r.opts.lockTimeout)
+	r = NewRelay(nil, &testPublisher{})
+	assert.Equal(t, time.Second*300, r.opts.lockTimeout)
+}
+
+func TestRelay(t *testing.T) {
+	db, closeDB := initDB(t)
+	if db == nil {
+		return
+	}
+	defer closeDB()
+	ctx := context.Background()
+
+	err := Save(ctx, db, &Event{Topic: "a"}, &Event{Topic: "b"}, &Event{Topic: "c"})
+	assert.NoError(t, err)
+
+	publisher := &testPublisher{fail: map[string]bool{"b": true}}
+	r := NewRelay(db, publisher, WithRelayBatchSize(2), WithRelayMaxAttempts(2),
+		WithRelayBackoff(time.Millisecond*10, time.Millisecond*10), WithRelayLogger(zap.NewNop()))
+
+	// the pending messages are counted when collecting metrics
+	expected := `
+# HELP outbox_pending Number of pending outbox messages.
+# TYPE outbox_pending gauge
+outbox_pending 3
+`
+	err = testutil.CollectAndCompare(r, strings.NewReader(expected), "outbox_pending")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), r.Stat().Pending)
+
+	n, err := r.relay(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = r.relay(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"a", "c"}, publisher.getTopics())
+
+	// retry after backoff, and dead after max attempts
+	n, _ = r.relay(ctx)
+	assert.Equal(t, 0, n)
+	time.Sleep(time.Millisecond * 20)
+	n, _ = r.relay(ctx)
+	assert.Equal(t, 1, n)
+
+	var msg Message
+	err = db.Where("topic = ?", "b").First(&msg).Error
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDead, msg.Status)
+	assert.Equal(t, 2, msg.Attempts)
+	assert.Equal(t, "publish failed", msg.LastError)
+
+	stat := r.Stat()
+	assert.Equal(t, Stat{Published: 2, Failed: 2, Dead: 1, Pending: 3}, stat)
+
+	// cleanup the published messages
+	r.opts.retention = time.Nanosecond
+	cleaned, err := r.cleanup(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), cleaned)
+
+	expected = `
+# HELP outbox_published_total Total number of outbox messages published.
+# TYPE outbox_published_total counter
+outbox_published_total 2
+# HELP outbox_cleaned_total Total number of published outbox messages deleted.
+# TYPE outbox_cleaned_total counter
+outbox_cleaned_total 2
+`
+	err = testutil.CollectAndCompare(r, strings.NewReader(expected), "outbox_published_total", "outbox_cleaned_total")
+	assert.NoError(t, err)
+}
+
+func TestRelay_claim(t *testing.T) {
+	db, closeDB := initDB(t)
+	if db == nil {
+		return
+	}
+	defer closeDB()
+	ctx := context.Background()
+
+	err := Save(ctx, db, &Event{Topic: "a"}, &Event{Topic: "b"})
+	assert.NoError(t, err)
+
+	r1 := NewRelay(db, &testPublisher{}, WithRelayLogger(zap.NewNop()))
+	r2 := NewRelay(db, &testPublisher{}, WithRelayLogger(zap.NewNop()), WithRelayBatchSize(2),
+		WithRelayPublishTimeout(time.Millisecond*100), WithRelayLockTimeout(time.Millisecond*200))
+
+	// the locked messages can not be claimed by others
+	messages, err := r2.claim(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	messages, err = r1.claim(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 0)
+
+	// claimed again after the lock expires
+	time.Sleep(time.Millisecond * 250)
+	messages, err = r1.claim(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+}
+
+func TestRelay_lockLost(t *testing.T) {
+	db, closeDB := initDB(t)
+	if db == nil {
+		return
+	}
+	defer closeDB()
+	ctx := context.Background()
+
+	err := Save(ctx, db, &Event{Topic: "a"})
+	assert.NoError(t, err)
+
+	r1 := NewRelay(db, &testPublisher{}, WithRelayLogger(zap.NewNop()), WithRelayBatchSize(1),
+		WithRelayPublishTimeout(time.Millisecond*10), WithRelayLockTimeout(time.Millisecond*10))
+	r2 := NewRelay(db, &testPublisher{}, WithRelayLogger(zap.NewNop()))
+	messages, err := r1.claim(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	// the lock of r1 expires, and the message is claimed by r2 before r1 saves the result
+	time.Sleep(time.Millisecond * 20)
+	messages2, err := r2.claim(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, messages2, 1)
+
+	r1.publish(ctx, messages[0])
+	assert.Equal(t, Stat{LockLost: 1}, r1.Stat())
+
+	r2.publish(ctx, messages2[0])
+	assert.Equal(t, int64(1), r2.Stat().Published)
+	var msg Message
+	err = db.First(&msg, messages2[0].ID).Error
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPublished, msg.Status)
+}
+
+func TestRelay_lockExpiring(t *testing.T) {
+	db, closeDB := initDB(t)
+	if db == nil {
+		return
+	}
+	defer closeDB()
+	ctx := context.Background()
+
+	err := Save(ctx, db, &Event{Topic: "a"}, &Event{Topic: "b"}, &Event{Topic: "c"})
+	assert.NoError(t, err)
+
+	// the lock of batch expires after 150ms, the third message is not published in the batch
+	publisher := &testPublisher{delay: time.Millisecond * 60}
+	r := NewRelay(db, publisher, WithRelayBatchSize(3), WithRelayPublishTimeout(time.Millisecond*50),
+		WithRelayLogger(zap.NewNop()))
+	n, err := r.relay(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"a", "b"}, publisher.getTopics())
+
+	// claimed again after the lock expires
+	time.Sleep(time.Millisecond * 50)
+	n, err = r.relay(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"a", "b", "c"}, publisher.getTopics())
+}
+
+func TestRelay_StartStop(t *testing.T) {
+	db, closeDB := initDB(t)
+	if db == nil {
+		return
+	}
+	defer closeDB()
+
+	err := Save(context.Background(), db, &Event{Topic: "a"})
+	assert.NoError(t, err)
+
+	publisher := &testPublisher{}
+	r := NewRelay(db, publisher, WithRelayInterval(time.Millisecond*10), WithRelayLogger(zap.NewNop()))
+	t.Log(r.String())
+	go func() {
+		_ = r.Start()
+	}()
+
+	time.Sleep(time.Millisecond * 100)
+	assert.NoError(t, r.Stop())
+	assert.Equal(t, []string{"a"}, publisher.getTopics())
+	assert.NoError(t, r.Start()) // return immediately after stopped
+}
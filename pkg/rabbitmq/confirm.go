@@ -0,0 +1,358 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/krand"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// the confirmations and returns are buffered, avoid blocking the reading of connection
+const confirmBufferSize = 1024
+
+var (
+	// ErrNacked the message is nacked by the broker, e.g. internal error of broker
+	ErrNacked = errors.New("message is nacked by broker")
+	// ErrPending the message has not been confirmed by the broker yet
+	ErrPending = errors.New("message is pending confirmation by broker")
+)
+
+// ReturnError the mandatory message is returned by the broker because it cannot be routed to any queue
+type ReturnError struct {
+	ReplyCode  uint16
+	ReplyText  string
+	Exchange   string
+	RoutingKey string
+}
+
+// Error returns the error message
+func (e *ReturnError) Error() string {
+	return fmt.Sprintf("message is returned by broker, code=%d, text=%s, exchange=%s, routingKey=%s",
+		e.ReplyCode, e.ReplyText, e.Exchange, e.RoutingKey)
+}
+
+// Confirmation the future of message published in confirm mode
+type Confirmation struct {
+	done chan struct{}
+	err  error
+	once sync.Once
+}
+
+func newConfirmation() *Confirmation {
+	return &Confirmation{done: make(chan struct{})}
+}
+
+func (c *Confirmation) resolve(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.done)
+	})
+}
+
+// Done returns a channel that is closed when the broker confirms the message
+func (c *Confirmation) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns nil if the message is acked by the broker, otherwise ErrNacked or *ReturnError,
+// return ErrPending before Done is closed.
+func (c *Confirmation) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return ErrPending
+	}
+}
+
+// Wait blocks until the broker confirms the message or ctx is done
+func (c *Confirmation) Wait(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitConfirms wait for the confirmations of a batch of messages, return the first error,
+// the error of each message can be got by Confirmation.Err.
+func WaitConfirms(ctx context.Context, confirmations ...*Confirmation) error {
+	var firstErr error
+	for _, c := range confirmations {
+		if err := c.Wait(ctx); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+type pendingMessage struct {
+	tag          uint64 // delivery tag of the current channel
+	routingKey   string
+	msg          amqp.Publishing
+	confirmation *Confirmation
+}
+
+// PublishAsync send message in confirm mode without waiting for the confirmation of broker, the confirmations of
+// a batch of messages can be waited by WaitConfirms for throughput. routingKey is the topic key of topic type, empty
+// means the routing key of exchange, headers are required for headers type, and the header x-delay (milliseconds)
+// is required for delayed message type.
+func (p *Producer) PublishAsync(ctx context.Context, routingKey string, headers map[string]interface{}, body []byte) (*Confirmation, error) {
+	if !p.isConfirm {
+		return nil, errors.New("confirm mode is not enabled, please set WithProducerConfirm()")
+	}
+	if routingKey == "" {
+		routingKey = p.Exchange.routingKey
+	}
+	return p.publishDeferred(ctx, routingKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		Headers:      headers,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
+}
+
+// PublishMessageAsync send the message envelope in confirm mode without waiting for the confirmation of broker,
+// the parameters are the same as PublishMessage.
+func (p *Producer) PublishMessageAsync(ctx context.Context, routingKey string, msg *Message) (*Confirmation, error) {
+	if !p.isConfirm {
+		return nil, errors.New("confirm mode is not enabled, please set WithProducerConfirm()")
+	}
+	if routingKey == "" {
+		routingKey = p.Exchange.routingKey
+	}
+	return p.publishDeferred(ctx, routingKey, msg.publishing(p.deliveryMode))
+}
+
+func (p *Producer) publishDeferred(ctx context.Context, routingKey string, msg amqp.Publishing) (_ *Confirmation, err error) {
+	if msg.MessageId == "" {
+		msg.MessageId = krand.String(krand.R_All, 16) // used to match the returned message
+	}
+	span := startPublishSpan(ctx, p.Exchange.name, routingKey, &msg)
+	defer func() { endSpan(span, err) }()
+	pm := &pendingMessage{routingKey: routingKey, msg: msg, confirmation: newConfirmation()}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.exit:
+		return nil, ErrClosed
+	default:
+	}
+
+	err = p.publishPending(ctx, pm)
+	if err != nil {
+		if !errors.Is(err, amqp.ErrClosed) {
+			return nil, err
+		}
+		// the message is published again after reconnected
+		p.unsent = append(p.unsent, pm)
+		p.zapLog.Warn("[rabbit producer] channel is closed, message will be published after reconnected",
+			zap.String("messageID", msg.MessageId))
+	}
+
+	return pm.confirmation, nil
+}
+
+// must be called with lock held
+func (p *Producer) publishPending(ctx context.Context, pm *pendingMessage) error {
+	tag := p.ch.GetNextPublishSeqNo()
+	err := p.ch.PublishWithContext(ctx, p.Exchange.name, pm.routingKey, p.mandatory, false, pm.msg)
+	if err != nil {
+		return err
+	}
+	pm.tag = tag
+	p.pending[tag] = pm
+	return nil
+}
+
+// must be called before publishing on the channel
+func (p *Producer) listenConfirms(ch *amqp.Channel) {
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, confirmBufferSize))
+	returns := ch.NotifyReturn(make(chan amqp.Return, confirmBufferSize))
+
+	go func() {
+		for {
+			select {
+			case r, ok := <-returns:
+				if !ok {
+					returns = nil
+					continue
+				}
+				p.handleReturn(ch, r)
+			case c, ok := <-confirms:
+				if !ok {
+					// the channel is closed, recover it if the connection is still alive, e.g. channel exception
+					select {
+					case p.reconnected <- struct{}{}:
+					default:
+					}
+					return
+				}
+				// the broker sends the return of message before the ack, handle returns first
+				p.drainReturns(ch, returns)
+				p.handleConfirm(ch, c)
+			}
+		}
+	}()
+}
+
+func (p *Producer) drainReturns(ch *amqp.Channel, returns <-chan amqp.Return) {
+	for {
+		select {
+		case r, ok := <-returns:
+			if !ok {
+				return
+			}
+			p.handleReturn(ch, r)
+		default:
+			return
+		}
+	}
+}
+
+func (p *Producer) handleReturn(ch *amqp.Channel, r amqp.Return) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ch != ch {
+		return
+	}
+	p.returned[r.MessageId] = &ReturnError{
+		ReplyCode:  r.ReplyCode,
+		ReplyText:  r.ReplyText,
+		Exchange:   r.Exchange,
+		RoutingKey: r.RoutingKey,
+	}
+}
+
+func (p *Producer) handleConfirm(ch *amqp.Channel, c amqp.Confirmation) {
+	p.mu.Lock()
+	if p.ch != ch { // confirmation of the old channel
+		p.mu.Unlock()
+		return
+	}
+	pm, ok := p.pending[c.DeliveryTag]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.pending, c.DeliveryTag)
+
+	var err error
+	if returnErr, ok := p.returned[pm.msg.MessageId]; ok {
+		delete(p.returned, pm.msg.MessageId)
+		err = returnErr
+	} else if !c.Ack {
+		err = ErrNacked
+	}
+	p.mu.Unlock()
+
+	pm.confirmation.resolve(err)
+}
+
+func (p *Producer) watchReconnect() {
+	for {
+		select {
+		case <-p.exit:
+			return
+		case <-p.connection.exit:
+			return
+		case <-p.reconnected:
+			p.recover()
+		}
+	}
+}
+
+// create a new channel and publish the unconfirmed messages again
+func (p *Producer) recover() {
+	p.mu.Lock()
+	isClosed := p.ch.IsClosed()
+	p.mu.Unlock()
+	if !isClosed {
+		return // already recovered
+	}
+
+	var ch *amqp.Channel
+	for {
+		if !p.connection.CheckConnected() {
+			return // wait for reconnection
+		}
+		var err error
+		ch, err = p.initialize()
+		if err == nil {
+			break
+		}
+		p.zapLog.Warn("[rabbit producer] recover channel error", zap.String("err", err.Error()))
+		select {
+		case <-p.exit:
+			return
+		case <-time.After(p.connection.reconnectTime):
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.exit:
+		_ = ch.Close()
+		return
+	default:
+	}
+
+	// keep the order of publishing
+	messages := make([]*pendingMessage, 0, len(p.pending)+len(p.unsent))
+	for _, pm := range p.pending {
+		messages = append(messages, pm)
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].tag < messages[j].tag })
+	messages = append(messages, p.unsent...)
+
+	p.ch = ch
+	p.pending = make(map[uint64]*pendingMessage)
+	p.returned = make(map[string]*ReturnError)
+	p.unsent = nil
+	p.listenConfirms(ch)
+
+	for _, pm := range messages {
+		err := p.publishPending(context.Background(), pm)
+		if err != nil {
+			if errors.Is(err, amqp.ErrClosed) {
+				p.unsent = append(p.unsent, pm)
+				continue
+			}
+			pm.confirmation.resolve(err)
+		}
+	}
+	p.zapLog.Info("[rabbit producer] channel recovered", zap.Int("republished", len(messages)-len(p.unsent)))
+}
+
+// the unconfirmed messages are failed with ErrClosed when the producer is closed
+func (p *Producer) closePending() {
+	p.mu.Lock()
+	messages := p.unsent
+	for _, pm := range p.pending {
+		messages = append(messages, pm)
+	}
+	p.pending = make(map[uint64]*pendingMessage)
+	p.unsent = nil
+	p.mu.Unlock()
+
+	for _, pm := range messages {
+		pm.confirmation.resolve(ErrClosed)
+	}
+}
@@ -0,0 +1,133 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/utils"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestConfirmation(t *testing.T) {
+	c := newConfirmation()
+	assert.Equal(t, ErrPending, c.Err())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, c.Wait(ctx))
+
+	c.resolve(ErrNacked)
+	c.resolve(nil) // resolved only once
+	<-c.Done()
+	assert.Equal(t, ErrNacked, c.Err())
+	assert.Equal(t, ErrNacked, c.Wait(context.Background()))
+
+	c1, c2 := newConfirmation(), newConfirmation()
+	c1.resolve(nil)
+	c2.resolve(&ReturnError{ReplyCode: 312, ReplyText: "NO_ROUTE"})
+	err := WaitConfirms(context.Background(), c1, c2)
+	var returnErr *ReturnError
+	assert.True(t, errors.As(err, &returnErr))
+	assert.Equal(t, uint16(312), returnErr.ReplyCode)
+	t.Log(err)
+}
+
+func TestProducer_handleConfirm(t *testing.T) {
+	ch := &amqp.Channel{}
+	p := &Producer{
+		ch:        ch,
+		isConfirm: true,
+		pending:   make(map[uint64]*pendingMessage),
+		returned:  make(map[string]*ReturnError),
+		zapLog:    zap.NewNop(),
+	}
+
+	var messages []*pendingMessage
+	for i := 1; i <= 4; i++ {
+		pm := &pendingMessage{
+			tag:          uint64(i),
+			msg:          amqp.Publishing{MessageId: strconv.Itoa(i)},
+			confirmation: newConfirmation(),
+		}
+		p.pending[pm.tag] = pm
+		messages = append(messages, pm)
+	}
+
+	p.handleConfirm(ch, amqp.Confirmation{DeliveryTag: 1, Ack: true})
+	p.handleConfirm(ch, amqp.Confirmation{DeliveryTag: 2, Ack: false})
+	p.handleReturn(ch, amqp.Return{MessageId: "3", ReplyCode: 312})
+	p.handleConfirm(ch, amqp.Confirmation{DeliveryTag: 3, Ack: true})
+	p.handleConfirm(&amqp.Channel{}, amqp.Confirmation{DeliveryTag: 4, Ack: true}) // old channel, ignored
+
+	assert.NoError(t, messages[0].confirmation.Wait(context.Background()))
+	assert.Equal(t, ErrNacked, messages[1].confirmation.Wait(context.Background()))
+	assert.IsType(t, &ReturnError{}, messages[2].confirmation.Wait(context.Background()))
+	assert.Len(t, p.pending, 1)
+	assert.Len(t, p.returned, 0)
+
+	p.closePending()
+	assert.Equal(t, ErrClosed, messages[3].confirmation.Wait(context.Background()))
+	assert.Len(t, p.pending, 0)
+}
+
+func TestPublishAsyncErr(t *testing.T) {
+	p := &Producer{Exchange: NewDirectExchange("foo", "bar")}
+	_, err := p.PublishAsync(context.Background(), "", nil, []byte("data"))
+	assert.Error(t, err)
+
+	connection := &Connection{}
+	ch := make(chan struct{}, 1)
+	connection.addReconnectListener(ch)
+	assert.Len(t, connection.reconnectListeners, 1)
+	connection.removeReconnectListener(ch)
+	assert.Len(t, connection.reconnectListeners, 0)
+}
+
+func TestProducer_confirm(t *testing.T) {
+	utils.SafeRunWithTimeout(time.Second*3, func(cancel context.CancelFunc) {
+		defer cancel()
+		connection, err := NewConnection(url)
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		defer connection.Close()
+
+		exchange := NewTopicExchange("confirm-exchange-demo", "key1.*")
+		p, err := NewProducer(exchange, "confirm-queue-1", connection, WithProducerConfirm())
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		defer p.Close()
+
+		ctx, cancelCtx := context.WithTimeout(context.Background(), time.Second*2)
+		defer cancelCtx()
+
+		// blocking publish
+		err = p.PublishTopic(ctx, "key1.key2", []byte("say hello"))
+		assert.NoError(t, err)
+
+		// the message cannot be routed to any queue
+		err = p.PublishTopic(ctx, "foo.bar", []byte("say hello"))
+		assert.IsType(t, &ReturnError{}, err)
+
+		// batch publish
+		var confirmations []*Confirmation
+		for i := 0; i < 10; i++ {
+			c, err := p.PublishAsync(ctx, "key1.key2", nil, []byte("say hello "+strconv.Itoa(i)))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			confirmations = append(confirmations, c)
+		}
+		assert.NoError(t, WaitConfirms(ctx, confirmations...))
+	})
+}
@@ -94,6 +94,8 @@ type Connection struct {
 	blockChan   chan amqp.Blocking
 	closeChan   chan *amqp.Error
 	isConnected bool
+
+	reconnectListeners map[chan struct{}]struct{} // notified after reconnected successfully
 }
 
 // NewConnection rabbitmq connection
@@ -198,6 +200,12 @@ func (c *Connection) monitor() {
 			c.conn = amqpConn
 			c.blockChan = c.conn.NotifyBlocked(make(chan amqp.Blocking, 1))
 			c.closeChan = c.conn.NotifyClose(make(chan *amqp.Error, 1))
+			for ch := range c.reconnectListeners {
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
 			c.mutex.Unlock()
 		}
 	}
@@ -212,6 +220,22 @@ func (c *Connection) Close() {
 	close(c.exit)
 }
 
+// the listener is notified after reconnected, it should be buffered
+func (c *Connection) addReconnectListener(ch chan struct{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.reconnectListeners == nil {
+		c.reconnectListeners = make(map[chan struct{}]struct{})
+	}
+	c.reconnectListeners[ch] = struct{}{}
+}
+
+func (c *Connection) removeReconnectListener(ch chan struct{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.reconnectListeners, ch)
+}
+
 func (c *Connection) closeConn() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -4,8 +4,12 @@ import (
 	"context"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/zhufuyi/sponge/pkg/krand"
+	"github.com/zhufuyi/sponge/pkg/requestid"
+
 	amqp "github.com/rabbitmq/amqp091-go"
 	"go.uber.org/zap"
 )
@@ -19,9 +23,15 @@ type consumerOptions struct {
 	queueBind       *queueBindOptions
 	qos             *qosOptions
 	consume         *consumeOptions
+	retry           *retryOptions // nil means retry is disabled
 
 	isPersistent bool // persistent or not
 	isAutoAck    bool // auto-answer or not, if false, manual ACK required
+	isRequeue    bool // whether to requeue the failed message when manual ack and retry is disabled
+
+	concurrency   int                           // number of workers handling messages
+	partition     func(d *amqp.Delivery) string // the messages with the same partition key are handled in order
+	handleTimeout time.Duration                 // timeout of handling a message, 0 means no timeout
 }
 
 func (o *consumerOptions) apply(opts ...ConsumerOption) {
@@ -41,6 +51,9 @@ func defaultConsumerOptions() *consumerOptions {
 
 		isPersistent: true,
 		isAutoAck:    true,
+		isRequeue:    true,
+
+		concurrency: 1,
 	}
 }
 
@@ -79,6 +92,41 @@ func WithConsumerConsumeOptions(opts ...ConsumeOption) ConsumerOption {
 	}
 }
 
+// WithConsumerRetry set consumer retry option, the failed message is retried with exponential backoff,
+// and published to the dead letter queue when the attempts are exhausted.
+func WithConsumerRetry(opts ...RetryOption) ConsumerOption {
+	return func(o *consumerOptions) {
+		o.retry = defaultRetryOptions()
+		o.retry.apply(opts...)
+	}
+}
+
+// WithConsumerConcurrency set the number of workers handling messages concurrently, default is 1.
+// if manual ack and qos is not set, the qos prefetch count is set to the number of workers,
+// if the qos prefetch count is less than the number of workers, the number of workers is the prefetch count.
+func WithConsumerConcurrency(n int) ConsumerOption {
+	return func(o *consumerOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithConsumerPartition set the partition key of message, the messages with the same partition key are handled
+// in order by the same worker, e.g. PartitionByRoutingKey. note: the retried message is out of order.
+func WithConsumerPartition(fn func(d *amqp.Delivery) string) ConsumerOption {
+	return func(o *consumerOptions) {
+		o.partition = fn
+	}
+}
+
+// WithConsumerHandleTimeout set the timeout of handling a message, the ctx of handler is cancelled after timeout.
+func WithConsumerHandleTimeout(d time.Duration) ConsumerOption {
+	return func(o *consumerOptions) {
+		o.handleTimeout = d
+	}
+}
+
 // WithConsumerAutoAck set consumer auto ack option.
 func WithConsumerAutoAck(enable bool) ConsumerOption {
 	return func(o *consumerOptions) {
@@ -86,6 +134,14 @@ func WithConsumerAutoAck(enable bool) ConsumerOption {
 	}
 }
 
+// WithConsumerRequeue set whether to requeue the failed message when manual ack and retry is disabled, default is true,
+// if false, the failed message is discarded or dead-lettered by the x-dead-letter-exchange argument of queue.
+func WithConsumerRequeue(enable bool) ConsumerOption {
+	return func(o *consumerOptions) {
+		o.isRequeue = enable
+	}
+}
+
 // WithConsumerPersistent set consumer persistent option.
 func WithConsumerPersistent(enable bool) ConsumerOption {
 	return func(o *consumerOptions) {
@@ -221,16 +277,32 @@ type Consumer struct {
 	Exchange   *Exchange
 	QueueName  string
 	connection *Connection
-	ch         *amqp.Channel
+
+	chMu         sync.Mutex         // guards ch, retryCh and retryReturns, they are replaced after reconnected
+	ch           *amqp.Channel      // channel of consuming
+	retryCh      *amqp.Channel      // confirm mode channel of publishing retry messages
+	retryReturns <-chan amqp.Return // the returned retry messages that cannot be routed to any queue
+	retryMu      sync.Mutex         // the retry messages are published one by one, so the return belongs to the message being published
 
 	exchangeDeclareOption *exchangeDeclareOptions
 	queueDeclareOption    *queueDeclareOptions
 	queueBindOption       *queueBindOptions
 	qosOption             *qosOptions
 	consumeOption         *consumeOptions
+	retryOption           *retryOptions
 
 	isPersistent bool // persistent or not
 	isAutoAck    bool // auto ack or not
+	isRequeue    bool // requeue the failed message or not
+
+	concurrency   int
+	partition     func(d *amqp.Delivery) string
+	handleTimeout time.Duration
+
+	mu         sync.Mutex
+	consuming  sync.WaitGroup
+	shutdown   chan struct{}
+	isShutdown bool
 
 	zapLog *zap.Logger
 }
@@ -244,6 +316,20 @@ type Handler func(ctx context.Context, data []byte, tagID string) error
 func NewConsumer(exchange *Exchange, queueName string, connection *Connection, opts ...ConsumerOption) (*Consumer, error) {
 	o := defaultConsumerOptions()
 	o.apply(opts...)
+	if o.retry != nil {
+		o.retry.setDefaultNames(queueName)
+	}
+	if o.consume.consumer == "" {
+		o.consume.consumer = queueName + "-" + krand.String(krand.R_All, 8) // used to cancel consuming
+	}
+	if !o.isAutoAck && o.concurrency > 1 {
+		if !o.qos.enable {
+			o.qos.enable = true
+			o.qos.prefetchCount = o.concurrency
+		} else if o.qos.prefetchCount > 0 && o.qos.prefetchCount < o.concurrency {
+			o.concurrency = o.qos.prefetchCount // the extra workers are always idle
+		}
+	}
 
 	c := &Consumer{
 		Exchange:   exchange,
@@ -255,9 +341,16 @@ func NewConsumer(exchange *Exchange, queueName string, connection *Connection, o
 		queueBindOption:       o.queueBind,
 		qosOption:             o.qos,
 		consumeOption:         o.consume,
+		retryOption:           o.retry,
 
 		isPersistent: o.isPersistent,
 		isAutoAck:    o.isAutoAck,
+		isRequeue:    o.isRequeue,
+
+		concurrency:   o.concurrency,
+		partition:     o.partition,
+		handleTimeout: o.handleTimeout,
+		shutdown:      make(chan struct{}),
 
 		zapLog: connection.zapLog,
 	}
@@ -270,12 +363,13 @@ func (c *Consumer) initialize() error {
 	c.connection.mutex.Lock()
 	// crate a new channel
 	ch, err := c.connection.conn.Channel()
+	c.connection.mutex.Unlock()
 	if err != nil {
-		c.connection.mutex.Unlock()
 		return err
 	}
+	c.chMu.Lock()
 	c.ch = ch
-	c.connection.mutex.Unlock()
+	c.chMu.Unlock()
 
 	if c.Exchange.eType == exchangeTypeDelayedMessage {
 		if c.exchangeDeclareOption.args == nil {
@@ -342,14 +436,31 @@ func (c *Consumer) initialize() error {
 		}
 	}
 
+	if c.retryOption != nil {
+		err = c.declareRetry(ch)
+		if err != nil {
+			_ = ch.Close()
+			return err
+		}
+	}
+
 	fields := logFields(c.QueueName, c.Exchange)
-	fields = append(fields, zap.Bool("autoAck", c.isAutoAck))
+	fields = append(fields, zap.Bool("autoAck", c.isAutoAck), zap.Int("concurrency", c.concurrency))
+	if c.retryOption != nil {
+		fields = append(fields, zap.Int("maxAttempts", c.retryOption.maxAttempts), zap.String("deadLetterQueue", c.retryOption.deadLetterQueue))
+	}
 	c.zapLog.Info("[rabbitmq consumer] initialized", fields...)
 	return nil
 }
 
+func (c *Consumer) getChannel() *amqp.Channel {
+	c.chMu.Lock()
+	defer c.chMu.Unlock()
+	return c.ch
+}
+
 func (c *Consumer) consumeWithContext(ctx context.Context) (<-chan amqp.Delivery, error) {
-	return c.ch.ConsumeWithContext(
+	return c.getChannel().ConsumeWithContext(
 		ctx,
 		c.QueueName,
 		c.consumeOption.consumer,
@@ -361,9 +472,32 @@ func (c *Consumer) consumeWithContext(ctx context.Context) (<-chan amqp.Delivery
 	)
 }
 
-// Consume messages for loop in goroutine
+// Consume messages for loop in goroutine, the messages are handled by the workers concurrently
 func (c *Consumer) Consume(ctx context.Context, handler Handler) {
+	c.ConsumeMessage(ctx, func(ctx context.Context, msg *Message) error {
+		return handler(ctx, msg.Body, msg.TagID)
+	})
+}
+
+// ConsumeMessage consume messages like Consume, the handler receives the message envelope, the trace context and
+// request id propagated from the producer are extracted into the ctx of handler.
+func (c *Consumer) ConsumeMessage(ctx context.Context, handler MessageHandler) {
+	c.mu.Lock()
+	if c.isShutdown {
+		c.mu.Unlock()
+		return
+	}
+	c.consuming.Add(1)
+	c.mu.Unlock()
+
+	pool := newWorkerPool(c.concurrency, c.partition, func(d amqp.Delivery) {
+		c.handle(ctx, handler, d)
+	})
+
 	go func() {
+		defer c.consuming.Done()
+		defer pool.close()
+
 		ticker := time.NewTicker(time.Second * 2)
 		isFirst := true
 		for {
@@ -383,6 +517,8 @@ func (c *Consumer) Consume(ctx context.Context, handler Handler) {
 			case <-c.connection.exit:
 				c.Close()
 				return
+			case <-c.shutdown:
+				return
 			}
 			ticker.Stop()
 
@@ -405,25 +541,20 @@ func (c *Consumer) Consume(ctx context.Context, handler Handler) {
 				case <-c.connection.exit:
 					c.Close()
 					return
+				case <-c.shutdown:
+					// stop receiving new messages, and dispatch the received messages
+					_ = c.getChannel().Cancel(c.consumeOption.consumer, false)
+					for d := range delivery {
+						pool.dispatch(d)
+					}
+					return
 				case d, ok := <-delivery:
 					if !ok {
 						c.zapLog.Warn("[rabbitmq consumer] exit consume message, queue=" + c.QueueName)
 						isContinueConsume = true
 						break
 					}
-					tagID := strings.Join([]string{d.Exchange, c.QueueName, strconv.FormatUint(d.DeliveryTag, 10)}, "/")
-					err = handler(ctx, d.Body, tagID)
-					if err != nil {
-						c.zapLog.Warn("[rabbitmq consumer] handle message error", zap.String("err", err.Error()), zap.String("tagID", tagID))
-						continue
-					}
-					if !c.isAutoAck {
-						if err = d.Ack(false); err != nil {
-							c.zapLog.Warn("[rabbitmq consumer] manual ack error", zap.String("err", err.Error()), zap.String("tagID", tagID))
-							continue
-						}
-						c.zapLog.Info("[rabbitmq consumer] manual ack done", zap.String("tagID", tagID))
-					}
+					pool.dispatch(d)
 				}
 
 				if isContinueConsume {
@@ -434,9 +565,82 @@ func (c *Consumer) Consume(ctx context.Context, handler Handler) {
 	}()
 }
 
+func (c *Consumer) handle(ctx context.Context, handler MessageHandler, d amqp.Delivery) {
+	tagID := strings.Join([]string{d.Exchange, c.QueueName, strconv.FormatUint(d.DeliveryTag, 10)}, "/")
+	attempt := getRetryCount(d.Headers) + 1
+	msg := newMessage(&d, tagID)
+
+	handleCtx, span := startConsumeSpan(ctx, c.QueueName, msg)
+	handleCtx = context.WithValue(handleCtx, attemptKey{}, attempt)
+	if c.handleTimeout > 0 {
+		var cancel context.CancelFunc
+		handleCtx, cancel = context.WithTimeout(handleCtx, c.handleTimeout)
+		defer cancel()
+	}
+
+	err := handler(handleCtx, msg)
+	endSpan(span, err)
+	if err != nil {
+		c.zapLog.Warn("[rabbitmq consumer] handle message error", zap.String("err", err.Error()),
+			zap.String("tagID", tagID), zap.Int("attempt", attempt), zap.String(requestid.ContextKey, msg.RequestID()))
+		if c.retryOption != nil {
+			c.retry(ctx, &d, err, tagID)
+			return
+		}
+		// the failed message must be answered, otherwise the consumer stalls when the prefetch count is reached
+		if !c.isAutoAck {
+			if err = d.Nack(false, c.isRequeue); err != nil {
+				c.zapLog.Warn("[rabbitmq consumer] manual nack error", zap.String("err", err.Error()), zap.String("tagID", tagID))
+			}
+		}
+		return
+	}
+	if !c.isAutoAck {
+		if err = d.Ack(false); err != nil {
+			c.zapLog.Warn("[rabbitmq consumer] manual ack error", zap.String("err", err.Error()), zap.String("tagID", tagID))
+			return
+		}
+		c.zapLog.Info("[rabbitmq consumer] manual ack done", zap.String("tagID", tagID))
+	}
+}
+
+// Shutdown stop consuming, wait for the received messages to be handled until ctx is done, then close the channel,
+// the unacked messages are requeued by the broker. it can be added to the closes of app.App, e.g.
+//
+//	func() error { ctx, cancel := context.WithTimeout(context.Background(), time.Second*10); defer cancel(); return c.Shutdown(ctx) }
+func (c *Consumer) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.isShutdown {
+		c.isShutdown = true
+		close(c.shutdown)
+	}
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.consuming.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	c.Close()
+
+	return err
+}
+
 // Close consumer
 func (c *Consumer) Close() {
+	c.chMu.Lock()
+	defer c.chMu.Unlock()
 	if c.ch != nil {
 		_ = c.ch.Close()
 	}
+	if c.retryCh != nil {
+		_ = c.retryCh.Close()
+	}
 }
@@ -2,6 +2,7 @@ package rabbitmq
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -44,8 +45,13 @@ func TestConsumerOptions(t *testing.T) {
 			WithQosPrefetchSize(4096),
 			WithQosPrefetchGlobal(true),
 		),
+		WithConsumerRetry(WithRetryMaxAttempts(5)),
+		WithConsumerConcurrency(4),
+		WithConsumerPartition(PartitionByRoutingKey),
+		WithConsumerHandleTimeout(time.Second),
 		WithConsumerAutoAck(true),
 		WithConsumerPersistent(true),
+		WithConsumerRequeue(false),
 	}
 
 	o := defaultConsumerOptions()
@@ -64,8 +70,14 @@ func TestConsumerOptions(t *testing.T) {
 	assert.True(t, o.queueBind.noWait)
 	assert.Equal(t, "bar2", o.queueBind.args["foo2"])
 
+	assert.Equal(t, 5, o.retry.maxAttempts)
+	assert.Equal(t, 4, o.concurrency)
+	assert.NotNil(t, o.partition)
+	assert.Equal(t, time.Second, o.handleTimeout)
+
 	assert.True(t, o.isPersistent)
 	assert.True(t, o.isAutoAck)
+	assert.False(t, o.isRequeue)
 }
 
 var handler = func(ctx context.Context, data []byte, tagID string) error {
@@ -381,3 +393,111 @@ func TestConsumerErr(t *testing.T) {
 	time.Sleep(time.Millisecond * 2500)
 	close(c.connection.exit)
 }
+
+func TestConsumerConcurrencyQos(t *testing.T) {
+	connection := &Connection{zapLog: zap.NewNop()}
+	exchange := NewDirectExchange("foo", "bar")
+
+	// the qos prefetch count is set to the number of workers
+	c, err := NewConsumer(exchange, "test", connection, WithConsumerAutoAck(false), WithConsumerConcurrency(4))
+	assert.NoError(t, err)
+	assert.True(t, c.qosOption.enable)
+	assert.Equal(t, 4, c.qosOption.prefetchCount)
+	assert.NotEmpty(t, c.consumeOption.consumer)
+
+	// the number of workers is limited by the qos prefetch count
+	c, err = NewConsumer(exchange, "test", connection, WithConsumerAutoAck(false), WithConsumerConcurrency(4),
+		WithConsumerQosOptions(WithQosEnable(), WithQosPrefetchCount(2)))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.concurrency)
+}
+
+func TestConsumer_Shutdown(t *testing.T) {
+	connection := &Connection{
+		exit:   make(chan struct{}),
+		zapLog: zap.NewNop(),
+	}
+	c, err := NewConsumer(NewDirectExchange("foo", "bar"), "test", connection)
+	assert.NoError(t, err)
+
+	// the connection is not connected, waiting in loop
+	c.Consume(context.Background(), handler)
+	time.Sleep(time.Millisecond * 50)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, c.Shutdown(ctx))
+	assert.NoError(t, c.Shutdown(ctx))
+
+	c.Consume(context.Background(), handler) // return immediately after shutdown
+	assert.NoError(t, c.Shutdown(ctx))
+}
+
+func TestConsumer_handle(t *testing.T) {
+	connection := &Connection{zapLog: zap.NewNop()}
+	c, err := NewConsumer(NewDirectExchange("foo", "bar"), "test", connection, WithConsumerHandleTimeout(time.Millisecond*10))
+	assert.NoError(t, err)
+
+	var attempt int
+	var ctxErr error
+	c.handle(context.Background(), func(ctx context.Context, msg *Message) error {
+		attempt = AttemptFromContext(ctx)
+		<-ctx.Done()
+		ctxErr = ctx.Err()
+		return ctxErr
+	}, amqp.Delivery{Headers: amqp.Table{HeaderRetryCount: int32(1)}})
+	assert.Equal(t, 2, attempt)
+	assert.Equal(t, context.DeadlineExceeded, ctxErr)
+}
+
+type testAcknowledger struct {
+	acked, nacked, requeued bool
+}
+
+func (a *testAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *testAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+func (a *testAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func TestConsumer_handleNack(t *testing.T) {
+	connection := &Connection{zapLog: zap.NewNop()}
+	failedHandler := func(ctx context.Context, msg *Message) error {
+		return errors.New("handle failed")
+	}
+
+	// manual ack and retry is disabled, the failed message is nacked and requeued
+	c, err := NewConsumer(NewDirectExchange("foo", "bar"), "test", connection,
+		WithConsumerAutoAck(false), WithConsumerConcurrency(2))
+	assert.NoError(t, err)
+	a := &testAcknowledger{}
+	c.handle(context.Background(), failedHandler, amqp.Delivery{Acknowledger: a})
+	assert.True(t, a.nacked)
+	assert.True(t, a.requeued)
+	assert.False(t, a.acked)
+
+	// the failed message is not requeued
+	c, err = NewConsumer(NewDirectExchange("foo", "bar"), "test", connection,
+		WithConsumerAutoAck(false), WithConsumerRequeue(false))
+	assert.NoError(t, err)
+	a = &testAcknowledger{}
+	c.handle(context.Background(), failedHandler, amqp.Delivery{Acknowledger: a})
+	assert.True(t, a.nacked)
+	assert.False(t, a.requeued)
+
+	// the successful message is acked
+	a = &testAcknowledger{}
+	c.handle(context.Background(), func(ctx context.Context, msg *Message) error {
+		return nil
+	}, amqp.Delivery{Acknowledger: a})
+	assert.True(t, a.acked)
+	assert.False(t, a.nacked)
+}
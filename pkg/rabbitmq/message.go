@@ -0,0 +1,162 @@
+package rabbitmq
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/requestid"
+	"github.com/zhufuyi/sponge/pkg/tracer"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Message the envelope of message, the trace context and request id of ctx are injected into the headers
+// when publishing, and extracted into the ctx of handler when consuming.
+type Message struct {
+	Headers       map[string]interface{} // custom headers, required for headers type exchange
+	ContentType   string                 // default is text/plain
+	MessageID     string                 // used by the consumer for deduplication
+	CorrelationID string                 // e.g. the message id of request in request-reply
+	ReplyTo       string                 // e.g. the queue name of reply in request-reply
+	Type          string                 // message type name
+	Timestamp     time.Time
+	Body          []byte
+
+	// the following fields are set only when consuming
+	TagID       string
+	Exchange    string
+	RoutingKey  string
+	Redelivered bool
+}
+
+// RequestID get the request id propagated from the producer
+func (m *Message) RequestID() string {
+	return headerString(m.Headers, requestid.HeaderKey)
+}
+
+func (m *Message) publishing(deliveryMode uint8) amqp.Publishing {
+	contentType := m.ContentType
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	return amqp.Publishing{
+		Headers:       m.Headers,
+		ContentType:   contentType,
+		DeliveryMode:  deliveryMode,
+		CorrelationId: m.CorrelationID,
+		ReplyTo:       m.ReplyTo,
+		MessageId:     m.MessageID,
+		Timestamp:     m.Timestamp,
+		Type:          m.Type,
+		Body:          m.Body,
+	}
+}
+
+func newMessage(d *amqp.Delivery, tagID string) *Message {
+	return &Message{
+		Headers:       d.Headers,
+		ContentType:   d.ContentType,
+		MessageID:     d.MessageId,
+		CorrelationID: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
+		Type:          d.Type,
+		Timestamp:     d.Timestamp,
+		Body:          d.Body,
+		TagID:         tagID,
+		Exchange:      d.Exchange,
+		RoutingKey:    d.RoutingKey,
+		Redelivered:   d.Redelivered,
+	}
+}
+
+// MessageHandler message envelope handler
+type MessageHandler func(ctx context.Context, msg *Message) error
+
+// -------------------------------------------------------------------------------------------
+
+// headerCarrier adapts the headers of amqp to propagation.TextMapCarrier
+type headerCarrier amqp.Table
+
+// Get returns the value associated with the passed key
+func (c headerCarrier) Get(key string) string {
+	return headerString(amqp.Table(c), key)
+}
+
+// Set stores the key-value pair
+func (c headerCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+// Keys lists the keys stored in this carrier
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func headerString(headers map[string]interface{}, key string) string {
+	switch v := headers[key].(type) {
+	case string:
+		return v
+	case []byte: // the string header set by some clients is decoded as byte array
+		return string(v)
+	}
+	return ""
+}
+
+// start a span of publishing, and inject the trace context and request id into the copy of headers,
+// the headers passed by the caller are not modified.
+func startPublishSpan(ctx context.Context, exchange string, routingKey string, msg *amqp.Publishing) trace.Span {
+	ctx, span := tracer.NewSpan(ctx, "rabbitmq publish "+exchange, map[string]interface{}{
+		"messaging.system":               "rabbitmq",
+		"messaging.destination":          exchange,
+		"messaging.rabbitmq.routing_key": routingKey,
+		"messaging.message_id":           msg.MessageId,
+	})
+
+	headers := make(amqp.Table, len(msg.Headers)+3)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(headers))
+	if headerString(headers, requestid.HeaderKey) == "" {
+		if requestID := requestid.FromContext(ctx); requestID != "" {
+			headers[requestid.HeaderKey] = requestID
+		}
+	}
+	msg.Headers = headers
+
+	return span
+}
+
+// extract the trace context and request id from the headers, and start a span of consuming, the request id
+// can be got by middleware.CtxRequestID in handler, and is passed to the rpc server called in handler.
+func startConsumeSpan(ctx context.Context, queueName string, msg *Message) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier(msg.Headers))
+	ctx, span := tracer.NewSpan(ctx, "rabbitmq consume "+queueName, map[string]interface{}{
+		"messaging.system":               "rabbitmq",
+		"messaging.destination":          msg.Exchange,
+		"messaging.rabbitmq.routing_key": msg.RoutingKey,
+		"messaging.message_id":           msg.MessageID,
+		"messaging.queue":                queueName,
+	})
+
+	if requestID := msg.RequestID(); requestID != "" {
+		ctx = requestid.NewContext(ctx, requestID)
+	}
+
+	return ctx, span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
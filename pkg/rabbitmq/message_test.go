@@ -0,0 +1,187 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/gin/middleware"
+	"github.com/zhufuyi/sponge/pkg/grpc/interceptor"
+	"github.com/zhufuyi/sponge/pkg/utils"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func TestMessage(t *testing.T) {
+	now := time.Now()
+	msg := &Message{
+		Headers:       map[string]interface{}{middleware.HeaderXRequestIDKey: "req-1"},
+		MessageID:     "id-1",
+		CorrelationID: "cid-1",
+		ReplyTo:       "reply",
+		Type:          "created",
+		Timestamp:     now,
+		Body:          []byte("data"),
+	}
+	assert.Equal(t, "req-1", msg.RequestID())
+
+	pub := msg.publishing(amqp.Persistent)
+	assert.Equal(t, "text/plain", pub.ContentType)
+	assert.Equal(t, amqp.Persistent, pub.DeliveryMode)
+
+	d := &amqp.Delivery{
+		Headers:       pub.Headers,
+		ContentType:   pub.ContentType,
+		MessageId:     pub.MessageId,
+		CorrelationId: pub.CorrelationId,
+		ReplyTo:       pub.ReplyTo,
+		Type:          pub.Type,
+		Timestamp:     pub.Timestamp,
+		Body:          pub.Body,
+		Exchange:      "foo",
+		RoutingKey:    "bar",
+	}
+	got := newMessage(d, "foo/test/1")
+	msg.ContentType = "text/plain"
+	msg.TagID = "foo/test/1"
+	msg.Exchange = "foo"
+	msg.RoutingKey = "bar"
+	assert.Equal(t, msg, got)
+}
+
+func TestHeaderCarrier(t *testing.T) {
+	c := headerCarrier{"bytes": []byte("b"), "int": 1}
+	c.Set("str", "s")
+	assert.Equal(t, "s", c.Get("str"))
+	assert.Equal(t, "b", c.Get("bytes"))
+	assert.Equal(t, "", c.Get("int"))
+	assert.Equal(t, "", c.Get("not-found"))
+	assert.ElementsMatch(t, []string{"bytes", "int", "str"}, c.Keys())
+}
+
+func TestPropagation(t *testing.T) {
+	propagator := otel.GetTextMapPropagator()
+	provider := otel.GetTracerProvider()
+	defer func() {
+		otel.SetTextMapPropagator(propagator)
+		otel.SetTracerProvider(provider)
+	}()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+	ctx = context.WithValue(ctx, middleware.ContextRequestIDKey, "req-1") //nolint
+
+	headers := amqp.Table{"foo": "bar"}
+	pub := amqp.Publishing{Headers: headers}
+	publishSpan := startPublishSpan(ctx, "exchange", "key", &pub)
+	endSpan(publishSpan, nil)
+	assert.Len(t, headers, 1) // the headers of caller are not modified
+	assert.Equal(t, "bar", pub.Headers["foo"])
+	assert.Equal(t, "req-1", pub.Headers[middleware.HeaderXRequestIDKey])
+	assert.NotEmpty(t, pub.Headers["traceparent"])
+
+	msg := newMessage(&amqp.Delivery{Headers: pub.Headers, Exchange: "exchange", RoutingKey: "key"}, "")
+	consumeCtx, consumeSpan := startConsumeSpan(context.Background(), "queue", msg)
+	endSpan(consumeSpan, errors.New("handle error"))
+	assert.Equal(t, span.SpanContext().TraceID(), trace.SpanContextFromContext(consumeCtx).TraceID())
+	assert.Equal(t, publishSpan.SpanContext().SpanID(), consumeSpan.(sdktrace.ReadOnlySpan).Parent().SpanID())
+	assert.Equal(t, "req-1", middleware.CtxRequestID(consumeCtx))
+	assert.Equal(t, "req-1", interceptor.ClientCtxRequestID(consumeCtx))
+
+	// the request id set by caller is not overwritten
+	pub = amqp.Publishing{Headers: amqp.Table{middleware.HeaderXRequestIDKey: "req-2"}}
+	endSpan(startPublishSpan(ctx, "exchange", "key", &pub), nil)
+	assert.Equal(t, "req-2", pub.Headers[middleware.HeaderXRequestIDKey])
+}
+
+func TestConsumer_handleMessage(t *testing.T) {
+	connection := &Connection{zapLog: zap.NewNop()}
+	c, err := NewConsumer(NewDirectExchange("foo", "bar"), "test", connection)
+	assert.NoError(t, err)
+
+	var got *Message
+	var requestID string
+	c.handle(context.Background(), func(ctx context.Context, msg *Message) error {
+		got = msg
+		requestID = middleware.CtxRequestID(ctx)
+		return nil
+	}, amqp.Delivery{
+		Headers:       amqp.Table{middleware.HeaderXRequestIDKey: "req-1"},
+		CorrelationId: "cid-1",
+		Exchange:      "foo",
+		DeliveryTag:   1,
+		Body:          []byte("data"),
+	})
+	assert.Equal(t, "foo/test/1", got.TagID)
+	assert.Equal(t, "cid-1", got.CorrelationID)
+	assert.Equal(t, []byte("data"), got.Body)
+	assert.Equal(t, "req-1", requestID)
+}
+
+func TestPublishMessageAsyncErr(t *testing.T) {
+	p := &Producer{Exchange: NewDirectExchange("foo", "bar")}
+	_, err := p.PublishMessageAsync(context.Background(), "", &Message{Body: []byte("data")})
+	assert.Error(t, err)
+}
+
+func TestProducer_message(t *testing.T) {
+	utils.SafeRunWithTimeout(time.Second*3, func(cancel context.CancelFunc) {
+		defer cancel()
+		connection, err := NewConnection(url)
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		defer connection.Close()
+
+		exchange := NewDirectExchange("message-exchange-demo", "info")
+		queueName := "message-queue-demo"
+		p, err := NewProducer(exchange, queueName, connection)
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		defer p.Close()
+
+		c, err := NewConsumer(exchange, queueName, connection)
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		received := make(chan *Message, 1)
+		c.ConsumeMessage(context.Background(), func(ctx context.Context, msg *Message) error {
+			received <- msg
+			return nil
+		})
+		defer c.Close()
+
+		ctx := context.WithValue(context.Background(), middleware.ContextRequestIDKey, "req-1") //nolint
+		err = p.PublishMessage(ctx, "", &Message{
+			ContentType:   "application/json",
+			MessageID:     "id-1",
+			CorrelationID: "cid-1",
+			Body:          []byte(`{"name":"foo"}`),
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		select {
+		case msg := <-received:
+			assert.Equal(t, "cid-1", msg.CorrelationID)
+			assert.Equal(t, "req-1", msg.RequestID())
+		case <-time.After(time.Second * 2):
+			t.Log("timeout")
+		}
+	})
+}
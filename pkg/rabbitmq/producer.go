@@ -3,6 +3,7 @@ package rabbitmq
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -22,6 +23,8 @@ type producerOptions struct {
 	// If true, the message will be returned to the sender if the queue cannot be
 	// found according to its own exchange type and routeKey rules.
 	mandatory bool
+
+	isConfirm bool // publisher confirms or not
 }
 
 func (o *producerOptions) apply(opts ...ProducerOption) {
@@ -77,6 +80,15 @@ func WithProducerMandatory(enable bool) ProducerOption {
 	}
 }
 
+// WithProducerConfirm set producer confirm mode option, the publishing is blocked until the broker confirms the message,
+// the returned mandatory message is reported as *ReturnError, and the unconfirmed messages are published again
+// automatically after reconnected, the delivery is at least once.
+func WithProducerConfirm() ProducerOption {
+	return func(o *producerOptions) {
+		o.isConfirm = true
+	}
+}
+
 // -------------------------------------------------------------------------------------------
 
 // Producer session
@@ -94,6 +106,21 @@ type Producer struct {
 	// found according to its own exchange type and routeKey rules.
 	mandatory bool
 
+	connection            *Connection
+	exchangeDeclareOption *exchangeDeclareOptions
+	queueDeclareOption    *queueDeclareOptions
+	queueBindOption       *queueBindOptions
+
+	// confirm mode, the channel and the following fields are guarded by mu
+	isConfirm   bool
+	mu          sync.Mutex
+	pending     map[uint64]*pendingMessage // published but unconfirmed messages, the key is delivery tag
+	unsent      []*pendingMessage          // messages failed to publish because the channel is closed
+	returned    map[string]*ReturnError    // returned messages, the key is message id
+	reconnected chan struct{}
+	exit        chan struct{}
+	closeOnce   sync.Once
+
 	zapLog *zap.Logger
 }
 
@@ -102,30 +129,80 @@ func NewProducer(exchange *Exchange, queueName string, connection *Connection, o
 	o := defaultProducerOptions()
 	o.apply(opts...)
 
+	deliveryMode := amqp.Persistent
+	if !o.isPersistent {
+		deliveryMode = amqp.Transient
+	}
+
+	p := &Producer{
+		QueueName:    queueName,
+		conn:         connection.conn,
+		Exchange:     exchange,
+		isPersistent: o.isPersistent,
+		deliveryMode: deliveryMode,
+		mandatory:    o.mandatory,
+
+		connection:            connection,
+		exchangeDeclareOption: o.exchangeDeclare,
+		queueDeclareOption:    o.queueDeclare,
+		queueBindOption:       o.queueBind,
+
+		isConfirm: o.isConfirm,
+		zapLog:    connection.zapLog,
+	}
+
+	ch, err := p.initialize()
+	if err != nil {
+		return nil, err
+	}
+	p.ch = ch
+
+	if p.isConfirm {
+		p.pending = make(map[uint64]*pendingMessage)
+		p.returned = make(map[string]*ReturnError)
+		p.reconnected = make(chan struct{}, 1)
+		p.exit = make(chan struct{})
+		p.listenConfirms(ch)
+		connection.addReconnectListener(p.reconnected)
+		go p.watchReconnect()
+	}
+
+	fields := logFields(queueName, exchange)
+	fields = append(fields, zap.Bool("isPersistent", o.isPersistent), zap.Bool("isConfirm", o.isConfirm))
+	connection.zapLog.Info("[rabbit producer] initialized", fields...)
+
+	return p, nil
+}
+
+// create a new channel, declare exchange and queue, and enable confirm mode if required
+func (p *Producer) initialize() (*amqp.Channel, error) {
+	p.connection.mutex.Lock()
 	// crate a new channel
-	ch, err := connection.conn.Channel()
+	ch, err := p.connection.conn.Channel()
+	p.connection.mutex.Unlock()
 	if err != nil {
 		return nil, err
 	}
 
+	exchange := p.Exchange
 	if exchange.eType == exchangeTypeDelayedMessage {
-		if o.exchangeDeclare.args == nil {
-			o.exchangeDeclare.args = amqp.Table{
+		if p.exchangeDeclareOption.args == nil {
+			p.exchangeDeclareOption.args = amqp.Table{
 				"x-delayed-type": exchange.delayedMessageType,
 			}
 		} else {
-			o.exchangeDeclare.args["x-delayed-type"] = exchange.delayedMessageType
+			p.exchangeDeclareOption.args["x-delayed-type"] = exchange.delayedMessageType
 		}
 	}
 	// declare the exchange type
 	err = ch.ExchangeDeclare(
 		exchange.name,
 		exchange.eType,
-		o.isPersistent,
-		o.exchangeDeclare.autoDelete,
-		o.exchangeDeclare.internal,
-		o.exchangeDeclare.noWait,
-		o.exchangeDeclare.args,
+		p.isPersistent,
+		p.exchangeDeclareOption.autoDelete,
+		p.exchangeDeclareOption.internal,
+		p.exchangeDeclareOption.noWait,
+		p.exchangeDeclareOption.args,
 	)
 	if err != nil {
 		_ = ch.Close()
@@ -134,19 +211,19 @@ func NewProducer(exchange *Exchange, queueName string, connection *Connection, o
 
 	// declare a queue and create it automatically if it doesn't exist, or skip creation if it does.
 	q, err := ch.QueueDeclare(
-		queueName,
-		o.isPersistent,
-		o.queueDeclare.autoDelete,
-		o.queueDeclare.exclusive,
-		o.queueDeclare.noWait,
-		o.queueDeclare.args,
+		p.QueueName,
+		p.isPersistent,
+		p.queueDeclareOption.autoDelete,
+		p.queueDeclareOption.exclusive,
+		p.queueDeclareOption.noWait,
+		p.queueDeclareOption.args,
 	)
 	if err != nil {
 		_ = ch.Close()
 		return nil, err
 	}
 
-	args := o.queueBind.args
+	args := p.queueBindOption.args
 	if exchange.eType == exchangeTypeHeaders {
 		args = exchange.headersKeys
 	}
@@ -155,7 +232,7 @@ func NewProducer(exchange *Exchange, queueName string, connection *Connection, o
 		q.Name,
 		exchange.routingKey,
 		exchange.name,
-		o.queueBind.noWait,
+		p.queueBindOption.noWait,
 		args,
 	)
 	if err != nil {
@@ -163,25 +240,14 @@ func NewProducer(exchange *Exchange, queueName string, connection *Connection, o
 		return nil, err
 	}
 
-	deliveryMode := amqp.Persistent
-	if !o.isPersistent {
-		deliveryMode = amqp.Transient
+	if p.isConfirm {
+		if err = ch.Confirm(false); err != nil {
+			_ = ch.Close()
+			return nil, err
+		}
 	}
 
-	fields := logFields(queueName, exchange)
-	fields = append(fields, zap.Bool("isPersistent", o.isPersistent))
-	connection.zapLog.Info("[rabbit producer] initialized", fields...)
-
-	return &Producer{
-		QueueName:    queueName,
-		conn:         connection.conn,
-		ch:           ch,
-		Exchange:     exchange,
-		isPersistent: o.isPersistent,
-		deliveryMode: deliveryMode,
-		mandatory:    o.mandatory,
-		zapLog:       connection.zapLog,
-	}, nil
+	return ch, nil
 }
 
 // PublishDirect send direct type message
@@ -189,18 +255,11 @@ func (p *Producer) PublishDirect(ctx context.Context, body []byte) error {
 	if p.Exchange.eType != exchangeTypeDirect {
 		return fmt.Errorf("invalid exchange type (%s), only supports direct type", p.Exchange.eType)
 	}
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		p.Exchange.routingKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
-	)
+	return p.publish(ctx, p.Exchange.routingKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
 }
 
 // PublishFanout send fanout type message
@@ -208,18 +267,11 @@ func (p *Producer) PublishFanout(ctx context.Context, body []byte) error {
 	if p.Exchange.eType != exchangeTypeFanout {
 		return fmt.Errorf("invalid exchange type (%s), only supports fanout type", p.Exchange.eType)
 	}
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		p.Exchange.routingKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
-	)
+	return p.publish(ctx, p.Exchange.routingKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
 }
 
 // PublishTopic send topic type message
@@ -227,18 +279,11 @@ func (p *Producer) PublishTopic(ctx context.Context, topicKey string, body []byt
 	if p.Exchange.eType != exchangeTypeTopic {
 		return fmt.Errorf("invalid exchange type (%s), only supports topic type", p.Exchange.eType)
 	}
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		topicKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
-	)
+	return p.publish(ctx, topicKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
 }
 
 // PublishHeaders send headers type message
@@ -246,19 +291,12 @@ func (p *Producer) PublishHeaders(ctx context.Context, headersKeys map[string]in
 	if p.Exchange.eType != exchangeTypeHeaders {
 		return fmt.Errorf("invalid exchange type (%s), only supports headers type", p.Exchange.eType)
 	}
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		p.Exchange.routingKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			Headers:      headersKeys,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
-	)
+	return p.publish(ctx, p.Exchange.routingKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		Headers:      headersKeys,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
 }
 
 // PublishDelayedMessage send delayed type message
@@ -285,25 +323,58 @@ func (p *Producer) PublishDelayedMessage(ctx context.Context, delayTime time.Dur
 	}
 	headersKeys["x-delay"] = int(delayTime / time.Millisecond) // delay time: milliseconds
 
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		routingKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			Headers:      headersKeys,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
-	)
+	return p.publish(ctx, routingKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		Headers:      headersKeys,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
+}
+
+// PublishMessage send the message envelope, routingKey is the topic key of topic type, empty means the routing key
+// of exchange, the headers are required for headers type, and the header x-delay (milliseconds) is required for
+// delayed message type. the trace context and request id of ctx are injected into the headers.
+func (p *Producer) PublishMessage(ctx context.Context, routingKey string, msg *Message) error {
+	if routingKey == "" {
+		routingKey = p.Exchange.routingKey
+	}
+	return p.publish(ctx, routingKey, msg.publishing(p.deliveryMode))
 }
 
-// Close the consumer
+func (p *Producer) publish(ctx context.Context, routingKey string, msg amqp.Publishing) error {
+	if !p.isConfirm {
+		span := startPublishSpan(ctx, p.Exchange.name, routingKey, &msg)
+		err := p.ch.PublishWithContext(ctx, p.Exchange.name, routingKey, p.mandatory, false, msg)
+		endSpan(span, err)
+		return err
+	}
+
+	confirmation, err := p.publishDeferred(ctx, routingKey, msg)
+	if err != nil {
+		return err
+	}
+	return confirmation.Wait(ctx)
+}
+
+// Close the producer
 func (p *Producer) Close() {
-	if p.ch != nil {
-		_ = p.ch.Close()
+	p.closeOnce.Do(func() {
+		if p.exit != nil {
+			close(p.exit)
+		}
+		if p.connection != nil && p.reconnected != nil {
+			p.connection.removeReconnectListener(p.reconnected)
+		}
+		if p.isConfirm {
+			p.closePending()
+		}
+	})
+
+	p.mu.Lock()
+	ch := p.ch // replaced when the channel is recovered
+	p.mu.Unlock()
+	if ch != nil {
+		_ = ch.Close()
 	}
 }
 
@@ -32,6 +32,7 @@ func TestProducerOptions(t *testing.T) {
 		),
 		WithProducerPersistent(true),
 		WithProducerMandatory(true),
+		WithProducerConfirm(),
 	}
 
 	o := defaultProducerOptions()
@@ -52,6 +53,7 @@ func TestProducerOptions(t *testing.T) {
 
 	assert.True(t, o.isPersistent)
 	assert.True(t, o.mandatory)
+	assert.True(t, o.isConfirm)
 }
 
 func TestProducer_direct(t *testing.T) {
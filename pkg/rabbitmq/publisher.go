@@ -61,18 +61,11 @@ func NewPublisher(channelName string, connection *Connection, opts ...ProducerOp
 }
 
 func (p *Publisher) Publish(ctx context.Context, body []byte) error {
-	return p.ch.PublishWithContext(
-		ctx,
-		p.Exchange.name,
-		p.Exchange.routingKey,
-		p.mandatory,
-		false,
-		amqp.Publishing{
-			DeliveryMode: p.deliveryMode,
-			ContentType:  "text/plain",
-			Body:         body,
-		},
-	)
+	return p.publish(ctx, p.Exchange.routingKey, amqp.Publishing{
+		DeliveryMode: p.deliveryMode,
+		ContentType:  "text/plain",
+		Body:         body,
+	})
 }
 
 // Close publisher
@@ -0,0 +1,345 @@
+package rabbitmq
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+const (
+	// HeaderRetryCount the number of times the message has been retried, the attempt is HeaderRetryCount+1
+	HeaderRetryCount = "x-retry-count"
+	// HeaderError the error returned by the handler of the last attempt, set when the message is dead-lettered
+	HeaderError = "x-error"
+	// HeaderOriginalExchange the exchange of the message before dead-lettered
+	HeaderOriginalExchange = "x-original-exchange"
+	// HeaderOriginalRoutingKey the routing key of the message before dead-lettered
+	HeaderOriginalRoutingKey = "x-original-routing-key"
+)
+
+// RetryOption retry option.
+type RetryOption func(*retryOptions)
+
+type retryOptions struct {
+	maxAttempts int // the maximum number of times the handler is called, including the first attempt
+
+	// the delay of the nth retry is initialBackoff * multiplier^(n-1), and no more than maxBackoff
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	multiplier     float64
+
+	// if true, the retry message is delayed by the x-delayed-message exchange (the plugin rabbitmq_delayed_message_exchange is required),
+	// otherwise it is delayed by the retry queues with message ttl, and returned to the queue when it expires.
+	isDelayedExchange bool
+
+	deadLetterExchange string // default is <queue name>.dlx
+	deadLetterQueue    string // default is <queue name>.dlq
+}
+
+func (o *retryOptions) apply(opts ...RetryOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// default retry settings
+func defaultRetryOptions() *retryOptions {
+	return &retryOptions{
+		maxAttempts:    3,
+		initialBackoff: time.Second,
+		maxBackoff:     time.Minute,
+		multiplier:     2,
+	}
+}
+
+// WithRetryMaxAttempts set the maximum number of attempts, including the first attempt, default is 3,
+// if it is 1, the failed message is dead-lettered directly.
+func WithRetryMaxAttempts(n int) RetryOption {
+	return func(o *retryOptions) {
+		if n < 1 {
+			n = 1
+		}
+		o.maxAttempts = n
+	}
+}
+
+// WithRetryBackoff set exponential backoff of retry, default is initial 1s, max 1m, multiplier 2.
+func WithRetryBackoff(initial time.Duration, max time.Duration, multiplier float64) RetryOption {
+	return func(o *retryOptions) {
+		if initial > 0 {
+			o.initialBackoff = initial
+		}
+		if max > 0 {
+			o.maxBackoff = max
+		}
+		if multiplier >= 1 {
+			o.multiplier = multiplier
+		}
+	}
+}
+
+// WithRetryDelayedExchange delay the retry message by the x-delayed-message exchange instead of the retry queues,
+// the plugin rabbitmq_delayed_message_exchange is required.
+func WithRetryDelayedExchange() RetryOption {
+	return func(o *retryOptions) {
+		o.isDelayedExchange = true
+	}
+}
+
+// WithRetryDeadLetter set the name of dead letter exchange and queue, default is <queue name>.dlx and <queue name>.dlq.
+func WithRetryDeadLetter(exchangeName string, queueName string) RetryOption {
+	return func(o *retryOptions) {
+		o.deadLetterExchange = exchangeName
+		o.deadLetterQueue = queueName
+	}
+}
+
+func (o *retryOptions) setDefaultNames(queueName string) {
+	if o.deadLetterExchange == "" {
+		o.deadLetterExchange = queueName + ".dlx"
+	}
+	if o.deadLetterQueue == "" {
+		o.deadLetterQueue = queueName + ".dlq"
+	}
+}
+
+// the delay before the nth retry
+func (o *retryOptions) backoff(retryCount int) time.Duration {
+	d := float64(o.initialBackoff) * math.Pow(o.multiplier, float64(retryCount-1))
+	if d > float64(o.maxBackoff) {
+		return o.maxBackoff
+	}
+	return time.Duration(d)
+}
+
+func retryExchangeName(queueName string) string {
+	return queueName + ".retry"
+}
+
+// the retry queues with the same delay are shared
+func retryQueueName(queueName string, delay time.Duration) string {
+	return queueName + ".retry." + strconv.FormatInt(delay.Milliseconds(), 10) + "ms"
+}
+
+// -------------------------------------------------------------------------------------------
+
+type attemptKey struct{}
+
+// AttemptFromContext get the attempt of the message being handled, starting from 1,
+// return 0 if the ctx is not passed from consumer handler.
+func AttemptFromContext(ctx context.Context) int {
+	if v, ok := ctx.Value(attemptKey{}).(int); ok {
+		return v
+	}
+	return 0
+}
+
+func getRetryCount(headers amqp.Table) int {
+	switch v := headers[HeaderRetryCount].(type) {
+	case int:
+		return v
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	}
+	return 0
+}
+
+// declare dead letter exchange and queue, retry exchange or queues
+func (c *Consumer) declareRetry(ch *amqp.Channel) error {
+	o := c.retryOption
+
+	err := ch.ExchangeDeclare(o.deadLetterExchange, exchangeTypeDirect, c.isPersistent, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	_, err = ch.QueueDeclare(o.deadLetterQueue, c.isPersistent, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	err = ch.QueueBind(o.deadLetterQueue, c.QueueName, o.deadLetterExchange, false, nil)
+	if err != nil {
+		return err
+	}
+
+	if o.maxAttempts <= 1 {
+		return nil
+	}
+
+	if o.isDelayedExchange {
+		e := NewDelayedMessageExchange(retryExchangeName(c.QueueName), NewDirectExchange("", c.QueueName))
+		err = ch.ExchangeDeclare(e.name, e.eType, c.isPersistent, false, false, false,
+			amqp.Table{"x-delayed-type": e.delayedMessageType})
+		if err != nil {
+			return err
+		}
+		return ch.QueueBind(c.QueueName, e.routingKey, e.name, false, nil)
+	}
+
+	// the expired message in the retry queue is returned to the queue through the default exchange
+	for i := 1; i < o.maxAttempts; i++ {
+		delay := o.backoff(i)
+		_, err = ch.QueueDeclare(retryQueueName(c.QueueName, delay), c.isPersistent, false, false, false, amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": c.QueueName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// the handler fails, the message is published to the retry exchange or queue, or dead letter exchange
+// if the attempts are exhausted, then the message is acked after the broker confirms the published message.
+func (c *Consumer) retry(ctx context.Context, d *amqp.Delivery, handleErr error, tagID string) {
+	o := c.retryOption
+	retryCount := getRetryCount(d.Headers)
+
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	var exchange, routingKey string
+	isDeadLetter := retryCount+1 >= o.maxAttempts
+	if !isDeadLetter {
+		retryCount++
+		delay := o.backoff(retryCount)
+		headers[HeaderRetryCount] = int32(retryCount)
+		if o.isDelayedExchange {
+			exchange, routingKey = retryExchangeName(c.QueueName), c.QueueName
+			headers["x-delay"] = delay.Milliseconds()
+		} else {
+			exchange, routingKey = "", retryQueueName(c.QueueName, delay)
+			delete(headers, "x-delay")
+		}
+	} else {
+		exchange, routingKey = o.deadLetterExchange, c.QueueName
+		headers[HeaderError] = handleErr.Error()
+		headers[HeaderOriginalExchange] = d.Exchange
+		headers[HeaderOriginalRoutingKey] = d.RoutingKey
+		delete(headers, "x-delay")
+	}
+
+	// the x-delayed-message exchange routes the message after the delay, it always returns the mandatory message
+	isMandatory := isDeadLetter || !o.isDelayedExchange
+	err := c.publishRetry(ctx, exchange, routingKey, isMandatory, amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	})
+	if err != nil {
+		c.zapLog.Warn("[rabbitmq consumer] publish retry message error", zap.String("err", err.Error()), zap.String("tagID", tagID))
+		if !c.isAutoAck {
+			_ = d.Nack(false, true) // requeue, avoid losing message
+		}
+		return
+	}
+
+	if isDeadLetter {
+		c.zapLog.Warn("[rabbitmq consumer] message is dead-lettered", zap.String("tagID", tagID), zap.Int("retryCount", retryCount))
+	} else {
+		c.zapLog.Info("[rabbitmq consumer] message will be retried", zap.String("tagID", tagID), zap.Int("retryCount", retryCount))
+	}
+	if !c.isAutoAck {
+		if err = d.Ack(false); err != nil {
+			c.zapLog.Warn("[rabbitmq consumer] manual ack error", zap.String("err", err.Error()), zap.String("tagID", tagID))
+		}
+	}
+}
+
+// publish the retry or dead letter message in confirm mode, and wait for the confirmation of broker,
+// if the mandatory message cannot be routed to any queue, e.g. the retry queue is deleted, return *ReturnError.
+func (c *Consumer) publishRetry(ctx context.Context, exchange string, routingKey string, mandatory bool, msg amqp.Publishing) error {
+	c.retryMu.Lock()
+	defer c.retryMu.Unlock()
+
+	ch, returns, err := c.getRetryChannel()
+	if err != nil {
+		return err
+	}
+	drainReturns(returns) // the returns of the messages that were not waited for, e.g. ctx is done
+
+	dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, mandatory, false, msg)
+	if err != nil {
+		return err
+	}
+	isAcked, err := dc.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	// the broker sends the return of message before the ack
+	if r, ok := drainReturns(returns); ok {
+		return &ReturnError{
+			ReplyCode:  r.ReplyCode,
+			ReplyText:  r.ReplyText,
+			Exchange:   r.Exchange,
+			RoutingKey: r.RoutingKey,
+		}
+	}
+	if !isAcked {
+		return ErrNacked
+	}
+	return nil
+}
+
+// read all the returns in the channel, return the last one
+func drainReturns(returns <-chan amqp.Return) (amqp.Return, bool) {
+	var last amqp.Return
+	isReturned := false
+	for {
+		select {
+		case r, ok := <-returns:
+			if !ok {
+				return last, isReturned
+			}
+			last, isReturned = r, true
+		default:
+			return last, isReturned
+		}
+	}
+}
+
+// get the confirm mode channel of publishing retry messages, create a new one if it is closed, e.g. reconnected
+func (c *Consumer) getRetryChannel() (*amqp.Channel, <-chan amqp.Return, error) {
+	c.chMu.Lock()
+	defer c.chMu.Unlock()
+	if c.retryCh != nil && !c.retryCh.IsClosed() {
+		return c.retryCh, c.retryReturns, nil
+	}
+
+	c.connection.mutex.Lock()
+	ch, err := c.connection.conn.Channel()
+	c.connection.mutex.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return nil, nil, err
+	}
+	c.retryCh = ch
+	c.retryReturns = ch.NotifyReturn(make(chan amqp.Return, confirmBufferSize))
+	return ch, c.retryReturns, nil
+}
@@ -0,0 +1,117 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/utils"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryOptions(t *testing.T) {
+	o := defaultRetryOptions()
+	o.apply(
+		WithRetryMaxAttempts(0),
+		WithRetryBackoff(time.Millisecond*100, time.Second, 3),
+		WithRetryDelayedExchange(),
+		WithRetryDeadLetter("foo.dlx", ""),
+	)
+	o.setDefaultNames("foo")
+
+	assert.Equal(t, 1, o.maxAttempts)
+	assert.Equal(t, time.Millisecond*100, o.initialBackoff)
+	assert.Equal(t, time.Second, o.maxBackoff)
+	assert.Equal(t, 3.0, o.multiplier)
+	assert.True(t, o.isDelayedExchange)
+	assert.Equal(t, "foo.dlx", o.deadLetterExchange)
+	assert.Equal(t, "foo.dlq", o.deadLetterQueue)
+
+	// invalid values are ignored
+	o.apply(WithRetryBackoff(0, 0, 0.5))
+	assert.Equal(t, time.Millisecond*100, o.initialBackoff)
+	assert.Equal(t, 3.0, o.multiplier)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	o := defaultRetryOptions()
+	assert.Equal(t, time.Second, o.backoff(1))
+	assert.Equal(t, time.Second*2, o.backoff(2))
+	assert.Equal(t, time.Second*4, o.backoff(3))
+	assert.Equal(t, time.Minute, o.backoff(10))
+
+	assert.Equal(t, "foo.retry", retryExchangeName("foo"))
+	assert.Equal(t, "foo.retry.2000ms", retryQueueName("foo", o.backoff(2)))
+}
+
+func TestGetRetryCount(t *testing.T) {
+	assert.Equal(t, 0, getRetryCount(nil))
+	assert.Equal(t, 0, getRetryCount(amqp.Table{HeaderRetryCount: "1"}))
+	assert.Equal(t, 1, getRetryCount(amqp.Table{HeaderRetryCount: 1}))
+	assert.Equal(t, 2, getRetryCount(amqp.Table{HeaderRetryCount: int32(2)}))
+	assert.Equal(t, 3, getRetryCount(amqp.Table{HeaderRetryCount: int64(3)}))
+
+	assert.Equal(t, 0, AttemptFromContext(context.Background()))
+	assert.Equal(t, 2, AttemptFromContext(context.WithValue(context.Background(), attemptKey{}, 2)))
+}
+
+func TestConsumer_retry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	exchangeName := "retry-exchange-demo"
+	queueName := "retry-queue-1"
+	routeKey := "retry-key-1"
+	exchange := NewDirectExchange(exchangeName, routeKey)
+
+	err := producerDirect(ctx, queueName, exchange)
+	if err != nil {
+		t.Log(err)
+		return
+	}
+
+	utils.SafeRunWithTimeout(time.Second*3, func(cancel context.CancelFunc) {
+		defer cancel()
+		connection, err := NewConnection(url)
+		if err != nil {
+			t.Log(err)
+			return
+		}
+
+		c, err := NewConsumer(exchange, queueName, connection, WithConsumerAutoAck(false),
+			WithConsumerRetry(WithRetryMaxAttempts(3), WithRetryBackoff(time.Millisecond*200, time.Second, 2)))
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		c.Consume(ctx, func(ctx context.Context, data []byte, tagID string) error {
+			t.Logf("[received]: tagID=%s, attempt=%d, data=%s", tagID, AttemptFromContext(ctx), data)
+			return errors.New("handle failed")
+		})
+	})
+
+	<-ctx.Done()
+	time.Sleep(time.Millisecond * 100)
+}
+
+func TestConsumer_publishRetryReturned(t *testing.T) {
+	connection, err := NewConnection(url)
+	if err != nil {
+		t.Log(err)
+		return
+	}
+	defer connection.Close()
+	c := &Consumer{connection: connection}
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	// the retry queue does not exist, the mandatory message is returned instead of being dropped
+	err = c.publishRetry(ctx, "", "not-exist-retry-queue", true, amqp.Publishing{Body: []byte("foo")})
+	var returnErr *ReturnError
+	assert.True(t, errors.As(err, &returnErr))
+	err = c.publishRetry(ctx, "", "not-exist-retry-queue", false, amqp.Publishing{Body: []byte("foo")})
+	assert.NoError(t, err)
+}
@@ -25,6 +25,11 @@ func (s *Subscriber) Subscribe(ctx context.Context, handler Handler) {
 	s.Consume(ctx, handler)
 }
 
+// SubscribeMessage subscribe and handle message envelope
+func (s *Subscriber) SubscribeMessage(ctx context.Context, handler MessageHandler) {
+	s.ConsumeMessage(ctx, handler)
+}
+
 // Close subscriber
 func (s *Subscriber) Close() {
 	if s.ch != nil {
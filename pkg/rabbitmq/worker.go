@@ -0,0 +1,70 @@
+package rabbitmq
+
+import (
+	"hash/fnv"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// PartitionByRoutingKey the messages with the same routing key are handled in order by the same worker
+func PartitionByRoutingKey(d *amqp.Delivery) string {
+	return d.RoutingKey
+}
+
+// the workers handle the deliveries concurrently, if partition is set, each worker has its own channel,
+// and the deliveries with the same partition key are dispatched to the same worker, otherwise all workers share a channel.
+type workerPool struct {
+	chans     []chan amqp.Delivery
+	partition func(d *amqp.Delivery) string
+	wg        sync.WaitGroup
+}
+
+func newWorkerPool(size int, partition func(d *amqp.Delivery) string, handle func(d amqp.Delivery)) *workerPool {
+	if size < 1 {
+		size = 1
+	}
+	n := 1
+	if partition != nil {
+		n = size
+	}
+
+	p := &workerPool{
+		chans:     make([]chan amqp.Delivery, n),
+		partition: partition,
+	}
+	for i := range p.chans {
+		p.chans[i] = make(chan amqp.Delivery) // unbuffered, the deliveries are buffered by prefetch of broker
+	}
+	for i := 0; i < size; i++ {
+		ch := p.chans[i%n]
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for d := range ch {
+				handle(d)
+			}
+		}()
+	}
+
+	return p
+}
+
+// block until a worker is idle
+func (p *workerPool) dispatch(d amqp.Delivery) {
+	idx := 0
+	if p.partition != nil && len(p.chans) > 1 {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(p.partition(&d)))
+		idx = int(h.Sum32() % uint32(len(p.chans)))
+	}
+	p.chans[idx] <- d
+}
+
+// wait for the workers to finish the dispatched deliveries
+func (p *workerPool) close() {
+	for _, ch := range p.chans {
+		close(ch)
+	}
+	p.wg.Wait()
+}
@@ -0,0 +1,56 @@
+package rabbitmq
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool(t *testing.T) {
+	var running, maxRunning int32
+	p := newWorkerPool(4, nil, func(d amqp.Delivery) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 20)
+		atomic.AddInt32(&running, -1)
+	})
+	for i := 0; i < 8; i++ {
+		p.dispatch(amqp.Delivery{DeliveryTag: uint64(i)})
+	}
+	p.close()
+	assert.Equal(t, int32(4), atomic.LoadInt32(&maxRunning))
+}
+
+func TestWorkerPool_partition(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string][]uint64)
+	p := newWorkerPool(3, PartitionByRoutingKey, func(d amqp.Delivery) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		received[d.RoutingKey] = append(received[d.RoutingKey], d.DeliveryTag)
+		mu.Unlock()
+	})
+	for i := 0; i < 30; i++ {
+		p.dispatch(amqp.Delivery{RoutingKey: "key" + strconv.Itoa(i%5), DeliveryTag: uint64(i)})
+	}
+	p.close()
+
+	assert.Len(t, received, 5)
+	for _, tags := range received {
+		for i := 1; i < len(tags); i++ {
+			assert.Less(t, tags[i-1], tags[i]) // in order
+		}
+	}
+
+	newWorkerPool(0, nil, func(d amqp.Delivery) {}).close()
+}
@@ -0,0 +1,43 @@
+// Package requestid is the keys and getters of request id shared by gin middleware, grpc interceptor and
+// message queue, it does not depend on gin or grpc server, so it is cheap to import.
+package requestid
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	// ContextKey request id key for context and grpc metadata, it is updated by
+	// middleware.WithContextRequestIDKey and interceptor.SetContextRequestIDKey
+	ContextKey = "request_id"
+
+	// HeaderKey request id key for http header and message header, it is updated by middleware.WithHeaderRequestIDKey
+	HeaderKey = "X-Request-Id"
+)
+
+// FromContext get request id from the context of gin (gin.Context or middleware.WrapCtx) or grpc (server or client side)
+func FromContext(ctx context.Context) string {
+	if v, ok := ctx.Value(ContextKey).(string); ok && v != "" {
+		return v
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(ContextKey); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if v := md.Get(ContextKey); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// NewContext set request id into the context, it can be got by middleware.CtxRequestID,
+// and is passed to the rpc server called with the context.
+func NewContext(ctx context.Context, requestID string) context.Context {
+	ctx = context.WithValue(ctx, ContextKey, requestID) //nolint
+	return metadata.AppendToOutgoingContext(ctx, ContextKey, requestID)
+}
@@ -0,0 +1,31 @@
+package requestid
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", FromContext(ctx))
+
+	ctx1 := context.WithValue(ctx, ContextKey, "gin-id") //nolint
+	assert.Equal(t, "gin-id", FromContext(ctx1))
+
+	ctx2 := metadata.NewIncomingContext(ctx, metadata.Pairs(ContextKey, "server-id"))
+	assert.Equal(t, "server-id", FromContext(ctx2))
+
+	ctx3 := metadata.AppendToOutgoingContext(ctx, ContextKey, "client-id")
+	assert.Equal(t, "client-id", FromContext(ctx3))
+}
+
+func TestNewContext(t *testing.T) {
+	ctx := NewContext(context.Background(), "req-1")
+	assert.Equal(t, "req-1", ctx.Value(ContextKey))
+	md, ok := metadata.FromOutgoingContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"req-1"}, md.Get(ContextKey))
+}
@@ -1,4 +1,5 @@
-// Package circuitbreaker is an adaptive circuit breaker library, support for use in gin middleware and grpc interceptors.
+// Package circuitbreaker is a circuit breaker library, includes the adaptive sre breaker and the classic three-state breaker,
+// support for use in gin middleware and grpc interceptors.
 package circuitbreaker
 
 import (
@@ -14,3 +15,52 @@ type CircuitBreaker interface {
 	MarkSuccess()
 	MarkFailed()
 }
+
+// Stat contains the metrics snapshot of breaker.
+type Stat struct {
+	State    int32 // StateClosed, StateOpen or StateHalfOpen
+	Accepts  int64 // number of successful requests in window
+	Total    int64 // number of requests in window
+	Rejected int64 // number of requests rejected by the breaker since it is created
+}
+
+// AcceptRatio the ratio of successful requests in window, return 1 if there is no request
+func (s Stat) AcceptRatio() float64 {
+	if s.Total <= 0 {
+		return 1
+	}
+	return float64(s.Accepts) / float64(s.Total)
+}
+
+// MarkRejected mark the request rejected by the breaker itself, if the breaker implements MarkRejected,
+// e.g. ClassicBreaker, call it, otherwise count the request as failed, the sre breaker keeps adding counter
+// let the drop ratio higher.
+func MarkRejected(b CircuitBreaker) {
+	if r, ok := b.(interface{ MarkRejected() }); ok {
+		r.MarkRejected()
+		return
+	}
+	b.MarkFailed()
+}
+
+// Acquire allow the request by breaker and return a function to mark the result of the request,
+// if the breaker implements Acquire, e.g. ClassicBreaker, the result of the request allowed before
+// the state changed is ignored, otherwise the result is marked by MarkSuccess or MarkFailed.
+func Acquire(b CircuitBreaker) (func(success bool), error) {
+	if a, ok := b.(interface {
+		Acquire() (func(success bool), error)
+	}); ok {
+		return a.Acquire()
+	}
+
+	if err := b.Allow(); err != nil {
+		return nil, err
+	}
+	return func(success bool) {
+		if success {
+			b.MarkSuccess()
+		} else {
+			b.MarkFailed()
+		}
+	}, nil
+}
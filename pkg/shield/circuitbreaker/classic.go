@@ -0,0 +1,330 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/shield/window"
+)
+
+// StateHalfOpen when classic circuit breaker half open, allow a limited number of probe requests,
+// if all probes succeed then state reset to closed, if any probe fails then state reset to open.
+const StateHalfOpen = StateClosed + 1
+
+var (
+	_ CircuitBreaker = &ClassicBreaker{}
+)
+
+// ClassicOption is classic breaker option function.
+type ClassicOption func(*classicOptions)
+
+type classicOptions struct {
+	consecutiveFailures int64
+	errorRate           float64
+	minRequests         int64
+	window              time.Duration
+	bucket              int
+	openDuration        time.Duration
+	halfOpenProbes      int
+	onStateChange       func(from, to int32)
+}
+
+func (o *classicOptions) apply(opts ...ClassicOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultClassicOptions() *classicOptions {
+	return &classicOptions{
+		consecutiveFailures: 5,
+		errorRate:           0.5,
+		minRequests:         20,
+		window:              10 * time.Second,
+		bucket:              10,
+		openDuration:        5 * time.Second,
+		halfOpenProbes:      1,
+	}
+}
+
+// WithConsecutiveFailures open the breaker after n consecutive failures, default 5, 0 means disabled.
+func WithConsecutiveFailures(n int64) ClassicOption {
+	return func(o *classicOptions) {
+		o.consecutiveFailures = n
+	}
+}
+
+// WithErrorRate open the breaker when the error rate in the window reaches rate,
+// and the number of requests in the window is not less than minRequests, default rate 0.5 and minRequests 20,
+// rate 0 means disabled.
+func WithErrorRate(rate float64, minRequests int64) ClassicOption {
+	return func(o *classicOptions) {
+		o.errorRate = rate
+		o.minRequests = minRequests
+	}
+}
+
+// WithErrorRateWindow set the duration and bucket number of the window to calculate the error rate, default 10s and 10.
+func WithErrorRateWindow(d time.Duration, bucket int) ClassicOption {
+	return func(o *classicOptions) {
+		if d > 0 && bucket > 0 {
+			o.window = d
+			o.bucket = bucket
+		}
+	}
+}
+
+// WithOpenDuration set how long the breaker stays open before half open, it is also the time to wait for
+// the results of probes when half open, the unmarked probes are expired after it, default 5s.
+func WithOpenDuration(d time.Duration) ClassicOption {
+	return func(o *classicOptions) {
+		if d > 0 {
+			o.openDuration = d
+		}
+	}
+}
+
+// WithHalfOpenProbes set the number of probe requests allowed when half open, default 1.
+func WithHalfOpenProbes(n int) ClassicOption {
+	return func(o *classicOptions) {
+		if n > 0 {
+			o.halfOpenProbes = n
+		}
+	}
+}
+
+// WithStateChange set the callback when the state changes, e.g. logging and metrics,
+// it is called synchronously outside the lock of breaker.
+func WithStateChange(fn func(from, to int32)) ClassicOption {
+	return func(o *classicOptions) {
+		o.onStateChange = fn
+	}
+}
+
+// ClassicBreaker is a classic CircuitBreaker pattern with closed, open and half open states.
+type ClassicBreaker struct {
+	opts *classicOptions
+
+	mu                  sync.Mutex
+	state               int32
+	stat                window.RollingCounter
+	consecutiveFailures int64
+	openedAt            time.Time
+	halfOpenedAt        time.Time
+	probes              int // number of probe requests allowed when half open
+	probeSuccesses      int
+	pendingProbes       int    // number of admitted probe requests whose result is not marked yet
+	generation          uint64 // incremented on each state change
+	rejected            int64
+}
+
+// NewClassicBreaker return a classic breaker with options
+func NewClassicBreaker(opts ...ClassicOption) *ClassicBreaker {
+	o := defaultClassicOptions()
+	o.apply(opts...)
+
+	return &ClassicBreaker{
+		opts:  o,
+		state: StateClosed,
+		stat:  newClassicStat(o),
+	}
+}
+
+func newClassicStat(o *classicOptions) window.RollingCounter {
+	return window.NewRollingCounter(window.RollingCounterOpts{
+		Size:           o.bucket,
+		BucketDuration: time.Duration(int64(o.window) / int64(o.bucket)),
+	})
+}
+
+// State get the current state, StateClosed, StateOpen or StateHalfOpen
+func (b *ClassicBreaker) State() int32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState()
+}
+
+// Stat takes a snapshot of the breaker.
+func (b *ClassicBreaker) Stat() Stat {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	accepts, total := b.summary()
+	return Stat{
+		State:    b.currentState(),
+		Accepts:  accepts,
+		Total:    total,
+		Rejected: b.rejected,
+	}
+}
+
+// the open state becomes half open after the open duration, must be called with lock held
+func (b *ClassicBreaker) currentState() int32 {
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.opts.openDuration {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// Allow request if error returns nil.
+func (b *ClassicBreaker) Allow() error {
+	_, err := b.allow()
+	return err
+}
+
+// Acquire allow request like Allow, and return a function to mark the result of the request,
+// the result is ignored if the state has changed since the request was allowed.
+func (b *ClassicBreaker) Acquire() (func(success bool), error) {
+	generation, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+	return func(success bool) {
+		b.mark(success, &generation)
+	}, nil
+}
+
+func (b *ClassicBreaker) allow() (uint64, error) {
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.opts.openDuration {
+			b.rejected++
+			b.mu.Unlock()
+			return 0, ErrNotAllowed
+		}
+		b.setState(StateHalfOpen)
+		b.probes = 1
+		b.pendingProbes = 1
+		generation := b.generation
+		b.mu.Unlock()
+		b.notify(from, StateHalfOpen)
+		return generation, nil
+
+	case StateHalfOpen:
+		if b.probes >= b.opts.halfOpenProbes {
+			// the probes not marked within the open duration are expired, e.g. the request panicked,
+			// restart half open to allow a new probe, the results of expired probes are ignored.
+			if b.pendingProbes > 0 && time.Since(b.halfOpenedAt) >= b.opts.openDuration {
+				b.setState(StateHalfOpen)
+				b.probes = 1
+				b.pendingProbes = 1
+				break
+			}
+			b.rejected++
+			b.mu.Unlock()
+			return 0, ErrNotAllowed
+		}
+		b.probes++
+		b.pendingProbes++
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	return generation, nil
+}
+
+// MarkSuccess mark request is success, when half open, only the results of allowed probe requests are counted.
+func (b *ClassicBreaker) MarkSuccess() {
+	b.mark(true, nil)
+}
+
+// MarkFailed mark request is failed, when half open, only the results of allowed probe requests are counted.
+func (b *ClassicBreaker) MarkFailed() {
+	b.mark(false, nil)
+}
+
+// if generation is not nil, the result of request allowed in other generation is ignored
+func (b *ClassicBreaker) mark(success bool, generation *uint64) {
+	b.mu.Lock()
+	if generation != nil && *generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+
+	from := b.state
+	switch b.state {
+	case StateClosed:
+		if success {
+			b.consecutiveFailures = 0
+			b.stat.Add(1)
+		} else {
+			b.consecutiveFailures++
+			b.stat.Add(0)
+			if b.shouldOpen() {
+				b.setState(StateOpen)
+			}
+		}
+
+	case StateHalfOpen:
+		// stale result of the request allowed before half open is not a probe result
+		if b.pendingProbes <= 0 {
+			break
+		}
+		b.pendingProbes--
+		if !success {
+			b.setState(StateOpen)
+			break
+		}
+		b.probeSuccesses++
+		if b.probeSuccesses >= b.opts.halfOpenProbes {
+			b.setState(StateClosed)
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// MarkRejected the request rejected by the breaker itself is not counted.
+func (b *ClassicBreaker) MarkRejected() {}
+
+func (b *ClassicBreaker) shouldOpen() bool {
+	if b.opts.consecutiveFailures > 0 && b.consecutiveFailures >= b.opts.consecutiveFailures {
+		return true
+	}
+	if b.opts.errorRate <= 0 {
+		return false
+	}
+
+	success, total := b.summary()
+	return total > 0 && total >= b.opts.minRequests && float64(total-success)/float64(total) >= b.opts.errorRate
+}
+
+func (b *ClassicBreaker) summary() (success int64, total int64) {
+	b.stat.Reduce(func(iterator window.Iterator) float64 {
+		for iterator.Next() {
+			bucket := iterator.Bucket()
+			total += bucket.Count
+			for _, p := range bucket.Points {
+				success += int64(p)
+			}
+		}
+		return 0
+	})
+	return //nolint
+}
+
+// reset the statistics when the state changes, must be called with lock held
+func (b *ClassicBreaker) setState(state int32) {
+	b.state = state
+	b.generation++
+	b.consecutiveFailures = 0
+	b.probes = 0
+	b.probeSuccesses = 0
+	b.pendingProbes = 0
+	switch state {
+	case StateOpen:
+		b.openedAt = time.Now()
+	case StateHalfOpen:
+		b.halfOpenedAt = time.Now()
+	case StateClosed:
+		b.stat = newClassicStat(b.opts)
+	}
+}
+
+func (b *ClassicBreaker) notify(from, to int32) {
+	if from != to && b.opts.onStateChange != nil {
+		b.opts.onStateChange(from, to)
+	}
+}
@@ -0,0 +1,220 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassicBreaker_ConsecutiveFailures(t *testing.T) {
+	b := NewClassicBreaker(WithConsecutiveFailures(3), WithErrorRate(0, 0))
+	assert.Equal(t, StateClosed, b.State())
+
+	b.MarkFailed()
+	b.MarkFailed()
+	b.MarkSuccess() // reset consecutive failures
+	b.MarkFailed()
+	b.MarkFailed()
+	assert.Equal(t, StateClosed, b.State())
+	assert.NoError(t, b.Allow())
+
+	b.MarkFailed()
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, ErrNotAllowed, b.Allow())
+}
+
+func TestClassicBreaker_ErrorRate(t *testing.T) {
+	b := NewClassicBreaker(WithConsecutiveFailures(0), WithErrorRate(0.5, 10),
+		WithErrorRateWindow(time.Second, 10))
+
+	for i := 0; i < 4; i++ {
+		b.MarkFailed()
+		b.MarkSuccess()
+	}
+	assert.Equal(t, StateClosed, b.State()) // requests less than minRequests
+
+	b.MarkSuccess()
+	b.MarkFailed()
+	assert.Equal(t, StateOpen, b.State()) // 5 failures of 10 requests
+}
+
+func TestClassicBreaker_HalfOpen(t *testing.T) {
+	var mu sync.Mutex
+	var changes [][2]int32
+	b := NewClassicBreaker(
+		WithConsecutiveFailures(1),
+		WithOpenDuration(50*time.Millisecond),
+		WithHalfOpenProbes(2),
+		WithStateChange(func(from, to int32) {
+			mu.Lock()
+			changes = append(changes, [2]int32{from, to})
+			mu.Unlock()
+		}),
+	)
+
+	b.MarkFailed()
+	assert.Equal(t, ErrNotAllowed, b.Allow())
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	// allow 2 probes, reject the others
+	assert.NoError(t, b.Allow())
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, ErrNotAllowed, b.Allow())
+	MarkRejected(b) // not counted
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	// any probe fails, reopen
+	b.MarkSuccess()
+	b.MarkFailed()
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, ErrNotAllowed, b.Allow())
+
+	// all probes succeed, close
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, b.Allow())
+	assert.NoError(t, b.Allow())
+	b.MarkSuccess()
+	b.MarkSuccess()
+	assert.Equal(t, StateClosed, b.State())
+	assert.NoError(t, b.Allow())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, [][2]int32{
+		{StateClosed, StateOpen},
+		{StateOpen, StateHalfOpen},
+		{StateHalfOpen, StateOpen},
+		{StateOpen, StateHalfOpen},
+		{StateHalfOpen, StateClosed},
+	}, changes)
+}
+
+func TestClassicBreaker_StaleResult(t *testing.T) {
+	b := NewClassicBreaker(WithConsecutiveFailures(1), WithOpenDuration(50*time.Millisecond))
+
+	// the request allowed before open
+	staleDone, err := b.Acquire()
+	assert.NoError(t, err)
+	assert.NoError(t, b.Allow())
+	b.MarkFailed()
+	assert.Equal(t, StateOpen, b.State())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	// no probe allowed, the results are not counted
+	b.MarkSuccess()
+	b.MarkFailed()
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	// the result of request allowed before half open is ignored
+	done, err := b.Acquire()
+	assert.NoError(t, err)
+	assert.Equal(t, StateHalfOpen, b.State())
+	staleDone(false)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	done(true)
+	assert.Equal(t, StateClosed, b.State())
+	done(false) // marked after state changed
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestClassicBreaker_ExpiredProbe(t *testing.T) {
+	b := NewClassicBreaker(WithConsecutiveFailures(1), WithOpenDuration(50*time.Millisecond))
+	assert.NoError(t, b.Allow())
+	b.MarkFailed()
+	time.Sleep(60 * time.Millisecond)
+
+	// the probe is never marked, e.g. the request panicked
+	expiredDone, err := b.Acquire()
+	assert.NoError(t, err)
+	assert.ErrorIs(t, b.Allow(), ErrNotAllowed)
+
+	// allow a new probe after the open duration
+	time.Sleep(60 * time.Millisecond)
+	done, err := b.Acquire()
+	assert.NoError(t, err)
+	assert.ErrorIs(t, b.Allow(), ErrNotAllowed)
+	expiredDone(false)
+	assert.Equal(t, StateHalfOpen, b.State())
+	done(true)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestAcquire(t *testing.T) {
+	b := getSREBreaker()
+	done, err := Acquire(b)
+	assert.NoError(t, err)
+	done(true)
+	done, err = Acquire(b)
+	assert.NoError(t, err)
+	done(false)
+	success, total := b.summary()
+	assert.Equal(t, int64(1), success)
+	assert.Equal(t, int64(2), total)
+
+	cb := NewClassicBreaker(WithConsecutiveFailures(1), WithOpenDuration(time.Minute))
+	done, err = Acquire(cb)
+	assert.NoError(t, err)
+	done(false)
+	assert.Equal(t, StateOpen, cb.State())
+	_, err = Acquire(cb)
+	assert.Equal(t, ErrNotAllowed, err)
+}
+
+func TestMarkRejected(t *testing.T) {
+	b := getSREBreaker()
+	MarkRejected(b)
+	_, total := b.summary()
+	assert.Equal(t, int64(1), total)
+
+	cb := NewClassicBreaker(WithConsecutiveFailures(1))
+	MarkRejected(cb)
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestClassicBreakerConcurrency(t *testing.T) {
+	b := NewClassicBreaker(WithOpenDuration(time.Millisecond))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if b.Allow() != nil {
+					MarkRejected(b)
+					continue
+				}
+				if (i+j)%3 == 0 {
+					b.MarkFailed()
+				} else {
+					b.MarkSuccess()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestClassicBreaker_Stat(t *testing.T) {
+	b := NewClassicBreaker(WithConsecutiveFailures(2), WithOpenDuration(time.Minute))
+	b.MarkSuccess()
+	b.MarkFailed()
+	stat := b.Stat()
+	assert.Equal(t, StateClosed, stat.State)
+	assert.Equal(t, int64(1), stat.Accepts)
+	assert.Equal(t, int64(2), stat.Total)
+	assert.Equal(t, 0.5, stat.AcceptRatio())
+
+	b.MarkFailed()
+	_ = b.Allow()
+	_ = b.Allow()
+	stat = b.Stat()
+	assert.Equal(t, StateOpen, stat.State)
+	assert.Equal(t, int64(2), stat.Rejected)
+	assert.Equal(t, 1.0, Stat{}.AcceptRatio())
+}
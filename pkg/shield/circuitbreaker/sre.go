@@ -78,7 +78,8 @@ type Breaker struct {
 	k       float64
 	request int64
 
-	state int32
+	state    int32
+	rejected int64
 }
 
 // NewBreaker return a sreBresker with options
@@ -135,6 +136,7 @@ func (b *Breaker) Allow() error {
 	dr := math.Max(0, (float64(total)-requests)/float64(total+1))
 	drop := b.trueOnProba(dr)
 	if drop {
+		atomic.AddInt64(&b.rejected, 1)
 		return ErrNotAllowed
 	}
 	return nil
@@ -151,6 +153,17 @@ func (b *Breaker) MarkFailed() {
 	b.stat.Add(0)
 }
 
+// Stat takes a snapshot of the breaker.
+func (b *Breaker) Stat() Stat {
+	accepts, total := b.summary()
+	return Stat{
+		State:    atomic.LoadInt32(&b.state),
+		Accepts:  accepts,
+		Total:    total,
+		Rejected: atomic.LoadInt64(&b.rejected),
+	}
+}
+
 func (b *Breaker) trueOnProba(proba float64) (truth bool) {
 	b.randLock.Lock()
 	truth = b.r.Float64() < proba
@@ -188,3 +188,18 @@ func TestNewBreaker(t *testing.T) {
 
 	assert.NotNil(t, breaker)
 }
+
+func TestSREStat(t *testing.T) {
+	b := getSREBreaker()
+	markSuccess(b, 50)
+	markFailed(b, 150)
+	for i := 0; i < 100; i++ {
+		_ = b.Allow()
+	}
+	stat := b.Stat()
+	assert.Equal(t, StateOpen, stat.State)
+	assert.Equal(t, int64(50), stat.Accepts)
+	assert.Equal(t, int64(200), stat.Total)
+	assert.Equal(t, 0.25, stat.AcceptRatio())
+	assert.Greater(t, stat.Rejected, int64(0))
+}
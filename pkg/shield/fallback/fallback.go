@@ -0,0 +1,66 @@
+// Package fallback is the registry of fallback functions of routes, the fallback function is called when the request
+// is rejected by circuit breaker or rate limiter, e.g. return cached value, static default or call alternative backend.
+package fallback
+
+import (
+	"strings"
+	"sync"
+)
+
+// Registry fallback functions of routes, the route is gin route path or grpc full method,
+// if it ends with *, match the routes with the prefix, it is safe for concurrent use.
+type Registry[F any] struct {
+	mu  sync.RWMutex
+	fns map[string]F
+}
+
+// NewRegistry create an empty registry
+func NewRegistry[F any]() *Registry[F] {
+	return &Registry[F]{
+		fns: make(map[string]F),
+	}
+}
+
+// Register set the fallback function of route, replace the old one if exists
+func (r *Registry[F]) Register(route string, fn F) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fns[route] = fn
+}
+
+// Deregister delete the fallback function of route
+func (r *Registry[F]) Deregister(route string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.fns, route)
+}
+
+// Get the fallback function matched by route, exact match first, then the longest prefix match
+func (r *Registry[F]) Get(route string) (F, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if fn, ok := r.fns[route]; ok {
+		return fn, true
+	}
+
+	var fn F
+	found, prefixLen := false, -1
+	for pattern, f := range r.fns {
+		if !strings.HasSuffix(pattern, "*") {
+			continue
+		}
+		prefix := strings.TrimSuffix(pattern, "*")
+		if strings.HasPrefix(route, prefix) && len(prefix) > prefixLen {
+			fn, found, prefixLen = f, true, len(prefix)
+		}
+	}
+	return fn, found
+}
+
+// Len get the number of fallback functions
+func (r *Registry[F]) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.fns)
+}
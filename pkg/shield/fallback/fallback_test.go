@@ -0,0 +1,35 @@
+package fallback
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry[func() string]()
+	r.Register("/api/v1/user/:id", func() string { return "exact" })
+	r.Register("/api/v1/*", func() string { return "v1" })
+	r.Register("/api/*", func() string { return "api" })
+	assert.Equal(t, 3, r.Len())
+
+	fn, ok := r.Get("/api/v1/user/:id")
+	assert.True(t, ok)
+	assert.Equal(t, "exact", fn())
+
+	fn, ok = r.Get("/api/v1/user/list")
+	assert.True(t, ok)
+	assert.Equal(t, "v1", fn())
+
+	fn, ok = r.Get("/api/v2/user/list")
+	assert.True(t, ok)
+	assert.Equal(t, "api", fn())
+
+	_, ok = r.Get("/health")
+	assert.False(t, ok)
+
+	r.Deregister("/api/v1/user/:id")
+	fn, ok = r.Get("/api/v1/user/:id")
+	assert.True(t, ok)
+	assert.Equal(t, "v1", fn())
+}
@@ -0,0 +1,225 @@
+// Package metrics is the prometheus metrics of rate limiters and circuit breakers, the metrics are collected
+// from the snapshots of the registered limiters and breakers when scraped. the collector is registered
+// by gin metrics(pkg/gin/middleware/metrics) and grpc server metrics(pkg/grpc/metrics).
+package metrics
+
+import (
+	"sync"
+
+	"github.com/zhufuyi/sponge/pkg/container/group"
+	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
+	rl "github.com/zhufuyi/sponge/pkg/shield/ratelimit"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	namespace = "shield"
+
+	// source is the component that creates the limiter or breaker, e.g. gin, grpc_server, grpc_client, policy,
+	// name is the route, the method or the name of limiter
+	labels = []string{"source", "name"}
+
+	limiterCPU = prometheus.NewDesc(prometheus.BuildFQName(namespace, "ratelimit", "cpu"),
+		"CPU usage of adaptive rate limiter, 1000 is 100%.", labels, nil)
+	limiterInFlight = prometheus.NewDesc(prometheus.BuildFQName(namespace, "ratelimit", "inflight"),
+		"Number of requests in flight.", labels, nil)
+	limiterMaxInFlight = prometheus.NewDesc(prometheus.BuildFQName(namespace, "ratelimit", "max_inflight"),
+		"Max number of requests in flight estimated by max pass and min rt.", labels, nil)
+	limiterMaxPass = prometheus.NewDesc(prometheus.BuildFQName(namespace, "ratelimit", "max_pass"),
+		"Max number of requests passed in a bucket of window.", labels, nil)
+	limiterMinRt = prometheus.NewDesc(prometheus.BuildFQName(namespace, "ratelimit", "min_rt_milliseconds"),
+		"Min response time of buckets in window.", labels, nil)
+	limiterDropped = prometheus.NewDesc(prometheus.BuildFQName(namespace, "ratelimit", "dropped_total"),
+		"Total number of requests dropped by rate limiter.", labels, nil)
+
+	breakerState = prometheus.NewDesc(prometheus.BuildFQName(namespace, "breaker", "state"),
+		"State of circuit breaker, 0 is open, 1 is closed, 2 is half open.", labels, nil)
+	breakerAcceptRatio = prometheus.NewDesc(prometheus.BuildFQName(namespace, "breaker", "accept_ratio"),
+		"Ratio of successful requests in window.", labels, nil)
+	breakerRequests = prometheus.NewDesc(prometheus.BuildFQName(namespace, "breaker", "requests"),
+		"Number of requests in window.", labels, nil)
+	breakerRejected = prometheus.NewDesc(prometheus.BuildFQName(namespace, "breaker", "rejected_total"),
+		"Total number of requests rejected by circuit breaker.", labels, nil)
+
+	defaultCollector = &collector{}
+)
+
+// the limiters and breakers of a source are got when collecting
+type source struct {
+	key      interface{} // the registered object, avoid registering repeatedly
+	name     string
+	limiters func() map[string]rl.Limiter
+	breakers func() map[string]circuitbreaker.CircuitBreaker
+}
+
+type collector struct {
+	mu      sync.RWMutex
+	sources []*source // in registration order, the first one is collected when the labels are duplicated
+}
+
+// Collector get the prometheus collector of the registered limiters and breakers
+func Collector() prometheus.Collector {
+	return defaultCollector
+}
+
+// RegisterLimiter add the rate limiter to be collected, the limiter which does not implement Stat is ignored,
+// e.g. rl.NewLimiter, source and name are the values of labels.
+func RegisterLimiter(sourceName string, name string, limiter rl.Limiter) {
+	defaultCollector.register(limiter, limiterSource(sourceName, name, limiter))
+}
+
+// the key of the limiter registered by ReplaceLimiter
+type limiterKey struct {
+	sourceName string
+	name       string
+}
+
+// ReplaceLimiter add the rate limiter to be collected, the limiter registered before by ReplaceLimiter with
+// the same source and name is replaced, e.g. the default limiter of middleware that is created by every call.
+func ReplaceLimiter(sourceName string, name string, limiter rl.Limiter) {
+	defaultCollector.register(limiterKey{sourceName: sourceName, name: name}, limiterSource(sourceName, name, limiter))
+}
+
+func limiterSource(sourceName string, name string, limiter rl.Limiter) *source {
+	return &source{
+		name: sourceName,
+		limiters: func() map[string]rl.Limiter {
+			return map[string]rl.Limiter{name: limiter}
+		},
+	}
+}
+
+// RegisterBreakerGroup add the circuit breakers of group to be collected, the key of group is the value of label name,
+// the breaker which does not implement Stat is ignored.
+func RegisterBreakerGroup(sourceName string, g *group.Group) {
+	defaultCollector.register(g, &source{
+		name: sourceName,
+		breakers: func() map[string]circuitbreaker.CircuitBreaker {
+			breakers := make(map[string]circuitbreaker.CircuitBreaker)
+			g.Range(func(key string, val interface{}) bool {
+				if b, ok := val.(circuitbreaker.CircuitBreaker); ok {
+					breakers[key] = b
+				}
+				return true
+			})
+			return breakers
+		},
+	})
+}
+
+// RegisterPolicy add the rate limiters and circuit breakers of policy manager to be collected, the value of label source is policy
+func RegisterPolicy(m *policy.Manager) {
+	defaultCollector.register(m, &source{
+		name:     "policy",
+		limiters: m.Limiters,
+		breakers: m.Breakers,
+	})
+}
+
+// Unregister remove the rate limiter, the group of circuit breakers or the policy manager registered before,
+// e.g. called when the grpc client connection is closed.
+func Unregister(key interface{}) {
+	defaultCollector.unregister(key)
+}
+
+func (c *collector) register(key interface{}, s *source) {
+	if key == nil {
+		return
+	}
+	s.key = key
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, v := range c.sources {
+		if v.key == key {
+			c.sources[i] = s
+			return
+		}
+	}
+	c.sources = append(c.sources, s)
+}
+
+func (c *collector) unregister(key interface{}) {
+	if key == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, v := range c.sources {
+		if v.key == key {
+			c.sources = append(c.sources[:i], c.sources[i+1:]...)
+			return
+		}
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		limiterCPU, limiterInFlight, limiterMaxInFlight, limiterMaxPass, limiterMinRt, limiterDropped,
+		breakerState, breakerAcceptRatio, breakerRequests, breakerRejected,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	sources := make([]*source, len(c.sources))
+	copy(sources, c.sources)
+	c.mu.RUnlock()
+
+	// the same labels may be registered by multiple sources, e.g. multiple grpc client connections,
+	// only the first registered one is collected, otherwise the registry reports error
+	seenLimiters := make(map[[2]string]struct{})
+	seenBreakers := make(map[[2]string]struct{})
+	for _, s := range sources {
+		if s.limiters != nil {
+			for name, limiter := range s.limiters() {
+				l, ok := limiter.(interface{ Stat() rl.Stat })
+				if !ok || isSeen(seenLimiters, s.name, name) {
+					continue
+				}
+				collectLimiter(ch, l.Stat(), s.name, name)
+			}
+		}
+		if s.breakers != nil {
+			for name, breaker := range s.breakers() {
+				b, ok := breaker.(interface{ Stat() circuitbreaker.Stat })
+				if !ok || isSeen(seenBreakers, s.name, name) {
+					continue
+				}
+				collectBreaker(ch, b.Stat(), s.name, name)
+			}
+		}
+	}
+}
+
+func isSeen(seen map[[2]string]struct{}, sourceName string, name string) bool {
+	key := [2]string{sourceName, name}
+	if _, ok := seen[key]; ok {
+		return true
+	}
+	seen[key] = struct{}{}
+	return false
+}
+
+func collectLimiter(ch chan<- prometheus.Metric, stat rl.Stat, lvs ...string) {
+	ch <- prometheus.MustNewConstMetric(limiterCPU, prometheus.GaugeValue, float64(stat.CPU), lvs...)
+	ch <- prometheus.MustNewConstMetric(limiterInFlight, prometheus.GaugeValue, float64(stat.InFlight), lvs...)
+	ch <- prometheus.MustNewConstMetric(limiterMaxInFlight, prometheus.GaugeValue, float64(stat.MaxInFlight), lvs...)
+	ch <- prometheus.MustNewConstMetric(limiterMaxPass, prometheus.GaugeValue, float64(stat.MaxPass), lvs...)
+	ch <- prometheus.MustNewConstMetric(limiterMinRt, prometheus.GaugeValue, float64(stat.MinRt), lvs...)
+	ch <- prometheus.MustNewConstMetric(limiterDropped, prometheus.CounterValue, float64(stat.Dropped), lvs...)
+}
+
+func collectBreaker(ch chan<- prometheus.Metric, stat circuitbreaker.Stat, lvs ...string) {
+	ch <- prometheus.MustNewConstMetric(breakerState, prometheus.GaugeValue, float64(stat.State), lvs...)
+	ch <- prometheus.MustNewConstMetric(breakerAcceptRatio, prometheus.GaugeValue, stat.AcceptRatio(), lvs...)
+	ch <- prometheus.MustNewConstMetric(breakerRequests, prometheus.GaugeValue, float64(stat.Total), lvs...)
+	ch <- prometheus.MustNewConstMetric(breakerRejected, prometheus.CounterValue, float64(stat.Rejected), lvs...)
+}
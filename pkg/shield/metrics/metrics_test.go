@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhufuyi/sponge/pkg/container/group"
+	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	"github.com/zhufuyi/sponge/pkg/shield/policy"
+	rl "github.com/zhufuyi/sponge/pkg/shield/ratelimit"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	defaultCollector = &collector{}
+
+	limiter := rl.NewLimiter()
+	RegisterLimiter("gin", "default", limiter)
+	RegisterLimiter("gin", "default", limiter) // registered repeatedly
+
+	g := group.NewGroup(func() interface{} {
+		return circuitbreaker.NewBreaker()
+	})
+	breaker := g.Get("/api/v1/user").(circuitbreaker.CircuitBreaker)
+	breaker.MarkSuccess()
+	breaker.MarkFailed()
+	RegisterBreakerGroup("gin", g)
+	// the same labels are collected once, the first registered one is collected
+	g2 := group.NewGroup(func() interface{} {
+		return circuitbreaker.NewClassicBreaker()
+	})
+	g2.Get("/api/v1/user")
+	RegisterBreakerGroup("gin", g2)
+
+	m := policy.NewManager()
+	err := m.Update(&policy.Config{
+		RateLimits:      []policy.RateLimit{{Route: "/api/v2/*"}},
+		CircuitBreakers: []policy.CircuitBreaker{{Route: "/api/v2/*", Type: policy.BreakerClassic}},
+	})
+	assert.NoError(t, err)
+	m.Breaker("/api/v2/user")
+	RegisterPolicy(m)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(Collector())
+
+	// 2 limiters and 2 breakers
+	assert.Equal(t, 2*6+2*4, testutil.CollectAndCount(Collector()))
+
+	expected := `
+# HELP shield_breaker_accept_ratio Ratio of successful requests in window.
+# TYPE shield_breaker_accept_ratio gauge
+shield_breaker_accept_ratio{name="/api/v1/user",source="gin"} 0.5
+shield_breaker_accept_ratio{name="/api/v2/user",source="policy"} 1
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected), "shield_breaker_accept_ratio")
+	assert.NoError(t, err)
+
+	expected = `
+# HELP shield_ratelimit_dropped_total Total number of requests dropped by rate limiter.
+# TYPE shield_ratelimit_dropped_total counter
+shield_ratelimit_dropped_total{name="/api/v2/*",source="policy"} 0
+shield_ratelimit_dropped_total{name="default",source="gin"} 0
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected), "shield_ratelimit_dropped_total")
+	assert.NoError(t, err)
+
+	Unregister(g)
+	Unregister(nil)
+	expected = `
+# HELP shield_breaker_accept_ratio Ratio of successful requests in window.
+# TYPE shield_breaker_accept_ratio gauge
+shield_breaker_accept_ratio{name="/api/v1/user",source="gin"} 1
+shield_breaker_accept_ratio{name="/api/v2/user",source="policy"} 1
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected), "shield_breaker_accept_ratio")
+	assert.NoError(t, err)
+
+	Unregister(g2)
+	Unregister(limiter)
+	Unregister(m)
+	assert.Equal(t, 0, testutil.CollectAndCount(Collector()))
+}
+
+func TestReplaceLimiter(t *testing.T) {
+	defaultCollector = &collector{}
+
+	for i := 0; i < 3; i++ {
+		ReplaceLimiter("gin", "default", rl.NewLimiter())
+	}
+	ReplaceLimiter("grpc_server", "unary", rl.NewLimiter())
+	assert.Equal(t, 2, len(defaultCollector.sources))
+	assert.Equal(t, 2*6, testutil.CollectAndCount(Collector()))
+}
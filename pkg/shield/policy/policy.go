@@ -0,0 +1,487 @@
+// Package policy is the rate limit and circuit breaker policies of routes, e.g. gin route path and grpc full method,
+// the policies can be loaded from the configuration file and updated at runtime.
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	rl "github.com/zhufuyi/sponge/pkg/shield/ratelimit"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// types of rate limiter
+const (
+	// LimiterAdaptive adaptive rate limiter based on cpu, it is the default type
+	LimiterAdaptive = "adaptive"
+	// LimiterTokenBucket token bucket rate limiter by key based on redis
+	LimiterTokenBucket = "tokenBucket"
+	// LimiterSlidingWindow sliding window rate limiter by key based on redis
+	LimiterSlidingWindow = "slidingWindow"
+)
+
+// types of circuit breaker
+const (
+	// BreakerSRE adaptive sre breaker, it is the default type
+	BreakerSRE = "sre"
+	// BreakerClassic classic three-state breaker
+	BreakerClassic = "classic"
+)
+
+// Config rate limit and circuit breaker policies
+type Config struct {
+	RateLimits      []RateLimit      `yaml:"rateLimits" json:"rateLimits"`
+	CircuitBreakers []CircuitBreaker `yaml:"circuitBreakers" json:"circuitBreakers"`
+}
+
+// RateLimit rate limit policy of route, the limiter is shared by all routes matched by the policy.
+type RateLimit struct {
+	// gin route path, e.g. /api/v1/userExample/:id, or grpc full method, e.g. /api.userExample.v1.userExample/GetByID,
+	// if it ends with *, match the routes with the prefix, e.g. /api/v1/*
+	Route string `yaml:"route" json:"route"`
+	// type of limiter, adaptive(default), tokenBucket, slidingWindow
+	Type string `yaml:"type" json:"type"`
+
+	Window       int     `yaml:"window" json:"window"`             // window size, unit(second), used by adaptive and slidingWindow
+	Bucket       int     `yaml:"bucket" json:"bucket"`             // bucket number of window, used by adaptive
+	CPUThreshold int64   `yaml:"cpuThreshold" json:"cpuThreshold"` // cpu threshold, 1000 is 100%, used by adaptive
+	Rate         float64 `yaml:"rate" json:"rate"`                 // number of tokens refilled per second, used by tokenBucket
+	Burst        int     `yaml:"burst" json:"burst"`               // max number of tokens, used by tokenBucket
+	Limit        int     `yaml:"limit" json:"limit"`               // max number of requests in window, used by slidingWindow
+}
+
+// CircuitBreaker circuit breaker policy of route, each route matched by the policy has its own breaker.
+type CircuitBreaker struct {
+	// gin route path or grpc full method, if it ends with *, match the routes with the prefix
+	Route string `yaml:"route" json:"route"`
+	// type of breaker, sre(default), classic
+	Type string `yaml:"type" json:"type"`
+
+	Success float64 `yaml:"success" json:"success"` // K = 1 / success, default 0.6, used by sre
+	Request int64   `yaml:"request" json:"request"` // minimum number of requests to trigger the breaker, default 100(sre) or 20(classic)
+	Window  int     `yaml:"window" json:"window"`   // window size, unit(second), default 3(sre) or 10(classic)
+	Bucket  int     `yaml:"bucket" json:"bucket"`   // bucket number of window, default 10
+
+	ConsecutiveFailures int64   `yaml:"consecutiveFailures" json:"consecutiveFailures"` // open after n consecutive failures, default 5, used by classic
+	ErrorRate           float64 `yaml:"errorRate" json:"errorRate"`                     // open when error rate in window reaches it, default 0.5, used by classic
+	OpenDuration        int     `yaml:"openDuration" json:"openDuration"`               // open duration before half open, unit(second), default 5, used by classic
+	HalfOpenProbes      int     `yaml:"halfOpenProbes" json:"halfOpenProbes"`           // number of probe requests when half open, default 1, used by classic
+}
+
+// UseRedis whether there are rate limit policies based on redis
+func (c *Config) UseRedis() bool {
+	for _, p := range c.RateLimits {
+		if p.Type == LimiterTokenBucket || p.Type == LimiterSlidingWindow {
+			return true
+		}
+	}
+	return false
+}
+
+// Option set the manager options.
+type Option func(*options)
+
+type options struct {
+	redisClient   redis.UniversalClient
+	onStateChange StateChangeFunc
+}
+
+func (o *options) apply(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithRedisClient set redis client, it is required by tokenBucket and slidingWindow rate limiters
+func WithRedisClient(client redis.UniversalClient) Option {
+	return func(o *options) {
+		o.redisClient = client
+	}
+}
+
+// StateChangeFunc is called when the state of classic breaker of route changes,
+// the states are circuitbreaker.StateClosed, StateOpen and StateHalfOpen.
+type StateChangeFunc func(route string, from, to int32)
+
+// WithStateChange set the callback when the state of classic breaker changes, e.g. logging and metrics,
+// it takes effect on the breakers created after it is set.
+func WithStateChange(fn StateChangeFunc) Option {
+	return func(o *options) {
+		o.onStateChange = fn
+	}
+}
+
+// Manager holds the limiters and breakers of policies, it is safe for concurrent use.
+type Manager struct {
+	updateMu      sync.Mutex
+	redisClient   redis.UniversalClient
+	onStateChange StateChangeFunc
+
+	mu       sync.RWMutex
+	limiters []*Limiter
+	breakers []*breakerGroup
+}
+
+// NewManager create a manager without policies, call Update to set the policies
+func NewManager(opts ...Option) *Manager {
+	o := &options{}
+	o.apply(opts...)
+
+	return &Manager{
+		redisClient:   o.redisClient,
+		onStateChange: o.onStateChange,
+	}
+}
+
+// Update replace all policies, the limiters and breakers of unchanged policies are retained,
+// if there is an invalid policy, return error and the policies are not changed.
+func (m *Manager) Update(cfg *Config) error {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+	m.mu.RLock()
+	oldLimiters, oldBreakers := m.limiters, m.breakers
+	m.mu.RUnlock()
+
+	limiters := make([]*Limiter, 0, len(cfg.RateLimits))
+	for _, p := range cfg.RateLimits {
+		if l := findLimiter(oldLimiters, p); l != nil {
+			limiters = append(limiters, l)
+			continue
+		}
+		l, err := newLimiter(p, m.redisClient)
+		if err != nil {
+			return err
+		}
+		limiters = append(limiters, l)
+	}
+
+	breakers := make([]*breakerGroup, 0, len(cfg.CircuitBreakers))
+	for _, p := range cfg.CircuitBreakers {
+		if b := findBreakerGroup(oldBreakers, p); b != nil {
+			breakers = append(breakers, b)
+			continue
+		}
+		b, err := newBreakerGroup(p, m.onStateChange)
+		if err != nil {
+			return err
+		}
+		breakers = append(breakers, b)
+	}
+
+	m.mu.Lock()
+	m.limiters, m.breakers = limiters, breakers
+	m.mu.Unlock()
+	return nil
+}
+
+// Limiter get the limiter of the policy matched by route, return nil if no policy matches
+func (m *Manager) Limiter(route string) *Limiter {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	index := matchRoute(len(m.limiters), func(i int) string { return m.limiters[i].policy.Route }, route)
+	if index < 0 {
+		return nil
+	}
+	return m.limiters[index]
+}
+
+// Breaker get the breaker of route in the policy matched by route, return nil if no policy matches
+func (m *Manager) Breaker(route string) circuitbreaker.CircuitBreaker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	index := matchRoute(len(m.breakers), func(i int) string { return m.breakers[i].policy.Route }, route)
+	if index < 0 {
+		return nil
+	}
+	return m.breakers[index].get(route)
+}
+
+// Limiters get the adaptive rate limiters of policies, the key is the route of policy, e.g. /api/v1/*
+func (m *Manager) Limiters() map[string]rl.Limiter {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	limiters := make(map[string]rl.Limiter, len(m.limiters))
+	for _, l := range m.limiters {
+		if l.adaptive != nil {
+			limiters[l.policy.Route] = l.adaptive
+		}
+	}
+	return limiters
+}
+
+// Breakers get the breakers created by policies, the key is the route matched by the policy, e.g. /api/v1/user/:id
+func (m *Manager) Breakers() map[string]circuitbreaker.CircuitBreaker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	breakers := make(map[string]circuitbreaker.CircuitBreaker)
+	for _, g := range m.breakers {
+		g.mu.RLock()
+		for route, b := range g.breakers {
+			if _, ok := breakers[route]; !ok {
+				breakers[route] = b
+			}
+		}
+		g.mu.RUnlock()
+	}
+	return breakers
+}
+
+// exact match first, then the longest prefix match
+func matchRoute(n int, getPattern func(i int) string, route string) int {
+	index, prefixLen := -1, -1
+	for i := 0; i < n; i++ {
+		pattern := getPattern(i)
+		if pattern == route {
+			return i
+		}
+		if strings.HasSuffix(pattern, "*") {
+			prefix := strings.TrimSuffix(pattern, "*")
+			if strings.HasPrefix(route, prefix) && len(prefix) > prefixLen {
+				index, prefixLen = i, len(prefix)
+			}
+		}
+	}
+	return index
+}
+
+// ------------------------------------------------------------------------------------------
+
+// Limiter rate limiter of policy
+type Limiter struct {
+	policy   RateLimit
+	adaptive rl.Limiter
+	keyed    rl.KeyLimiter
+}
+
+func newLimiter(p RateLimit, redisClient redis.UniversalClient) (*Limiter, error) {
+	if p.Route == "" {
+		return nil, errors.New("rate limit policy: route cannot be empty")
+	}
+
+	l := &Limiter{policy: p}
+	window := time.Duration(p.Window) * time.Second
+	switch p.Type {
+	case "", LimiterAdaptive:
+		var opts []rl.Option
+		if window > 0 {
+			opts = append(opts, rl.WithWindow(window))
+		}
+		if p.Bucket > 0 {
+			opts = append(opts, rl.WithBucket(p.Bucket))
+		}
+		if p.CPUThreshold > 0 {
+			opts = append(opts, rl.WithCPUThreshold(p.CPUThreshold))
+		}
+		l.adaptive = rl.NewLimiter(opts...)
+
+	case LimiterTokenBucket:
+		if redisClient == nil {
+			return nil, fmt.Errorf("rate limit policy %s: redis client is required", p.Route)
+		}
+		keyed, err := rl.NewTokenBucket(redisClient, p.Rate, p.Burst)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit policy %s: %v", p.Route, err)
+		}
+		l.keyed = keyed
+
+	case LimiterSlidingWindow:
+		if redisClient == nil {
+			return nil, fmt.Errorf("rate limit policy %s: redis client is required", p.Route)
+		}
+		keyed, err := rl.NewSlidingWindow(redisClient, p.Limit, window)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit policy %s: %v", p.Route, err)
+		}
+		l.keyed = keyed
+
+	default:
+		return nil, fmt.Errorf("rate limit policy %s: unsupported type %s", p.Route, p.Type)
+	}
+
+	return l, nil
+}
+
+func findLimiter(limiters []*Limiter, p RateLimit) *Limiter {
+	for _, l := range limiters {
+		if l.policy == p {
+			return l
+		}
+	}
+	return nil
+}
+
+// Allow check whether the request is allowed, key is used by the rate limiter by key, e.g. user id, ip,
+// the request is not limited by the rate limiter by key if key is empty or redis is unavailable.
+// result is not nil if the limiter is a rate limiter by key, return rl.ErrLimitExceed if the request is rejected.
+func (l *Limiter) Allow(ctx context.Context, key string) (rl.DoneFunc, *rl.Result, error) {
+	if l.adaptive != nil {
+		done, err := l.adaptive.Allow()
+		return done, nil, err
+	}
+
+	if key == "" {
+		return noopDone, nil, nil
+	}
+	result, err := l.keyed.Allow(ctx, l.policy.Route+":"+key)
+	if err != nil {
+		return noopDone, nil, nil
+	}
+	if !result.Allowed {
+		return noopDone, result, rl.ErrLimitExceed
+	}
+	return noopDone, result, nil
+}
+
+// Policy get the policy of limiter
+func (l *Limiter) Policy() RateLimit {
+	return l.policy
+}
+
+func noopDone(rl.DoneInfo) {}
+
+// ------------------------------------------------------------------------------------------
+
+type breakerGroup struct {
+	policy     CircuitBreaker
+	newBreaker func(route string) circuitbreaker.CircuitBreaker
+
+	mu       sync.RWMutex
+	breakers map[string]circuitbreaker.CircuitBreaker
+}
+
+func newBreakerGroup(p CircuitBreaker, onStateChange StateChangeFunc) (*breakerGroup, error) {
+	if p.Route == "" {
+		return nil, errors.New("circuit breaker policy: route cannot be empty")
+	}
+
+	g := &breakerGroup{
+		policy:   p,
+		breakers: make(map[string]circuitbreaker.CircuitBreaker),
+	}
+	window := time.Duration(p.Window) * time.Second
+	switch p.Type {
+	case "", BreakerSRE:
+		var opts []circuitbreaker.Option
+		if p.Success > 0 {
+			opts = append(opts, circuitbreaker.WithSuccess(p.Success))
+		}
+		if p.Request > 0 {
+			opts = append(opts, circuitbreaker.WithRequest(p.Request))
+		}
+		if window > 0 {
+			opts = append(opts, circuitbreaker.WithWindow(window))
+		}
+		if p.Bucket > 0 {
+			opts = append(opts, circuitbreaker.WithBucket(p.Bucket))
+		}
+		g.newBreaker = func(string) circuitbreaker.CircuitBreaker {
+			return circuitbreaker.NewBreaker(opts...)
+		}
+
+	case BreakerClassic:
+		var opts []circuitbreaker.ClassicOption
+		if p.ConsecutiveFailures > 0 {
+			opts = append(opts, circuitbreaker.WithConsecutiveFailures(p.ConsecutiveFailures))
+		}
+		if p.ErrorRate > 0 {
+			request := p.Request
+			if request <= 0 {
+				request = 20
+			}
+			opts = append(opts, circuitbreaker.WithErrorRate(p.ErrorRate, request))
+		}
+		if window > 0 {
+			bucket := p.Bucket
+			if bucket <= 0 {
+				bucket = 10
+			}
+			opts = append(opts, circuitbreaker.WithErrorRateWindow(window, bucket))
+		}
+		if p.OpenDuration > 0 {
+			opts = append(opts, circuitbreaker.WithOpenDuration(time.Duration(p.OpenDuration)*time.Second))
+		}
+		if p.HalfOpenProbes > 0 {
+			opts = append(opts, circuitbreaker.WithHalfOpenProbes(p.HalfOpenProbes))
+		}
+		g.newBreaker = func(route string) circuitbreaker.CircuitBreaker {
+			if onStateChange == nil {
+				return circuitbreaker.NewClassicBreaker(opts...)
+			}
+			return circuitbreaker.NewClassicBreaker(append(opts, circuitbreaker.WithStateChange(func(from, to int32) {
+				onStateChange(route, from, to)
+			}))...)
+		}
+
+	default:
+		return nil, fmt.Errorf("circuit breaker policy %s: unsupported type %s", p.Route, p.Type)
+	}
+
+	return g, nil
+}
+
+// get the breaker of route, create it if not exists
+func (g *breakerGroup) get(route string) circuitbreaker.CircuitBreaker {
+	g.mu.RLock()
+	breaker, ok := g.breakers[route]
+	g.mu.RUnlock()
+	if ok {
+		return breaker
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if breaker, ok = g.breakers[route]; ok {
+		return breaker
+	}
+	breaker = g.newBreaker(route)
+	g.breakers[route] = breaker
+	return breaker
+}
+
+func findBreakerGroup(breakers []*breakerGroup, p CircuitBreaker) *breakerGroup {
+	for _, b := range breakers {
+		if b.policy == p {
+			return b
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------------------------------------------------
+
+var defaultManager = NewManager()
+
+// Init set the policies of default manager, opts are applied before updating the policies
+func Init(cfg *Config, opts ...Option) error {
+	o := &options{}
+	o.apply(opts...)
+	defaultManager.updateMu.Lock()
+	if o.redisClient != nil {
+		defaultManager.redisClient = o.redisClient
+	}
+	if o.onStateChange != nil {
+		defaultManager.onStateChange = o.onStateChange
+	}
+	defaultManager.updateMu.Unlock()
+
+	return defaultManager.Update(cfg)
+}
+
+// Get default manager, there is no policy if Init is not called
+func Get() *Manager {
+	return defaultManager
+}
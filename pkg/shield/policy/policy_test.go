@@ -0,0 +1,163 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/shield/circuitbreaker"
+	rl "github.com/zhufuyi/sponge/pkg/shield/ratelimit"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+
+	m := NewManager(WithRedisClient(client))
+	cfg := &Config{
+		RateLimits: []RateLimit{
+			{Route: "/api/v1/user/:id", Type: LimiterSlidingWindow, Window: 60, Limit: 1},
+			{Route: "/api/v1/*", Window: 5, Bucket: 50, CPUThreshold: 900},
+			{Route: "/api.user.v1.User/*", Type: LimiterTokenBucket, Rate: 1, Burst: 1},
+		},
+		CircuitBreakers: []CircuitBreaker{
+			{Route: "/api/v1/*", Success: 0.5, Request: 10, Window: 5, Bucket: 5},
+		},
+	}
+	assert.True(t, cfg.UseRedis())
+	err = m.Update(cfg)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	l := m.Limiter("/api/v1/user/:id")
+	assert.Equal(t, LimiterSlidingWindow, l.Policy().Type)
+	_, result, err := l.Allow(ctx, "127.0.0.1")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	_, result, err = l.Allow(ctx, "127.0.0.1")
+	assert.ErrorIs(t, err, rl.ErrLimitExceed)
+	assert.False(t, result.Allowed)
+	done, result, err := l.Allow(ctx, "")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	done(rl.DoneInfo{})
+
+	l = m.Limiter("/api/v1/user/list")
+	assert.Equal(t, "/api/v1/*", l.Policy().Route)
+	done, result, err = l.Allow(ctx, "")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	done(rl.DoneInfo{})
+
+	l = m.Limiter("/api.user.v1.User/GetByID")
+	_, _, err = l.Allow(ctx, "foo")
+	assert.NoError(t, err)
+	_, _, err = l.Allow(ctx, "foo")
+	assert.ErrorIs(t, err, rl.ErrLimitExceed)
+	assert.Nil(t, m.Limiter("/health"))
+
+	// each route has its own breaker
+	b1 := m.Breaker("/api/v1/user/:id")
+	b2 := m.Breaker("/api/v1/user/list")
+	assert.NotNil(t, b1)
+	assert.False(t, b1 == b2)
+	assert.True(t, b1 == m.Breaker("/api/v1/user/:id"))
+	assert.NoError(t, b1.Allow())
+	assert.Nil(t, m.Breaker("/health"))
+	assert.Len(t, m.Limiters(), 1)
+	assert.Len(t, m.Breakers(), 2)
+
+	// the limiters and breakers of unchanged policies are retained
+	oldLimiter := m.Limiter("/api/v1/user/list")
+	cfg.RateLimits = cfg.RateLimits[1:2]
+	err = m.Update(cfg)
+	assert.NoError(t, err)
+	assert.True(t, oldLimiter == m.Limiter("/api/v1/user/:id"))
+	assert.True(t, b1 == m.Breaker("/api/v1/user/:id"))
+	assert.Nil(t, m.Limiter("/api.user.v1.User/GetByID"))
+
+	// clear policies
+	err = m.Update(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, m.Limiter("/api/v1/user/:id"))
+	assert.Nil(t, m.Breaker("/api/v1/user/:id"))
+
+	// redis is unavailable
+	err = m.Update(&Config{RateLimits: []RateLimit{{Route: "/foo", Type: LimiterTokenBucket, Rate: 1, Burst: 1}}})
+	assert.NoError(t, err)
+	s.Close()
+	_, result, err = m.Limiter("/foo").Allow(ctx, "foo")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestManager_UpdateError(t *testing.T) {
+	m := NewManager()
+	configs := []*Config{
+		{RateLimits: []RateLimit{{Type: LimiterAdaptive}}},
+		{RateLimits: []RateLimit{{Route: "/foo", Type: "unknown"}}},
+		{RateLimits: []RateLimit{{Route: "/foo", Type: LimiterTokenBucket, Rate: 1, Burst: 1}}},
+		{RateLimits: []RateLimit{{Route: "/foo", Type: LimiterSlidingWindow, Limit: 1, Window: 1}}},
+		{CircuitBreakers: []CircuitBreaker{{}}},
+		{CircuitBreakers: []CircuitBreaker{{Route: "/foo", Type: "unknown"}}},
+	}
+	for _, cfg := range configs {
+		assert.Error(t, m.Update(cfg))
+	}
+
+	m = NewManager(WithRedisClient(redis.NewClient(&redis.Options{})))
+	assert.Error(t, m.Update(&Config{RateLimits: []RateLimit{{Route: "/foo", Type: LimiterTokenBucket}}}))
+	assert.Error(t, m.Update(&Config{RateLimits: []RateLimit{{Route: "/foo", Type: LimiterSlidingWindow, Limit: 1}}}))
+
+	// the policies are not changed
+	assert.NoError(t, m.Update(&Config{RateLimits: []RateLimit{{Route: "/foo"}}}))
+	assert.Error(t, m.Update(&Config{RateLimits: []RateLimit{{Route: "/bar", Type: "unknown"}}}))
+	assert.NotNil(t, m.Limiter("/foo"))
+}
+
+func TestManager_ClassicBreaker(t *testing.T) {
+	var changes []string
+	m := NewManager(WithStateChange(func(route string, from, to int32) {
+		changes = append(changes, route)
+	}))
+	err := m.Update(&Config{CircuitBreakers: []CircuitBreaker{
+		{Route: "/api/v1/*", Type: BreakerClassic, ConsecutiveFailures: 2, ErrorRate: 0.8,
+			Window: 5, OpenDuration: 10, HalfOpenProbes: 2},
+	}})
+	assert.NoError(t, err)
+
+	b := m.Breaker("/api/v1/user/:id")
+	assert.IsType(t, &circuitbreaker.ClassicBreaker{}, b)
+	b.MarkFailed()
+	b.MarkFailed()
+	assert.ErrorIs(t, b.Allow(), circuitbreaker.ErrNotAllowed)
+	assert.NoError(t, m.Breaker("/api/v1/user/list").Allow())
+	assert.Equal(t, []string{"/api/v1/user/:id"}, changes)
+}
+
+func TestInit(t *testing.T) {
+	err := Init(&Config{CircuitBreakers: []CircuitBreaker{{Route: "/foo"}}},
+		WithRedisClient(redis.NewClient(&redis.Options{DialTimeout: time.Second})))
+	assert.NoError(t, err)
+	assert.NotNil(t, Get().Breaker("/foo"))
+	assert.NoError(t, Init(nil))
+	assert.False(t, (&Config{}).UseRedis())
+}
+
+func Test_matchRoute(t *testing.T) {
+	patterns := []string{"/api/*", "/api/v1/*", "/api/v1/user", "*"}
+	getPattern := func(i int) string { return patterns[i] }
+	assert.Equal(t, 2, matchRoute(len(patterns), getPattern, "/api/v1/user"))
+	assert.Equal(t, 1, matchRoute(len(patterns), getPattern, "/api/v1/user/:id"))
+	assert.Equal(t, 0, matchRoute(len(patterns), getPattern, "/api/v2/user"))
+	assert.Equal(t, 3, matchRoute(len(patterns), getPattern, "/health"))
+	assert.Equal(t, -1, matchRoute(0, getPattern, "/health"))
+}
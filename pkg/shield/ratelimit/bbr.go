@@ -64,6 +64,7 @@ type Stat struct {
 	MaxInFlight int64
 	MinRt       int64
 	MaxPass     int64
+	Dropped     int64 // number of requests dropped since the limiter is created
 }
 
 // counterCache is used to cache maxPASS and minRt result.
@@ -122,6 +123,7 @@ type BBR struct {
 	passStat        window.RollingCounter
 	rtStat          window.RollingCounter
 	inFlight        int64
+	dropped         int64
 	bucketPerSecond int64
 	bucketDuration  time.Duration
 
@@ -284,6 +286,7 @@ func (l *BBR) Stat() Stat {
 		MaxPass:     l.maxPASS(),
 		MaxInFlight: l.maxInFlight(),
 		InFlight:    atomic.LoadInt64(&l.inFlight),
+		Dropped:     atomic.LoadInt64(&l.dropped),
 	}
 }
 
@@ -291,6 +294,7 @@ func (l *BBR) Stat() Stat {
 // Once overload is detected, it raises limit.ErrLimitExceed error.
 func (l *BBR) Allow() (DoneFunc, error) {
 	if l.shouldDrop() {
+		atomic.AddInt64(&l.dropped, 1)
 		return nil, ErrLimitExceed
 	}
 	atomic.AddInt64(&l.inFlight, 1)
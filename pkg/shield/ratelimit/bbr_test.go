@@ -204,6 +204,19 @@ func TestBBRShouldDrop(t *testing.T) {
 	t.Log(false, bbr.shouldDrop())
 }
 
+func TestBBRDropped(t *testing.T) {
+	bbr := NewLimiter(optsForTest...)
+	bbr.cpu = func() int64 {
+		return 900
+	}
+	atomic.StoreInt64(&bbr.inFlight, 100)
+	_, err := bbr.Allow()
+	assert.ErrorIs(t, err, ErrLimitExceed)
+	_, err = bbr.Allow()
+	assert.ErrorIs(t, err, ErrLimitExceed)
+	assert.Equal(t, int64(2), bbr.Stat().Dropped)
+}
+
 func BenchmarkBBRAllowUnderLowLoad(b *testing.B) {
 	bbr := NewLimiter(optsForTest...)
 	bbr.cpu = func() int64 {
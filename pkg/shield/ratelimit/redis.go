@@ -0,0 +1,221 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/zhufuyi/sponge/pkg/krand"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	_ KeyLimiter = &TokenBucket{}
+	_ KeyLimiter = &SlidingWindow{}
+)
+
+// KeyLimiter is a rate limiter by key, e.g. user id, api key, ip, the quota of the same key is shared across replicas.
+type KeyLimiter interface {
+	Allow(ctx context.Context, key string) (*Result, error)
+}
+
+// Result is the result of KeyLimiter.Allow.
+type Result struct {
+	Allowed    bool
+	Limit      int           // max number of requests
+	Remaining  int           // remaining number of requests
+	RetryAfter time.Duration // time to wait before the next request is allowed, 0 if allowed
+	ResetAfter time.Duration // time to wait before the quota is fully reset
+}
+
+// RedisOption set the redis limiter options.
+type RedisOption func(*redisOptions)
+
+type redisOptions struct {
+	keyPrefix string
+}
+
+func (o *redisOptions) apply(opts ...RedisOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+func defaultRedisOptions() *redisOptions {
+	return &redisOptions{
+		keyPrefix: "ratelimit:",
+	}
+}
+
+// WithKeyPrefix set the prefix of redis key, default is "ratelimit:".
+func WithKeyPrefix(prefix string) RedisOption {
+	return func(o *redisOptions) {
+		o.keyPrefix = prefix
+	}
+}
+
+// the tokens are refilled at a constant rate and the bucket holds at most burst tokens,
+// return {allowed, remaining, retry_after(ms), reset_after(ms)}
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local requested = tonumber(ARGV[4])
+
+local values = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(values[1])
+local ts = tonumber(values[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+local elapsed = math.max(0, now - ts)
+tokens = math.min(burst, tokens + elapsed * rate / 1000)
+
+local allowed = 0
+local retry_after = 0
+if tokens >= requested then
+	tokens = tokens - requested
+	allowed = 1
+else
+	retry_after = math.ceil((requested - tokens) * 1000 / rate)
+end
+
+local reset_after = math.ceil((burst - tokens) * 1000 / rate)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.max(reset_after, 1))
+return {allowed, math.floor(tokens), retry_after, reset_after}
+`)
+
+// TokenBucket is a token bucket limiter based on redis, allow bursts of up to burst requests,
+// and refill the tokens at rate per second.
+type TokenBucket struct {
+	client    redis.UniversalClient
+	keyPrefix string
+	rate      float64
+	burst     int
+	now       func() time.Time
+}
+
+// NewTokenBucket returns a token bucket limiter, rate is the number of tokens refilled per second,
+// burst is the max number of tokens. the current time is taken from the client, the clocks of replicas should be synchronized.
+// return an error if rate or burst is not greater than 0.
+func NewTokenBucket(client redis.UniversalClient, rate float64, burst int, opts ...RedisOption) (*TokenBucket, error) {
+	if rate <= 0 || burst <= 0 {
+		return nil, errors.New("rate and burst must be greater than 0")
+	}
+
+	o := defaultRedisOptions()
+	o.apply(opts...)
+
+	return &TokenBucket{
+		client:    client,
+		keyPrefix: o.keyPrefix,
+		rate:      rate,
+		burst:     burst,
+		now:       time.Now,
+	}, nil
+}
+
+// Allow takes one token of key
+func (l *TokenBucket) Allow(ctx context.Context, key string) (*Result, error) {
+	values, err := tokenBucketScript.Run(ctx, l.client, []string{l.keyPrefix + key},
+		l.rate, l.burst, l.now().UnixMilli(), 1).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	return newResult(l.burst, values), nil
+}
+
+// the timestamps of requests in the window are recorded in a sorted set,
+// return {allowed, remaining, retry_after(ms), reset_after(ms)}
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+
+local reset_after = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset_after = tonumber(oldest[2]) + window - now
+end
+
+local retry_after = 0
+if allowed == 0 then
+	retry_after = reset_after
+end
+return {allowed, limit - count, retry_after, reset_after}
+`)
+
+// SlidingWindow is a sliding window limiter based on redis, allow at most limit requests in any window.
+type SlidingWindow struct {
+	client    redis.UniversalClient
+	keyPrefix string
+	limit     int
+	window    time.Duration
+	now       func() time.Time
+}
+
+// NewSlidingWindow returns a sliding window limiter, allow at most limit requests in window.
+// the current time is taken from the client, the clocks of replicas should be synchronized.
+// return an error if limit is not greater than 0 or window is less than 1 millisecond.
+func NewSlidingWindow(client redis.UniversalClient, limit int, window time.Duration, opts ...RedisOption) (*SlidingWindow, error) {
+	if limit <= 0 {
+		return nil, errors.New("limit must be greater than 0")
+	}
+	if window < time.Millisecond {
+		return nil, errors.New("window must be at least 1 millisecond")
+	}
+
+	o := defaultRedisOptions()
+	o.apply(opts...)
+
+	return &SlidingWindow{
+		client:    client,
+		keyPrefix: o.keyPrefix,
+		limit:     limit,
+		window:    window,
+		now:       time.Now,
+	}, nil
+}
+
+// Allow records one request of key
+func (l *SlidingWindow) Allow(ctx context.Context, key string) (*Result, error) {
+	now := l.now().UnixMilli()
+	member := strconv.FormatInt(now, 10) + "-" + krand.String(krand.R_All, 8)
+	values, err := slidingWindowScript.Run(ctx, l.client, []string{l.keyPrefix + key},
+		l.limit, l.window.Milliseconds(), now, member).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	return newResult(l.limit, values), nil
+}
+
+func newResult(limit int, values []int64) *Result {
+	if len(values) < 4 {
+		return &Result{Allowed: true, Limit: limit, Remaining: limit}
+	}
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRedisClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, redis.NewClient(&redis.Options{Addr: s.Addr()})
+}
+
+func TestTokenBucket(t *testing.T) {
+	s, client := newRedisClient(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	l, err := NewTokenBucket(client, 10, 5, WithKeyPrefix("rl:"))
+	assert.NoError(t, err)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		r, err := l.Allow(ctx, "foo")
+		assert.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 5, r.Limit)
+		assert.Equal(t, 4-i, r.Remaining)
+	}
+	r, err := l.Allow(ctx, "foo")
+	assert.NoError(t, err)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 100*time.Millisecond, r.RetryAfter)
+	assert.Equal(t, 500*time.Millisecond, r.ResetAfter)
+	assert.True(t, s.Exists("rl:foo"))
+
+	// other keys are not affected
+	r, err = l.Allow(ctx, "bar")
+	assert.NoError(t, err)
+	assert.True(t, r.Allowed)
+
+	// refill 2 tokens
+	now = now.Add(200 * time.Millisecond)
+	r, err = l.Allow(ctx, "foo")
+	assert.NoError(t, err)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 1, r.Remaining)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	s, client := newRedisClient(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	l, err := NewSlidingWindow(client, 3, time.Second)
+	assert.NoError(t, err)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		r, err := l.Allow(ctx, "foo")
+		assert.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 2-i, r.Remaining)
+		now = now.Add(100 * time.Millisecond)
+	}
+	r, err := l.Allow(ctx, "foo")
+	assert.NoError(t, err)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+	assert.Equal(t, 700*time.Millisecond, r.RetryAfter)
+	assert.True(t, s.Exists("ratelimit:foo"))
+
+	// the first request slides out of the window
+	now = now.Add(701 * time.Millisecond)
+	r, err = l.Allow(ctx, "foo")
+	assert.NoError(t, err)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+}
+
+func TestKeyLimiterError(t *testing.T) {
+	s, client := newRedisClient(t)
+	s.Close()
+	ctx := context.Background()
+
+	tb, _ := NewTokenBucket(client, 1, 1)
+	_, err := tb.Allow(ctx, "foo")
+	assert.Error(t, err)
+	sw, _ := NewSlidingWindow(client, 1, time.Second)
+	_, err = sw.Allow(ctx, "foo")
+	assert.Error(t, err)
+}
+
+func TestNewKeyLimiterError(t *testing.T) {
+	_, err := NewTokenBucket(nil, 0, 1)
+	assert.Error(t, err)
+	_, err = NewTokenBucket(nil, 1, -1)
+	assert.Error(t, err)
+	_, err = NewSlidingWindow(nil, 0, time.Second)
+	assert.Error(t, err)
+	_, err = NewSlidingWindow(nil, 1, time.Microsecond)
+	assert.Error(t, err)
+}
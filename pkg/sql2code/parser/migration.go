@@ -0,0 +1,633 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/tidbparser/ast"
+	"github.com/blastrain/vitess-sqlparser/tidbparser/dependency/types"
+	"github.com/blastrain/vitess-sqlparser/tidbparser/parser"
+)
+
+// MigrationCodes migration sql, Up is used to upgrade the schema, Down is used to roll back the schema
+type MigrationCodes struct {
+	Up   string
+	Down string
+}
+
+// IsEmpty whether there are no schema changes
+func (m *MigrationCodes) IsEmpty() bool {
+	return m.Up == "" && m.Down == ""
+}
+
+type migrationColumn struct {
+	name       string
+	definition string // column definition without name, e.g. varchar(50) NOT NULL DEFAULT '' COMMENT 'name'
+	colType    string
+	notNull    bool
+	autoIncr   bool
+	hasDefault bool   // DEFAULT NULL is the same as no default
+	defaultVal string // the value in sql, e.g. '', 'abc', CURRENT_TIMESTAMP
+	onUpdate   string // the value of ON UPDATE, e.g. CURRENT_TIMESTAMP, only supported by mysql
+	comment    string
+}
+
+type migrationIndex struct {
+	name     string
+	isUnique bool
+	columns  []string
+}
+
+type migrationTable struct {
+	name       string
+	text       string // original create table sql
+	columns    []*migrationColumn
+	columnMap  map[string]*migrationColumn
+	indexes    []*migrationIndex
+	indexMap   map[string]*migrationIndex
+	primaryKey []string
+}
+
+// ParseMigrationSQL compare the old and new DDL sql, generate the up and down migration sql,
+// the dialect of the generated sql is determined by WithDBDriver, default is mysql.
+// if WithTables is set, only the specified tables are compared.
+func ParseMigrationSQL(oldSQL string, newSQL string, options ...Option) (*MigrationCodes, error) {
+	opt := parseOption(options)
+
+	oldTables, oldNames, err := parseMigrationTables(oldSQL, opt)
+	if err != nil {
+		return nil, fmt.Errorf("parse old sql error: %v", err)
+	}
+	newTables, newNames, err := parseMigrationTables(newSQL, opt)
+	if err != nil {
+		return nil, fmt.Errorf("parse new sql error: %v", err)
+	}
+
+	d := newDialect(opt.DBDriver)
+	var ups []string
+	var downBlocks [][]string // the down sql of each table
+
+	// new tables and changed tables, in the order of the new sql
+	for _, name := range newNames {
+		newTable := newTables[name]
+		oldTable, ok := oldTables[name]
+		if !ok {
+			ups = append(ups, d.createTable(newTable))
+			downBlocks = append(downBlocks, []string{d.dropTable(name)})
+			continue
+		}
+		up, down := diffTable(d, oldTable, newTable)
+		ups = append(ups, up...)
+		downBlocks = append(downBlocks, down)
+	}
+
+	// deleted tables
+	for _, name := range oldNames {
+		if _, ok := newTables[name]; ok {
+			continue
+		}
+		ups = append(ups, d.dropTable(name))
+		downBlocks = append(downBlocks, []string{d.createTable(oldTables[name])})
+	}
+
+	// roll back the tables in reverse order
+	var downs []string
+	for i := len(downBlocks) - 1; i >= 0; i-- {
+		downs = append(downs, downBlocks[i]...)
+	}
+
+	return &MigrationCodes{
+		Up:   joinStatements(ups),
+		Down: joinStatements(downs),
+	}, nil
+}
+
+func joinStatements(stmts []string) string {
+	if len(stmts) == 0 {
+		return ""
+	}
+	return strings.Join(stmts, "\n\n") + "\n"
+}
+
+func parseMigrationTables(sql string, opt options) (map[string]*migrationTable, []string, error) {
+	tables := make(map[string]*migrationTable)
+	var names []string
+	if strings.TrimSpace(sql) == "" {
+		return tables, names, nil
+	}
+
+	stmts, err := parser.New().Parse(sql, opt.Charset, opt.Collation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, stmt := range stmts {
+		ct, ok := stmt.(*ast.CreateTableStmt)
+		if !ok {
+			continue
+		}
+		if !opt.isSelectedTable(ct.Table.Name.String()) {
+			continue
+		}
+		table := toMigrationTable(ct)
+		if _, ok = tables[table.name]; !ok {
+			names = append(names, table.name)
+		}
+		tables[table.name] = table
+	}
+
+	return tables, names, nil
+}
+
+func toMigrationTable(ct *ast.CreateTableStmt) *migrationTable {
+	table := &migrationTable{
+		name:      ct.Table.Name.String(),
+		text:      strings.TrimSpace(ct.Text()),
+		columnMap: make(map[string]*migrationColumn),
+		indexMap:  make(map[string]*migrationIndex),
+	}
+
+	for _, col := range ct.Cols {
+		mc := &migrationColumn{
+			name:    col.Name.Name.String(),
+			colType: col.Tp.InfoSchemaStr(),
+		}
+		for _, o := range col.Options {
+			switch o.Tp {
+			case ast.ColumnOptionPrimaryKey:
+				table.primaryKey = append(table.primaryKey, mc.name)
+			case ast.ColumnOptionNotNull:
+				mc.notNull = true
+			case ast.ColumnOptionAutoIncrement:
+				mc.autoIncr = true
+			case ast.ColumnOptionDefaultValue:
+				mc.defaultVal = toSQLValue(o.Expr, col.Tp.Decimal)
+				mc.hasDefault = mc.defaultVal != ""
+			case ast.ColumnOptionOnUpdate:
+				mc.onUpdate = toSQLValue(o.Expr, col.Tp.Decimal)
+			case ast.ColumnOptionComment:
+				mc.comment = o.Expr.GetDatum().GetString()
+			}
+		}
+
+		definition := mc.colType
+		if mc.notNull {
+			definition += " NOT NULL"
+		}
+		if mc.autoIncr {
+			definition += " AUTO_INCREMENT"
+		}
+		if mc.hasDefault {
+			definition += " DEFAULT " + mc.defaultVal
+		}
+		if mc.onUpdate != "" {
+			definition += " ON UPDATE " + mc.onUpdate
+		}
+		if mc.comment != "" {
+			definition += " COMMENT " + quoteSQLString(mc.comment)
+		}
+		mc.definition = definition
+
+		table.columns = append(table.columns, mc)
+		table.columnMap[mc.name] = mc
+	}
+
+	for _, con := range ct.Constraints {
+		var columns []string
+		for _, key := range con.Keys {
+			columns = append(columns, key.Column.Name.String())
+		}
+		switch con.Tp {
+		case ast.ConstraintPrimaryKey:
+			table.primaryKey = columns
+		case ast.ConstraintKey, ast.ConstraintIndex, ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+			idx := &migrationIndex{
+				name:     con.Name,
+				isUnique: con.Tp == ast.ConstraintUniq || con.Tp == ast.ConstraintUniqKey || con.Tp == ast.ConstraintUniqIndex,
+				columns:  columns,
+			}
+			if idx.name == "" {
+				idx.name = "idx_" + table.name + "_" + strings.Join(columns, "_")
+			}
+			table.indexes = append(table.indexes, idx)
+			table.indexMap[idx.name] = idx
+		}
+	}
+
+	return table
+}
+
+// diffTable returns the up and down sql of a table, the statements are sorted in the order of execution
+func diffTable(d *dialect, oldTable *migrationTable, newTable *migrationTable) (ups []string, downs []string) {
+	tableName := newTable.name
+	pkChanged := strings.Join(oldTable.primaryKey, ",") != strings.Join(newTable.primaryKey, ",")
+	var (
+		dropIndexes, addedColumns, modifiedColumns, changePK, droppedColumns, createIndexes []string // up
+		undoIndexes, undoAdded, undoModified, undoPK, undoDropped, restoreIndexes           []string // down
+	)
+
+	// drop the changed or deleted indexes first, the indexed column may be deleted
+	for _, idx := range oldTable.indexes {
+		if newIdx, ok := newTable.indexMap[idx.name]; !ok || !idx.equal(newIdx) {
+			dropIndexes = append(dropIndexes, d.dropIndex(tableName, idx))
+			restoreIndexes = append(restoreIndexes, d.createIndex(tableName, idx))
+		}
+	}
+
+	for _, col := range newTable.columns {
+		oldCol, ok := oldTable.columnMap[col.name]
+		if !ok {
+			addedColumns = append(addedColumns, d.addColumn(tableName, col))
+			undoAdded = append(undoAdded, d.dropColumn(tableName, col.name))
+			continue
+		}
+		if !col.equal(oldCol) {
+			modifiedColumns = append(modifiedColumns, d.modifyColumn(tableName, oldCol, col)...)
+			undoModified = append(undoModified, d.modifyColumn(tableName, col, oldCol)...)
+		}
+	}
+
+	// sqlite does not support modifying columns and primary key, and adding the column which is not null
+	// without default value, the table is rebuilt
+	if d.dbDriver == DBDriverSqlite && (pkChanged || len(modifiedColumns) > 0 || hasRequiredColumn(oldTable, newTable)) {
+		return d.rebuildTable(oldTable, newTable), d.rebuildTable(newTable, oldTable)
+	}
+
+	if pkChanged {
+		changePK = d.changePrimaryKey(tableName, oldTable.primaryKey, newTable.primaryKey)
+		undoPK = d.changePrimaryKey(tableName, newTable.primaryKey, oldTable.primaryKey)
+	}
+
+	for _, col := range oldTable.columns {
+		if _, ok := newTable.columnMap[col.name]; !ok {
+			droppedColumns = append(droppedColumns, d.dropColumn(tableName, col.name))
+			undoDropped = append(undoDropped, d.addColumn(tableName, col))
+		}
+	}
+
+	for _, idx := range newTable.indexes {
+		if oldIdx, ok := oldTable.indexMap[idx.name]; !ok || !idx.equal(oldIdx) {
+			createIndexes = append(createIndexes, d.createIndex(tableName, idx))
+			undoIndexes = append(undoIndexes, d.dropIndex(tableName, idx))
+		}
+	}
+
+	ups = append(ups, dropIndexes...)
+	ups = append(ups, addedColumns...)
+	ups = append(ups, modifiedColumns...)
+	ups = append(ups, changePK...)
+	ups = append(ups, droppedColumns...)
+	ups = append(ups, createIndexes...)
+
+	// the reverse order of up
+	downs = append(downs, undoIndexes...)
+	downs = append(downs, undoDropped...)
+	downs = append(downs, undoPK...)
+	downs = append(downs, undoModified...)
+	downs = append(downs, undoAdded...)
+	downs = append(downs, restoreIndexes...)
+
+	return ups, downs
+}
+
+func (c *migrationColumn) equal(o *migrationColumn) bool {
+	return strings.EqualFold(c.colType, o.colType) &&
+		c.notNull == o.notNull &&
+		c.autoIncr == o.autoIncr &&
+		c.hasDefault == o.hasDefault &&
+		c.defaultVal == o.defaultVal &&
+		strings.EqualFold(c.onUpdate, o.onUpdate) &&
+		c.comment == o.comment
+}
+
+// whether the column must be given a value when inserting, the data of the column can not be copied from the old table
+func (c *migrationColumn) isRequired() bool {
+	return c.notNull && !c.hasDefault && !c.autoIncr
+}
+
+// whether the new table has the columns that are required but not in the old table
+func hasRequiredColumn(oldTable *migrationTable, newTable *migrationTable) bool {
+	for _, col := range newTable.columns {
+		if _, ok := oldTable.columnMap[col.name]; !ok && col.isRequired() {
+			return true
+		}
+	}
+	return false
+}
+
+func (idx *migrationIndex) equal(o *migrationIndex) bool {
+	return idx.isUnique == o.isUnique && strings.Join(idx.columns, ",") == strings.Join(o.columns, ",")
+}
+
+// ------------------------------------------------------------------------------------------
+
+type dialect struct {
+	dbDriver string
+	quote    string
+}
+
+func newDialect(dbDriver string) *dialect {
+	switch dbDriver {
+	case DBDriverPostgresql, DBDriverSqlite:
+		return &dialect{dbDriver: dbDriver, quote: `"`}
+	default:
+		return &dialect{dbDriver: DBDriverMysql, quote: "`"}
+	}
+}
+
+func (d *dialect) name(s string) string {
+	return d.quote + s + d.quote
+}
+
+func (d *dialect) names(ss []string) string {
+	names := make([]string, 0, len(ss))
+	for _, s := range ss {
+		names = append(names, d.name(s))
+	}
+	return strings.Join(names, ", ")
+}
+
+// columnDefinition the column definition adapted to the dialect
+func (d *dialect) columnDefinition(col *migrationColumn) string {
+	if d.dbDriver == DBDriverMysql {
+		return col.definition
+	}
+
+	definition := d.columnType(col)
+	if col.notNull {
+		definition += " NOT NULL"
+	}
+	if col.hasDefault {
+		definition += " DEFAULT " + col.defaultVal
+	}
+	return definition
+}
+
+// the auto increment primary key of sqlite must be INTEGER PRIMARY KEY AUTOINCREMENT, which is the alias of rowid
+func (d *dialect) isSqliteRowID(table *migrationTable, col *migrationColumn) bool {
+	return d.dbDriver == DBDriverSqlite && col.autoIncr && len(table.primaryKey) == 1 && table.primaryKey[0] == col.name
+}
+
+// the sql of column comments, postgresql and sqlite do not support the comment in column definition
+func (d *dialect) commentOnColumn(tableName string, col *migrationColumn) string {
+	comment := "NULL"
+	if col.comment != "" {
+		comment = quoteSQLString(col.comment)
+	}
+	return fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;", d.name(tableName), d.name(col.name), comment)
+}
+
+func (d *dialect) columnType(col *migrationColumn) string {
+	if d.dbDriver == DBDriverPostgresql {
+		pgType := mysqlTypeToPg(col.colType)
+		if col.autoIncr {
+			if pgType == "bigint" {
+				return "bigserial"
+			}
+			return "serial"
+		}
+		return pgType
+	}
+	return col.colType
+}
+
+func (d *dialect) createTable(table *migrationTable) string {
+	if d.dbDriver == DBDriverMysql && table.text != "" {
+		return strings.TrimSuffix(table.text, ";") + ";"
+	}
+
+	lines := make([]string, 0, len(table.columns)+1)
+	hasRowID := false
+	for _, col := range table.columns {
+		if d.isSqliteRowID(table, col) {
+			hasRowID = true
+			lines = append(lines, "    "+d.name(col.name)+" INTEGER PRIMARY KEY AUTOINCREMENT")
+			continue
+		}
+		lines = append(lines, "    "+d.name(col.name)+" "+d.columnDefinition(col))
+	}
+	if len(table.primaryKey) > 0 && !hasRowID {
+		lines = append(lines, "    PRIMARY KEY ("+d.names(table.primaryKey)+")")
+	}
+	stmts := []string{fmt.Sprintf("CREATE TABLE %s (\n%s\n);", d.name(table.name), strings.Join(lines, ",\n"))}
+	if d.dbDriver == DBDriverPostgresql {
+		for _, col := range table.columns {
+			if col.comment != "" {
+				stmts = append(stmts, d.commentOnColumn(table.name, col))
+			}
+		}
+	}
+	for _, idx := range table.indexes {
+		stmts = append(stmts, d.createIndex(table.name, idx))
+	}
+	return strings.Join(stmts, "\n")
+}
+
+func (d *dialect) dropTable(tableName string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;", d.name(tableName))
+}
+
+func (d *dialect) addColumn(tableName string, col *migrationColumn) string {
+	stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", d.name(tableName), d.name(col.name), d.columnDefinition(col))
+	if d.dbDriver == DBDriverPostgresql && col.comment != "" {
+		stmt += "\n" + d.commentOnColumn(tableName, col)
+	}
+	return stmt
+}
+
+func (d *dialect) dropColumn(tableName string, colName string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", d.name(tableName), d.name(colName))
+}
+
+// modifyColumn returns the sql of changing the column from oldCol to newCol
+func (d *dialect) modifyColumn(tableName string, oldCol *migrationColumn, newCol *migrationColumn) []string {
+	if d.dbDriver != DBDriverPostgresql {
+		return []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s;", d.name(tableName), d.name(newCol.name), d.columnDefinition(newCol))}
+	}
+
+	// serial is not a real type of postgresql, the auto increment column is an integer with a sequence as default value
+	table, column := d.name(tableName), d.name(newCol.name)
+	seq := d.name(tableName + "_" + newCol.name + "_seq")
+	stmts := []string{fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s;", table, column, mysqlTypeToPg(newCol.colType))}
+	if newCol.notNull {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;", table, column))
+	} else {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL;", table, column))
+	}
+	switch {
+	case newCol.autoIncr && !oldCol.autoIncr:
+		stmts = append(stmts,
+			fmt.Sprintf("CREATE SEQUENCE IF NOT EXISTS %s OWNED BY %s.%s;", seq, table, column),
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT nextval(%s);", table, column, quoteSQLString(seq)),
+			fmt.Sprintf("SELECT setval(%s, COALESCE(MAX(%s), 0) + 1, false) FROM %s;", quoteSQLString(seq), column, table),
+		)
+	case newCol.autoIncr:
+		// keep the sequence as default value
+	case newCol.hasDefault:
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s;", table, column, newCol.defaultVal))
+	default:
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT;", table, column))
+	}
+	if oldCol.autoIncr && !newCol.autoIncr {
+		stmts = append(stmts, fmt.Sprintf("DROP SEQUENCE IF EXISTS %s;", seq))
+	}
+	if oldCol.comment != newCol.comment {
+		stmts = append(stmts, d.commentOnColumn(tableName, newCol))
+	}
+	return stmts
+}
+
+// changePrimaryKey returns the sql of changing the primary key from oldKey to newKey, empty means no primary key
+func (d *dialect) changePrimaryKey(tableName string, oldKey []string, newKey []string) []string {
+	if d.dbDriver == DBDriverPostgresql {
+		var stmts []string
+		if len(oldKey) > 0 {
+			// the default name of primary key constraint
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", d.name(tableName), d.name(tableName+"_pkey")))
+		}
+		if len(newKey) > 0 {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s);", d.name(tableName), d.names(newKey)))
+		}
+		return stmts
+	}
+
+	// in one statement, the auto increment column of mysql must be a key
+	var actions []string
+	if len(oldKey) > 0 {
+		actions = append(actions, "DROP PRIMARY KEY")
+	}
+	if len(newKey) > 0 {
+		actions = append(actions, "ADD PRIMARY KEY ("+d.names(newKey)+")")
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %s %s;", d.name(tableName), strings.Join(actions, ", "))}
+}
+
+// rebuildTable returns the sql of rebuilding the sqlite table from the schema of "from" to "to",
+// the data of the common columns are copied into the new table, the added columns which are not null
+// without default value are filled with the zero value of type.
+func (d *dialect) rebuildTable(from *migrationTable, to *migrationTable) []string {
+	tmpTable := *to
+	tmpTable.name = to.name + "_tmp_migration"
+	tmpTable.indexes = nil
+
+	var columns, values []string
+	for _, col := range to.columns {
+		if _, ok := from.columnMap[col.name]; ok {
+			columns = append(columns, d.name(col.name))
+			values = append(values, d.name(col.name))
+		} else if col.isRequired() {
+			columns = append(columns, d.name(col.name))
+			values = append(values, zeroValue(col.colType))
+		}
+	}
+
+	stmts := []string{d.createTable(&tmpTable)}
+	if len(columns) > 0 {
+		stmts = append(stmts, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s;",
+			d.name(tmpTable.name), strings.Join(columns, ", "), strings.Join(values, ", "), d.name(from.name)))
+	}
+	stmts = append(stmts,
+		fmt.Sprintf("DROP TABLE %s;", d.name(from.name)),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", d.name(tmpTable.name), d.name(to.name)),
+	)
+	for _, idx := range to.indexes {
+		stmts = append(stmts, d.createIndex(to.name, idx))
+	}
+	return stmts
+}
+
+func (d *dialect) createIndex(tableName string, idx *migrationIndex) string {
+	unique := ""
+	if idx.isUnique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", unique, d.name(idx.name), d.name(tableName), d.names(idx.columns))
+}
+
+func (d *dialect) dropIndex(tableName string, idx *migrationIndex) string {
+	if d.dbDriver == DBDriverMysql {
+		return fmt.Sprintf("DROP INDEX %s ON %s;", d.name(idx.name), d.name(tableName))
+	}
+	return fmt.Sprintf("DROP INDEX IF EXISTS %s;", d.name(idx.name))
+}
+
+// toSQLValue returns the value of DEFAULT or ON UPDATE in sql, e.g. 'abc', CURRENT_TIMESTAMP(3), empty means NULL,
+// the parser drops the arguments of function, the precision of CURRENT_TIMESTAMP is the same as the column.
+func toSQLValue(expr ast.ExprNode, fsp int) string {
+	if funcExpr, ok := expr.(*ast.FuncCallExpr); ok {
+		value := funcExpr.FnName.O
+		if fsp > 0 && (strings.EqualFold(value, "CURRENT_TIMESTAMP") || strings.EqualFold(value, "NOW")) {
+			value += fmt.Sprintf("(%d)", fsp)
+		}
+		return value
+	}
+	if expr.GetDatum().Kind() == types.KindNull {
+		return ""
+	}
+	return quoteSQLString(fmt.Sprintf("%v", expr.GetDatum().GetValue()))
+}
+
+// the zero value of type, it is used to fill the column which is not null without default value
+func zeroValue(colType string) string {
+	t := strings.ToLower(colType)
+	switch {
+	case strings.HasPrefix(t, "datetime"), strings.HasPrefix(t, "timestamp"):
+		return "CURRENT_TIMESTAMP"
+	case strings.HasPrefix(t, "date"):
+		return "CURRENT_DATE"
+	case strings.HasPrefix(t, "time"):
+		return "CURRENT_TIME"
+	}
+	switch mysqlTypeToPg(t) {
+	case "smallint", "integer", "bigint", "real", "double precision", "boolean":
+		return "0"
+	}
+	if strings.HasPrefix(t, "decimal") || strings.HasPrefix(t, "numeric") {
+		return "0"
+	}
+	return "''"
+}
+
+func quoteSQLString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// nolint
+func mysqlTypeToPg(colType string) string {
+	t := strings.ToLower(colType)
+	name := t
+	if i := strings.IndexAny(t, "( "); i > 0 {
+		name = t[:i]
+	}
+	switch name {
+	case "tinyint", "smallint":
+		return "smallint"
+	case "mediumint", "int", "integer":
+		return "integer"
+	case "bigint":
+		return "bigint"
+	case "float":
+		return "real"
+	case "double":
+		return "double precision"
+	case "decimal", "numeric":
+		return strings.Replace(t, " unsigned", "", 1)
+	case "char", "varchar":
+		return strings.Replace(t, " binary", "", 1)
+	case "tinytext", "text", "mediumtext", "longtext", "enum", "set":
+		return "text"
+	case "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary":
+		return "bytea"
+	case "datetime", "timestamp":
+		return "timestamp"
+	case "date":
+		return "date"
+	case "time":
+		return "time"
+	case "json":
+		return "json"
+	case "bit", "bool", "boolean":
+		return "boolean"
+	}
+	return t
+}
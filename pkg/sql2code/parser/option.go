@@ -27,8 +27,9 @@ type options struct {
 	Package        string
 	GormType       bool
 	ForceTableName bool
-	IsEmbed        bool // is gorm.Model embedded
-	IsWebProto     bool // true: proto file include router path and swagger info, false: normal proto file without router and swagger
+	IsEmbed        bool     // is gorm.Model embedded
+	IsWebProto     bool     // true: proto file include router path and swagger info, false: normal proto file without router and swagger
+	Tables         []string // only the specified tables are parsed, if empty, all tables are parsed
 }
 
 var defaultOptions = options{
@@ -141,6 +142,29 @@ func WithWebProto() Option {
 	}
 }
 
+// WithTables only parse the specified tables
+func WithTables(tableNames ...string) Option {
+	return func(o *options) {
+		for _, name := range tableNames {
+			if name != "" {
+				o.Tables = append(o.Tables, name)
+			}
+		}
+	}
+}
+
+func (o *options) isSelectedTable(tableName string) bool {
+	if len(o.Tables) == 0 {
+		return true
+	}
+	for _, name := range o.Tables {
+		if name == tableName {
+			return true
+		}
+	}
+	return false
+}
+
 func parseOption(options []Option) options {
 	o := defaultOptions
 	for _, f := range options {
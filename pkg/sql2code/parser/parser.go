@@ -79,9 +79,16 @@ func ParseSQL(sql string, options ...Option) (map[string]string, error) {
 	modelJSONCodes := make([]string, 0, len(stmts))
 	importPath := make(map[string]struct{})
 	tableNames := make([]string, 0, len(stmts))
+	primaryKey := ""
+	relation := ""
+	queryColumns := ""
+	relations := parseRelations(stmts, opt)
 	for _, stmt := range stmts {
 		if ct, ok := stmt.(*ast.CreateTableStmt); ok {
-			code, err2 := makeCode(ct, opt)
+			if !opt.isSelectedTable(ct.Table.Name.String()) {
+				continue
+			}
+			code, err2 := makeCode(ct, opt, relations[ct.Table.Name.String()])
 			if err2 != nil {
 				return nil, err2
 			}
@@ -92,6 +99,11 @@ func ParseSQL(sql string, options ...Option) (map[string]string, error) {
 			serviceStructCodes = append(serviceStructCodes, code.serviceStruct)
 			modelJSONCodes = append(modelJSONCodes, code.modelJSON)
 			tableNames = append(tableNames, toCamel(ct.Table.Name.String()))
+			if len(tableNames) == 1 {
+				primaryKey = code.primaryKey // only the primary key, relation and query columns of the first table are recorded
+				relation = code.relation
+				queryColumns = code.queryColumns
+			}
 			for _, s := range code.importPaths {
 				importPath[s] = struct{}{}
 			}
@@ -123,6 +135,16 @@ func ParseSQL(sql string, options ...Option) (map[string]string, error) {
 		CodeTypeService: strings.Join(serviceStructCodes, "\n\n"),
 		TableName:       strings.Join(tableNames, ", "),
 	}
+	// the following info is set only if it is not empty
+	if primaryKey != "" {
+		codesMap[PrimaryKey] = primaryKey
+	}
+	if relation != "" {
+		codesMap[Relation] = relation
+	}
+	if queryColumns != "" {
+		codesMap[QueryColumns] = queryColumns
+	}
 
 	return codesMap, nil
 }
@@ -137,6 +159,10 @@ type tmplData struct {
 	SubStructs      string // sub structs for model
 	ProtoSubStructs string // sub structs for protobuf
 	DBDriver        string
+	PrimaryKeys     []PrimaryKeyField
+	Relations       []RelationField // association fields of model and handler detail struct
+	ProtoRelations  []RelationField // association fields of proto message
+	ProtoImports    []string        // proto files of the related tables
 }
 
 type tmplField struct {
@@ -147,6 +173,8 @@ type tmplField struct {
 	Comment  string
 	JSONName string
 	DBDriver string
+
+	IsPrimaryKey bool
 }
 
 // ConditionZero type of condition 0, used in dao template code
@@ -247,10 +275,10 @@ func (t tmplField) AddOne(i int) int {
 	return i + 1
 }
 
-// AddOneWithTag counter and add id tag
+// AddOneWithTag counter and add uri tag to primary key
 func (t tmplField) AddOneWithTag(i int) string {
-	if t.ColName == "id" {
-		return fmt.Sprintf(`%d [(tagger.tags) = "uri:\"id\"" ]`, i+1)
+	if t.IsPrimaryKey || t.ColName == columnID {
+		return fmt.Sprintf(`%d [(tagger.tags) = "uri:\"%s\"" ]`, i+1, t.JSONName)
 	}
 
 	return fmt.Sprintf("%d", i+1)
@@ -302,15 +330,21 @@ type codeText struct {
 	handlerStruct string
 	protoFile     string
 	serviceStruct string
+	primaryKey    string
+	relation      string
+	queryColumns  string
 }
 
 // nolint
-func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
+func makeCode(stmt *ast.CreateTableStmt, opt options, relations []RelationField) (*codeText, error) {
 	importPath := make([]string, 0, 1)
 	data := tmplData{
-		TableName:    stmt.Table.Name.String(),
-		RawTableName: stmt.Table.Name.String(),
-		Fields:       make([]tmplField, 0, 1),
+		TableName:      stmt.Table.Name.String(),
+		RawTableName:   stmt.Table.Name.String(),
+		Fields:         make([]tmplField, 0, 1),
+		Relations:      relations,
+		ProtoRelations: getProtoRelations(relations),
+		ProtoImports:   getProtoImports(relations),
 	}
 	tablePrefix := opt.TablePrefix
 	if tablePrefix != "" && strings.HasPrefix(data.TableName, tablePrefix) {
@@ -343,7 +377,9 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 	isPrimaryKey := make(map[string]bool)
 	for _, con := range stmt.Constraints {
 		if con.Tp == ast.ConstraintPrimaryKey {
-			isPrimaryKey[con.Keys[0].Column.String()] = true
+			for _, key := range con.Keys { // composite primary key
+				isPrimaryKey[key.Column.String()] = true
+			}
 		}
 	}
 
@@ -414,6 +450,7 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 		}
 
 		field.DBDriver = opt.DBDriver
+		field.IsPrimaryKey = isPrimaryKey[colName] && opt.DBDriver != DBDriverMongodb
 		switch opt.DBDriver {
 		case DBDriverMongodb: // mongodb
 			tags = append(tags, "bson", gormTag.String())
@@ -463,7 +500,20 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 	}
 	data.DBDriver = opt.DBDriver
 
-	updateFieldsCode, err := getUpdateFieldsCode(data, opt.IsEmbed)
+	data.PrimaryKeys = getPrimaryKeys(data)
+	if len(data.PrimaryKeys) == 1 && isIntegerType(data.PrimaryKeys[0].GoType) {
+		// force conversion of single integer primary key to uint64 type
+		data.PrimaryKeys[0].GoType = "uint64"
+		for i, field := range data.Fields {
+			if field.IsPrimaryKey {
+				data.Fields[i].GoType = "uint64"
+			}
+		}
+	}
+	// gorm.Model contains the id primary key, it cannot be embedded when the primary key is not the default
+	isEmbed := opt.IsEmbed && IsDefaultPrimaryKey(data.PrimaryKeys)
+
+	updateFieldsCode, err := getUpdateFieldsCode(data, isEmbed)
 	if err != nil {
 		return nil, err
 	}
@@ -473,7 +523,7 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 		return nil, err
 	}
 
-	modelStructCode, importPaths, err := getModelStructCode(data, importPath, opt.IsEmbed)
+	modelStructCode, importPaths, err := getModelStructCode(data, importPath, isEmbed)
 	if err != nil {
 		return nil, err
 	}
@@ -501,6 +551,9 @@ func makeCode(stmt *ast.CreateTableStmt, opt options) (*codeText, error) {
 		handlerStruct: handlerStructCode,
 		protoFile:     protoFileCode,
 		serviceStruct: serviceStructCode,
+		primaryKey:    getPrimaryKeyInfo(data.PrimaryKeys),
+		relation:      getRelationInfo(data.Relations),
+		queryColumns:  getQueryColumnInfo(data),
 	}, nil
 }
 
@@ -556,7 +609,7 @@ func getModelStructCode(data tmplData, importPaths []string, isEmbed bool) (stri
 					continue
 				}
 				// force conversion of ID field to uint64 type
-				if field.Name == "ID" {
+				if field.Name == "ID" && isIntegerType(field.GoType) {
 					data.Fields[i].GoType = "uint64"
 				}
 			}
@@ -580,6 +633,14 @@ func getModelStructCode(data tmplData, importPaths []string, isEmbed bool) (stri
 		structCode = strings.ReplaceAll(structCode, __type__, replaceFields[__type__])
 	}
 
+	if IsCompositePrimaryKey(data.PrimaryKeys) {
+		keyCode, err := getPrimaryKeyStructCode(data.TableName, data.PrimaryKeys)
+		if err != nil {
+			return "", nil, err
+		}
+		structCode += keyCode
+		newImportPaths = append(newImportPaths, "fmt")
+	}
 	if data.SubStructs != "" {
 		structCode += data.SubStructs
 	}
@@ -615,7 +676,7 @@ func getUpdateFieldsCode(data tmplData, isEmbed bool) (string, error) {
 	var newFields = []tmplField{}
 	for _, field := range data.Fields {
 		falseColumns := []string{}
-		if isIgnoreFields(field.ColName, falseColumns...) || field.ColName == columnID || field.ColName == _columnID {
+		if isIgnoreFields(field.ColName, falseColumns...) || field.ColName == columnID || field.ColName == _columnID || field.IsPrimaryKey {
 			continue
 		}
 		newFields = append(newFields, field)
@@ -735,7 +796,7 @@ func getProtoFileCode(data tmplData, isWebProto bool) (string, error) {
 		return "", fmt.Errorf("handlerCreateStructTmpl error: %v", err)
 	}
 	if !isWebProto {
-		protoMessageUpdateCode = strings.ReplaceAll(protoMessageUpdateCode, ` [(tagger.tags) = "uri:\"id\"" ]`, "")
+		protoMessageUpdateCode = removeURITag(protoMessageUpdateCode)
 	}
 
 	protoMessageDetailCode, err := tmplExecuteWithFilter(data, protoMessageDetailTmpl, columnID, columnCreatedAt, columnUpdatedAt)
@@ -807,7 +868,13 @@ func adaptedDbType(data tmplData, isWebProto bool, code string) string {
 			code = replaceProtoMessageFieldCode(code, grpcProtoMessageFieldCodes)
 		}
 	default:
-		if isWebProto {
+		if !IsDefaultPrimaryKey(data.PrimaryKeys) {
+			code = replaceProtoMessageFieldCode(code, getPrimaryKeyProtoFieldCodes(data.TableName, data.PrimaryKeys, isWebProto))
+			code = strings.ReplaceAll(code, `/{id}"`, getPrimaryKeyRoutePath(data.PrimaryKeys)+`"`)
+			if IsCompositePrimaryKey(data.PrimaryKeys) {
+				code += "\n" + getPrimaryKeyProtoMessageCode(data.TableName, data.PrimaryKeys)
+			}
+		} else if isWebProto {
 			code = replaceProtoMessageFieldCode(code, webDefaultProtoMessageFieldCodes)
 		} else {
 			code = replaceProtoMessageFieldCode(code, grpcDefaultProtoMessageFieldCodes)
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/blastrain/vitess-sqlparser/tidbparser/dependency/mysql"
@@ -406,3 +407,135 @@ func Test_embedTimeFields(t *testing.T) {
 	fields = embedTimeField(names, []*MgoField{})
 	t.Log(fields)
 }
+
+func TestParseMigrationSQL(t *testing.T) {
+	oldSQL := `CREATE TABLE user (
+  id BIGINT(11) PRIMARY KEY AUTO_INCREMENT NOT NULL COMMENT 'id',
+  name VARCHAR(30) NOT NULL DEFAULT '' COMMENT 'name',
+  age INT(11) NULL,
+  KEY idx_name (name)
+  );
+CREATE TABLE t_old (
+  id BIGINT(11) PRIMARY KEY AUTO_INCREMENT NOT NULL
+  );`
+	newSQL := `CREATE TABLE user (
+  id BIGINT(11) PRIMARY KEY AUTO_INCREMENT NOT NULL COMMENT 'id',
+  name VARCHAR(50) NOT NULL DEFAULT '' COMMENT 'name',
+  phone VARCHAR(20) NOT NULL DEFAULT '' COMMENT 'phone',
+  UNIQUE KEY uk_phone (phone)
+  );
+CREATE TABLE t_new (
+  id BIGINT(11) PRIMARY KEY AUTO_INCREMENT NOT NULL
+  );`
+
+	for _, driver := range []string{DBDriverMysql, DBDriverPostgresql, DBDriverSqlite} {
+		codes, err := ParseMigrationSQL(oldSQL, newSQL, WithDBDriver(driver))
+		assert.NoError(t, err)
+		assert.False(t, codes.IsEmpty())
+		assert.Contains(t, codes.Up, "phone")
+		assert.Contains(t, codes.Up, "t_new")
+		assert.Contains(t, codes.Down, "t_old")
+		t.Log(codes.Up, codes.Down)
+	}
+
+	codes, err := ParseMigrationSQL(oldSQL, newSQL, WithTables("t_new"))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes.Up, "phone")
+
+	codes, err = ParseMigrationSQL(oldSQL, oldSQL)
+	assert.NoError(t, err)
+	assert.True(t, codes.IsEmpty())
+
+	_, err = ParseMigrationSQL("create table", newSQL)
+	assert.Error(t, err)
+}
+
+func TestParseMigrationSQL_alter(t *testing.T) {
+	oldSQL := `CREATE TABLE user (
+  id BIGINT(11) NOT NULL,
+  name VARCHAR(30) NOT NULL DEFAULT '' COMMENT 'name',
+  PRIMARY KEY (id)
+  );`
+	newSQL := `CREATE TABLE user (
+  id BIGINT(11) AUTO_INCREMENT NOT NULL,
+  name VARCHAR(30) NOT NULL DEFAULT '' COMMENT 'name',
+  tenant_id BIGINT(11) NOT NULL DEFAULT 0,
+  PRIMARY KEY (tenant_id, id),
+  KEY idx_name (name)
+  );`
+
+	codes, err := ParseMigrationSQL(oldSQL, newSQL)
+	assert.NoError(t, err)
+	assert.Contains(t, codes.Up, "ALTER TABLE `user` MODIFY COLUMN `id` bigint(11) NOT NULL AUTO_INCREMENT;")
+	assert.Contains(t, codes.Up, "ALTER TABLE `user` DROP PRIMARY KEY, ADD PRIMARY KEY (`tenant_id`, `id`);")
+	assert.Contains(t, codes.Down, "ALTER TABLE `user` DROP PRIMARY KEY, ADD PRIMARY KEY (`id`);")
+	assert.Less(t, strings.Index(codes.Up, "ADD COLUMN `tenant_id`"), strings.Index(codes.Up, "ADD PRIMARY KEY"))
+	assert.Less(t, strings.Index(codes.Down, "ADD PRIMARY KEY"), strings.Index(codes.Down, "DROP COLUMN `tenant_id`"))
+
+	codes, err = ParseMigrationSQL(oldSQL, newSQL, WithDBDriver(DBDriverPostgresql))
+	assert.NoError(t, err)
+	assert.Contains(t, codes.Up, `ALTER TABLE "user" ALTER COLUMN "id" TYPE bigint;`)
+	assert.Contains(t, codes.Up, `CREATE SEQUENCE IF NOT EXISTS "user_id_seq" OWNED BY "user"."id";`)
+	assert.Contains(t, codes.Up, `ALTER TABLE "user" ALTER COLUMN "id" SET DEFAULT nextval('"user_id_seq"');`)
+	assert.Contains(t, codes.Up, `ALTER TABLE "user" DROP CONSTRAINT IF EXISTS "user_pkey";`)
+	assert.Contains(t, codes.Up, `ALTER TABLE "user" ADD PRIMARY KEY ("tenant_id", "id");`)
+	assert.NotContains(t, codes.Up, "serial")
+	assert.Contains(t, codes.Down, `ALTER TABLE "user" ALTER COLUMN "id" DROP DEFAULT;`)
+	assert.Contains(t, codes.Down, `DROP SEQUENCE IF EXISTS "user_id_seq";`)
+	assert.Contains(t, codes.Down, `ALTER TABLE "user" ADD PRIMARY KEY ("id");`)
+
+	codes, err = ParseMigrationSQL(oldSQL, newSQL, WithDBDriver(DBDriverSqlite))
+	assert.NoError(t, err)
+	assert.NotContains(t, codes.Up, "TODO")
+	assert.Contains(t, codes.Up, `CREATE TABLE "user_tmp_migration" (`)
+	assert.Contains(t, codes.Up, `INSERT INTO "user_tmp_migration" ("id", "name") SELECT "id", "name" FROM "user";`)
+	assert.Contains(t, codes.Up, `ALTER TABLE "user_tmp_migration" RENAME TO "user";`)
+	assert.Contains(t, codes.Up, `CREATE INDEX "idx_name" ON "user" ("name");`)
+	assert.Contains(t, codes.Down, `PRIMARY KEY ("id")`)
+	assert.NotContains(t, codes.Down, "idx_name")
+	t.Log(codes.Up, codes.Down)
+}
+
+func TestParseMigrationSQL_default(t *testing.T) {
+	oldSQL := `CREATE TABLE user (
+  id BIGINT(20) UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+  name VARCHAR(50) NOT NULL DEFAULT '',
+  email VARCHAR(50) NOT NULL,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated time'
+  );`
+	newSQL := `CREATE TABLE user (
+  id BIGINT(20) UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+  name VARCHAR(100) NOT NULL DEFAULT '',
+  email VARCHAR(50) NOT NULL DEFAULT '',
+  updated_at DATETIME(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT 'updated time',
+  age INT(11) NOT NULL COMMENT 'age'
+  );`
+
+	codes, err := ParseMigrationSQL(oldSQL, newSQL)
+	assert.NoError(t, err)
+	assert.Contains(t, codes.Up, "ALTER TABLE `user` MODIFY COLUMN `name` varchar(100) NOT NULL DEFAULT '';")
+	assert.Contains(t, codes.Up, "ALTER TABLE `user` MODIFY COLUMN `email` varchar(50) NOT NULL DEFAULT '';")
+	assert.Contains(t, codes.Down, "ALTER TABLE `user` MODIFY COLUMN `email` varchar(50) NOT NULL;")
+	assert.Contains(t, codes.Up, "ALTER TABLE `user` MODIFY COLUMN `updated_at` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT 'updated time';")
+	assert.Contains(t, codes.Down, "ALTER TABLE `user` MODIFY COLUMN `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated time';")
+
+	codes, err = ParseMigrationSQL(oldSQL, newSQL, WithDBDriver(DBDriverPostgresql))
+	assert.NoError(t, err)
+	assert.Contains(t, codes.Up, `ALTER TABLE "user" ALTER COLUMN "email" SET DEFAULT '';`)
+	assert.Contains(t, codes.Up, `COMMENT ON COLUMN "user"."age" IS 'age';`)
+	assert.NotContains(t, codes.Up, `COMMENT ON COLUMN "user"."updated_at"`) // comment is not changed
+	assert.NotContains(t, codes.Up, "ON UPDATE")
+
+	// the auto increment primary key of sqlite is the alias of rowid,
+	// the added column which is not null without default value is filled with zero value
+	codes, err = ParseMigrationSQL(oldSQL, newSQL, WithDBDriver(DBDriverSqlite))
+	assert.NoError(t, err)
+	assert.Contains(t, codes.Up, `"id" INTEGER PRIMARY KEY AUTOINCREMENT,`)
+	assert.NotContains(t, codes.Up, `PRIMARY KEY ("id")`)
+	assert.Contains(t, codes.Up, `INSERT INTO "user_tmp_migration" ("id", "name", "email", "updated_at", "age") SELECT "id", "name", "email", "updated_at", 0 FROM "user";`)
+	assert.Contains(t, codes.Down, `INSERT INTO "user_tmp_migration" ("id", "name", "email", "updated_at") SELECT "id", "name", "email", "updated_at" FROM "user";`)
+
+	codes, err = ParseMigrationSQL("", oldSQL, WithDBDriver(DBDriverPostgresql))
+	assert.NoError(t, err)
+	assert.Contains(t, codes.Up, `COMMENT ON COLUMN "user"."updated_at" IS 'updated time';`)
+}
@@ -0,0 +1,245 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// PrimaryKey primary key information, the value is a json array of PrimaryKeyField
+const PrimaryKey = "__primary_key__"
+
+// PrimaryKeyField primary key column
+type PrimaryKeyField struct {
+	Name     string `json:"name"`     // field name in go struct
+	ColName  string `json:"colName"`  // column name in table
+	GoType   string `json:"goType"`   // type in golang
+	JSONName string `json:"jsonName"` // json tag name, also used as the name of the path parameter
+}
+
+// GetPrimaryKeys get the primary key fields from the codes generated by ParseSQL, the codes must come from one table
+func GetPrimaryKeys(codes map[string]string) []PrimaryKeyField {
+	var fields []PrimaryKeyField
+	if v := codes[PrimaryKey]; v != "" {
+		_ = json.Unmarshal([]byte(v), &fields)
+	}
+	return fields
+}
+
+// IsDefaultPrimaryKey single primary key named id and type is integer,
+// if there is no primary key, it is also regarded as the default primary key.
+func IsDefaultPrimaryKey(fields []PrimaryKeyField) bool {
+	if len(fields) == 0 {
+		return true
+	}
+	return len(fields) == 1 && fields[0].ColName == columnID && isIntegerType(fields[0].GoType)
+}
+
+// IsCompositePrimaryKey more than one primary key column
+func IsCompositePrimaryKey(fields []PrimaryKeyField) bool {
+	return len(fields) > 1
+}
+
+func isIntegerType(goType string) bool {
+	switch goType {
+	case "int8", "int16", "int32", "int64", "int", "uint8", "uint16", "uint32", "uint64", "uint":
+		return true
+	}
+	return false
+}
+
+func getPrimaryKeys(data tmplData) []PrimaryKeyField {
+	var fields []PrimaryKeyField
+	for _, field := range data.Fields {
+		if field.IsPrimaryKey {
+			fields = append(fields, PrimaryKeyField{
+				Name:     field.Name,
+				ColName:  field.ColName,
+				GoType:   field.GoType,
+				JSONName: field.JSONName,
+			})
+		}
+	}
+	return fields
+}
+
+func getPrimaryKeyInfo(fields []PrimaryKeyField) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(fields)
+	return string(data)
+}
+
+var primaryKeyStructTmpl = template.Must(template.New("primaryKeyStruct").Parse(`
+// {{.TableName}}Key composite primary key of {{.TableName}}
+type {{.TableName}}Key struct {
+{{- range .PrimaryKeys}}
+	{{.Name}} {{.GoType}} ` + "`" + `json:"{{.JSONName}}"` + "`" + `
+{{- end}}
+}
+
+// Key get the composite primary key
+func (m *{{.TableName}}) Key() {{.TableName}}Key {
+	return {{.TableName}}Key{
+	{{- range .PrimaryKeys}}
+		{{.Name}}: m.{{.Name}},
+	{{- end}}
+	}
+}
+
+// String convert to string, used as cache key
+func (k {{.TableName}}Key) String() string {
+	return fmt.Sprintf("{{range $i, $v := .PrimaryKeys}}{{if $i}}:{{end}}%v{{end}}"{{range .PrimaryKeys}}, k.{{.Name}}{{end}})
+}
+
+// IsZero whether the key is empty
+func (k {{.TableName}}Key) IsZero() bool {
+	return k == {{.TableName}}Key{}
+}
+
+// Conditions query conditions, e.g. db.Where(key.Conditions())
+func (k {{.TableName}}Key) Conditions() map[string]interface{} {
+	return map[string]interface{}{
+	{{- range .PrimaryKeys}}
+		"{{.ColName}}": k.{{.Name}},
+	{{- end}}
+	}
+}
+
+// Values the values of primary key columns, e.g. db.Where({{.TableName}}KeyColumns+" < ?", key.Values())
+func (k {{.TableName}}Key) Values() []interface{} {
+	return []interface{}{ {{- range $i, $v := .PrimaryKeys}}{{if $i}}, {{end}}k.{{$v.Name}}{{end -}} }
+}
+
+// {{.TableName}}KeyColumns primary key columns, e.g. db.Where({{.TableName}}KeyColumns+" IN ?", {{.TableName}}KeyValues(keys))
+const {{.TableName}}KeyColumns = "({{range $i, $v := .PrimaryKeys}}{{if $i}}, {{end}}{{$v.ColName}}{{end}})"
+
+// {{.TableName}}KeyValues convert keys to the values of IN query
+func {{.TableName}}KeyValues(keys []{{.TableName}}Key) [][]interface{} {
+	values := make([][]interface{}, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, k.Values())
+	}
+	return values
+}
+`))
+
+// composite primary key struct code, used in model
+func getPrimaryKeyStructCode(tableName string, fields []PrimaryKeyField) (string, error) {
+	builder := strings.Builder{}
+	err := primaryKeyStructTmpl.Execute(&builder, map[string]interface{}{
+		"TableName":   tableName,
+		"PrimaryKeys": fields,
+	})
+	if err != nil {
+		return "", fmt.Errorf("primaryKeyStructTmpl.Execute error: %v", err)
+	}
+	return builder.String(), nil
+}
+
+func toProtoType(goType string) string {
+	switch goType {
+	case "int", "int8", "int16":
+		return "int32"
+	case "uint", "uint8", "uint16":
+		return "uint32"
+	case "float32":
+		return "float"
+	case "float64":
+		return "double"
+	}
+	return goType
+}
+
+func protoValidateRule(protoType string) string {
+	switch protoType {
+	case "string":
+		return "(validate.rules).string.min_len = 1"
+	case "int32", "int64", "uint32", "uint64", "float", "double":
+		return fmt.Sprintf("(validate.rules).%s.gt = 0", protoType)
+	}
+	return ""
+}
+
+func joinProtoOptions(options ...string) string {
+	var ss []string
+	for _, o := range options {
+		if o != "" {
+			ss = append(ss, o)
+		}
+	}
+	if len(ss) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(ss, ", ") + "]"
+}
+
+// proto message field codes of the primary key which is not the default, replace the default id field codes
+func getPrimaryKeyProtoFieldCodes(tableName string, fields []PrimaryKeyField, isWebProto bool) map[string]string {
+	uriTag := func(name string) string {
+		if !isWebProto {
+			return ""
+		}
+		return fmt.Sprintf(`(tagger.tags) = "uri:\"%s\""`, name)
+	}
+	formTag := func(name string) string {
+		if !isWebProto {
+			return ""
+		}
+		return fmt.Sprintf(`(tagger.tags) = "form:\"%s\""`, name)
+	}
+
+	var keyFields, pathFields []string
+	for i, field := range fields {
+		protoType := toProtoType(field.GoType)
+		keyFields = append(keyFields, fmt.Sprintf("%s %s = %d;", protoType, field.JSONName, i+1))
+		pathFields = append(pathFields, fmt.Sprintf("%s %s = %d%s;", protoType, field.JSONName, i+1,
+			joinProtoOptions(protoValidateRule(protoType), uriTag(field.JSONName))))
+	}
+
+	first := fields[0]
+	firstType := toProtoType(first.GoType)
+	idsType := "repeated " + firstType
+	if IsCompositePrimaryKey(fields) {
+		idsType = "repeated " + tableName + "Key"
+	}
+
+	return map[string]string{
+		createTableReplyFieldCodeMark:         strings.Join(keyFields, "\n  "),
+		deleteTableByIDRequestFieldCodeMark:   strings.Join(pathFields, "\n  "),
+		deleteTableByIDsRequestFieldCodeMark:  idsType + " ids = 1 [(validate.rules).repeated.min_items = 1];",
+		getTableByIDRequestFieldCodeMark:      strings.Join(pathFields, "\n  "),
+		getTableByIDsRequestFieldCodeMark:     idsType + " ids = 1 [(validate.rules).repeated.min_items = 1];",
+		listTableByLastIDRequestFieldCodeMark: fmt.Sprintf("%s lastID = 1%s; // last %s", firstType, joinProtoOptions(formTag("lastID")), first.ColName),
+	}
+}
+
+// composite primary key message, used as the element of batch request
+func getPrimaryKeyProtoMessageCode(tableName string, fields []PrimaryKeyField) string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("message %sKey {\n", tableName))
+	for i, field := range fields {
+		builder.WriteString(fmt.Sprintf("  %s %s = %d;\n", toProtoType(field.GoType), field.JSONName, i+1))
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// path of route, e.g. /{id} --> /{orderID}/{productID}
+func getPrimaryKeyRoutePath(fields []PrimaryKeyField) string {
+	var ss []string
+	for _, field := range fields {
+		ss = append(ss, "{"+field.JSONName+"}")
+	}
+	return "/" + strings.Join(ss, "/")
+}
+
+var uriTagRegexp = regexp.MustCompile(` \[\(tagger\.tags\) = "uri:\\"\w+\\"" \]`)
+
+// remove the uri tag of update message fields in grpc proto
+func removeURITag(code string) string {
+	return uriTagRegexp.ReplaceAllString(code, "")
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSQL_PrimaryKey(t *testing.T) {
+	sql := `CREATE TABLE order_item (
+  order_id BIGINT(11) NOT NULL,
+  product_id VARCHAR(36) NOT NULL,
+  num INT(11) NOT NULL DEFAULT 1,
+  PRIMARY KEY (order_id, product_id)
+  ) COMMENT="order item";`
+
+	codes, err := ParseSQL(sql, WithJSONTag(1), WithEmbed(), WithWebProto())
+	assert.NoError(t, err)
+	fields := GetPrimaryKeys(codes)
+	assert.Equal(t, 2, len(fields))
+	assert.True(t, IsCompositePrimaryKey(fields))
+	assert.False(t, IsDefaultPrimaryKey(fields))
+	assert.True(t, strings.Contains(codes[CodeTypeModel], "type OrderItemKey struct"))
+	assert.True(t, strings.Contains(codes[CodeTypeModel], "func (k OrderItemKey) Values() []interface{}"))
+	assert.True(t, strings.Contains(codes[CodeTypeProto], "message OrderItemKey"))
+	assert.False(t, strings.Contains(codes[CodeTypeModel], "ggorm.Model"))
+
+	sql = `CREATE TABLE device (
+  uuid VARCHAR(36) PRIMARY KEY NOT NULL,
+  name VARCHAR(30) NOT NULL
+  );`
+	codes, err = ParseSQL(sql, WithJSONTag(1))
+	assert.NoError(t, err)
+	fields = GetPrimaryKeys(codes)
+	assert.Equal(t, 1, len(fields))
+	assert.Equal(t, "string", fields[0].GoType)
+	assert.False(t, IsDefaultPrimaryKey(fields))
+
+	sql = `CREATE TABLE user (
+  id INT(11) PRIMARY KEY AUTO_INCREMENT NOT NULL,
+  name VARCHAR(30) NOT NULL
+  );`
+	codes, err = ParseSQL(sql, WithJSONTag(1))
+	assert.NoError(t, err)
+	fields = GetPrimaryKeys(codes)
+	assert.True(t, IsDefaultPrimaryKey(fields))
+	assert.Equal(t, "uint64", fields[0].GoType)
+}
@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// QueryColumns columns that can be used in the query conditions and sort, the value is a json array of QueryColumnField
+const QueryColumns = "__query_columns__"
+
+// QueryColumnField column of query conditions
+type QueryColumnField struct {
+	ColName string `json:"colName"` // column name in table
+	Type    string `json:"type"`    // value type of query conditions, string, int, uint, float, bool, time, any
+}
+
+// GetQueryColumns get the query columns from the codes generated by ParseSQL, the codes must come from one table
+func GetQueryColumns(codes map[string]string) []QueryColumnField {
+	var fields []QueryColumnField
+	if v := codes[QueryColumns]; v != "" {
+		_ = json.Unmarshal([]byte(v), &fields)
+	}
+	return fields
+}
+
+// the value type of query conditions, corresponding to the type constants of the query package
+func toQueryType(goType string) string {
+	goType = strings.TrimPrefix(goType, "*")
+	switch goType {
+	case "string", "sql.NullString", goTypeOID:
+		return "string"
+	case "int8", "int16", "int32", "int64", "int", "sql.NullInt32", "sql.NullInt64":
+		return "int"
+	case "uint8", "uint16", "uint32", "uint64", "uint":
+		return "uint"
+	case "float32", "float64", "sql.NullFloat64":
+		return "float"
+	case "bool", "sql.NullBool":
+		return "bool"
+	case "time.Time", "sql.NullTime":
+		return "time"
+	}
+	return "any"
+}
+
+func getQueryColumnInfo(data tmplData) string {
+	fields := make([]QueryColumnField, 0, len(data.Fields))
+	for _, field := range data.Fields {
+		fields = append(fields, QueryColumnField{
+			ColName: field.ColName,
+			Type:    toQueryType(field.GoType),
+		})
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	d, _ := json.Marshal(fields)
+	return string(d)
+}
@@ -0,0 +1,33 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSQL_QueryColumns(t *testing.T) {
+	sql := `CREATE TABLE user (
+  id BIGINT(20) UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+  name VARCHAR(30) NOT NULL,
+  age INT(11) NULL,
+  score DOUBLE NOT NULL,
+  attrs JSON NULL,
+  created_at DATETIME NOT NULL
+  );`
+
+	codes, err := ParseSQL(sql, WithNullStyle(NullInPointer))
+	assert.NoError(t, err)
+	assert.Equal(t, []QueryColumnField{
+		{ColName: "id", Type: "uint"},
+		{ColName: "name", Type: "string"},
+		{ColName: "age", Type: "int"},
+		{ColName: "score", Type: "float"},
+		{ColName: "attrs", Type: "string"},
+		{ColName: "created_at", Type: "time"},
+	}, GetQueryColumns(codes))
+
+	assert.Equal(t, "any", toQueryType("[]byte"))
+	assert.Equal(t, "bool", toQueryType("sql.NullBool"))
+	assert.Nil(t, GetQueryColumns(map[string]string{}))
+}
@@ -0,0 +1,272 @@
+package parser
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/tidbparser/ast"
+	"github.com/huandu/xstrings"
+	"github.com/jinzhu/inflection"
+)
+
+// Relation relation information of the table, the value is a json array of RelationField
+const Relation = "__relation__"
+
+const (
+	// RelationBelongsTo the table has a foreign key that references another table
+	RelationBelongsTo = "belongsTo"
+	// RelationHasMany another table has a foreign key that references the table
+	RelationHasMany = "hasMany"
+)
+
+// RelationField association field of the model
+type RelationField struct {
+	Type     string `json:"type"`     // belongsTo or hasMany
+	Name     string `json:"name"`     // field name in model struct, e.g. User, Orders
+	Table    string `json:"table"`    // struct name of the related table, e.g. User, Order
+	TName    string `json:"tName"`    // struct name of the related table with first letter lowercase, e.g. user, order
+	JSONName string `json:"jsonName"` // json tag name
+
+	ForeignKey     string `json:"foreignKey"`     // field name of the foreign key, e.g. UserID
+	ForeignKeyCol  string `json:"foreignKeyCol"`  // column name of the foreign key, e.g. user_id
+	ForeignKeyType string `json:"foreignKeyType"` // go type of the foreign key
+}
+
+// GetRelations get the relation fields from the codes generated by ParseSQL, the codes must come from one table
+func GetRelations(codes map[string]string) []RelationField {
+	var fields []RelationField
+	if v := codes[Relation]; v != "" {
+		_ = json.Unmarshal([]byte(v), &fields)
+	}
+	return fields
+}
+
+// ModelType type of the field in model struct
+func (r RelationField) ModelType() string {
+	if r.Type == RelationHasMany {
+		return "[]*" + r.Table
+	}
+	return "*" + r.Table
+}
+
+// DetailType type of the field in handler detail struct
+func (r RelationField) DetailType() string {
+	if r.Type == RelationHasMany {
+		return "[]*" + r.Table + "ObjDetail"
+	}
+	return "*" + r.Table + "ObjDetail"
+}
+
+// ProtoNumber field number in proto message, the relation fields follow the table fields
+func (r RelationField) ProtoNumber(fieldCount int, i int) int {
+	return fieldCount + i + 1
+}
+
+type relationTable struct {
+	name      string // struct name
+	columns   map[string]string
+	hasID     bool // single integer primary key named id
+	relations []RelationField
+}
+
+// parse the relations between tables from foreign keys or columns named xxx_id,
+// only the tables with the default primary key are supported.
+func parseRelations(stmts []ast.StmtNode, opt options) map[string][]RelationField {
+	if opt.DBDriver == DBDriverMongodb {
+		return nil
+	}
+
+	tables := map[string]*relationTable{}
+	var tableNames []string
+	for _, stmt := range stmts {
+		ct, ok := stmt.(*ast.CreateTableStmt)
+		if !ok {
+			continue
+		}
+		rawName := ct.Table.Name.String()
+		t := &relationTable{name: toCamel(trimPrefix(rawName, opt.TablePrefix)), columns: map[string]string{}}
+		pkCount := 0
+		for _, con := range ct.Constraints {
+			if con.Tp == ast.ConstraintPrimaryKey {
+				pkCount += len(con.Keys)
+			}
+		}
+		for _, col := range ct.Cols {
+			colName := col.Name.Name.String()
+			goType, _ := mysqlToGoType(col.Tp, NullDisable)
+			t.columns[colName] = goType
+			if colName == columnID && isIntegerType(goType) {
+				t.hasID = true
+			}
+			for _, o := range col.Options {
+				if o.Tp == ast.ColumnOptionPrimaryKey {
+					pkCount++
+				}
+			}
+		}
+		t.hasID = t.hasID && pkCount <= 1
+		tables[rawName] = t
+		tableNames = append(tableNames, rawName)
+	}
+
+	// the explicit foreign keys
+	isForeignKey := map[string]bool{} // table.column
+	for _, stmt := range stmts {
+		ct, ok := stmt.(*ast.CreateTableStmt)
+		if !ok {
+			continue
+		}
+		child := ct.Table.Name.String()
+		for _, con := range ct.Constraints {
+			if con.Tp != ast.ConstraintForeignKey || con.Refer == nil || len(con.Keys) != 1 {
+				continue
+			}
+			refCols := con.Refer.IndexColNames
+			if len(refCols) != 1 || refCols[0].Column.String() != columnID {
+				continue
+			}
+			colName := con.Keys[0].Column.String()
+			if addRelation(tables, child, con.Refer.Table.Name.String(), colName, opt) {
+				isForeignKey[child+"."+colName] = true
+			}
+		}
+	}
+
+	// the implicit foreign keys, e.g. column user_id references the table user or users
+	for _, child := range tableNames {
+		colNames := make([]string, 0, len(tables[child].columns))
+		for colName := range tables[child].columns {
+			colNames = append(colNames, colName)
+		}
+		sort.Strings(colNames)
+		for _, colName := range colNames {
+			if isForeignKey[child+"."+colName] || !strings.HasSuffix(colName, "_id") {
+				continue
+			}
+			if parent := matchTable(tableNames, strings.TrimSuffix(colName, "_id"), opt.TablePrefix); parent != "" {
+				addRelation(tables, child, parent, colName, opt)
+			}
+		}
+	}
+
+	relations := map[string][]RelationField{}
+	for name, t := range tables {
+		if len(t.relations) > 0 {
+			relations[name] = t.relations
+		}
+	}
+	return relations
+}
+
+func addRelation(tables map[string]*relationTable, child string, parent string, colName string, opt options) bool {
+	c, p := tables[child], tables[parent]
+	if c == nil || p == nil || child == parent || !c.hasID || !p.hasID || !isIntegerType(c.columns[colName]) {
+		return false
+	}
+
+	fkName := toCamel(trimPrefix(colName, opt.ColumnPrefix))
+	name := strings.TrimSuffix(fkName, "ID")
+	if name == "" || name == fkName || hasField(c, name) {
+		return false
+	}
+	belongsTo := RelationField{
+		Type:           RelationBelongsTo,
+		Name:           name,
+		Table:          p.name,
+		TName:          firstLetterToLow(p.name),
+		JSONName:       relationJSONName(name, opt.JSONNamedType),
+		ForeignKey:     fkName,
+		ForeignKeyCol:  colName,
+		ForeignKeyType: c.columns[colName],
+	}
+
+	manyName := inflection.Plural(c.name)
+	if hasField(p, manyName) {
+		manyName = name + manyName // e.g. SenderMessages
+		if hasField(p, manyName) {
+			return false
+		}
+	}
+	hasMany := belongsTo
+	hasMany.Type = RelationHasMany
+	hasMany.Name = manyName
+	hasMany.Table = c.name
+	hasMany.TName = firstLetterToLow(c.name)
+	hasMany.JSONName = relationJSONName(manyName, opt.JSONNamedType)
+
+	c.relations = append(c.relations, belongsTo)
+	p.relations = append(p.relations, hasMany)
+	return true
+}
+
+func hasField(t *relationTable, name string) bool {
+	for colName := range t.columns {
+		if toCamel(colName) == name {
+			return true
+		}
+	}
+	for _, r := range t.relations {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// match the table name by the column name prefix, e.g. user --> user, users, t_user, t_users
+func matchTable(tableNames []string, name string, tablePrefix string) string {
+	for _, tableName := range tableNames {
+		n := trimPrefix(tableName, tablePrefix)
+		if n == name || n == inflection.Plural(name) {
+			return tableName
+		}
+	}
+	return ""
+}
+
+func trimPrefix(name string, prefix string) string {
+	if prefix != "" && strings.HasPrefix(name, prefix) {
+		return name[len(prefix):]
+	}
+	return name
+}
+
+func relationJSONName(name string, jsonNamedType int) string {
+	if jsonNamedType == 0 {
+		return xstrings.ToSnakeCase(name)
+	}
+	return firstLetterToLow(name)
+}
+
+func getRelationInfo(fields []RelationField) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(fields)
+	return string(data)
+}
+
+// belongsTo relations are referenced in proto messages, hasMany relations are ignored to avoid circular imports
+func getProtoRelations(fields []RelationField) []RelationField {
+	var relations []RelationField
+	for _, field := range fields {
+		if field.Type == RelationBelongsTo {
+			relations = append(relations, field)
+		}
+	}
+	return relations
+}
+
+// proto files of the related tables, e.g. user --> api/serverNameExample/v1/user.proto
+func getProtoImports(fields []RelationField) []string {
+	var imports []string
+	exist := map[string]bool{}
+	for _, field := range getProtoRelations(fields) {
+		if !exist[field.TName] {
+			exist[field.TName] = true
+			imports = append(imports, field.TName)
+		}
+	}
+	return imports
+}
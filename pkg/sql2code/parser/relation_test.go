@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSQL_Relation(t *testing.T) {
+	sql := `CREATE TABLE user (
+  id BIGINT(20) UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+  name VARCHAR(30) NOT NULL
+  );
+CREATE TABLE orders (
+  id BIGINT(20) UNSIGNED PRIMARY KEY AUTO_INCREMENT NOT NULL,
+  user_id BIGINT(20) UNSIGNED NOT NULL,
+  amount INT(11) NOT NULL
+  );
+CREATE TABLE comment (
+  id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
+  author BIGINT(20) UNSIGNED NOT NULL,
+  content VARCHAR(255) NOT NULL,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_author FOREIGN KEY (author) REFERENCES user (id)
+  );`
+
+	// the implicit foreign key
+	codes, err := ParseSQL(sql, WithJSONTag(1), WithEmbed(), WithWebProto(), WithTables("orders"))
+	assert.NoError(t, err)
+	relations := GetRelations(codes)
+	assert.Equal(t, 1, len(relations))
+	assert.Equal(t, RelationBelongsTo, relations[0].Type)
+	assert.Equal(t, "User", relations[0].Name)
+	assert.Equal(t, "UserID", relations[0].ForeignKey)
+	assert.True(t, strings.Contains(codes[CodeTypeModel], `*User  `+"`"+`gorm:"foreignKey:UserID" json:"user,omitempty"`))
+	assert.True(t, strings.Contains(codes[CodeTypeHandler], `*UserObjDetail`))
+	assert.True(t, strings.Contains(codes[CodeTypeProto], `import "api/serverNameExample/v1/user.proto";`))
+	assert.True(t, strings.Contains(codes[CodeTypeProto], "User user = "))
+
+	codes, err = ParseSQL(sql, WithJSONTag(0), WithTables("user"))
+	assert.NoError(t, err)
+	relations = GetRelations(codes)
+	assert.Equal(t, 1, len(relations))
+	assert.Equal(t, RelationHasMany, relations[0].Type)
+	assert.Equal(t, "Orders", relations[0].Name)
+	assert.Equal(t, "orders", relations[0].JSONName)
+	assert.True(t, strings.Contains(codes[CodeTypeModel], `[]*Orders `+"`"+`gorm:"foreignKey:UserID" json:"orders,omitempty"`))
+	assert.False(t, strings.Contains(codes[CodeTypeProto], "Orders orders"))
+
+	// the foreign key which name is not xxx_id is ignored
+	codes, err = ParseSQL(sql, WithTables("comment"))
+	assert.NoError(t, err)
+	assert.Empty(t, GetRelations(codes))
+
+	// no relation
+	codes, err = ParseSQL(sql)
+	assert.NoError(t, err)
+	assert.Equal(t, "User, Orders, Comment", codes[TableName])
+}
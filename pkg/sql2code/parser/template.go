@@ -17,6 +17,9 @@ type {{.TableName}} struct {
 {{- range .Fields}}
 	{{.Name}} {{.GoType}} {{if .Tag}}` + "`{{.Tag}}`" + `{{end}}{{if .Comment}} // {{.Comment}}{{end}}
 {{- end}}
+{{- range .Relations}}
+	{{.Name}} {{.ModelType}} ` + "`" + `gorm:"foreignKey:{{.ForeignKey}}" json:"{{.JSONName}},omitempty"` + "`" + `
+{{- end}}
 }
 {{if .NameFunc}}
 // TableName table name
@@ -74,6 +77,9 @@ type {{.TableName}}ObjDetail struct {
 {{- range .Fields}}
 	{{.Name}}  {{.GoType}} ` + "`" + `json:"{{.JSONName}}"` + "`" + `{{if .Comment}} // {{.Comment}}{{end}}
 {{- end}}
+{{- range .Relations}}
+	{{.Name}}  {{.DetailType}} ` + "`" + `json:"{{.JSONName}},omitempty"` + "`" + `
+{{- end}}
 }`
 
 	modelJSONTmpl    *template.Template
@@ -90,6 +96,9 @@ type {{.TableName}}ObjDetail struct {
 package api.serverNameExample.v1;
 
 import "api/types/types.proto";
+{{- range .ProtoImports}}
+import "api/serverNameExample/v1/{{.}}.proto";
+{{- end}}
 import "validate/validate.proto";
 
 option go_package = "github.com/zhufuyi/sponge/api/serverNameExample/v1;v1";
@@ -197,6 +206,7 @@ message List{{.TableName}}Request {
 message List{{.TableName}}Reply {
   int64 total =1;
   repeated {{.TableName}} {{.TName}}s = 2;
+  string nextCursor = 3; // cursor of next page, empty if there is no more data
 }
 `
 
@@ -206,6 +216,9 @@ message List{{.TableName}}Reply {
 package api.serverNameExample.v1;
 
 import "api/types/types.proto";
+{{- range .ProtoImports}}
+import "api/serverNameExample/v1/{{.}}.proto";
+{{- end}}
 import "google/api/annotations.proto";
 import "protoc-gen-openapiv2/options/annotations.proto";
 import "tagger/tagger.proto";
@@ -479,6 +492,7 @@ message List{{.TableName}}Request {
 message List{{.TableName}}Reply {
   int64 total =1;
   repeated {{.TableName}} {{.TName}}s = 2;
+  string nextCursor = 3; // cursor of next page, empty if there is no more data
 }
 `
 
@@ -501,6 +515,9 @@ message List{{.TableName}}Reply {
 {{- range $i, $v := .Fields}}
 	{{$v.GoType}} {{$v.JSONName}} = {{$v.AddOne $i}}; {{if $v.Comment}} // {{$v.Comment}}{{end}}
 {{- end}}
+{{- range $i, $v := .ProtoRelations}}
+	{{$v.Table}} {{$v.JSONName}} = {{$v.ProtoNumber (len $.Fields) $i}};
+{{- end}}
 }`
 
 	serviceStructTmpl    *template.Template
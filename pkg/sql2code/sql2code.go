@@ -25,6 +25,8 @@ type Args struct {
 	DBTable    string            // table name
 	fieldTypes map[string]string // field name:type
 
+	RelatedTables []string // other tables in the same batch, used to parse the relations between tables, only valid for DBDsn
+
 	Package        string // specify the package name (only valid for model types)
 	GormType       bool   // whether to display the gorm type name (only valid for model type codes)
 	JSONTag        bool   // does it include a json tag
@@ -209,5 +211,81 @@ func Generate(args *Args) (map[string]string, error) {
 
 	opt := setOptions(args)
 
+	if relatedSQL := getRelatedSQL(args); relatedSQL != "" {
+		sql = strings.TrimRight(strings.TrimSpace(sql), ";") + ";\n" + relatedSQL
+		opt = append(opt, parser.WithTables(args.DBTable)) // only generate the code of the specified table
+	}
+
 	return parser.ParseSQL(sql, opt...)
 }
+
+// get the DDL of the related tables from db, ignore the tables that failed to get
+func getRelatedSQL(args *Args) string {
+	if args.SQL != "" || args.DDLFile != "" || args.DBDsn == "" || args.DBDriver == parser.DBDriverMongodb {
+		return ""
+	}
+
+	var sqls []string
+	for _, table := range args.RelatedTables {
+		if table == "" || table == args.DBTable {
+			continue
+		}
+		relatedArgs := *args
+		relatedArgs.DBTable = table
+		sql, _, err := getSQL(&relatedArgs)
+		if err != nil || sql == "" {
+			continue
+		}
+		sqls = append(sqls, strings.TrimRight(strings.TrimSpace(sql), ";"))
+	}
+	if len(sqls) == 0 {
+		return ""
+	}
+	return strings.Join(sqls, ";\n") + ";"
+}
+
+// GenerateMigration compare the DDL of the old and new sources, generate the up and down migration sql,
+// sql can be obtained from parameter, file and db, sql and file must be mysql syntax,
+// the dialect of the migration sql is the db driver of the new source.
+func GenerateMigration(oldArgs *Args, newArgs *Args) (*parser.MigrationCodes, error) {
+	if oldArgs == nil || newArgs == nil {
+		return nil, errors.New("old and new sources cannot be nil")
+	}
+
+	var sqls []string
+	for _, a := range []*Args{oldArgs, newArgs} {
+		args := *a
+		if args.SQL != "" || args.DDLFile != "" {
+			args.DBDriver = parser.DBDriverMysql // sql and ddl file are mysql syntax
+		}
+		if err := args.checkValid(); err != nil {
+			return nil, err
+		}
+		sql, _, err := getSQL(&args)
+		if err != nil {
+			return nil, err
+		}
+		sqls = append(sqls, sql)
+	}
+
+	var tables []string
+	if oldArgs.DBTable != "" {
+		tables = append(tables, oldArgs.DBTable)
+	}
+	if newArgs.DBTable != "" && newArgs.DBTable != oldArgs.DBTable {
+		tables = append(tables, newArgs.DBTable)
+	}
+
+	opts := []parser.Option{
+		parser.WithDBDriver(newArgs.DBDriver),
+		parser.WithTables(tables...),
+	}
+	if newArgs.Charset != "" {
+		opts = append(opts, parser.WithCharset(newArgs.Charset))
+	}
+	if newArgs.Collation != "" {
+		opts = append(opts, parser.WithCollation(newArgs.Collation))
+	}
+
+	return parser.ParseMigrationSQL(sqls[0], sqls[1], opts...)
+}
@@ -1,8 +1,11 @@
 package sql2code
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/zhufuyi/sponge/pkg/sql2code/parser"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -152,3 +155,19 @@ func Test_getOptions(t *testing.T) {
 	a.NullStyle = "default"
 	assert.NotNil(t, o)
 }
+
+func TestGenerateMigration(t *testing.T) {
+	newSQL := strings.Replace(sqlData, "age        tinyint         not null comment 'age',",
+		"age        tinyint         not null comment 'age',\n    address    varchar(100)    not null comment 'address',", 1)
+
+	codes, err := GenerateMigration(&Args{SQL: sqlData}, &Args{SQL: newSQL, DBDriver: parser.DBDriverPostgresql})
+	assert.NoError(t, err)
+	assert.Contains(t, codes.Up, "address")
+	assert.Contains(t, codes.Down, "address")
+
+	_, err = GenerateMigration(&Args{SQL: sqlData}, nil)
+	assert.Error(t, err)
+
+	_, err = GenerateMigration(&Args{}, &Args{SQL: newSQL})
+	assert.Error(t, err)
+}
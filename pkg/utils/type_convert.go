@@ -1,6 +1,9 @@
 package utils
 
-import "strconv"
+import (
+	"fmt"
+	"strconv"
+)
 
 // StrToInt string to int
 func StrToInt(str string) int {
@@ -80,3 +83,18 @@ func Uint64ToStr(v uint64) string {
 func Int64ToStr(v int64) string {
 	return strconv.FormatInt(v, 10)
 }
+
+// AnyToStr any type to string, if the value implements fmt.Stringer, the String method is used
+func AnyToStr(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case uint64:
+		return strconv.FormatUint(val, 10)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case int:
+		return strconv.Itoa(val)
+	}
+	return fmt.Sprintf("%v", v)
+}
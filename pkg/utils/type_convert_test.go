@@ -75,3 +75,11 @@ func TestInt64ToStr(t *testing.T) {
 	val := Int64ToStr(1)
 	assert.Equal(t, "1", val)
 }
+
+func TestAnyToStr(t *testing.T) {
+	assert.Equal(t, "foo", AnyToStr("foo"))
+	assert.Equal(t, "1", AnyToStr(uint64(1)))
+	assert.Equal(t, "-1", AnyToStr(int64(-1)))
+	assert.Equal(t, "1", AnyToStr(1))
+	assert.Equal(t, "1.5", AnyToStr(1.5))
+}